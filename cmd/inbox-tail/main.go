@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	webhookredis "github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+/* inbox-tail - Standalone CLI tool to watch webhooks arriving on a route
+ * Usage: go run cmd/inbox-tail/main.go -route user-events [-redis redis://localhost:6379/0]
+ * Reads the route's FIFO and PubSub streams with XREAD, starting from the newest entry ($),
+ * without joining the consumer group: workers are unaffected and nothing is acknowledged
+ * Stop with Ctrl-C
+ */
+
+// blockTimeout bounds each XREAD so Ctrl-C is noticed promptly
+const blockTimeout = time.Second
+
+func main() {
+	routeID := flag.String("route", "", "route ID to tail (required)")
+	redisURL := flag.String("redis", envOr("REDIS_URL", "redis://localhost:6379/0"), "Redis URL (default: $REDIS_URL)")
+	flag.Parse()
+
+	if *routeID == "" {
+		fmt.Fprintln(os.Stderr, "Error: -route is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts, err := redis.ParseURL(*redisURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid Redis URL: %v\n", err)
+		os.Exit(1)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := tail(ctx, client, *routeID); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// tail prints every webhook added to the route's streams until ctx is cancelled
+func tail(ctx context.Context, client *redis.Client, routeID string) error {
+	// A route only uses one of them, but tailing both avoids needing routes.yaml
+	modes := []webhook.DeliveryMode{webhook.FIFO, webhook.PubSub}
+	streams := make([]string, len(modes))
+	for i, mode := range modes {
+		streams[i] = webhookredis.StreamKey(routeID, mode)
+	}
+
+	// Resolve $ once so entries added between two reads are not skipped
+	lastIDs := make([]string, len(streams))
+	for i, stream := range streams {
+		id, err := lastEntryID(ctx, client, stream)
+		if err != nil {
+			return err
+		}
+		lastIDs[i] = id
+	}
+
+	fmt.Printf("Tailing route %s (Ctrl-C to stop)\n", routeID)
+	fmt.Printf("%-20s %-8s %-36s %-24s %s\n", "RECEIVED", "MODE", "ID", "TYPE", "STATUS")
+
+	for ctx.Err() == nil {
+		result, err := client.XRead(ctx, &redis.XReadArgs{
+			Streams: append(append([]string{}, streams...), lastIDs...),
+			Block:   blockTimeout,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("reading streams: %w", err)
+		}
+
+		for _, stream := range result {
+			i := indexOf(streams, stream.Stream)
+			for _, msg := range stream.Messages {
+				lastIDs[i] = msg.ID
+				printEntry(ctx, client, modes[i], msg)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// lastEntryID returns the ID of the newest entry in a stream, or 0-0 when it is empty or missing
+func lastEntryID(ctx context.Context, client *redis.Client, stream string) (string, error) {
+	entries, err := client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", stream, err)
+	}
+	if len(entries) == 0 {
+		return "0-0", nil
+	}
+	return entries[0].ID, nil
+}
+
+// printEntry prints a stream entry with the event type from its payload and the webhook's current status
+func printEntry(ctx context.Context, client *redis.Client, mode webhook.DeliveryMode, msg redis.XMessage) {
+	id, _ := msg.Values["event_id"].(string)
+
+	// Raw payloads have no type; entries written by older versions carry the payload instead
+	eventType, _ := msg.Values["event_type"].(string)
+	if raw, ok := msg.Values["payload"].(string); ok {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal([]byte(raw), &event) == nil {
+			eventType = event.Type
+		}
+	}
+	if eventType == "" {
+		eventType = "-"
+	}
+
+	status, err := client.HGet(ctx, fmt.Sprintf("webhook:%s", id), "status").Result()
+	if err != nil {
+		status = "unknown"
+	}
+
+	fmt.Printf("%-20s %-8s %-36s %-24s %s\n", entryTime(msg.ID).Format("2006-01-02 15:04:05"), mode, id, eventType, status)
+}
+
+// entryTime returns when a stream entry was added, from the milliseconds part of its ID
+func entryTime(id string) time.Time {
+	var ms, seq int64
+	fmt.Sscanf(id, "%d-%d", &ms, &seq)
+	return time.UnixMilli(ms)
+}
+
+// indexOf returns the position of s in list
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// envOr returns the environment variable, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
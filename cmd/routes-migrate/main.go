@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+)
+
+/* routes-migrate - Standalone CLI tool to upgrade routes.yaml to the current schema
+ * Usage: go run cmd/routes-migrate/main.go [-w] [routes.yaml]
+ * Prints the migrated file, or rewrites it in place with -w; what changed is reported on stderr
+ * Comments are not kept. Run validate-routes afterwards to review the loaded routes
+ * Exit codes: 0 = migrated, 1 = the file could not be read, migrated or written
+ */
+
+func main() {
+	write := flag.Bool("w", false, "rewrite the file in place instead of printing it")
+	flag.Parse()
+
+	// Get routes file path from args or use default
+	routesFile := "routes.yaml"
+	if flag.NArg() > 0 {
+		routesFile = flag.Arg(0)
+	}
+
+	data, err := os.ReadFile(routesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading routes file: %v\n", err)
+		os.Exit(1)
+	}
+
+	migration, err := routes.Migrate(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ MIGRATION FAILED\n\n")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", line)
+		}
+		os.Exit(1)
+	}
+
+	if len(migration.Changes) == 0 {
+		fmt.Fprintf(os.Stderr, "✓ %s is up to date\n", routesFile)
+	} else {
+		fmt.Fprintf(os.Stderr, "Migrated %s (%d change(s)):\n", routesFile, len(migration.Changes))
+		for _, change := range migration.Changes {
+			fmt.Fprintf(os.Stderr, "  - %s\n", change)
+		}
+	}
+
+	if !*write {
+		os.Stdout.Write(migration.Output)
+		return
+	}
+	if err := os.WriteFile(routesFile, migration.Output, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing routes file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote %s\n", routesFile)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/marcelsud/webhook-inbox/config"
 	"github.com/marcelsud/webhook-inbox/routes"
 )
 
@@ -23,11 +25,15 @@ func main() {
 	fmt.Printf("Validating routes file: %s\n", routesFile)
 	fmt.Println(string(make([]byte, 50))) // separator line
 
-	// Create loader and attempt to load routes
-	loader := routes.NewLoader()
-	if err := loader.Load(routesFile); err != nil {
+	// Create loader and validate every route, reporting all failures at once
+	// require_signature routes may rely on the default signing secret of the environment
+	cfg := &config.Config{WebhookDefaultSigningSecret: os.Getenv("WEBHOOK_DEFAULT_SIGNING_SECRET")}
+	loader := routes.NewLoader(routes.WithConfig(cfg))
+	if err := loader.LoadStrict(routesFile); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ VALIDATION FAILED\n\n")
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", line)
+		}
 		os.Exit(1)
 	}
 
@@ -43,7 +49,7 @@ func main() {
 		fmt.Printf("   Parallelism:   %d\n", route.Parallelism)
 		fmt.Printf("   Max Retries:   %d\n", route.MaxRetries)
 		fmt.Printf("   Retry Backoff: %s\n", route.RetryBackoff)
-		fmt.Printf("   Expected Status: %d\n", route.ExpectedStatus)
+		fmt.Printf("   Expected Status: %s\n", strings.Join(route.AcceptedStatuses(), ", "))
 
 		if route.DeliveredTTLHours != nil {
 			fmt.Printf("   Delivered TTL: %d hours\n", *route.DeliveredTTLHours)
@@ -53,6 +59,14 @@ func main() {
 		}
 	}
 
+	// Warnings don't fail validation
+	if warnings := loader.Warnings(); len(warnings) > 0 {
+		fmt.Printf("\n⚠ %d warning(s):\n", len(warnings))
+		for _, warning := range warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+	}
+
 	fmt.Printf("\n✓ All routes are valid!\n")
 	os.Exit(0)
 }
@@ -0,0 +1,125 @@
+package chi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// reloadRoutesResponse represents the API response after reloading routes
+type reloadRoutesResponse struct {
+	RoutesLoaded int `json:"routes_loaded"`
+}
+
+// groupPositionRequest represents the body of a consumer group position change
+type groupPositionRequest struct {
+	ID string `json:"id"`
+}
+
+// groupPositionResponse represents the API response after moving a consumer group
+type groupPositionResponse struct {
+	RouteID string `json:"route_id"`
+	ID      string `json:"id"`
+}
+
+// routeStateResponse represents the API response after enabling or disabling a route
+type routeStateResponse struct {
+	RouteID string `json:"route_id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// requireAdminToken rejects requests without "Authorization: Bearer <token>"
+func requireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reloadRoutes handles POST /v1/admin/reload-routes
+// Invalid routes files are reported with 400 and leave the current routes in place
+func reloadRoutes(routeLoader *routes.Loader, routesFile string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loaded, err := routeLoader.Reload(routesFile)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_routes", fmt.Sprintf("reloading routes: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reloadRoutesResponse{RoutesLoaded: loaded}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// setGroupPosition handles POST /v1/admin/routes/:route_id/position
+// Workers of the route read the entries after the given ID next ("$" skips the backlog, "0" reprocesses everything)
+func setGroupPosition(positioner webhook.GroupPositioner, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+
+		route, err := routeLoader.Get(routeID)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		var request groupPositionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ID == "" {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", `body must be a JSON object with an "id"`)
+			return
+		}
+
+		err = positioner.SetGroupPosition(r.Context(), routeID, route.Mode, request.ID)
+		if errors.Is(err, webhook.ErrInvalidPosition) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_position", err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(groupPositionResponse{RouteID: routeID, ID: request.ID}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// setRouteEnabled handles POST /v1/admin/routes/:route_id/enable and /disable
+// Disabled routes keep accepting events; workers using routes.Loader.IsEnabled pause their deliveries
+func setRouteEnabled(routeLoader *routes.Loader, enabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+
+		route, err := routeLoader.SetEnabled(routeID, enabled)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(routeStateResponse{RouteID: routeID, Enabled: route.IsEnabled()}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package chi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadedRoutesYAML = `
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users-v2"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "billing"
+    target_url: "https://example.com/billing"
+    mode: "pubsub"
+    max_retries: 5
+    retry_backoff: "1000"
+    parallelism: 2
+`
+
+const invalidRoutesYAML = `
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 4
+  - route_id: "billing"
+    target_url: ""
+    mode: "pubsub"
+    max_retries: 5
+    retry_backoff: "1000"
+    parallelism: 2
+`
+
+func TestReloadRoutes(t *testing.T) {
+	const token = "s3cret"
+
+	// setup loads the handler test routes from a file that tests can rewrite
+	setup := func(t *testing.T) (*routes.Loader, string) {
+		path := filepath.Join(t.TempDir(), "routes.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(testRoutesYAML), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+		return loader, path
+	}
+
+	reload := func(t *testing.T, loader *routes.Loader, path, authorization string, opts ...httpchi.Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/reload-routes", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		opts = append([]httpchi.Option{httpchi.WithRoutesFile(path)}, opts...)
+		return DoRequest(t, mocks.NewUseCase(t), loader, req, opts...)
+	}
+
+	t.Run("success - swaps in the routes from the file", func(t *testing.T) {
+		loader, path := setup(t)
+		require.NoError(t, os.WriteFile(path, []byte(reloadedRoutesYAML), 0o644))
+
+		rec := reload(t, loader, path, "Bearer "+token, httpchi.WithAdminToken(token))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response map[string]int
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, 2, response["routes_loaded"])
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/users-v2", route.TargetURL)
+		assert.True(t, loader.Exists("billing"))
+		assert.False(t, loader.Exists("analytics"), "routes removed from the file are dropped")
+	})
+
+	t.Run("error - validation failure keeps the current routes", func(t *testing.T) {
+		loader, path := setup(t)
+		require.NoError(t, os.WriteFile(path, []byte(invalidRoutesYAML), 0o644))
+
+		rec := reload(t, loader, path, "Bearer "+token, httpchi.WithAdminToken(token))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "FIFO mode requires parallelism=1 for route user-events")
+		assert.Contains(t, rec.Body.String(), "target_url")
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/users", route.TargetURL)
+		assert.True(t, loader.Exists("analytics"))
+		assert.False(t, loader.Exists("billing"))
+	})
+
+	t.Run("error - missing or wrong token", func(t *testing.T) {
+		loader, path := setup(t)
+		require.NoError(t, os.WriteFile(path, []byte(reloadedRoutesYAML), 0o644))
+
+		for _, authorization := range []string{"", "Bearer wrong", token} {
+			rec := reload(t, loader, path, authorization, httpchi.WithAdminToken(token))
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+		}
+		assert.True(t, loader.Exists("analytics"), "routes must not be reloaded")
+	})
+
+	t.Run("not mounted without an admin token", func(t *testing.T) {
+		loader, path := setup(t)
+
+		rec := reload(t, loader, path, "Bearer "+token)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestSetGroupPosition(t *testing.T) {
+	const token = "s3cret"
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	post := func(t *testing.T, positioner webhook.GroupPositioner, routeID, body string, opts ...httpchi.Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/routes/"+routeID+"/position", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		opts = append([]httpchi.Option{httpchi.WithGroupPositioner(positioner)}, opts...)
+		return DoRequest(t, mocks.NewUseCase(t), loader, req, opts...)
+	}
+
+	t.Run("success - moves the route's group", func(t *testing.T) {
+		positioner := mocks.NewGroupPositioner(t)
+		positioner.On("SetGroupPosition", mock.Anything, "user-events", webhook.FIFO, "1700000000000-0").Return(nil).Once()
+
+		rec := post(t, positioner, "user-events", `{"id":"1700000000000-0"}`, httpchi.WithAdminToken(token))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"route_id":"user-events","id":"1700000000000-0"}`, rec.Body.String())
+	})
+
+	t.Run("error - unknown entry", func(t *testing.T) {
+		positioner := mocks.NewGroupPositioner(t)
+		positioner.On("SetGroupPosition", mock.Anything, "user-events", webhook.FIFO, "1-0").
+			Return(fmt.Errorf("%w: no entry 1-0 in the route's streams", webhook.ErrInvalidPosition)).Once()
+
+		rec := post(t, positioner, "user-events", `{"id":"1-0"}`, httpchi.WithAdminToken(token))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "no entry 1-0")
+	})
+
+	t.Run("error - storage failure", func(t *testing.T) {
+		positioner := mocks.NewGroupPositioner(t)
+		positioner.On("SetGroupPosition", mock.Anything, "user-events", webhook.FIFO, "$").Return(errors.New("connection refused")).Once()
+
+		rec := post(t, positioner, "user-events", `{"id":"$"}`, httpchi.WithAdminToken(token))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("error - missing id", func(t *testing.T) {
+		for _, body := range []string{``, `{}`, `{"id":""}`, `not json`} {
+			rec := post(t, mocks.NewGroupPositioner(t), "user-events", body, httpchi.WithAdminToken(token))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+
+	t.Run("error - route not found", func(t *testing.T) {
+		rec := post(t, mocks.NewGroupPositioner(t), "unknown", `{"id":"$"}`, httpchi.WithAdminToken(token))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("error - wrong token", func(t *testing.T) {
+		rec := post(t, mocks.NewGroupPositioner(t), "user-events", `{"id":"$"}`, httpchi.WithAdminToken("other"))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("not mounted without an admin token", func(t *testing.T) {
+		rec := post(t, mocks.NewGroupPositioner(t), "user-events", `{"id":"$"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestSetRouteEnabled(t *testing.T) {
+	const token = "s3cret"
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	post := func(t *testing.T, service webhook.UseCase, path string, opts ...httpchi.Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return DoRequest(t, service, loader, req, opts...)
+	}
+
+	// listed returns the enabled flag GET /v1/routes reports for a route
+	listed := func(t *testing.T, routeID string) bool {
+		rec := DoRequest(t, mocks.NewUseCase(t), loader, httptest.NewRequest(http.MethodGet, "/v1/routes", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var listing []struct {
+			RouteID string `json:"route_id"`
+			Enabled bool   `json:"enabled"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+		for _, route := range listing {
+			if route.RouteID == routeID {
+				return route.Enabled
+			}
+		}
+		t.Fatalf("route %s not listed", routeID)
+		return false
+	}
+
+	t.Run("success - disabled routes are reported and keep accepting events", func(t *testing.T) {
+		rec := post(t, mocks.NewUseCase(t), "/v1/admin/routes/user-events/disable", httpchi.WithAdminToken(token))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"route_id":"user-events","enabled":false}`, rec.Body.String())
+		assert.False(t, loader.IsEnabled("user-events"))
+		assert.False(t, listed(t, "user-events"))
+
+		service := mocks.NewUseCase(t)
+		body := StandardPayload("user.created")
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, mock.Anything).Return("evt-1", nil).Once()
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec = DoRequest(t, service, loader, req)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - enabling resumes the route", func(t *testing.T) {
+		rec := post(t, mocks.NewUseCase(t), "/v1/admin/routes/user-events/enable", httpchi.WithAdminToken(token))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"route_id":"user-events","enabled":true}`, rec.Body.String())
+		assert.True(t, listed(t, "user-events"))
+	})
+
+	t.Run("error - route not found", func(t *testing.T) {
+		rec := post(t, mocks.NewUseCase(t), "/v1/admin/routes/unknown/disable", httpchi.WithAdminToken(token))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("error - wrong token", func(t *testing.T) {
+		rec := post(t, mocks.NewUseCase(t), "/v1/admin/routes/user-events/disable", httpchi.WithAdminToken("other"))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.True(t, loader.IsEnabled("user-events"))
+	})
+
+	t.Run("not mounted without an admin token", func(t *testing.T) {
+		rec := post(t, mocks.NewUseCase(t), "/v1/admin/routes/user-events/disable")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,75 @@
+package chi
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* Ingestion backpressure for routes with max_queue_depth
+ * Events are rejected with 429 while a route's queue is at capacity, so a stalled
+ * target can't grow its stream until Redis runs out of memory
+ * Depths are cached for queueDepthTTL to avoid a Redis round trip per request,
+ * so a route may briefly overshoot its limit under bursts
+ */
+
+const (
+	queueDepthTTL       = time.Second     // How long a route's queue depth is reused
+	queueFullRetryAfter = 5 * time.Second // Retry-After sent with 429 responses
+)
+
+// queueDepths caches queue depths per route
+type queueDepths struct {
+	inspector webhook.QueueInspector
+
+	mu      sync.Mutex
+	entries map[string]queueDepth
+}
+
+// queueDepth is a cached depth and when it was read
+type queueDepth struct {
+	depth     int64
+	checkedAt time.Time
+}
+
+func newQueueDepths(inspector webhook.QueueInspector) *queueDepths {
+	return &queueDepths{
+		inspector: inspector,
+		entries:   make(map[string]queueDepth),
+	}
+}
+
+// full reports whether the route's queue holds at least MaxQueueDepth unacknowledged webhooks
+// Fails open: events are accepted when the depth cannot be read
+func (q *queueDepths) full(ctx context.Context, route *routes.Route) bool {
+	if q == nil || route.MaxQueueDepth <= 0 {
+		return false
+	}
+
+	q.mu.Lock()
+	cached, ok := q.entries[route.RouteID]
+	q.mu.Unlock()
+
+	if !ok || time.Since(cached.checkedAt) >= queueDepthTTL {
+		depth, err := q.inspector.QueueDepth(ctx, route.RouteID, route.Mode)
+		if err != nil {
+			return false
+		}
+		cached = queueDepth{depth: depth, checkedAt: time.Now()}
+
+		q.mu.Lock()
+		q.entries[route.RouteID] = cached
+		q.mu.Unlock()
+	}
+
+	return cached.depth >= int64(route.MaxQueueDepth)
+}
+
+// retryAfterSeconds is the Retry-After header value for a full queue
+func retryAfterSeconds() string {
+	return strconv.Itoa(int(queueFullRetryAfter.Seconds()))
+}
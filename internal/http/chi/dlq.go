@@ -0,0 +1,164 @@
+package chi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+const (
+	defaultDLQLimit = 50  // Page size when ?limit is not given
+	maxDLQLimit     = 500 // Upper bound for ?limit
+)
+
+// dlqEntryResponse represents a dead-lettered webhook in the API
+type dlqEntryResponse struct {
+	EventID    string          `json:"event_id"`
+	RouteID    string          `json:"route_id"`
+	Status     string          `json:"status"`
+	RetryCount int             `json:"retry_count"`
+	MaxRetries int             `json:"max_retries"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// dlqListResponse represents a page of the dead letter queue
+type dlqListResponse struct {
+	RouteID string             `json:"route_id"`
+	Offset  int                `json:"offset"`
+	Limit   int                `json:"limit"`
+	Events  []dlqEntryResponse `json:"events"`
+}
+
+// getDLQ handles GET /v1/routes/:route_id/dlq?offset=&limit=
+func getDLQ(dlq webhook.DeadLetterQueue, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		if !routeLoader.Exists(routeID) {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "offset must be a non-negative integer")
+			return
+		}
+		limit, err := queryInt(r, "limit", defaultDLQLimit)
+		if err != nil || limit < 1 || limit > maxDLQLimit {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("limit must be between 1 and %d", maxDLQLimit))
+			return
+		}
+
+		webhooks, err := dlq.ListDLQ(r.Context(), routeID, offset, limit)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		response := dlqListResponse{
+			RouteID: routeID,
+			Offset:  offset,
+			Limit:   limit,
+			Events:  make([]dlqEntryResponse, 0, len(webhooks)),
+		}
+		for _, wh := range webhooks {
+			response.Events = append(response.Events, dlqEntryResponse{
+				EventID:    wh.ID,
+				RouteID:    wh.RouteID,
+				Status:     wh.Status.String(),
+				RetryCount: wh.RetryCount,
+				MaxRetries: wh.MaxRetries,
+				Payload:    rawJSON(wh.Payload),
+				CreatedAt:  wh.CreatedAt,
+				UpdatedAt:  wh.UpdatedAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// replayDLQ handles POST /v1/routes/:route_id/dlq/:event_id/replay
+// The webhook is re-enqueued under a fresh event ID and removed from the DLQ
+func replayDLQ(webhookService webhook.UseCase, dlq webhook.DeadLetterQueue, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		eventID := chi.URLParam(r, "event_id")
+
+		route, err := routeLoader.Get(routeID)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		wh, err := dlq.GetDLQ(r.Context(), routeID, eventID)
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "event_not_found", fmt.Sprintf("event not found in DLQ: %s", eventID))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		newEventID, err := webhookService.Receive(
+			r.Context(),
+			routeID,
+			route.Mode,
+			wh.Payload,
+			wh.Headers,
+			route.MaxRetries,
+		)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		if err := dlq.RemoveFromDLQ(r.Context(), routeID, eventID); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := replayResponse{
+			EventID:      newEventID,
+			RouteID:      routeID,
+			ReplayedFrom: eventID,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// queryInt reads an integer query parameter, returning def when it is absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// rawJSON returns the payload as embedded JSON, or as a JSON string when it isn't valid JSON
+func rawJSON(payload []byte) json.RawMessage {
+	if json.Valid(payload) {
+		return payload
+	}
+	quoted, _ := json.Marshal(string(payload))
+	return quoted
+}
@@ -0,0 +1,143 @@
+package chi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDLQ(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - lists dead-lettered events", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		dlq.On("ListDLQ", mock.Anything, "user-events", 10, 5).Return([]webhook.Webhook{
+			{ID: "evt-1", RouteID: "user-events", Status: webhook.Failed, RetryCount: 3, MaxRetries: 3, Payload: []byte(`{"a":1}`)},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/dlq?offset=10&limit=5", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			RouteID string `json:"route_id"`
+			Offset  int    `json:"offset"`
+			Limit   int    `json:"limit"`
+			Events  []struct {
+				EventID string          `json:"event_id"`
+				Status  string          `json:"status"`
+				Payload json.RawMessage `json:"payload"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "user-events", body.RouteID)
+		assert.Equal(t, 10, body.Offset)
+		assert.Equal(t, 5, body.Limit)
+		require.Len(t, body.Events, 1)
+		assert.Equal(t, "evt-1", body.Events[0].EventID)
+		assert.Equal(t, "failed", body.Events[0].Status)
+		assert.JSONEq(t, `{"a":1}`, string(body.Events[0].Payload))
+	})
+
+	t.Run("default pagination", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		dlq.On("ListDLQ", mock.Anything, "user-events", 0, 50).Return([]webhook.Webhook{}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/dlq", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/dlq", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/dlq?limit=0", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("not mounted without a DLQ", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/dlq", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestReplayDLQ(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - re-enqueues with a new event id", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		original := webhook.Webhook{
+			ID:      "evt-1",
+			RouteID: "user-events",
+			Payload: []byte(StandardPayload("user.created")),
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Status:  webhook.Failed,
+		}
+		dlq.On("GetDLQ", mock.Anything, "user-events", "evt-1").Return(original, nil)
+		service.On("Receive", mock.Anything, "user-events", webhook.FIFO, original.Payload, original.Headers, 3).Return("evt-2", nil)
+		dlq.On("RemoveFromDLQ", mock.Anything, "user-events", "evt-1").Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/dlq/evt-1/replay", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-2","route_id":"user-events","replayed_from":"evt-1"}`, rec.Body.String())
+	})
+
+	t.Run("event not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		dlq.On("GetDLQ", mock.Anything, "user-events", "missing").
+			Return(webhook.Webhook{}, fmt.Errorf("%w: missing", webhook.ErrNotFound))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/dlq/missing/replay", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		dlq := mocks.NewDeadLetterQueue(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/unknown/dlq/evt-1/replay", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithDeadLetterQueue(dlq))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
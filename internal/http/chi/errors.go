@@ -0,0 +1,35 @@
+package chi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+/* Error responses
+ * Every handler reports errors as {"error":{"code":...,"message":...}} so JSON clients
+ * don't have to special-case plain-text bodies. Codes are stable identifiers to branch on;
+ * messages are for humans and may change
+ */
+
+// errorResponse is the body of an error response
+type errorResponse struct {
+	Error errorDetail `json:"error"`
+}
+
+// errorDetail describes what went wrong
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeJSONError writes an error response with the given status, code and message
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	// Messages quote user input, keep it readable
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(errorResponse{Error: errorDetail{Code: code, Message: message}})
+}
@@ -0,0 +1,101 @@
+package chi_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponses(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	postEvent := func(routeID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(service *mocks.UseCase)
+		req         *http.Request
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "400 - invalid payload",
+			req:         postEvent("user-events", `{"foo":"bar"}`),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "invalid_payload",
+			wantMessage: "invalid payload format",
+		},
+		{
+			name:        "404 - unknown route",
+			req:         postEvent("unknown", StandardPayload("user.created")),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "route_not_found",
+			wantMessage: "route not found: unknown",
+		},
+		{
+			name: "404 - unknown event",
+			setup: func(service *mocks.UseCase) {
+				service.On("Get", mock.Anything, "user-events", "evt-missing").Return(webhook.Webhook{}, webhook.ErrNotFound)
+			},
+			req:         httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events/evt-missing", nil),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "event_not_found",
+			wantMessage: "event not found: evt-missing",
+		},
+		{
+			name:        "404 - unknown endpoint",
+			req:         httptest.NewRequest(http.MethodGet, "/v2/nothing", nil),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "not_found",
+			wantMessage: "no such endpoint",
+		},
+		{
+			name:        "405 - method not allowed",
+			req:         httptest.NewRequest(http.MethodDelete, "/v1/routes/user-events/events", nil),
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantCode:    "method_not_allowed",
+			wantMessage: "method DELETE not allowed",
+		},
+		{
+			name: "500 - storage failure",
+			setup: func(service *mocks.UseCase) {
+				service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).
+					Return("", errors.New("redis unavailable"))
+			},
+			req:         postEvent("user-events", StandardPayload("user.created")),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    "internal_error",
+			wantMessage: "redis unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := mocks.NewUseCase(t)
+			if tt.setup != nil {
+				tt.setup(service)
+			}
+
+			rec := DoRequest(t, service, loader, tt.req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			code, message := DecodeError(t, rec)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Contains(t, message, tt.wantMessage)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// exportEntryResponse represents a single webhook line in an export
+type exportEntryResponse struct {
+	EventID      string            `json:"event_id"`
+	RouteID      string            `json:"route_id"`
+	DeliveryMode string            `json:"delivery_mode"`
+	Status       string            `json:"status"`
+	RetryCount   int               `json:"retry_count"`
+	MaxRetries   int               `json:"max_retries"`
+	Headers      map[string]string `json:"headers"`
+	Payload      json.RawMessage   `json:"payload"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// getExport handles GET /v1/routes/:route_id/export?from=&to=
+// Webhooks are written as newline-delimited JSON while they are read from the repository
+// from defaults to the Unix epoch and to defaults to now, both as RFC 3339 timestamps
+func getExport(exporter webhook.Exporter, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		if !routeLoader.Exists(routeID) {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		from, err := queryTime(r, "from", time.Unix(0, 0))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "from must be an RFC 3339 timestamp")
+			return
+		}
+		to, err := queryTime(r, "to", time.Now())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "to must be an RFC 3339 timestamp")
+			return
+		}
+		if to.Before(from) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "from must not be after to")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+
+		err = exporter.ExportEach(r.Context(), routeID, from, to, func(wh webhook.Webhook) error {
+			if err := encoder.Encode(exportEntryResponse{
+				EventID:      wh.ID,
+				RouteID:      wh.RouteID,
+				DeliveryMode: wh.DeliveryMode.String(),
+				Status:       wh.Status.String(),
+				RetryCount:   wh.RetryCount,
+				MaxRetries:   wh.MaxRetries,
+				Headers:      wh.Headers,
+				Payload:      rawJSON(wh.Payload),
+				CreatedAt:    wh.CreatedAt,
+				UpdatedAt:    wh.UpdatedAt,
+			}); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			// Headers are already sent once the first line is written, so the
+			// truncated body is the only signal left to the client
+			httplog.LogEntrySetField(r.Context(), "export_error", err.Error())
+		}
+	})
+}
+
+// queryTime reads an RFC 3339 query parameter, returning def when it is absent
+func queryTime(r *http.Request, name string, def time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,136 @@
+package chi_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// exportWebhooks makes an Exporter mock yield the given webhooks
+func exportWebhooks(webhooks ...webhook.Webhook) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		fn := args.Get(4).(func(webhook.Webhook) error)
+		for _, wh := range webhooks {
+			if err := fn(wh); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestGetExport(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success - streams newline-delimited JSON", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		exporter.On("ExportEach", mock.Anything, "user-events", from, to, mock.Anything).
+			Run(exportWebhooks(
+				webhook.Webhook{ID: "evt-1", RouteID: "user-events", Status: webhook.Delivered, Payload: []byte(`{"a":1}`)},
+				webhook.Webhook{ID: "evt-2", RouteID: "user-events", Status: webhook.Failed, Payload: []byte(`{"a":2}`)},
+			)).
+			Return(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export?from=2024-01-01T00:00:00Z&to=2024-01-31T00:00:00Z", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		require.Len(t, lines, 2)
+		assert.Equal(t, "evt-1", lines[0]["event_id"])
+		assert.Equal(t, "delivered", lines[0]["status"])
+		assert.Equal(t, "evt-2", lines[1]["event_id"])
+		assert.Equal(t, "failed", lines[1]["status"])
+	})
+
+	t.Run("default range ends now", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		exporter.On("ExportEach", mock.Anything, "user-events", time.Unix(0, 0), mock.MatchedBy(func(to time.Time) bool {
+			return time.Since(to) < time.Minute
+		}), mock.Anything).Return(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("error mid-stream truncates the body", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		exporter.On("ExportEach", mock.Anything, "user-events", from, to, mock.Anything).
+			Run(exportWebhooks(webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`)})).
+			Return(errors.New("redis unavailable"))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export?from=2024-01-01T00:00:00Z&to=2024-01-31T00:00:00Z", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "evt-1")
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/export", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid from", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export?from=yesterday", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("from after to", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := mocks.NewExporter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithExporter(exporter))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("not mounted without exporter", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/export", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,47 @@
+package chi
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+)
+
+// apiKeyHeader carries the key authenticating event ingestion
+const apiKeyHeader = "X-API-Key"
+
+/* requireIngestKey rejects events posted without an accepted X-API-Key
+ * A route's ingest_api_keys take precedence over the server-wide key; when neither is
+ * configured the route stays open. Unknown routes are passed on to answer 404
+ */
+func requireIngestKey(routeLoader *routes.Loader, globalKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, err := routeLoader.GetOrDefault(chi.URLParam(r, "route_id"))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keys := route.IngestAPIKeys
+			if len(keys) == 0 && globalKey != "" {
+				keys = []string{globalKey}
+			}
+			if len(keys) > 0 && !acceptedKey(r.Header.Get(apiKeyHeader), keys) {
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptedKey reports whether given matches one of keys, comparing every key in constant time
+func acceptedKey(given string, keys []string) bool {
+	accepted := 0
+	for _, key := range keys {
+		accepted |= subtle.ConstantTimeCompare([]byte(given), []byte(key))
+	}
+	return given != "" && accepted == 1
+}
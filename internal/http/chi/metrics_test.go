@@ -0,0 +1,51 @@
+package chi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/metrics"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - serves the exporter", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("webhook_queue_length 0\n"))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithMetrics(exporter))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "webhook_queue_length 0\n", rec.Body.String())
+	})
+
+	t.Run("disabled exporter answers 404", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		exporter, err := metrics.NewExporter(metrics.ExporterNone, nil)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithMetrics(exporter))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("not mounted without WithMetrics", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "not_found")
+	})
+}
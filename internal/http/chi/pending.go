@@ -0,0 +1,56 @@
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// pendingResponse represents a route's unacknowledged webhooks in the API
+type pendingResponse struct {
+	RouteID   string           `json:"route_id"`
+	Count     int64            `json:"count"`
+	LowestID  string           `json:"lowest_id,omitempty"`
+	HighestID string           `json:"highest_id,omitempty"`
+	Consumers map[string]int64 `json:"consumers"`
+}
+
+// getPending handles GET /v1/routes/:route_id/pending
+func getPending(inspector webhook.PendingInspector, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+
+		route, err := routeLoader.Get(routeID)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		info, err := inspector.PendingSummary(r.Context(), routeID, route.Mode)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		response := pendingResponse{
+			RouteID:   routeID,
+			Count:     info.Count,
+			LowestID:  info.LowestID,
+			HighestID: info.HighestID,
+			Consumers: info.Consumers,
+		}
+		if response.Consumers == nil {
+			response.Consumers = map[string]int64{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
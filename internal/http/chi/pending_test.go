@@ -0,0 +1,92 @@
+package chi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPending(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - summarizes pending webhooks", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		inspector := mocks.NewPendingInspector(t)
+
+		inspector.On("PendingSummary", mock.Anything, "user-events", webhook.FIFO).Return(webhook.PendingInfo{
+			Count:     3,
+			LowestID:  "1700000000000-0",
+			HighestID: "1700000000500-0",
+			Consumers: map[string]int64{"worker-a": 2, "worker-b": 1},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/pending", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithPendingInspector(inspector))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"route_id": "user-events",
+			"count": 3,
+			"lowest_id": "1700000000000-0",
+			"highest_id": "1700000000500-0",
+			"consumers": {"worker-a": 2, "worker-b": 1}
+		}`, rec.Body.String())
+	})
+
+	t.Run("nothing pending", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		inspector := mocks.NewPendingInspector(t)
+
+		inspector.On("PendingSummary", mock.Anything, "user-events", webhook.FIFO).Return(webhook.PendingInfo{}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/pending", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithPendingInspector(inspector))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, float64(0), body["count"])
+		assert.Equal(t, map[string]any{}, body["consumers"])
+		assert.NotContains(t, body, "lowest_id")
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		inspector := mocks.NewPendingInspector(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/pending", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithPendingInspector(inspector))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		inspector := mocks.NewPendingInspector(t)
+
+		inspector.On("PendingSummary", mock.Anything, "user-events", webhook.FIFO).Return(webhook.PendingInfo{}, errors.New("redis down"))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/pending", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithPendingInspector(inspector))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("not mounted without an inspector", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/pending", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
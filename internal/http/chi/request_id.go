@@ -0,0 +1,43 @@
+package chi
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// maxRequestIDLength bounds request IDs accepted from senders, as they are stored with every webhook
+const maxRequestIDLength = 128
+
+/* propagateRequestID gives every request an X-Request-ID and echoes it on the response
+ * A sender's ID is kept when it is valid, otherwise one is generated. The ID is put in the
+ * request header before chi's RequestID middleware reads it, so request logs use the same
+ * ID, and in the context so webhook.Service stores it with the webhook
+ */
+func propagateRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(middleware.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = rand.Text()
+			r.Header.Set(middleware.RequestIDHeader, requestID)
+		}
+
+		w.Header().Set(middleware.RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(webhook.ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// validRequestID reports whether id is non-empty, at most maxRequestIDLength long and printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
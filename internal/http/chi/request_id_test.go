@@ -0,0 +1,87 @@
+package chi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	postEvent := func(requestID string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/events", strings.NewReader(StandardPayload("user.created")))
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		return req
+	}
+
+	// expectReceive stores the event and captures the request ID the service was given
+	expectReceive := func(service *mocks.UseCase) *string {
+		var requestID string
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).
+			Return("evt-1", nil).
+			Run(func(args mock.Arguments) { requestID = webhook.RequestID(args.Get(0).(context.Context)) })
+		return &requestID
+	}
+
+	t.Run("echoes the sender's ID and stores it", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		stored := expectReceive(service)
+
+		rec := DoRequest(t, service, loader, postEvent("sender-req-42"))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "sender-req-42", rec.Header().Get("X-Request-ID"))
+		assert.Equal(t, "sender-req-42", *stored)
+	})
+
+	t.Run("generates an ID when absent", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		stored := expectReceive(service)
+
+		first := DoRequest(t, service, loader, postEvent(""))
+		firstStored := *stored
+		second := DoRequest(t, service, loader, postEvent(""))
+
+		require.Equal(t, http.StatusAccepted, first.Code)
+		generated := first.Header().Get("X-Request-ID")
+		assert.NotEmpty(t, generated)
+		assert.Equal(t, generated, firstStored)
+		assert.NotEqual(t, generated, second.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("replaces an invalid ID", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		stored := expectReceive(service)
+
+		rec := DoRequest(t, service, loader, postEvent(strings.Repeat("x", 200)))
+
+		generated := rec.Header().Get("X-Request-ID")
+		assert.NotEmpty(t, generated)
+		assert.Less(t, len(generated), 200)
+		assert.Equal(t, generated, *stored)
+	})
+
+	t.Run("echoed on error responses", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/events/evt-1", nil)
+		req.Header.Set("X-Request-ID", "sender-req-43")
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "sender-req-43", rec.Header().Get("X-Request-ID"))
+	})
+}
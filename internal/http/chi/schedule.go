@@ -0,0 +1,40 @@
+package chi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deliverAfterHeader delays delivery of an ingested webhook
+// It holds a delay in seconds ("3600") or an RFC 3339 time ("2025-01-01T09:00:00Z")
+const deliverAfterHeader = "X-Deliver-After"
+
+// parseDeliverAfter returns the earliest delivery time requested by the header
+// An empty value, or a time already past, means delivering immediately (zero time)
+func parseDeliverAfter(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return time.Time{}, fmt.Errorf("delay cannot be negative (got %d)", seconds)
+		}
+		if seconds == 0 {
+			return time.Time{}, nil
+		}
+		return now.Add(time.Duration(seconds) * time.Second), nil
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a number of seconds or an RFC 3339 time (got %q)", value)
+	}
+	if !at.After(now) {
+		return time.Time{}, nil
+	}
+	return at, nil
+}
@@ -0,0 +1,89 @@
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+const (
+	defaultSearchLimit = 50  // Page size when ?limit is not given
+	maxSearchLimit     = 500 // Upper bound for ?limit
+)
+
+// searchResponse represents a page of a route's events matching the search filters
+type searchResponse struct {
+	RouteID string            `json:"route_id"`
+	Offset  int               `json:"offset"`
+	Limit   int               `json:"limit"`
+	Events  []webhook.Webhook `json:"events"`
+}
+
+// searchWebhooks handles GET /v1/routes/:route_id/events?status=&since=&until=&offset=&limit=
+// since and until are RFC 3339 timestamps bounding the creation time (since inclusive, until exclusive)
+func searchWebhooks(searcher webhook.Searcher, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		if !routeLoader.Exists(routeID) {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		var opts webhook.SearchOptions
+		var err error
+		if value := r.URL.Query().Get("status"); value != "" {
+			opts.Status, err = webhook.NewStatusStrict(value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+		}
+
+		opts.CreatedAfter, err = queryTime(r, "since", time.Time{})
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "since must be an RFC 3339 timestamp")
+			return
+		}
+		opts.CreatedBefore, err = queryTime(r, "until", time.Time{})
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "until must be an RFC 3339 timestamp")
+			return
+		}
+		if !opts.CreatedAfter.IsZero() && !opts.CreatedBefore.IsZero() && opts.CreatedBefore.Before(opts.CreatedAfter) {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "since must not be after until")
+			return
+		}
+
+		opts.Offset, err = queryInt(r, "offset", 0)
+		if err != nil || opts.Offset < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "offset must be a non-negative integer")
+			return
+		}
+		opts.Limit, err = queryInt(r, "limit", defaultSearchLimit)
+		if err != nil || opts.Limit < 1 || opts.Limit > maxSearchLimit {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+
+		webhooks, err := searcher.Search(r.Context(), routeID, opts)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		if webhooks == nil {
+			webhooks = []webhook.Webhook{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := searchResponse{RouteID: routeID, Offset: opts.Offset, Limit: opts.Limit, Events: webhooks}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package chi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchWebhooks(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success - passes every filter", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		searcher.On("Search", mock.Anything, "user-events", mock.MatchedBy(func(opts webhook.SearchOptions) bool {
+			return opts.Status == webhook.Failed &&
+				opts.CreatedAfter.Equal(since) &&
+				opts.CreatedBefore.Equal(until) &&
+				opts.Offset == 10 && opts.Limit == 5
+		})).Return([]webhook.Webhook{
+			{ID: "evt-1", RouteID: "user-events", Status: webhook.Failed, DeliveryMode: webhook.FIFO, Payload: []byte(`{"a":1}`), CreatedAt: since},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet,
+			"/v1/routes/user-events/events?status=failed&since=2024-01-01T00:00:00Z&until=2024-02-01T00:00:00Z&offset=10&limit=5", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			RouteID string `json:"route_id"`
+			Offset  int    `json:"offset"`
+			Limit   int    `json:"limit"`
+			Events  []struct {
+				EventID string `json:"event_id"`
+				Status  string `json:"status"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "user-events", body.RouteID)
+		assert.Equal(t, 10, body.Offset)
+		assert.Equal(t, 5, body.Limit)
+		require.Len(t, body.Events, 1)
+		assert.Equal(t, "evt-1", body.Events[0].EventID)
+		assert.Equal(t, "failed", body.Events[0].Status)
+	})
+
+	t.Run("filters are optional", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		searcher.On("Search", mock.Anything, "user-events", webhook.SearchOptions{Limit: 50}).Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"route_id":"user-events","offset":0,"limit":50,"events":[]}`, rec.Body.String())
+	})
+
+	t.Run("status only", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		searcher.On("Search", mock.Anything, "user-events", webhook.SearchOptions{Status: webhook.Retrying, Limit: 50}).Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events?status=retrying", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("since only", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		searcher.On("Search", mock.Anything, "user-events", mock.MatchedBy(func(opts webhook.SearchOptions) bool {
+			return opts.Status == 0 && opts.CreatedAfter.Equal(since) && opts.CreatedBefore.IsZero()
+		})).Return(nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events?since=2024-01-01T00:00:00Z", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"status=lost",
+			"status=unknown",
+			"since=yesterday",
+			"until=2024-01-01",
+			"since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z",
+			"offset=-1",
+			"limit=0",
+			"limit=501",
+		} {
+			service := mocks.NewUseCase(t)
+			searcher := mocks.NewSearcher(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events?"+query, nil)
+			rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/events", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		searcher := mocks.NewSearcher(t)
+
+		searcher.On("Search", mock.Anything, "user-events", mock.Anything).Return(nil, errors.New("redis down"))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithSearcher(searcher))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
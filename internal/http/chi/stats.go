@@ -0,0 +1,41 @@
+package chi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// statsResponse represents a route's webhook counts in the API
+type statsResponse struct {
+	RouteID string           `json:"route_id"`
+	Counts  map[string]int64 `json:"counts"`
+}
+
+// getStats handles GET /v1/routes/:route_id/stats
+func getStats(counter webhook.StatusCounter, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+
+		if _, err := routeLoader.Get(routeID); err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		counts, err := counter.CountByStatus(r.Context(), routeID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statsResponse{RouteID: routeID, Counts: counts}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package chi_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStats(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - returns counts by status", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		counter := mocks.NewStatusCounter(t)
+
+		counter.On("CountByStatus", mock.Anything, "user-events").Return(map[string]int64{
+			"pending":    2,
+			"delivering": 1,
+			"delivered":  40,
+			"failed":     3,
+			"retrying":   0,
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/stats", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithStatusCounter(counter))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"route_id": "user-events",
+			"counts": {"pending": 2, "delivering": 1, "delivered": 40, "failed": 3, "retrying": 0}
+		}`, rec.Body.String())
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		counter := mocks.NewStatusCounter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/stats", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithStatusCounter(counter))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		counter := mocks.NewStatusCounter(t)
+
+		counter.On("CountByStatus", mock.Anything, "user-events").Return(nil, errors.New("redis down"))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/stats", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithStatusCounter(counter))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("not mounted without a counter", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/stats", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
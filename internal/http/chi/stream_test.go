@@ -0,0 +1,106 @@
+package chi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamStatus(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	t.Run("success - streams status changes as SSE frames", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		notifier := mocks.NewStatusNotifier(t)
+
+		events := make(chan webhook.StatusEvent)
+		unsubscribed := make(chan struct{})
+		notifier.On("SubscribeStatus", mock.Anything, "user-events").Return((<-chan webhook.StatusEvent)(events), nil).Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			go func() {
+				<-ctx.Done()
+				close(unsubscribed)
+			}()
+		})
+
+		server := httptest.NewServer(httpchi.WebhookHandlers(context.Background(), service, loader, httpchi.WithStatusNotifier(notifier)))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/routes/user-events/events/stream", nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		events <- webhook.StatusEvent{ID: "evt-1", RouteID: "user-events", Status: "delivered", At: at}
+
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "event: status\n", line)
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+
+		var event webhook.StatusEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &event))
+		assert.Equal(t, webhook.StatusEvent{ID: "evt-1", RouteID: "user-events", Status: "delivered", At: at}, event)
+
+		// Disconnecting ends the subscription
+		cancel()
+		select {
+		case <-unsubscribed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscription was not cancelled")
+		}
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		notifier := mocks.NewStatusNotifier(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/unknown/events/stream", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithStatusNotifier(notifier))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("other event routes are still served", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		notifier := mocks.NewStatusNotifier(t)
+
+		service.On("Get", mock.Anything, "user-events", "evt-1").Return(webhook.Webhook{}, webhook.ErrNotFound)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events/evt-1", nil)
+		rec := DoRequest(t, service, loader, req, httpchi.WithStatusNotifier(notifier))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("not mounted without a notifier", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Get", mock.Anything, "user-events", "stream").Return(webhook.Webhook{}, webhook.ErrNotFound)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/routes/user-events/events/stream", nil)
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
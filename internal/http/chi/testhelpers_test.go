@@ -0,0 +1,151 @@
+package chi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRoutesYAML defines the routes available to handler tests
+const testRoutesYAML = `
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "strict-events"
+    target_url: "https://example.com/strict"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_types: ["user.*"]
+    reject_unsubscribed: true
+  - route_id: "lenient-events"
+    target_url: "https://example.com/lenient"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_types: ["user.*"]
+  - route_id: "filtered-events"
+    target_url: "https://example.com/filtered"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_types: ["order.created", "user.*"]
+  - route_id: "raw-events"
+    target_url: "https://example.com/raw"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    accept_raw: true
+  - route_id: "raw-json"
+    target_url: "https://example.com/raw-json"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    payload_format: "raw"
+  - route_id: "allowlisted-headers"
+    target_url: "https://example.com/allowlisted"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    forward_headers: ["X-Request-Id", "authorization"]
+  - route_id: "bounded-events"
+    target_url: "https://example.com/bounded"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    max_queue_depth: 100
+  - route_id: "shallow-events"
+    target_url: "https://example.com/shallow"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    max_json_depth: 3
+  - route_id: "shallow-raw"
+    target_url: "https://example.com/shallow-raw"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    payload_format: "raw"
+    max_json_depth: 3
+  - route_id: "analytics"
+    target_url: "https://example.com/analytics"
+    mode: "pubsub"
+    max_retries: 5
+    retry_backoff: "1000"
+    parallelism: 5
+`
+
+// NewTestLoader loads routes from the given YAML content
+func NewTestLoader(t *testing.T, content string) *routes.Loader {
+	t.Helper()
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "routes-*.yaml")
+	require.NoError(t, err)
+
+	_, err = tmpFile.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, tmpFile.Close())
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(tmpFile.Name()))
+
+	return loader
+}
+
+// DoRequest sends a request through the webhook router and returns the recorded response
+func DoRequest(t *testing.T, service webhook.UseCase, loader *routes.Loader, req *http.Request, opts ...httpchi.Option) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := httpchi.WebhookHandlers(context.Background(), service, loader, opts...)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// StandardPayload returns a valid Standard Webhooks payload for the given event type
+func StandardPayload(eventType string) string {
+	return fmt.Sprintf(`{"type":%q,"timestamp":"2024-01-01T12:00:00Z","data":{"id":1}}`, eventType)
+}
+
+// DecodeError decodes a JSON error response, failing the test if the body has another shape
+func DecodeError(t *testing.T, rec *httptest.ResponseRecorder) (code, message string) {
+	t.Helper()
+
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	decoder := json.NewDecoder(rec.Body)
+	decoder.DisallowUnknownFields()
+	require.NoError(t, decoder.Decode(&body), "error response must be JSON")
+
+	return body.Error.Code, body.Error.Message
+}
@@ -2,9 +2,12 @@ package chi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/marcelsud/webhook-inbox/routes"
@@ -23,88 +26,340 @@ type webhookRequest struct {
 }
 
 // webhookResponse represents the API response when creating a webhook
+// RouteID is the route the webhook is stored under; RequestedRouteID is set when an unconfigured
+// route ID fell back to the default route
 type webhookResponse struct {
-	EventID string `json:"event_id"`
-	RouteID string `json:"route_id"`
+	EventID          string `json:"event_id"`
+	RouteID          string `json:"route_id"`
+	RequestedRouteID string `json:"requested_route_id,omitempty"`
+}
+
+// replayResponse represents the API response when replaying a webhook
+type replayResponse struct {
+	EventID      string `json:"event_id"`
+	RouteID      string `json:"route_id"`
+	ReplayedFrom string `json:"replayed_from"`
 }
 
 // routeResponse represents a route in the API
 type routeResponse struct {
-	RouteID        string `json:"route_id"`
-	TargetURL      string `json:"target_url"`
-	Mode           string `json:"mode"`
-	MaxRetries     int    `json:"max_retries"`
-	RetryBackoff   string `json:"retry_backoff"`
-	Parallelism    int    `json:"parallelism"`
-	ExpectedStatus int    `json:"expected_status"`
+	RouteID          string   `json:"route_id"`
+	TargetURL        string   `json:"target_url"`
+	Mode             string   `json:"mode"`
+	MaxRetries       int      `json:"max_retries"`
+	RetryBackoff     string   `json:"retry_backoff"`
+	Parallelism      int      `json:"parallelism"`
+	ExpectedStatus   int      `json:"expected_status"`
+	ExpectedStatuses []string `json:"expected_statuses"`
+	Enabled          bool     `json:"enabled"`
+}
+
+// matchResponse reports how a route's event type filter treats an event (dry_run=match)
+type matchResponse struct {
+	RouteID       string `json:"route_id"`
+	EventType     string `json:"event_type,omitempty"`
+	Delivered     bool   `json:"delivered"`
+	MatchedFilter string `json:"matched_filter,omitempty"`
 }
 
+// dryRunMatch is the dry_run value that validates an event and reports the filter it matches, without storing it
+const dryRunMatch = "match"
+
+// requestedRouteHeader carries the route ID an event was posted to when it fell back to the default route
+// It is stored with the webhook's headers, so the default route's target learns the original route
+const requestedRouteHeader = "X-Requested-Route-Id"
+
 // postWebhook handles POST /v1/routes/:route_id/events
-func postWebhook(webhookService webhook.UseCase, routeLoader *routes.Loader) http.Handler {
+// Routes whose queue is at max_queue_depth get 429 when depths is set
+// With ?dry_run=match the event is validated and matched against the route's event_types but not stored
+// Bodies larger than maxPayload bytes get 413 (0 = unlimited)
+func postWebhook(webhookService webhook.UseCase, routeLoader *routes.Loader, depths *queueDepths, maxPayload int) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		routeID := chi.URLParam(r, "route_id")
 		if routeID == "" {
-			http.Error(w, "route_id is required", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "route_id is required")
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dry_run")
+		if dryRun != "" && dryRun != dryRunMatch {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("dry_run must be %q", dryRunMatch))
+			return
+		}
+
+		// Check if route exists, unconfigured route IDs fall back to the default route if any
+		route, err := routeLoader.GetOrDefault(routeID)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		// Shed load before reading the body while consumers are far behind
+		if dryRun == "" && depths.full(r.Context(), route) {
+			w.Header().Set("Retry-After", retryAfterSeconds())
+			writeJSONError(w, http.StatusTooManyRequests, "queue_full", fmt.Sprintf("queue for route %s is full", routeID))
 			return
 		}
 
-		// Check if route exists
-		route, err := routeLoader.Get(routeID)
+		deliverAt, err := parseDeliverAfter(r.Header.Get(deliverAfterHeader), time.Now())
 		if err != nil {
-			http.Error(w, fmt.Sprintf("route not found: %s", routeID), http.StatusNotFound)
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("invalid %s header: %v", deliverAfterHeader, err))
 			return
 		}
 
-		// Read request body
+		// Read request body, stopping at the payload limit
+		if maxPayload > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, int64(maxPayload))
+		}
 		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("%v: limit is %d bytes", payload.ErrTooLarge, tooLarge.Limit))
+			return
+		}
 		if err != nil {
-			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
 			return
 		}
 		defer r.Body.Close()
 
-		// Validate Standard Webhooks payload format
-		if _, err := payload.Parse(body); err != nil {
-			http.Error(w, fmt.Sprintf("invalid payload format: %v (expected Standard Webhooks format with type, timestamp, and data)", err), http.StatusBadRequest)
+		// Raw passthrough routes store the body verbatim
+		if !route.AcceptRaw {
+			if !isJSONContentType(r.Header.Get("Content-Type")) {
+				writeJSONError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+				return
+			}
+
+			if route.PayloadFormat == routes.PayloadFormatRaw {
+				// Raw JSON is forwarded verbatim, without Standard Webhooks fields
+				if !json.Valid(body) {
+					writeJSONError(w, http.StatusBadRequest, "invalid_payload", "invalid payload format: body must be valid JSON")
+					return
+				}
+				if err := payload.CheckDepth(body, route.MaxJSONDepth); err != nil {
+					writeJSONError(w, http.StatusUnprocessableEntity, "invalid_payload", fmt.Sprintf("invalid payload: %v", err))
+					return
+				}
+			} else {
+				// Plain JSON bodies are typed from a header or JSON path and wrapped as Standard Webhooks payloads
+				if route.DerivesEventType() {
+					body, err = synthesizePayload(route, r.Header, body)
+					if err != nil {
+						writeJSONError(w, http.StatusBadRequest, "invalid_payload", fmt.Sprintf("invalid payload format: %v", err))
+						return
+					}
+				}
+
+				// Validate Standard Webhooks payload format
+				p, err := payload.Parse(body)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid_payload", fmt.Sprintf("invalid payload format: %v (expected Standard Webhooks format with type, timestamp, and data)", err))
+					return
+				}
+
+				if err := payload.CheckDepth(p.Data, route.MaxJSONDepth); err != nil {
+					writeJSONError(w, http.StatusUnprocessableEntity, "invalid_payload", fmt.Sprintf("invalid payload data: %v", err))
+					return
+				}
+
+				// Optionally reject event types the route doesn't subscribe to
+				if dryRun == "" && route.RejectUnsubscribed && !route.MatchesType(p.Type) {
+					writeJSONError(w, http.StatusUnprocessableEntity, "event_type_not_subscribed", fmt.Sprintf("event type %q is not subscribed by route %s", p.Type, routeID))
+					return
+				}
+			}
+		}
+
+		if dryRun == dryRunMatch {
+			writeMatch(w, route, body)
 			return
 		}
 
-		// Extract headers (optionally filter to only forward certain headers)
-		headers := make(map[string]string)
-		for key, values := range r.Header {
-			if len(values) > 0 {
-				headers[key] = values[0]
-			}
+		// Keep only the headers the route forwards to its target
+		// The scheduling header is meant for the inbox, not the target
+		headers := route.FilterHeaders(r.Header)
+		delete(headers, deliverAfterHeader)
+		delete(headers, requestedRouteHeader)
+
+		// Events for unconfigured routes are queued on the default route, keeping the requested route ID
+		response := webhookResponse{RouteID: route.RouteID}
+		if route.RouteID != routeID {
+			headers[requestedRouteHeader] = routeID
+			response.RequestedRouteID = routeID
 		}
 
 		// Create webhook
-		eventID, err := webhookService.Receive(
+		eventID, err := webhookService.ReceiveAt(
 			r.Context(),
-			routeID,
+			route.RouteID,
 			route.Mode,
 			body,
 			headers,
 			route.MaxRetries,
+			deliverAt,
 		)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
 			return
 		}
 
 		// Return 202 Accepted with event ID
 		w.WriteHeader(http.StatusAccepted)
-		response := webhookResponse{
-			EventID: eventID,
-			RouteID: routeID,
+		response.EventID = eventID
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// writeMatch reports whether a validated event would be delivered and which event_types entry matched
+// Mirrors the worker: raw routes and bodies that aren't Standard Webhooks are delivered unfiltered
+func writeMatch(w http.ResponseWriter, route *routes.Route, body []byte) {
+	response := matchResponse{RouteID: route.RouteID, Delivered: true}
+	if route.PayloadFormat != routes.PayloadFormatRaw {
+		if p, err := payload.Parse(body); err == nil {
+			response.EventType = p.Type
+			response.MatchedFilter, response.Delivered = p.MatchingFilter(route.EventTypes)
 		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// synthesizePayload wraps a plain JSON body as the data of a Standard Webhooks payload
+// The event type comes from the route's event_type_source and the timestamp is the time of receipt
+func synthesizePayload(route *routes.Route, header http.Header, body []byte) ([]byte, error) {
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("body must be valid JSON")
+	}
 
+	eventType, err := route.EventType(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := payload.New(eventType, json.RawMessage(body))
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes()
+}
+
+// getWebhook handles GET /v1/routes/:route_id/events/:event_id
+// The webhook is rendered in its canonical JSON form (see webhook.Webhook.MarshalJSON),
+// with an "attempts" array added when an attempt log is configured
+func getWebhook(webhookService webhook.UseCase, attempts webhook.AttemptLog, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		eventID := chi.URLParam(r, "event_id")
+
+		if !routeLoader.Exists(routeID) {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		wh, err := webhookService.Get(r.Context(), routeID, eventID)
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "event_not_found", fmt.Sprintf("event not found: %s", eventID))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		var response any = wh
+		if attempts != nil {
+			history, err := attempts.GetAttempts(r.Context(), eventID)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+				return
+			}
+			response, err = withAttempts(wh, history)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
 			return
 		}
 	})
 }
 
+// withAttempts adds the attempt history to the webhook's canonical JSON object
+func withAttempts(wh webhook.Webhook, attempts []webhook.Attempt) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(wh)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling webhook: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshaling webhook: %w", err)
+	}
+
+	if attempts == nil {
+		attempts = []webhook.Attempt{}
+	}
+	fields["attempts"], err = json.Marshal(attempts)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling attempts: %w", err)
+	}
+
+	return fields, nil
+}
+
+// replayWebhook handles POST /v1/routes/:route_id/events/:event_id/replay
+// Any stored webhook, whatever its status, is re-enqueued under a fresh event ID
+func replayWebhook(webhookService webhook.UseCase, routeLoader *routes.Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeID := chi.URLParam(r, "route_id")
+		eventID := chi.URLParam(r, "event_id")
+
+		if !routeLoader.Exists(routeID) {
+			writeJSONError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("route not found: %s", routeID))
+			return
+		}
+
+		newEventID, err := webhookService.Replay(r.Context(), routeID, eventID)
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "event_not_found", fmt.Sprintf("event not found: %s", eventID))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		response := replayResponse{
+			EventID:      newEventID,
+			RouteID:      routeID,
+			ReplayedFrom: eventID,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	})
+}
+
+// isJSONContentType reports whether a Content-Type header denotes JSON (parameters like charset are allowed)
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
 // getRoutes handles GET /v1/routes
 func getRoutes(routeLoader *routes.Loader) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -113,19 +368,21 @@ func getRoutes(routeLoader *routes.Loader) http.Handler {
 		responses := make([]routeResponse, 0, len(allRoutes))
 		for _, route := range allRoutes {
 			responses = append(responses, routeResponse{
-				RouteID:        route.RouteID,
-				TargetURL:      route.TargetURL,
-				Mode:           route.Mode.String(),
-				MaxRetries:     route.MaxRetries,
-				RetryBackoff:   route.RetryBackoff,
-				Parallelism:    route.Parallelism,
-				ExpectedStatus: route.ExpectedStatus,
+				RouteID:          route.RouteID,
+				TargetURL:        route.TargetURL,
+				Mode:             route.Mode.String(),
+				MaxRetries:       route.MaxRetries,
+				RetryBackoff:     route.RetryBackoff,
+				Parallelism:      route.Parallelism,
+				ExpectedStatus:   route.PrimaryExpectedStatus(),
+				ExpectedStatuses: route.AcceptedStatuses(),
+				Enabled:          route.IsEnabled(),
 			})
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(responses); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
 			return
 		}
 	})
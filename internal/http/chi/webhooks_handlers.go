@@ -2,6 +2,7 @@ package chi
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,34 +11,252 @@ import (
 	"github.com/go-chi/httplog"
 	"github.com/marcelsud/webhook-inbox/routes"
 	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"go.opentelemetry.io/otel/propagation"
 )
 
+// Option enables optional endpoints backed by additional dependencies
+type Option func(*handlerOptions)
+
+type handlerOptions struct {
+	dlq        webhook.DeadLetterQueue
+	exporter   webhook.Exporter
+	attempts   webhook.AttemptLog
+	adminToken string
+	ingestKey  string
+	routesFile string
+	queues     webhook.QueueInspector
+	pending    webhook.PendingInspector
+	searcher   webhook.Searcher
+	statuses   webhook.StatusNotifier
+	counter    webhook.StatusCounter
+	positioner webhook.GroupPositioner
+	metrics    http.Handler
+	maxPayload int
+}
+
+// WithDeadLetterQueue enables the DLQ inspection and replay endpoints
+func WithDeadLetterQueue(dlq webhook.DeadLetterQueue) Option {
+	return func(o *handlerOptions) {
+		o.dlq = dlq
+	}
+}
+
+// WithExporter enables the route export endpoint
+func WithExporter(exporter webhook.Exporter) Option {
+	return func(o *handlerOptions) {
+		o.exporter = exporter
+	}
+}
+
+// WithAttemptLog includes the delivery attempt history when inspecting an event
+func WithAttemptLog(attempts webhook.AttemptLog) Option {
+	return func(o *handlerOptions) {
+		o.attempts = attempts
+	}
+}
+
+// WithQueueInspector rejects events with 429 while a route's queue is at its max_queue_depth
+func WithQueueInspector(queues webhook.QueueInspector) Option {
+	return func(o *handlerOptions) {
+		o.queues = queues
+	}
+}
+
+// WithPendingInspector enables the endpoint listing a route's unacknowledged webhooks
+func WithPendingInspector(inspector webhook.PendingInspector) Option {
+	return func(o *handlerOptions) {
+		o.pending = inspector
+	}
+}
+
+// WithSearcher enables searching a route's events by status and creation time
+func WithSearcher(searcher webhook.Searcher) Option {
+	return func(o *handlerOptions) {
+		o.searcher = searcher
+	}
+}
+
+// WithStatusNotifier enables the Server-Sent Events stream of a route's status transitions
+func WithStatusNotifier(notifier webhook.StatusNotifier) Option {
+	return func(o *handlerOptions) {
+		o.statuses = notifier
+	}
+}
+
+// WithStatusCounter enables the endpoint reporting a route's webhook counts by status
+func WithStatusCounter(counter webhook.StatusCounter) Option {
+	return func(o *handlerOptions) {
+		o.counter = counter
+	}
+}
+
+// WithAdminToken enables the /v1/admin endpoints, authenticated with "Authorization: Bearer <token>"
+func WithAdminToken(token string) Option {
+	return func(o *handlerOptions) {
+		o.adminToken = token
+	}
+}
+
+// WithIngestAPIKey requires "X-API-Key: <key>" when posting events to routes without ingest_api_keys
+// Routes with their own keys only accept those
+func WithIngestAPIKey(key string) Option {
+	return func(o *handlerOptions) {
+		o.ingestKey = key
+	}
+}
+
+// WithRoutesFile enables POST /v1/admin/reload-routes, which reloads routes from this file
+// Requires WithAdminToken
+func WithRoutesFile(path string) Option {
+	return func(o *handlerOptions) {
+		o.routesFile = path
+	}
+}
+
+// WithGroupPositioner enables POST /v1/admin/routes/{route_id}/position, which moves a route's consumer group
+// Requires WithAdminToken
+func WithGroupPositioner(positioner webhook.GroupPositioner) Option {
+	return func(o *handlerOptions) {
+		o.positioner = positioner
+	}
+}
+
+// WithMaxPayloadBytes rejects event bodies larger than max bytes with 413, typically cfg.GetMaxPayloadBytes()
+// Without it payload.DefaultMaxBytes applies; 0 lifts the limit
+func WithMaxPayloadBytes(max int) Option {
+	return func(o *handlerOptions) {
+		o.maxPayload = max
+	}
+}
+
+// WithMetrics mounts GET /metrics, typically metrics.NewExporter(cfg.GetMetricsExporter(), collector)
+// so METRICS_EXPORTER selects the format; the caller shuts the exporter down
+func WithMetrics(handler http.Handler) Option {
+	return func(o *handlerOptions) {
+		o.metrics = handler
+	}
+}
+
 // WebhookHandlers sets up the webhook API routes
-func WebhookHandlers(ctx context.Context, webhookService webhook.UseCase, routeLoader *routes.Loader) *chi.Mux {
+// Endpoints that need extra dependencies are only mounted when the matching Option is given
+func WebhookHandlers(ctx context.Context, webhookService webhook.UseCase, routeLoader *routes.Loader, opts ...Option) *chi.Mux {
+	options := handlerOptions{maxPayload: payload.DefaultMaxBytes}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var depths *queueDepths
+	if options.queues != nil {
+		depths = newQueueDepths(options.queues)
+	}
+
 	logger := httplog.NewLogger("webhook-api", httplog.Options{
 		JSON: true,
 	})
 
 	r := chi.NewRouter()
+	r.Use(propagateRequestID)
 	r.Use(httplog.RequestLogger(logger))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(extractTraceContext)
 
-	// Health check
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
+	// Unknown paths and methods get JSON errors too
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "not_found", "no such endpoint")
 	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("method %s not allowed", r.Method))
+	})
+
+	// Long-lived streams are exempt from the request timeout
+	if options.statuses != nil {
+		r.Get("/v1/routes/{route_id}/events/stream", streamStatus(options.statuses, routeLoader).ServeHTTP)
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(30 * time.Second))
+
+		// Health check
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"healthy"}`))
+		})
+
+		// Metrics in the format chosen by METRICS_EXPORTER
+		if options.metrics != nil {
+			r.Method(http.MethodGet, "/metrics", options.metrics)
+		}
+
+		// Webhook API routes
+		r.Route("/v1", func(r chi.Router) {
+			// List available routes
+			r.Get("/routes", getRoutes(routeLoader).ServeHTTP)
 
-	// Webhook API routes
-	r.Route("/v1", func(r chi.Router) {
-		// List available routes
-		r.Get("/routes", getRoutes(routeLoader).ServeHTTP)
+			// Send event to route
+			r.With(requireIngestKey(routeLoader, options.ingestKey)).
+				Post("/routes/{route_id}/events", postWebhook(webhookService, routeLoader, depths, options.maxPayload).ServeHTTP)
 
-		// Send event to route
-		r.Post("/routes/{route_id}/events", postWebhook(webhookService, routeLoader).ServeHTTP)
+			// Search a route's events by status and creation time
+			if options.searcher != nil {
+				r.Get("/routes/{route_id}/events", searchWebhooks(options.searcher, routeLoader).ServeHTTP)
+			}
+
+			// Inspect a stored event
+			r.Get("/routes/{route_id}/events/{event_id}", getWebhook(webhookService, options.attempts, routeLoader).ServeHTTP)
+
+			// Re-deliver a previously received event under a new event ID
+			r.Post("/routes/{route_id}/events/{event_id}/replay", replayWebhook(webhookService, routeLoader).ServeHTTP)
+
+			// Dead letter queue inspection and replay
+			if options.dlq != nil {
+				r.Get("/routes/{route_id}/dlq", getDLQ(options.dlq, routeLoader).ServeHTTP)
+				r.Post("/routes/{route_id}/dlq/{event_id}/replay", replayDLQ(webhookService, options.dlq, routeLoader).ServeHTTP)
+			}
+
+			// Webhooks read by workers but never acknowledged, e.g. held by a crashed worker
+			if options.pending != nil {
+				r.Get("/routes/{route_id}/pending", getPending(options.pending, routeLoader).ServeHTTP)
+			}
+
+			// Webhook counts by status, read from counters for dashboard badges
+			if options.counter != nil {
+				r.Get("/routes/{route_id}/stats", getStats(options.counter, routeLoader).ServeHTTP)
+			}
+
+			// Audit export of a route's webhooks as newline-delimited JSON
+			if options.exporter != nil {
+				r.Get("/routes/{route_id}/export", getExport(options.exporter, routeLoader).ServeHTTP)
+			}
+
+			// Operational endpoints, never mounted without a token
+			if options.adminToken != "" {
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(requireAdminToken(options.adminToken))
+
+					if options.routesFile != "" {
+						r.Post("/reload-routes", reloadRoutes(routeLoader, options.routesFile).ServeHTTP)
+					}
+					if options.positioner != nil {
+						r.Post("/routes/{route_id}/position", setGroupPosition(options.positioner, routeLoader).ServeHTTP)
+					}
+
+					// Pause or resume a route's deliveries; events are still accepted meanwhile
+					r.Post("/routes/{route_id}/enable", setRouteEnabled(routeLoader, true).ServeHTTP)
+					r.Post("/routes/{route_id}/disable", setRouteEnabled(routeLoader, false).ServeHTTP)
+				})
+			}
+		})
 	})
 
 	return r
 }
+
+// extractTraceContext continues the sender's trace when a request carries a W3C traceparent header
+func extractTraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
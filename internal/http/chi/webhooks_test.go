@@ -0,0 +1,1049 @@
+package chi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPostWebhook(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	newRequest := func(routeID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("success - stores event", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := StandardPayload("user.created")
+
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", body))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-1","route_id":"user-events"}`, rec.Body.String())
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("unknown", StandardPayload("user.created")))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid payload format", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", `{"foo":"bar"}`))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("reject unsubscribed - unmatched type returns 422", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("strict-events", StandardPayload("order.created")))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		code, message := DecodeError(t, rec)
+		assert.Equal(t, "event_type_not_subscribed", code)
+		assert.Contains(t, message, `event type "order.created" is not subscribed`)
+	})
+
+	t.Run("reject unsubscribed - matched type is stored", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "strict-events", webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).Return("evt-2", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("strict-events", StandardPayload("user.created")))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("store then filter - unmatched type is still stored", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "lenient-events", webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).Return("evt-3", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("lenient-events", StandardPayload("order.created")))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+}
+
+func TestPostWebhook_DryRunMatch(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	dryRun := func(routeID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events?dry_run=match", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		routeID  string
+		body     string
+		wantJSON string
+	}{
+		{
+			name:     "exact filter",
+			routeID:  "filtered-events",
+			body:     StandardPayload("order.created"),
+			wantJSON: `{"route_id":"filtered-events","event_type":"order.created","delivered":true,"matched_filter":"order.created"}`,
+		},
+		{
+			name:     "wildcard filter",
+			routeID:  "filtered-events",
+			body:     StandardPayload("user.deleted"),
+			wantJSON: `{"route_id":"filtered-events","event_type":"user.deleted","delivered":true,"matched_filter":"user.*"}`,
+		},
+		{
+			name:     "no match",
+			routeID:  "filtered-events",
+			body:     StandardPayload("invoice.paid"),
+			wantJSON: `{"route_id":"filtered-events","event_type":"invoice.paid","delivered":false}`,
+		},
+		{
+			name:     "no match on a rejecting route is reported, not rejected",
+			routeID:  "strict-events",
+			body:     StandardPayload("order.created"),
+			wantJSON: `{"route_id":"strict-events","event_type":"order.created","delivered":false}`,
+		},
+		{
+			name:     "route without filters",
+			routeID:  "user-events",
+			body:     StandardPayload("order.created"),
+			wantJSON: `{"route_id":"user-events","event_type":"order.created","delivered":true}`,
+		},
+		{
+			name:     "raw route is delivered unfiltered",
+			routeID:  "raw-json",
+			body:     `{"anything":true}`,
+			wantJSON: `{"route_id":"raw-json","delivered":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing is stored: the mock fails the test on any call
+			service := mocks.NewUseCase(t)
+
+			rec := DoRequest(t, service, loader, dryRun(tt.routeID, tt.body))
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, tt.wantJSON, rec.Body.String())
+		})
+	}
+
+	t.Run("invalid payloads are still rejected", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, dryRun("filtered-events", `{"foo":"bar"}`))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown dry_run mode", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/filtered-events/events?dry_run=store", strings.NewReader(StandardPayload("order.created")))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := DoRequest(t, service, loader, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		code, _ := DecodeError(t, rec)
+		assert.Equal(t, "invalid_request", code)
+	})
+}
+
+func TestPostWebhook_DefaultRoute(t *testing.T) {
+	loader := NewTestLoader(t, `
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "catch-all"
+    target_url: "https://example.com/catch-all"
+    mode: "pubsub"
+    max_retries: 5
+    retry_backoff: "1000"
+    parallelism: 2
+    is_default: true
+`)
+
+	newRequest := func(routeID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("success - configured route is used when it matches", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := StandardPayload("user.created")
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", body))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-1","route_id":"user-events"}`, rec.Body.String())
+	})
+
+	t.Run("success - unconfigured route is queued on the default route", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := StandardPayload("tenant.created")
+		requestedRoute := mock.MatchedBy(func(headers map[string]string) bool {
+			return headers["X-Requested-Route-Id"] == "tenant-42"
+		})
+		service.On("ReceiveAt", mock.Anything, "catch-all", webhook.PubSub, []byte(body), requestedRoute, 5, time.Time{}).Return("evt-2", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("tenant-42", body))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-2","route_id":"catch-all","requested_route_id":"tenant-42"}`, rec.Body.String())
+	})
+
+	t.Run("success - a spoofed requested route header is replaced", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := StandardPayload("user.created")
+		noRequestedRoute := mock.MatchedBy(func(headers map[string]string) bool {
+			_, ok := headers["X-Requested-Route-Id"]
+			return !ok
+		})
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), noRequestedRoute, 3, time.Time{}).Return("evt-3", nil)
+
+		req := newRequest("user-events", body)
+		req.Header.Set("X-Requested-Route-Id", "tenant-42")
+		rec := DoRequest(t, service, loader, req)
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-3","route_id":"user-events"}`, rec.Body.String())
+	})
+
+	t.Run("route not found without a default route", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, NewTestLoader(t, testRoutesYAML), newRequest("tenant-42", StandardPayload("tenant.created")))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		service.AssertNotCalled(t, "ReceiveAt", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+func TestPostWebhook_ContentType(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	newRequest := func(routeID, contentType, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	t.Run("JSON with charset is accepted", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "application/json; charset=utf-8", StandardPayload("user.created")))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("missing content type returns 415", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "", StandardPayload("user.created")))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("non-JSON content type returns 415", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "text/plain", StandardPayload("user.created")))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("raw route stores any body verbatim", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := "field=value&other=1"
+		service.On("ReceiveAt", mock.Anything, "raw-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-2", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("raw-events", "application/x-www-form-urlencoded", body))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("raw route skips Standard Webhooks validation", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := `{"event":"legacy"}`
+		service.On("ReceiveAt", mock.Anything, "raw-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-3", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("raw-events", "", body))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+}
+
+func TestPostWebhook_RawPayloadFormat(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/raw-json/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("success - stores arbitrary JSON verbatim", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		body := `{"action":"opened","repository":{"id":42}}`
+		service.On("ReceiveAt", mock.Anything, "raw-json", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest(body))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest(`{"action":`))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("still requires JSON content type", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		req := newRequest(`{"action":"opened"}`)
+		req.Header.Set("Content-Type", "text/plain")
+
+		rec := DoRequest(t, service, loader, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+}
+
+func TestPostWebhook_ForwardHeaders(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	body := `{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`
+
+	newRequest := func(routeID string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Cookie", "session=abc")
+		req.Header.Set("Connection", "keep-alive, X-Hop")
+		req.Header.Set("X-Hop", "1")
+		req.Header.Set("X-Request-Id", "req-1")
+		return req
+	}
+
+	t.Run("success - strips credentials and hop-by-hop headers by default", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.MatchedBy(func(headers map[string]string) bool {
+			_, auth := headers["Authorization"]
+			_, cookie := headers["Cookie"]
+			_, connection := headers["Connection"]
+			_, hop := headers["X-Hop"]
+			return !auth && !cookie && !connection && !hop && headers["X-Request-Id"] == "req-1" && headers["Content-Type"] == "application/json"
+		}), 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events"))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - allow-list forwards only listed headers", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		expected := map[string]string{"X-Request-Id": "req-1", "Authorization": "Bearer secret"}
+		service.On("ReceiveAt", mock.Anything, "allowlisted-headers", webhook.FIFO, []byte(body), expected, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("allowlisted-headers"))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+}
+
+func TestPostWebhook_QueueDepth(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	body := StandardPayload("user.created")
+
+	newRequest := func(routeID string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("over-depth queue returns 429 with Retry-After", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		queues := mocks.NewQueueInspector(t)
+		queues.On("QueueDepth", mock.Anything, "bounded-events", webhook.FIFO).Return(int64(150), nil)
+
+		rec := DoRequest(t, service, loader, newRequest("bounded-events"), httpchi.WithQueueInspector(queues))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), "queue for route bounded-events is full")
+	})
+
+	t.Run("queue at exactly max_queue_depth is full", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		queues := mocks.NewQueueInspector(t)
+		queues.On("QueueDepth", mock.Anything, "bounded-events", webhook.FIFO).Return(int64(100), nil)
+
+		rec := DoRequest(t, service, loader, newRequest("bounded-events"), httpchi.WithQueueInspector(queues))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	t.Run("success - queue below max_queue_depth", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "bounded-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+		queues := mocks.NewQueueInspector(t)
+		queues.On("QueueDepth", mock.Anything, "bounded-events", webhook.FIFO).Return(int64(99), nil)
+
+		rec := DoRequest(t, service, loader, newRequest("bounded-events"), httpchi.WithQueueInspector(queues))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - routes without max_queue_depth are never checked", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+		queues := mocks.NewQueueInspector(t)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events"), httpchi.WithQueueInspector(queues))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - accepted when the depth cannot be read", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "bounded-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+		queues := mocks.NewQueueInspector(t)
+		queues.On("QueueDepth", mock.Anything, "bounded-events", webhook.FIFO).Return(int64(0), errors.New("connection refused"))
+
+		rec := DoRequest(t, service, loader, newRequest("bounded-events"), httpchi.WithQueueInspector(queues))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("depth is cached between requests", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		queues := mocks.NewQueueInspector(t)
+		queues.On("QueueDepth", mock.Anything, "bounded-events", webhook.FIFO).Return(int64(150), nil).Once()
+
+		router := httpchi.WebhookHandlers(context.Background(), service, loader, httpchi.WithQueueInspector(queues))
+		for i := 0; i < 3; i++ {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newRequest("bounded-events"))
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		}
+	})
+}
+
+func TestPostWebhook_DeliverAfter(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	body := StandardPayload("user.created")
+
+	newRequest := func(deliverAfter string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Deliver-After", deliverAfter)
+		return req
+	}
+	notForwarded := mock.MatchedBy(func(headers map[string]string) bool {
+		_, ok := headers["X-Deliver-After"]
+		return !ok
+	})
+
+	t.Run("success - delay in seconds", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		before := time.Now()
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), notForwarded, 3, mock.MatchedBy(func(at time.Time) bool {
+			return !at.Before(before.Add(time.Hour)) && !at.After(time.Now().Add(time.Hour))
+		})).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("3600"))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - RFC 3339 time", func(t *testing.T) {
+		at := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), notForwarded, 3, mock.MatchedBy(at.Equal)).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest(at.Format(time.RFC3339)))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("success - past time delivers immediately", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), notForwarded, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("2020-01-01T00:00:00Z"))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	for _, value := range []string{"-5", "tomorrow", "2025-13-01"} {
+		t.Run("error - invalid value "+value, func(t *testing.T) {
+			service := mocks.NewUseCase(t)
+
+			rec := DoRequest(t, service, loader, newRequest(value))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "invalid X-Deliver-After header")
+		})
+	}
+}
+
+func TestPostWebhook_TraceContext(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	body := StandardPayload("user.created")
+
+	service := mocks.NewUseCase(t)
+	service.On("ReceiveAt", mock.MatchedBy(func(ctx context.Context) bool {
+		sc := trace.SpanContextFromContext(ctx)
+		return sc.IsRemote() && sc.TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" && sc.SpanID().String() == "00f067aa0ba902b7"
+	}), "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/events", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	rec := DoRequest(t, service, loader, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestReplayWebhook(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	newRequest := func(routeID, eventID string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events/"+eventID+"/replay", nil)
+	}
+
+	t.Run("success - returns new event ID", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Replay", mock.Anything, "user-events", "evt-1").Return("evt-2", nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "evt-1"))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		assert.JSONEq(t, `{"event_id":"evt-2","route_id":"user-events","replayed_from":"evt-1"}`, rec.Body.String())
+	})
+
+	t.Run("event not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Replay", mock.Anything, "user-events", "missing").Return("", fmt.Errorf("getting webhook: %w", webhook.ErrNotFound))
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "missing"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("unknown", "evt-1"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Replay", mock.Anything, "user-events", "evt-1").Return("", errors.New("redis unavailable"))
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "evt-1"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestGetWebhook(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	newRequest := func(routeID, eventID string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/v1/routes/"+routeID+"/events/"+eventID, nil)
+	}
+
+	t.Run("success - renders canonical JSON", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Get", mock.Anything, "user-events", "evt-1").Return(webhook.Webhook{
+			ID:           "evt-1",
+			RouteID:      "user-events",
+			Payload:      []byte(`{"a":1}`),
+			Status:       webhook.Delivered,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			UpdatedAt:    time.Date(2024, 1, 1, 12, 0, 5, 0, time.UTC),
+		}, nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "evt-1"))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"event_id": "evt-1",
+			"route_id": "user-events",
+			"payload": {"a": 1},
+			"headers": null,
+			"status": "delivered",
+			"retry_count": 0,
+			"max_retries": 0,
+			"delivery_mode": "fifo",
+			"created_at": "2024-01-01T12:00:00Z",
+			"updated_at": "2024-01-01T12:00:05Z"
+		}`, rec.Body.String())
+	})
+
+	t.Run("success - includes attempt history", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Get", mock.Anything, "user-events", "evt-1").Return(webhook.Webhook{
+			ID:           "evt-1",
+			RouteID:      "user-events",
+			Payload:      []byte(`{"a":1}`),
+			Status:       webhook.Delivered,
+			RetryCount:   1,
+			DeliveryMode: webhook.FIFO,
+		}, nil)
+
+		attempts := mocks.NewAttemptLog(t)
+		attempts.On("GetAttempts", mock.Anything, "evt-1").Return([]webhook.Attempt{
+			{Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), StatusCode: 503, Error: "webhook delivery failed with status: 503", LatencyMs: 120},
+			{Timestamp: time.Date(2024, 1, 1, 12, 0, 1, 0, time.UTC), StatusCode: 202, LatencyMs: 80},
+		}, nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "evt-1"), httpchi.WithAttemptLog(attempts))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			EventID  string            `json:"event_id"`
+			Attempts []webhook.Attempt `json:"attempts"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "evt-1", body.EventID)
+		require.Len(t, body.Attempts, 2)
+		assert.Equal(t, 503, body.Attempts[0].StatusCode)
+		assert.Equal(t, 202, body.Attempts[1].StatusCode)
+		assert.Empty(t, body.Attempts[1].Error)
+	})
+
+	t.Run("success - empty attempt history", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Get", mock.Anything, "user-events", "evt-1").Return(webhook.Webhook{ID: "evt-1", RouteID: "user-events"}, nil)
+
+		attempts := mocks.NewAttemptLog(t)
+		attempts.On("GetAttempts", mock.Anything, "evt-1").Return([]webhook.Attempt{}, nil)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "evt-1"), httpchi.WithAttemptLog(attempts))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"attempts":[]`)
+	})
+
+	t.Run("event not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("Get", mock.Anything, "user-events", "missing").Return(webhook.Webhook{}, webhook.ErrNotFound)
+
+		rec := DoRequest(t, service, loader, newRequest("user-events", "missing"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("route not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := DoRequest(t, service, loader, newRequest("unknown", "evt-1"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestPostWebhook_MaxJSONDepth(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+
+	post := func(t *testing.T, service *mocks.UseCase, routeID, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return DoRequest(t, service, loader, req)
+	}
+	standard := func(data string) string {
+		return fmt.Sprintf(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":%s}`, data)
+	}
+
+	t.Run("success - data within the limit", func(t *testing.T) {
+		body := standard(`{"user":{"tags":["a","b"]}}`)
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "shallow-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, "shallow-events", body)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("deeply nested data is rejected", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "shallow-events", standard(`{"a":{"b":{"c":{"d":1}}}}`))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "too deeply nested")
+	})
+
+	t.Run("envelope does not count toward the limit", func(t *testing.T) {
+		// Three levels of data inside the payload object
+		body := standard(`{"a":{"b":[1]}}`)
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "shallow-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, "shallow-events", body)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("routes without a limit accept deep data", func(t *testing.T) {
+		body := standard(strings.Repeat(`[`, 50) + strings.Repeat(`]`, 50))
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, "user-events", body)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("raw payloads check the whole body", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "shallow-raw", webhook.FIFO, []byte(`{"a":{"b":[1]}}`), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, "shallow-raw", `{"a":{"b":[1]}}`)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		rec = post(t, service, "shallow-raw", `{"a":{"b":[[1]]}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
+
+func TestPostWebhook_MaxPayloadBytes(t *testing.T) {
+	loader := NewTestLoader(t, testRoutesYAML)
+	body := `{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{"name":"John Doe"}}`
+
+	post := func(t *testing.T, service *mocks.UseCase, body string, opts ...httpchi.Option) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/user-events/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return DoRequest(t, service, loader, req, opts...)
+	}
+
+	t.Run("success - body within the limit", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(body), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, body, httpchi.WithMaxPayloadBytes(len(body)))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("body over the limit is rejected", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, body, httpchi.WithMaxPayloadBytes(len(body)-1))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Contains(t, rec.Body.String(), "payload_too_large")
+	})
+
+	t.Run("default limit applies without the option", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		huge := fmt.Sprintf(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{"blob":%q}}`, strings.Repeat("a", payload.DefaultMaxBytes))
+
+		rec := post(t, service, huge)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("zero lifts the limit", func(t *testing.T) {
+		huge := fmt.Sprintf(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{"blob":%q}}`, strings.Repeat("a", payload.DefaultMaxBytes))
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "user-events", webhook.FIFO, []byte(huge), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, huge, httpchi.WithMaxPayloadBytes(0))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+}
+
+func TestPostWebhook_EventTypeSource(t *testing.T) {
+	loader := NewTestLoader(t, `
+routes:
+  - route_id: "header-typed"
+    target_url: "https://example.com/header"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_type_source: "header:X-Event-Type"
+  - route_id: "path-typed"
+    target_url: "https://example.com/path"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_type_source: "jsonpath:$.event.kind"
+    event_types: ["order.*"]
+    reject_unsubscribed: true
+`)
+
+	post := func(t *testing.T, service *mocks.UseCase, routeID, body, eventType string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if eventType != "" {
+			req.Header.Set("X-Event-Type", eventType)
+		}
+		return DoRequest(t, service, loader, req)
+	}
+	// synthesized matches a stored Standard Webhooks payload wrapping data
+	synthesized := func(eventType, data string) interface{} {
+		return mock.MatchedBy(func(body []byte) bool {
+			var p struct {
+				Type      string          `json:"type"`
+				Timestamp time.Time       `json:"timestamp"`
+				Data      json.RawMessage `json:"data"`
+			}
+			return json.Unmarshal(body, &p) == nil && p.Type == eventType && !p.Timestamp.IsZero() && string(p.Data) == data
+		})
+	}
+
+	t.Run("success - type from header", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "header-typed", webhook.FIFO, synthesized("user.created", `{"id":1}`), mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+
+		rec := post(t, service, "header-typed", `{"id":1}`, "user.created")
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "header-typed", `{"id":1}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "missing X-Event-Type header")
+	})
+
+	t.Run("invalid header type", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "header-typed", `{"id":1}`, "user created")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("success - type from JSON path", func(t *testing.T) {
+		body := `{"event":{"kind":"order.shipped"},"order":{"id":7}}`
+		service := mocks.NewUseCase(t)
+		service.On("ReceiveAt", mock.Anything, "path-typed", webhook.FIFO, synthesized("order.shipped", body), mock.Anything, 3, time.Time{}).Return("evt-2", nil)
+
+		rec := post(t, service, "path-typed", body, "")
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("derived types are filtered like payload types", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "path-typed", `{"event":{"kind":"user.created"}}`, "")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("missing JSON path", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "path-typed", `{"order":{"id":7}}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "event type not found")
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "header-typed", `{"id":`, "user.created")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestPostWebhook_IngestAPIKey(t *testing.T) {
+	loader := NewTestLoader(t, `
+routes:
+  - route_id: "keyed"
+    target_url: "https://example.com/keyed"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    ingest_api_keys: ["key-current", "key-previous"]
+  - route_id: "open"
+    target_url: "https://example.com/open"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`)
+
+	post := func(t *testing.T, service *mocks.UseCase, routeID, apiKey string, opts ...httpchi.Option) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/routes/"+routeID+"/events", strings.NewReader(StandardPayload("user.created")))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		return DoRequest(t, service, loader, req, opts...)
+	}
+	accept := func(service *mocks.UseCase, routeID string) {
+		service.On("ReceiveAt", mock.Anything, routeID, webhook.FIFO, mock.Anything, mock.Anything, 3, time.Time{}).Return("evt-1", nil)
+	}
+
+	t.Run("success - route keys", func(t *testing.T) {
+		for _, key := range []string{"key-current", "key-previous"} {
+			service := mocks.NewUseCase(t)
+			service.On("ReceiveAt", mock.Anything, "keyed", webhook.FIFO, mock.Anything,
+				mock.MatchedBy(func(headers map[string]string) bool {
+					// The key authenticates with the inbox and is not forwarded
+					_, forwarded := headers["X-Api-Key"]
+					return !forwarded
+				}), 3, time.Time{}).Return("evt-1", nil)
+
+			rec := post(t, service, "keyed", key)
+
+			assert.Equal(t, http.StatusAccepted, rec.Code, key)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "keyed", "key-wrong")
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "keyed", "")
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("route keys take precedence over the global key", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "keyed", "global-key", httpchi.WithIngestAPIKey("global-key"))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("global key protects routes without keys", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		rec := post(t, service, "open", "", httpchi.WithIngestAPIKey("global-key"))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		accept(service, "open")
+		rec = post(t, service, "open", "global-key", httpchi.WithIngestAPIKey("global-key"))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("no auth configured - passthrough", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+		accept(service, "open")
+
+		rec := post(t, service, "open", "")
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("unknown route is still not found", func(t *testing.T) {
+		service := mocks.NewUseCase(t)
+
+		rec := post(t, service, "missing", "", httpchi.WithIngestAPIKey("global-key"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestGetRoutes(t *testing.T) {
+	loader := NewTestLoader(t, `
+routes:
+  - route_id: "legacy"
+    target_url: "https://example.com/legacy"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_status: 202
+  - route_id: "listed"
+    target_url: "https://example.com/listed"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_statuses: ["204", "200"]
+  - route_id: "unset"
+    target_url: "https://example.com/unset"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`)
+
+	rec := DoRequest(t, mocks.NewUseCase(t), loader, httptest.NewRequest(http.MethodGet, "/v1/routes", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var listing []map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listing))
+	require.Len(t, listing, 3)
+
+	// expected_status is always present, next to expected_statuses
+	want := map[string][2]string{
+		"legacy": {`202`, `["202"]`},
+		"listed": {`204`, `["204","200"]`},
+		"unset":  {`200`, `["2xx"]`},
+	}
+	for _, route := range listing {
+		var routeID string
+		require.NoError(t, json.Unmarshal(route["route_id"], &routeID))
+		assert.JSONEq(t, want[routeID][0], string(route["expected_status"]), routeID)
+		assert.JSONEq(t, want[routeID][1], string(route["expected_statuses"]), routeID)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Metrics exporter kinds accepted by NewExporter (METRICS_EXPORTER)
+const (
+	ExporterPrometheus = "prometheus" // OpenTelemetry metrics in Prometheus text format
+	ExporterJSON       = "json"       // A Metrics snapshot encoded as JSON
+	ExporterNone       = "none"       // Metrics disabled: GET /metrics answers 404
+)
+
+// Exporter serves GET /metrics and releases its resources on Shutdown
+type Exporter interface {
+	http.Handler
+	Shutdown(ctx context.Context) error
+}
+
+// handlerExporter pairs a handler with an optional shutdown function
+type handlerExporter struct {
+	http.Handler
+	shutdown func(ctx context.Context) error
+}
+
+// Shutdown releases the exporter's resources
+func (e handlerExporter) Shutdown(ctx context.Context) error {
+	if e.shutdown == nil {
+		return nil
+	}
+	return e.shutdown(ctx)
+}
+
+// NewExporter returns the exporter serving GET /metrics in the given format
+// The Prometheus exporter registers its instruments on the global meter provider;
+// use NewOTelExporter directly to also record payload sizes
+func NewExporter(kind string, collector Collector) (Exporter, error) {
+	switch kind {
+	case ExporterPrometheus:
+		exporter, err := NewOTelExporter(collector)
+		if err != nil {
+			return nil, err
+		}
+		return handlerExporter{Handler: exporter.ServeHTTP(), shutdown: exporter.Shutdown}, nil
+	case ExporterJSON:
+		return handlerExporter{Handler: jsonHandler(collector)}, nil
+	case ExporterNone:
+		return handlerExporter{Handler: http.NotFoundHandler()}, nil
+	default:
+		return nil, fmt.Errorf("unknown metrics exporter %q (want %s, %s or %s)", kind, ExporterPrometheus, ExporterJSON, ExporterNone)
+	}
+}
+
+// jsonHandler serves a snapshot collected on every request
+func jsonHandler(collector Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := collector.Collect(r.Context())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("collecting metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+}
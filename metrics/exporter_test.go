@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotCollector returns a fixed snapshot, or err when set
+type snapshotCollector struct {
+	emptyCollector
+	snapshot Metrics
+	err      error
+}
+
+func (c snapshotCollector) Collect(ctx context.Context) (Metrics, error) { return c.snapshot, c.err }
+
+func serveMetrics(t *testing.T, handler http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec
+}
+
+func TestNewExporter(t *testing.T) {
+	t.Run("prometheus", func(t *testing.T) {
+		handler, err := NewExporter(ExporterPrometheus, emptyCollector{})
+		require.NoError(t, err)
+		t.Cleanup(func() { handler.Shutdown(context.Background()) })
+
+		rec := serveMetrics(t, handler)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		collector := snapshotCollector{snapshot: Metrics{
+			QueueLengths: map[string]int64{"user-events": 3},
+			StatusCounts: map[string]int64{"delivered": 7},
+			Throughput:   ThroughputMetrics{LastMinute: 2},
+			Timestamp:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}}
+		handler, err := NewExporter(ExporterJSON, collector)
+		require.NoError(t, err)
+
+		rec := serveMetrics(t, handler)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var got Metrics
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, collector.snapshot.QueueLengths, got.QueueLengths)
+		assert.Equal(t, collector.snapshot.StatusCounts, got.StatusCounts)
+		assert.Equal(t, int64(2), got.Throughput.LastMinute)
+		assert.True(t, collector.snapshot.Timestamp.Equal(got.Timestamp))
+	})
+
+	t.Run("json collection error", func(t *testing.T) {
+		handler, err := NewExporter(ExporterJSON, snapshotCollector{err: errors.New("redis unavailable")})
+		require.NoError(t, err)
+
+		rec := serveMetrics(t, handler)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "redis unavailable")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		handler, err := NewExporter(ExporterNone, snapshotCollector{err: errors.New("must not be called")})
+		require.NoError(t, err)
+
+		rec := serveMetrics(t, handler)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, handler.Shutdown(context.Background()))
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		_, err := NewExporter("statsd", emptyCollector{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown metrics exporter "statsd"`)
+	})
+}
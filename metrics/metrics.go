@@ -19,6 +19,15 @@ type Metrics struct {
 	// Workers maps route_id to list of active workers
 	Workers map[string][]WorkerInfo `json:"workers"`
 
+	// PayloadSizeP50 maps route_id to the median payload size, in bytes, of its recent webhooks
+	PayloadSizeP50 map[string]int64 `json:"payload_size_p50"`
+
+	// PayloadSizeP95 maps route_id to the 95th percentile payload size, in bytes, of its recent webhooks
+	PayloadSizeP95 map[string]int64 `json:"payload_size_p95"`
+
+	// EventTypeCounts maps event type to the number of recent webhooks of that type, across routes
+	EventTypeCounts map[string]int64 `json:"event_type_counts"`
+
 	// Timestamp when metrics were collected
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -35,6 +44,20 @@ type ThroughputMetrics struct {
 	LastFifteenMinutes int64 `json:"last_fifteen_minutes"`
 }
 
+// PayloadSummary describes the shape of recent traffic: payload sizes per route and event types
+// Routes without recent webhooks are left out of the size percentiles
+type PayloadSummary struct {
+	// SizeP50 maps route_id to the median payload size in bytes
+	SizeP50 map[string]int64
+
+	// SizeP95 maps route_id to the 95th percentile payload size in bytes
+	SizeP95 map[string]int64
+
+	// EventTypeCounts maps event type to the number of webhooks of that type, across routes
+	// Payloads that are not Standard Webhooks (e.g. raw routes) have no type and are not counted
+	EventTypeCounts map[string]int64
+}
+
 // WorkerInfo represents information about an active worker.
 type WorkerInfo struct {
 	// WorkerID is a unique identifier for the worker
@@ -43,7 +66,7 @@ type WorkerInfo struct {
 	// RouteID is the route this worker is processing
 	RouteID string `json:"route_id"`
 
-	// Status is the current status of the worker (e.g., "idle", "processing")
+	// Status is the current status of the worker (e.g., "idle", "processing", "paused")
 	Status string `json:"status"`
 
 	// LastHeartbeat is the timestamp of the last heartbeat
@@ -61,9 +84,21 @@ type Collector interface {
 	// GetStatusCounts returns the count of webhooks by status
 	GetStatusCounts(ctx context.Context) (map[string]int64, error)
 
+	// GetStatusCountsByRoute returns the count of webhooks by route, then status
+	GetStatusCountsByRoute(ctx context.Context) (map[string]map[string]int64, error)
+
 	// GetThroughput returns webhooks processed over time windows
 	GetThroughput(ctx context.Context) (ThroughputMetrics, error)
 
 	// GetActiveWorkers returns information about active workers per route
 	GetActiveWorkers(ctx context.Context) (map[string][]WorkerInfo, error)
+
+	// GetConsumerLags returns the number of webhooks per route not yet read by its workers
+	GetConsumerLags(ctx context.Context) (map[string]int64, error)
+
+	// GetOldestMessageAges returns the age in seconds of the oldest webhook not yet acknowledged on each route
+	GetOldestMessageAges(ctx context.Context) (map[string]float64, error)
+
+	// GetPayloadSummary returns payload size percentiles per route and event type counts of recent webhooks
+	GetPayloadSummary(ctx context.Context) (PayloadSummary, error)
 }
@@ -24,6 +24,9 @@ type OTelExporter struct {
 	statusCountGauge      metric.Int64ObservableGauge
 	throughputGauge       metric.Int64ObservableGauge
 	activeWorkersGauge    metric.Int64ObservableGauge
+	consumerLagGauge      metric.Int64ObservableGauge
+	oldestAgeGauge        metric.Float64ObservableGauge
+	payloadSizeHistogram  metric.Int64Histogram
 }
 
 // NewOTelExporter creates a new OpenTelemetry metrics exporter with Prometheus format
@@ -78,7 +81,7 @@ func (oe *OTelExporter) registerInstruments() error {
 	// Status count gauge (per status)
 	oe.statusCountGauge, err = oe.meter.Int64ObservableGauge(
 		"webhook.status.count",
-		metric.WithDescription("Number of webhooks by status"),
+		metric.WithDescription("Number of webhooks by route and status"),
 		metric.WithUnit("{webhooks}"),
 		metric.WithInt64Callback(oe.observeStatusCounts),
 	)
@@ -108,9 +111,50 @@ func (oe *OTelExporter) registerInstruments() error {
 		return fmt.Errorf("creating active workers gauge: %w", err)
 	}
 
+	// Consumer lag gauge (per route): entries not yet read by the route's consumer group
+	oe.consumerLagGauge, err = oe.meter.Int64ObservableGauge(
+		"webhook.consumer.lag",
+		metric.WithDescription("Number of webhooks not yet read by workers per route"),
+		metric.WithUnit("{webhooks}"),
+		metric.WithInt64Callback(oe.observeConsumerLags),
+	)
+	if err != nil {
+		return fmt.Errorf("creating consumer lag gauge: %w", err)
+	}
+
+	// Oldest message age gauge (per route): how long the first stream entry has been retained
+	oe.oldestAgeGauge, err = oe.meter.Float64ObservableGauge(
+		"webhook.queue.oldest_age",
+		metric.WithDescription("Age of the oldest webhook in the queue per route"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(oe.observeOldestAges),
+	)
+	if err != nil {
+		return fmt.Errorf("creating oldest age gauge: %w", err)
+	}
+
+	// Payload size histogram (per route), recorded as webhooks are received
+	oe.payloadSizeHistogram, err = oe.meter.Int64Histogram(
+		"webhook.payload.size",
+		metric.WithDescription("Size of received webhook payloads per route"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304),
+	)
+	if err != nil {
+		return fmt.Errorf("creating payload size histogram: %w", err)
+	}
+
 	return nil
 }
 
+// RecordPayloadSize records the size in bytes of a received payload
+// Implements webhook.PayloadSizeRecorder so the service can report sizes as it stores webhooks
+func (oe *OTelExporter) RecordPayloadSize(ctx context.Context, routeID string, size int) {
+	oe.payloadSizeHistogram.Record(ctx, int64(size), metric.WithAttributes(
+		attribute.String("route.id", routeID),
+	))
+}
+
 // observeQueueLengths is a callback that reports queue lengths
 func (oe *OTelExporter) observeQueueLengths(ctx context.Context, observer metric.Int64Observer) error {
 	queueLengths, err := oe.collector.GetQueueLengths(ctx)
@@ -127,17 +171,20 @@ func (oe *OTelExporter) observeQueueLengths(ctx context.Context, observer metric
 	return nil
 }
 
-// observeStatusCounts is a callback that reports webhook counts by status
+// observeStatusCounts is a callback that reports webhook counts by route and status
 func (oe *OTelExporter) observeStatusCounts(ctx context.Context, observer metric.Int64Observer) error {
-	statusCounts, err := oe.collector.GetStatusCounts(ctx)
+	countsByRoute, err := oe.collector.GetStatusCountsByRoute(ctx)
 	if err != nil {
 		return err
 	}
 
-	for status, count := range statusCounts {
-		observer.Observe(count, metric.WithAttributes(
-			attribute.String("webhook.status", status),
-		))
+	for routeID, statusCounts := range countsByRoute {
+		for status, count := range statusCounts {
+			observer.Observe(count, metric.WithAttributes(
+				attribute.String("route.id", routeID),
+				attribute.String("webhook.status", status),
+			))
+		}
 	}
 
 	return nil
@@ -179,6 +226,38 @@ func (oe *OTelExporter) observeActiveWorkers(ctx context.Context, observer metri
 	return nil
 }
 
+// observeConsumerLags is a callback that reports consumer group lag
+func (oe *OTelExporter) observeConsumerLags(ctx context.Context, observer metric.Int64Observer) error {
+	lags, err := oe.collector.GetConsumerLags(ctx)
+	if err != nil {
+		return err
+	}
+
+	for routeID, lag := range lags {
+		observer.Observe(lag, metric.WithAttributes(
+			attribute.String("route.id", routeID),
+		))
+	}
+
+	return nil
+}
+
+// observeOldestAges is a callback that reports the age of each route's oldest queued webhook
+func (oe *OTelExporter) observeOldestAges(ctx context.Context, observer metric.Float64Observer) error {
+	ages, err := oe.collector.GetOldestMessageAges(ctx)
+	if err != nil {
+		return err
+	}
+
+	for routeID, age := range ages {
+		observer.Observe(age, metric.WithAttributes(
+			attribute.String("route.id", routeID),
+		))
+	}
+
+	return nil
+}
+
 // ServeHTTP serves Prometheus-formatted metrics on the given HTTP handler
 func (oe *OTelExporter) ServeHTTP() http.Handler {
 	return promhttp.Handler()
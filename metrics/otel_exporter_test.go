@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyCollector reports no queues, statuses or workers
+type emptyCollector struct{}
+
+func (emptyCollector) Collect(ctx context.Context) (Metrics, error) { return Metrics{}, nil }
+func (emptyCollector) GetQueueLengths(ctx context.Context) (map[string]int64, error) {
+	return nil, nil
+}
+func (emptyCollector) GetStatusCounts(ctx context.Context) (map[string]int64, error) {
+	return nil, nil
+}
+func (emptyCollector) GetStatusCountsByRoute(ctx context.Context) (map[string]map[string]int64, error) {
+	return nil, nil
+}
+func (emptyCollector) GetThroughput(ctx context.Context) (ThroughputMetrics, error) {
+	return ThroughputMetrics{}, nil
+}
+func (emptyCollector) GetActiveWorkers(ctx context.Context) (map[string][]WorkerInfo, error) {
+	return nil, nil
+}
+func (emptyCollector) GetConsumerLags(ctx context.Context) (map[string]int64, error) {
+	return nil, nil
+}
+func (emptyCollector) GetOldestMessageAges(ctx context.Context) (map[string]float64, error) {
+	return nil, nil
+}
+func (emptyCollector) GetPayloadSummary(ctx context.Context) (PayloadSummary, error) {
+	return PayloadSummary{}, nil
+}
+
+var _ webhook.PayloadSizeRecorder = (*OTelExporter)(nil)
+
+func TestOTelExporter_RecordPayloadSize(t *testing.T) {
+	exporter, err := NewOTelExporter(emptyCollector{})
+	require.NoError(t, err)
+	t.Cleanup(func() { exporter.Shutdown(context.Background()) })
+
+	ctx := context.Background()
+	exporter.RecordPayloadSize(ctx, "user-events", 100)
+	exporter.RecordPayloadSize(ctx, "user-events", 2000)
+	exporter.RecordPayloadSize(ctx, "orders", 70000)
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	output := string(body)
+	assert.Regexp(t, `webhook_payload_size_bytes_count\{[^}]*route_id="user-events"[^}]*\} 2\n`, output)
+	assert.Regexp(t, `webhook_payload_size_bytes_sum\{[^}]*route_id="user-events"[^}]*\} 2100\n`, output)
+	assert.Regexp(t, `webhook_payload_size_bytes_bucket\{[^}]*route_id="user-events",le="4096"\} 2\n`, output)
+	assert.Regexp(t, `webhook_payload_size_bytes_count\{[^}]*route_id="orders"[^}]*\} 1\n`, output)
+}
+
+// lagCollector reports fixed consumer lags
+type lagCollector struct {
+	emptyCollector
+	lags map[string]int64
+}
+
+func (c lagCollector) GetConsumerLags(ctx context.Context) (map[string]int64, error) {
+	return c.lags, nil
+}
+
+func TestOTelExporter_ConsumerLag(t *testing.T) {
+	exporter, err := NewOTelExporter(lagCollector{lags: map[string]int64{"user-events": 7, "orders": 0}})
+	require.NoError(t, err)
+	t.Cleanup(func() { exporter.Shutdown(context.Background()) })
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	output := string(body)
+	assert.Regexp(t, `webhook_consumer_lag\{[^}]*route_id="user-events"[^}]*\} 7\n`, output)
+	assert.Regexp(t, `webhook_consumer_lag\{[^}]*route_id="orders"[^}]*\} 0\n`, output)
+}
+
+// ageCollector reports fixed oldest message ages
+type ageCollector struct {
+	emptyCollector
+	ages map[string]float64
+}
+
+func (c ageCollector) GetOldestMessageAges(ctx context.Context) (map[string]float64, error) {
+	return c.ages, nil
+}
+
+func TestOTelExporter_OldestMessageAge(t *testing.T) {
+	exporter, err := NewOTelExporter(ageCollector{ages: map[string]float64{"user-events": 42.5, "orders": 0}})
+	require.NoError(t, err)
+	t.Cleanup(func() { exporter.Shutdown(context.Background()) })
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	output := string(body)
+	assert.Regexp(t, `webhook_queue_oldest_age_seconds\{[^}]*route_id="user-events"[^}]*\} 42.5\n`, output)
+	assert.Regexp(t, `webhook_queue_oldest_age_seconds\{[^}]*route_id="orders"[^}]*\} 0\n`, output)
+}
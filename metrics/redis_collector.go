@@ -4,24 +4,59 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	webhookredis "github.com/marcelsud/webhook-inbox/webhook/redis"
 	"github.com/redis/go-redis/v9"
 )
 
+// payloadSampleSize is how many of the newest stream entries of each route GetPayloadSummary samples
+const payloadSampleSize = 1000
+
+// reconcileKey expires once the counters are due for reconciling, so only one collector scans per interval
+const reconcileKey = "metrics:counters:reconciled"
+
+// backfillClaimTTL is how long a collector backfilling missing counters keeps others from backfilling too
+const backfillClaimTTL = time.Minute
+
 // RedisCollector implements the Collector interface for Redis-backed metrics
 type RedisCollector struct {
-	client       *redis.Client
-	routesLoader *routes.Loader
+	client            redis.UniversalClient
+	repo              *webhookredis.Repository
+	routesLoader      *routes.Loader
+	reconcileInterval time.Duration
 }
 
-// NewRedisCollector creates a new Redis metrics collector
-func NewRedisCollector(client *redis.Client, loader *routes.Loader) *RedisCollector {
-	return &RedisCollector{
-		client:       client,
+// CollectorOption configures a RedisCollector
+type CollectorOption func(*RedisCollector)
+
+// WithReconcileInterval enables correcting the status counters with a keyspace scan every interval
+// Counters aren't decremented when webhook hashes expire, so they drift until reconciled; each pass
+// scans every webhook, so pick an interval the keyspace can afford. Zero (the default) only backfills
+// counters that don't exist yet
+func WithReconcileInterval(interval time.Duration) CollectorOption {
+	return func(c *RedisCollector) {
+		c.reconcileInterval = interval
+	}
+}
+
+// NewRedisCollector creates a new Redis metrics collector reading the repository's keys
+// Streams and consumer groups are named by the repository, so route groups and Cluster hash tags match
+func NewRedisCollector(repo *webhookredis.Repository, loader *routes.Loader, opts ...CollectorOption) *RedisCollector {
+	c := &RedisCollector{
+		client:       repo.GetClient(),
+		repo:         repo,
 		routesLoader: loader,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Collect gathers all metrics from Redis
@@ -46,22 +81,31 @@ func (c *RedisCollector) Collect(ctx context.Context) (Metrics, error) {
 		return Metrics{}, fmt.Errorf("getting active workers: %w", err)
 	}
 
+	payloads, err := c.GetPayloadSummary(ctx)
+	if err != nil {
+		return Metrics{}, fmt.Errorf("getting payload summary: %w", err)
+	}
+
 	return Metrics{
-		QueueLengths: queueLengths,
-		StatusCounts: statusCounts,
-		Throughput:   throughput,
-		Workers:      workers,
-		Timestamp:    time.Now(),
+		QueueLengths:    queueLengths,
+		StatusCounts:    statusCounts,
+		Throughput:      throughput,
+		Workers:         workers,
+		PayloadSizeP50:  payloads.SizeP50,
+		PayloadSizeP95:  payloads.SizeP95,
+		EventTypeCounts: payloads.EventTypeCounts,
+		Timestamp:       time.Now(),
 	}, nil
 }
 
 // GetQueueLengths returns the number of pending webhooks in each stream
+// Routes sharing a group report the length of the group's stream
 func (c *RedisCollector) GetQueueLengths(ctx context.Context) (map[string]int64, error) {
 	queueLengths := make(map[string]int64)
 	allRoutes := c.routesLoader.List()
 
 	for _, route := range allRoutes {
-		streamKey := fmt.Sprintf("webhooks:%s:%s", route.Mode.String(), route.RouteID)
+		streamKey := c.repo.StreamKey(route.RouteID, route.Mode)
 
 		length, err := c.client.XLen(ctx, streamKey).Result()
 		if err != nil && err != redis.Nil {
@@ -75,143 +119,357 @@ func (c *RedisCollector) GetQueueLengths(ctx context.Context) (map[string]int64,
 	return queueLengths, nil
 }
 
-// GetStatusCounts returns counts of webhooks grouped by status
-func (c *RedisCollector) GetStatusCounts(ctx context.Context) (map[string]int64, error) {
-	statusCounts := map[string]int64{
-		"pending":    0,
-		"delivering": 0,
-		"delivered":  0,
-		"failed":     0,
-		"retrying":   0,
-	}
-
-	// Scan for all webhook:* keys
-	var cursor uint64
-	var keys []string
+// GetConsumerLags returns the number of webhooks in each stream its consumer group has not read yet
+// Mirrors redis.Repository.GroupLag: the whole stream counts while no worker has created the group
+func (c *RedisCollector) GetConsumerLags(ctx context.Context) (map[string]int64, error) {
+	lags := make(map[string]int64)
+	allRoutes := c.routesLoader.List()
 
-	for {
-		var scanKeys []string
-		var err error
+	for _, route := range allRoutes {
+		streamKey := c.repo.StreamKey(route.RouteID, route.Mode)
+		groupName := c.repo.GroupName(route.RouteID)
 
-		scanKeys, cursor, err = c.client.Scan(ctx, cursor, "webhook:*", 1000).Result()
-		if err != nil {
-			return nil, fmt.Errorf("scanning webhook keys: %w", err)
+		groups, err := c.client.XInfoGroups(ctx, streamKey).Result()
+		if err != nil && !strings.Contains(err.Error(), "no such key") {
+			// Continue even if one stream fails
+			continue
 		}
 
-		keys = append(keys, scanKeys...)
-
-		if cursor == 0 {
-			break
+		lag, found := int64(0), false
+		for _, group := range groups {
+			if group.Name == groupName && group.Lag >= 0 {
+				lag, found = group.Lag, true
+			}
+		}
+		if !found {
+			lag, err = c.client.XLen(ctx, streamKey).Result()
+			if err != nil && err != redis.Nil {
+				continue
+			}
 		}
+
+		lags[route.RouteID] = lag
 	}
 
-	// Filter out message ID keys (webhook:*:msgid)
-	var webhookKeys []string
-	for _, key := range keys {
-		// Skip message ID keys
-		if len(key) > 6 && key[len(key)-6:] == ":msgid" {
+	return lags, nil
+}
+
+// GetOldestMessageAges returns how many seconds ago the oldest webhook not yet acknowledged was added to each route
+// Mirrors redis.Repository.OldestUndeliveredAge: acknowledged entries kept until trimming are ignored, so a
+// drained queue reports 0 like an empty or missing stream
+func (c *RedisCollector) GetOldestMessageAges(ctx context.Context) (map[string]float64, error) {
+	ages := make(map[string]float64)
+	allRoutes := c.routesLoader.List()
+
+	for _, route := range allRoutes {
+		age, err := c.repo.OldestUndeliveredAge(ctx, route.RouteID, route.Mode)
+		if err != nil {
+			// Continue even if one stream fails
 			continue
 		}
-		webhookKeys = append(webhookKeys, key)
+
+		ages[route.RouteID] = age.Seconds()
 	}
 
-	// Batch get status for all webhooks
-	if len(webhookKeys) == 0 {
-		return statusCounts, nil
+	return ages, nil
+}
+
+// GetPayloadSummary samples the newest entries of each route's stream for payload sizes and event types
+// Stream entries record each payload's size and event type, and acknowledged entries stay until the
+// stream is trimmed, so the sample covers recently received webhooks whatever their status
+func (c *RedisCollector) GetPayloadSummary(ctx context.Context) (PayloadSummary, error) {
+	summary := PayloadSummary{
+		SizeP50:         make(map[string]int64),
+		SizeP95:         make(map[string]int64),
+		EventTypeCounts: make(map[string]int64),
 	}
 
-	// Use pipeline for efficient batch operations
-	pipe := c.client.Pipeline()
-	cmds := make([]*redis.MapStringStringCmd, len(webhookKeys))
+	allRoutes := c.routesLoader.List()
+	if len(allRoutes) == 0 {
+		return summary, nil
+	}
 
-	for i, key := range webhookKeys {
-		cmds[i] = pipe.HGetAll(ctx, key)
+	pipe := c.client.Pipeline()
+	cmds := make(map[string]*redis.XMessageSliceCmd, len(allRoutes))
+	for _, route := range allRoutes {
+		streamKey := c.repo.StreamKey(route.RouteID, route.Mode)
+		cmds[route.RouteID] = pipe.XRevRangeN(ctx, streamKey, "+", "-", payloadSampleSize)
 	}
 
-	_, err := pipe.Exec(ctx)
-	if err != nil && err != redis.Nil {
-		return nil, fmt.Errorf("executing pipeline: %w", err)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return PayloadSummary{}, fmt.Errorf("sampling stream entries: %w", err)
 	}
 
-	// Count statuses
-	for _, cmd := range cmds {
-		data, err := cmd.Result()
-		if err != nil || len(data) == 0 {
+	for routeID, cmd := range cmds {
+		var sizes []int64
+		for _, msg := range cmd.Val() {
+			// A group's stream also holds its other routes' webhooks
+			if id, ok := msg.Values["route_id"].(string); ok && id != routeID {
+				continue
+			}
+			size, eventType, ok := entryPayload(msg.Values)
+			if !ok {
+				continue
+			}
+			sizes = append(sizes, size)
+			if eventType != "" {
+				summary.EventTypeCounts[eventType]++
+			}
+		}
+		if len(sizes) == 0 {
 			continue
 		}
 
-		status := data["status"]
-		if _, exists := statusCounts[status]; exists {
-			statusCounts[status]++
+		slices.Sort(sizes)
+		summary.SizeP50[routeID] = percentile(sizes, 50)
+		summary.SizeP95[routeID] = percentile(sizes, 95)
+	}
+
+	return summary, nil
+}
+
+// entryPayload returns the payload size and event type a stream entry records
+// Entries written before they stopped carrying the payload hold it instead
+func entryPayload(values map[string]interface{}) (int64, string, bool) {
+	if size, ok := values["payload_size"].(string); ok {
+		n, err := strconv.ParseInt(size, 10, 64)
+		eventType, _ := values["event_type"].(string)
+		return n, eventType, err == nil
+	}
+
+	body, ok := values["payload"].(string)
+	if !ok {
+		return 0, "", false
+	}
+	var eventType string
+	if p, err := payload.Parse([]byte(body)); err == nil {
+		eventType = p.Type
+	}
+	return int64(len(body)), eventType, true
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, non-empty values
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
+
+// GetStatusCounts returns counts of webhooks grouped by status
+func (c *RedisCollector) GetStatusCounts(ctx context.Context) (map[string]int64, error) {
+	countsByRoute, err := c.GetStatusCountsByRoute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statusCounts := newStatusCounts()
+	for _, counts := range countsByRoute {
+		for status, count := range counts {
+			statusCounts[status] += count
 		}
 	}
 
 	return statusCounts, nil
 }
 
-// GetThroughput calculates webhooks delivered over different time windows
-func (c *RedisCollector) GetThroughput(ctx context.Context) (ThroughputMetrics, error) {
-	now := time.Now()
-	oneMinuteAgo := now.Add(-1 * time.Minute).Unix()
-	fiveMinutesAgo := now.Add(-5 * time.Minute).Unix()
-	fifteenMinutesAgo := now.Add(-15 * time.Minute).Unix()
+// GetStatusCountsByRoute returns counts of webhooks grouped by route_id, then status
+// Reads the per-route counters maintained by the repository. They are backfilled from a scan when
+// none exists yet (e.g. data written before counters were introduced), and reconciled with one every
+// reconcile interval, when one is set, to drop webhooks whose hash expired
+func (c *RedisCollector) GetStatusCountsByRoute(ctx context.Context) (map[string]map[string]int64, error) {
+	allRoutes := c.routesLoader.List()
+	countsByRoute, found, err := c.readStatusCounters(ctx, allRoutes)
+	if err != nil {
+		return nil, err
+	}
+	for _, counts := range countsByRoute {
+		for status, count := range counts {
+			// Counters can drift below zero when webhooks expire between transitions
+			counts[status] = max(count, 0)
+		}
+	}
 
-	var lastMinute, lastFiveMinutes, lastFifteenMinutes int64
+	if len(allRoutes) > 0 && ((!found && c.claim(ctx, backfillClaimTTL)) || c.reconcileDue(ctx)) {
+		return c.BackfillStatusCounters(ctx)
+	}
 
-	// Scan for all webhook:* keys (excluding msgid keys)
-	var cursor uint64
+	return countsByRoute, nil
+}
 
-	for {
-		keys, nextCursor, err := c.client.Scan(ctx, cursor, "webhook:*", 1000).Result()
-		if err != nil {
-			return ThroughputMetrics{}, fmt.Errorf("scanning webhook keys: %w", err)
-		}
+// reconcileDue reports whether the counters should be reconciled, claiming the reconciliation
+// for this interval so other collectors sharing the Redis don't scan too
+func (c *RedisCollector) reconcileDue(ctx context.Context) bool {
+	return c.reconcileInterval > 0 && c.claim(ctx, c.reconcileInterval)
+}
 
-		// Filter and process keys
-		for _, key := range keys {
-			// Skip message ID keys
-			if len(key) > 6 && key[len(key)-6:] == ":msgid" {
-				continue
-			}
+// claim reports whether this collector may rebuild the counters, keeping others from doing so for ttl
+func (c *RedisCollector) claim(ctx context.Context, ttl time.Duration) bool {
+	claimed, err := c.client.SetNX(ctx, reconcileKey, time.Now().Unix(), ttl).Result()
+	return err == nil && claimed
+}
 
-			// Get status and updated_at
-			data, err := c.client.HMGet(ctx, key, "status", "updated_at").Result()
-			if err != nil || len(data) < 2 {
-				continue
+// setIfUnchangedScript sets a counter to ARGV[2] only if it still holds ARGV[1] (a missing counter holds 0)
+// Returns the counter's value afterwards
+var setIfUnchangedScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1]) or '0'
+if current ~= ARGV[1] then
+	return tonumber(current)
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return tonumber(ARGV[2])
+`)
+
+// BackfillStatusCounters corrects the per-route status counters of the configured routes with a keyspace scan
+// Use it to seed counters for existing data or to correct drift from expired webhooks
+// A counter is only replaced if it still holds the value read before the scan: counters moved by a
+// transition during the scan keep their INCR/DECR and are left for the next pass
+// Returns the resulting counts; every configured route is included, even without webhooks
+func (c *RedisCollector) BackfillStatusCounters(ctx context.Context) (map[string]map[string]int64, error) {
+	before, _, err := c.readStatusCounters(ctx, c.routesLoader.List())
+	if err != nil {
+		return nil, err
+	}
+
+	scanned, err := c.scanStatusCountsByRoute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make(map[string]map[string]*redis.Cmd, len(before))
+	_, err = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for routeID, counts := range before {
+			cmds[routeID] = make(map[string]*redis.Cmd, len(counts))
+			for status, count := range counts {
+				key := webhookredis.StatusCounterKey(routeID, status)
+				cmds[routeID][status] = setIfUnchangedScript.Eval(ctx, pipe, []string{key}, count, scanned[routeID][status])
 			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writing status counters: %w", err)
+	}
 
-			status, ok1 := data[0].(string)
-			updatedAtStr, ok2 := data[1].(string)
-			if !ok1 || !ok2 || status != "delivered" {
-				continue
+	countsByRoute := make(map[string]map[string]int64, len(cmds))
+	for routeID, routeCmds := range cmds {
+		countsByRoute[routeID] = newStatusCounts()
+		for status, cmd := range routeCmds {
+			count, _ := cmd.Int64()
+			// Counters can drift below zero when webhooks expire between transitions
+			countsByRoute[routeID][status] = max(count, 0)
+		}
+	}
+	return countsByRoute, nil
+}
+
+// readStatusCounters reads the raw status counters of routes, reporting whether any exists; missing counters read as zero
+func (c *RedisCollector) readStatusCounters(ctx context.Context, allRoutes []*routes.Route) (map[string]map[string]int64, bool, error) {
+	statuses := statusNames()
+	cmds := make(map[string][]*redis.StringCmd, len(allRoutes))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, route := range allRoutes {
+			for _, status := range statuses {
+				cmds[route.RouteID] = append(cmds[route.RouteID], pipe.Get(ctx, webhookredis.StatusCounterKey(route.RouteID, status)))
 			}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, false, fmt.Errorf("reading status counters: %w", err)
+	}
 
-			var updatedAt int64
-			fmt.Sscanf(updatedAtStr, "%d", &updatedAt)
-
-			// Count in time windows
-			if updatedAt >= fifteenMinutesAgo {
-				lastFifteenMinutes++
-				if updatedAt >= fiveMinutesAgo {
-					lastFiveMinutes++
-					if updatedAt >= oneMinuteAgo {
-						lastMinute++
-					}
-				}
+	found := false
+	counters := make(map[string]map[string]int64, len(allRoutes))
+	for routeID, routeCmds := range cmds {
+		counts := newStatusCounts()
+		for i, cmd := range routeCmds {
+			count, err := cmd.Int64()
+			if err != nil {
+				continue
 			}
+			found = true
+			counts[statuses[i]] = count
 		}
+		counters[routeID] = counts
+	}
+	return counters, found, nil
+}
 
-		cursor = nextCursor
-		if cursor == 0 {
-			break
+// scanStatusCountsByRoute counts webhooks by route and status by scanning every webhook hash
+func (c *RedisCollector) scanStatusCountsByRoute(ctx context.Context) (map[string]map[string]int64, error) {
+	countsByRoute := make(map[string]map[string]int64)
+	for _, route := range c.routesLoader.List() {
+		countsByRoute[route.RouteID] = newStatusCounts()
+	}
+
+	err := c.repo.ScanStatuses(ctx, func(routeID, status string) {
+		counts, ok := countsByRoute[routeID]
+		if !ok {
+			counts = newStatusCounts()
+			countsByRoute[routeID] = counts
+		}
+		if _, exists := counts[status]; exists {
+			counts[status]++
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return ThroughputMetrics{
-		LastMinute:         lastMinute,
-		LastFiveMinutes:    lastFiveMinutes,
-		LastFifteenMinutes: lastFifteenMinutes,
-	}, nil
+	return countsByRoute, nil
+}
+
+// statusNames lists every webhook status tracked by the metrics
+func statusNames() []string {
+	return []string{"pending", "delivering", "delivered", "failed", "retrying"}
+}
+
+// newStatusCounts returns a zeroed count for every webhook status
+func newStatusCounts() map[string]int64 {
+	counts := make(map[string]int64)
+	for _, status := range statusNames() {
+		counts[status] = 0
+	}
+	return counts
+}
+
+// GetThroughput calculates webhooks delivered over different time windows
+// Counts entries of each route's delivery time series (metrics:{route_id}:deliveries)
+func (c *RedisCollector) GetThroughput(ctx context.Context) (ThroughputMetrics, error) {
+	now := time.Now()
+	oneMinuteAgo := strconv.FormatInt(now.Add(-1*time.Minute).Unix(), 10)
+	fiveMinutesAgo := strconv.FormatInt(now.Add(-5*time.Minute).Unix(), 10)
+	fifteenMinutesAgo := strconv.FormatInt(now.Add(-15*time.Minute).Unix(), 10)
+
+	type windowCmds struct {
+		lastMinute, lastFiveMinutes, lastFifteenMinutes *redis.IntCmd
+	}
+
+	pipe := c.client.Pipeline()
+	var cmds []windowCmds
+	for _, route := range c.routesLoader.List() {
+		key := webhookredis.DeliveriesKey(route.RouteID)
+		cmds = append(cmds, windowCmds{
+			lastMinute:         pipe.ZCount(ctx, key, oneMinuteAgo, "+inf"),
+			lastFiveMinutes:    pipe.ZCount(ctx, key, fiveMinutesAgo, "+inf"),
+			lastFifteenMinutes: pipe.ZCount(ctx, key, fifteenMinutesAgo, "+inf"),
+		})
+	}
+	if len(cmds) == 0 {
+		return ThroughputMetrics{}, nil
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return ThroughputMetrics{}, fmt.Errorf("counting deliveries: %w", err)
+	}
+
+	var throughput ThroughputMetrics
+	for _, cmd := range cmds {
+		throughput.LastMinute += cmd.lastMinute.Val()
+		throughput.LastFiveMinutes += cmd.lastFiveMinutes.Val()
+		throughput.LastFifteenMinutes += cmd.lastFifteenMinutes.Val()
+	}
+
+	return throughput, nil
 }
 
 // GetActiveWorkers returns information about active workers
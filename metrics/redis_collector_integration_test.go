@@ -0,0 +1,530 @@
+//go:build integration
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcontainersredis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+const collectorTestRoutesYAML = `
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "analytics"
+    target_url: "https://example.com/analytics"
+    mode: "pubsub"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 2
+  - route_id: "idle-route"
+    target_url: "https://example.com/idle"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`
+
+// setupCollector starts a Redis container and returns a repository and a collector sharing its client
+func setupCollector(t testing.TB, ctx context.Context) (*redis.Repository, *RedisCollector) {
+	t.Helper()
+
+	container, err := testcontainersredis.Run(ctx, "redis:7-alpine")
+	require.NoError(t, err, "failed to start Redis container")
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate Redis container: %v", err)
+		}
+	})
+
+	addr, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	repo, err := redis.NewRepository(strings.TrimPrefix(addr, "redis://"), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close(ctx) })
+
+	routesFile := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(routesFile, []byte(collectorTestRoutesYAML), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(routesFile))
+
+	return repo, NewRedisCollector(repo, loader)
+}
+
+// storeWebhooks stores n webhooks for a route and moves them to the given status
+func storeWebhooks(t testing.TB, ctx context.Context, repo *redis.Repository, routeID string, mode webhook.DeliveryMode, status webhook.Status, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("%s-%s-%d-%d", routeID, status, i, time.Now().UnixNano())
+		_, err := repo.Store(ctx, webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(`{}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: mode,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+
+		if status != webhook.Pending {
+			require.NoError(t, repo.UpdateStatus(ctx, id, status))
+		}
+	}
+}
+
+func TestRedisCollector_GetStatusCountsByRoute_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Delivered, 3)
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Failed, 1)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Pending, 2)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Failed, 4)
+
+	t.Run("counts are split by route", func(t *testing.T) {
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(3), countsByRoute["user-events"]["delivered"])
+		assert.Equal(t, int64(1), countsByRoute["user-events"]["failed"])
+		assert.Equal(t, int64(0), countsByRoute["user-events"]["pending"])
+
+		assert.Equal(t, int64(2), countsByRoute["analytics"]["pending"])
+		assert.Equal(t, int64(4), countsByRoute["analytics"]["failed"])
+		assert.Equal(t, int64(0), countsByRoute["analytics"]["delivered"])
+
+		// Configured routes without webhooks still report zeroes
+		require.Contains(t, countsByRoute, "idle-route")
+		assert.Equal(t, int64(0), countsByRoute["idle-route"]["failed"])
+	})
+
+	t.Run("aggregate counts match the per-route sum", func(t *testing.T) {
+		statusCounts, err := collector.GetStatusCounts(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(3), statusCounts["delivered"])
+		assert.Equal(t, int64(5), statusCounts["failed"])
+		assert.Equal(t, int64(2), statusCounts["pending"])
+	})
+}
+
+func TestRedisCollector_StatusCounters_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Delivered, 2)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Retrying, 3)
+
+	scanned, err := collector.scanStatusCountsByRoute(ctx)
+	require.NoError(t, err)
+
+	t.Run("counters match a full scan", func(t *testing.T) {
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, scanned, countsByRoute)
+	})
+
+	t.Run("missing counters are backfilled from a scan", func(t *testing.T) {
+		client := repo.GetClient()
+		keys, err := client.Keys(ctx, "metrics:*").Result()
+		require.NoError(t, err)
+		require.NotEmpty(t, keys)
+		require.NoError(t, client.Del(ctx, keys...).Err())
+
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, scanned, countsByRoute)
+
+		retrying, err := client.Get(ctx, redis.StatusCounterKey("analytics", "retrying")).Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), retrying)
+	})
+
+	t.Run("backfill corrects drifted counters", func(t *testing.T) {
+		client := repo.GetClient()
+		require.NoError(t, client.Set(ctx, redis.StatusCounterKey("user-events", "delivered"), 99, 0).Err())
+
+		_, err := collector.BackfillStatusCounters(ctx)
+		require.NoError(t, err)
+
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), countsByRoute["user-events"]["delivered"])
+	})
+
+	t.Run("transitions during a backfill are kept", func(t *testing.T) {
+		client := repo.GetClient()
+		storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 1)
+		scanned, err := collector.scanStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		require.NoError(t, client.Set(ctx, redis.StatusCounterKey("analytics", "retrying"), 99, 0).Err())
+
+		// The pending webhook is delivered right after the scan read its status
+		keys, err := client.Keys(ctx, "webhook:user-events-pending-*").Result()
+		require.NoError(t, err)
+		var id string
+		for _, key := range keys {
+			if !strings.HasSuffix(key, ":msgid") {
+				id = strings.TrimPrefix(key, "webhook:")
+			}
+		}
+		require.NotEmpty(t, id)
+		client.AddHook(&afterScan{fn: func() {
+			require.NoError(t, repo.UpdateStatus(ctx, id, webhook.Delivered))
+		}})
+
+		countsByRoute, err := collector.BackfillStatusCounters(ctx)
+		require.NoError(t, err)
+
+		// The transition isn't overwritten by the scan that missed it, drift elsewhere is still corrected
+		assert.Equal(t, scanned["user-events"]["pending"]-1, countsByRoute["user-events"]["pending"])
+		assert.Equal(t, scanned["user-events"]["delivered"]+1, countsByRoute["user-events"]["delivered"])
+		assert.Equal(t, int64(3), countsByRoute["analytics"]["retrying"])
+
+		counted, err := repo.CountByStatus(ctx, "user-events")
+		require.NoError(t, err)
+		assert.Equal(t, countsByRoute["user-events"], counted)
+	})
+
+	t.Run("drifted counters are left alone without a reconcile interval", func(t *testing.T) {
+		client := repo.GetClient()
+		require.NoError(t, client.Del(ctx, reconcileKey).Err())
+		require.NoError(t, client.Set(ctx, redis.StatusCounterKey("analytics", "retrying"), 99, 0).Err())
+
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(99), countsByRoute["analytics"]["retrying"])
+		require.NoError(t, client.Set(ctx, redis.StatusCounterKey("analytics", "retrying"), 3, 0).Err())
+	})
+
+	t.Run("drifted counters are reconciled every interval", func(t *testing.T) {
+		client := repo.GetClient()
+		collector.reconcileInterval = 200 * time.Millisecond
+		require.NoError(t, client.Del(ctx, reconcileKey).Err())
+
+		// The first read claims the interval, so drift shows until it elapses
+		_, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		require.NoError(t, client.Set(ctx, redis.StatusCounterKey("user-events", "delivered"), 99, 0).Err())
+
+		countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(99), countsByRoute["user-events"]["delivered"])
+
+		assert.Eventually(t, func() bool {
+			countsByRoute, err := collector.GetStatusCountsByRoute(ctx)
+			return err == nil && countsByRoute["user-events"]["delivered"] == 3
+		}, 2*time.Second, 50*time.Millisecond)
+	})
+}
+
+// afterScan runs fn once, right after the first pipeline reading webhook statuses (HMGET) was sent
+type afterScan struct {
+	fn   func()
+	done atomic.Bool
+}
+
+func (h *afterScan) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (h *afterScan) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return next
+}
+
+func (h *afterScan) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		err := next(ctx, cmds)
+		if len(cmds) > 0 && cmds[0].Name() == "hmget" && h.done.CompareAndSwap(false, true) {
+			h.fn()
+		}
+		return err
+	}
+}
+
+func TestRedisCollector_GetThroughput_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	now := time.Now()
+	deliveries := []struct {
+		routeID string
+		ago     time.Duration
+	}{
+		{"user-events", 10 * time.Second},
+		{"analytics", 30 * time.Second},
+		{"user-events", 3 * time.Minute},
+		{"analytics", 10 * time.Minute},
+		{"analytics", 14 * time.Minute},
+		{"user-events", 20 * time.Minute}, // outside every window
+	}
+	for i, d := range deliveries {
+		require.NoError(t, repo.RecordDelivery(ctx, d.routeID, fmt.Sprintf("evt-%d", i), now.Add(-d.ago)))
+	}
+
+	throughput, err := collector.GetThroughput(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), throughput.LastMinute)
+	assert.Equal(t, int64(3), throughput.LastFiveMinutes)
+	assert.Equal(t, int64(5), throughput.LastFifteenMinutes)
+
+	t.Run("old deliveries are pruned", func(t *testing.T) {
+		count, err := repo.GetClient().ZCard(ctx, redis.DeliveriesKey("user-events")).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("delivered transitions are recorded", func(t *testing.T) {
+		storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Delivered, 2)
+
+		throughput, err := collector.GetThroughput(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), throughput.LastMinute)
+	})
+}
+
+func TestRedisCollector_GetConsumerLags_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 3)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Pending, 2)
+
+	lags, err := collector.GetConsumerLags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), lags["user-events"])
+	assert.Equal(t, int64(2), lags["analytics"])
+	assert.Equal(t, int64(0), lags["idle-route"])
+
+	webhooks, err := repo.Consume(ctx, "user-events", webhook.FIFO)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.NoError(t, repo.Acknowledge(ctx, "user-events", webhook.FIFO, webhooks[0].ID))
+
+	lags, err = collector.GetConsumerLags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), lags["user-events"])
+
+	// The collector agrees with the repository
+	lag, err := repo.GroupLag(ctx, "user-events", webhook.FIFO)
+	require.NoError(t, err)
+	assert.Equal(t, lag, lags["user-events"])
+
+	// XLEN still counts the acknowledged entry
+	queueLengths, err := collector.GetQueueLengths(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), queueLengths["user-events"])
+}
+
+func TestRedisCollector_GetOldestMessageAges_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 1)
+	time.Sleep(500 * time.Millisecond)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Pending, 1)
+
+	ages, err := collector.GetOldestMessageAges(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ages["user-events"], 0.5)
+	assert.Less(t, ages["analytics"], ages["user-events"])
+	assert.Equal(t, 0.0, ages["idle-route"])
+
+	// The collector agrees with the repository
+	age, err := repo.OldestUndeliveredAge(ctx, "user-events", webhook.FIFO)
+	require.NoError(t, err)
+	assert.InDelta(t, age.Seconds(), ages["user-events"], 1)
+
+	var pendingID string
+	t.Run("acknowledged entries before the oldest pending one are ignored", func(t *testing.T) {
+		// user-events now holds an older entry, then one stored after a pause
+		time.Sleep(time.Second)
+		storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 1)
+
+		acked, err := repo.Consume(ctx, "user-events", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, acked, 1)
+		require.NoError(t, repo.Acknowledge(ctx, "user-events", webhook.FIFO, acked[0].ID))
+
+		// The newer entry is read but left pending
+		pending, err := repo.Consume(ctx, "user-events", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		pendingID = pending[0].ID
+
+		// A newer entry nobody has read yet doesn't hide the pending one
+		time.Sleep(500 * time.Millisecond)
+		storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 1)
+
+		info, err := repo.StreamInfo(ctx, "user-events", webhook.FIFO)
+		require.NoError(t, err)
+
+		ages, err := collector.GetOldestMessageAges(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, ages["user-events"], 0.5, "the pending entry is older than the unread one")
+		assert.Less(t, ages["user-events"], info.OldestAge.Seconds()-0.5, "the acknowledged first entry is ignored")
+	})
+
+	t.Run("a drained stream reports 0", func(t *testing.T) {
+		require.NotEmpty(t, pendingID)
+		require.NoError(t, repo.Acknowledge(ctx, "user-events", webhook.FIFO, pendingID))
+		unread, err := repo.Consume(ctx, "user-events", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, unread, 1)
+		require.NoError(t, repo.Acknowledge(ctx, "user-events", webhook.FIFO, unread[0].ID))
+
+		length, err := collector.GetQueueLengths(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), length["user-events"], "acknowledged entries stay until trimmed")
+
+		ages, err := collector.GetOldestMessageAges(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, ages["user-events"])
+	})
+}
+
+func TestRedisCollector_StreamKeys_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+	client := repo.GetClient()
+
+	storeWebhooks(t, ctx, repo, "user-events", webhook.FIFO, webhook.Pending, 2)
+	storeWebhooks(t, ctx, repo, "analytics", webhook.PubSub, webhook.Pending, 1)
+
+	queueLengths, err := collector.GetQueueLengths(ctx)
+	require.NoError(t, err)
+
+	// The repository writes to the keys the exported helpers name
+	for routeID, mode := range map[string]webhook.DeliveryMode{"user-events": webhook.FIFO, "analytics": webhook.PubSub} {
+		length, err := client.XLen(ctx, redis.StreamKey(routeID, mode)).Result()
+		require.NoError(t, err)
+		assert.Equal(t, queueLengths[routeID], length, routeID)
+
+		indexed, err := client.ZCard(ctx, redis.IndexKey(routeID)).Result()
+		require.NoError(t, err)
+		assert.Equal(t, length, indexed, routeID)
+	}
+
+	webhooks, err := repo.Consume(ctx, "analytics", webhook.PubSub)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.NoError(t, repo.MoveToDLQ(ctx, webhooks[0]))
+
+	deadLettered, err := client.ZScore(ctx, redis.DLQKey("analytics"), webhooks[0].ID).Result()
+	require.NoError(t, err)
+	assert.Positive(t, deadLettered)
+}
+
+func TestRedisCollector_GetPayloadSummary_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo, collector := setupCollector(t, ctx)
+
+	store := func(routeID string, mode webhook.DeliveryMode, body string) {
+		t.Helper()
+		_, err := repo.Store(ctx, webhook.Webhook{
+			ID:           fmt.Sprintf("%s-%d", routeID, time.Now().UnixNano()),
+			RouteID:      routeID,
+			Payload:      []byte(body),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: mode,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	event := func(eventType string, padding int) string {
+		return fmt.Sprintf(`{"type":%q,"timestamp":"2024-01-01T12:00:00Z","data":{"padding":%q}}`,
+			eventType, strings.Repeat("x", padding))
+	}
+
+	// 19 small user events and one large one
+	for i := 0; i < 19; i++ {
+		store("user-events", webhook.FIFO, event("user.created", 10))
+	}
+	store("user-events", webhook.FIFO, event("user.deleted", 5000))
+	store("analytics", webhook.PubSub, event("page.viewed", 100))
+	store("analytics", webhook.PubSub, `{"not":"a standard webhook"}`)
+
+	summary, err := collector.GetPayloadSummary(ctx)
+	require.NoError(t, err)
+
+	small, large := int64(len(event("user.created", 10))), int64(len(event("user.deleted", 5000)))
+	assert.Equal(t, small, summary.SizeP50["user-events"])
+	assert.Equal(t, small, summary.SizeP95["user-events"])
+	assert.Equal(t, int64(len(`{"not":"a standard webhook"}`)), summary.SizeP50["analytics"])
+	assert.Equal(t, int64(len(event("page.viewed", 100))), summary.SizeP95["analytics"])
+	assert.NotContains(t, summary.SizeP50, "idle-route", "routes without webhooks have no percentiles")
+
+	assert.Equal(t, map[string]int64{"user.created": 19, "user.deleted": 1, "page.viewed": 1}, summary.EventTypeCounts)
+
+	t.Run("large outliers show in the 95th percentile", func(t *testing.T) {
+		store("user-events", webhook.FIFO, event("user.deleted", 5000))
+
+		summary, err := collector.GetPayloadSummary(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, small, summary.SizeP50["user-events"])
+		assert.Equal(t, large, summary.SizeP95["user-events"])
+	})
+
+	t.Run("collect includes the summary", func(t *testing.T) {
+		m, err := collector.Collect(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, small, m.PayloadSizeP50["user-events"])
+		assert.Equal(t, large, m.PayloadSizeP95["user-events"])
+		assert.Equal(t, int64(2), m.EventTypeCounts["user.deleted"])
+	})
+}
+
+/* Compare reading counters against scanning every webhook hash:
+ *   go test -tags=integration -bench=StatusCounts -run=^$ ./metrics/
+ */
+func BenchmarkStatusCounts_Integration(b *testing.B) {
+	ctx := context.Background()
+	repo, collector := setupCollector(b, ctx)
+
+	storeWebhooks(b, ctx, repo, "user-events", webhook.FIFO, webhook.Delivered, 1000)
+	storeWebhooks(b, ctx, repo, "analytics", webhook.PubSub, webhook.Pending, 1000)
+
+	b.Run("counters", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := collector.GetStatusCountsByRoute(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := collector.scanStatusCountsByRoute(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -1,10 +1,24 @@
 package metrics
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	webhookredis "github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/marcelsud/webhook-inbox/webhook/webhooktest"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRedisCollector_NewRedisCollector(t *testing.T) {
@@ -13,9 +27,7 @@ func TestRedisCollector_NewRedisCollector(t *testing.T) {
 		// It doesn't require Redis connection for the constructor
 		loader := routes.NewLoader()
 
-		// Note: In a real test, you would use a mock Redis client
-		// For this unit test, we're just testing the constructor
-		collector := NewRedisCollector(nil, loader)
+		collector := NewRedisCollector(newTestRepository(t, false), loader)
 
 		assert.NotNil(t, collector)
 		assert.NotNil(t, collector.routesLoader)
@@ -100,5 +112,232 @@ func TestCollector_Interface(t *testing.T) {
 	})
 }
 
+func TestPercentile(t *testing.T) {
+	sizes := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+
+	assert.Equal(t, int64(50), percentile(sizes, 50))
+	assert.Equal(t, int64(100), percentile(sizes, 95))
+	assert.Equal(t, int64(10), percentile(sizes, 0))
+	assert.Equal(t, int64(7), percentile([]int64{7}, 50))
+	assert.Equal(t, int64(7), percentile([]int64{7}, 95))
+}
+
+func TestEntryPayload(t *testing.T) {
+	t.Run("entries record the payload size and event type", func(t *testing.T) {
+		size, eventType, ok := entryPayload(map[string]interface{}{"payload_size": "1234", "event_type": "user.created"})
+		require.True(t, ok)
+		assert.Equal(t, int64(1234), size)
+		assert.Equal(t, "user.created", eventType)
+	})
+
+	t.Run("raw payloads have no event type", func(t *testing.T) {
+		size, eventType, ok := entryPayload(map[string]interface{}{"payload_size": "12"})
+		require.True(t, ok)
+		assert.Equal(t, int64(12), size)
+		assert.Empty(t, eventType)
+	})
+
+	t.Run("older entries carry the payload", func(t *testing.T) {
+		body := `{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`
+		size, eventType, ok := entryPayload(map[string]interface{}{"payload": body})
+		require.True(t, ok)
+		assert.Equal(t, int64(len(body)), size)
+		assert.Equal(t, "user.created", eventType)
+	})
+
+	t.Run("entries without either are skipped", func(t *testing.T) {
+		_, _, ok := entryPayload(map[string]interface{}{"event_id": "evt-1"})
+		assert.False(t, ok)
+	})
+}
+
+// errNotSent fails every command keyRecorder intercepts, so no Redis server is needed
+var errNotSent = errors.New("command not sent")
+
+// keyRecorder records the key of every command instead of sending it
+type keyRecorder struct {
+	keys  map[string]bool
+	reply error // Returned for every command; nil pretends it succeeded
+}
+
+func (h *keyRecorder) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *keyRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.record(cmd)
+		return h.reply
+	}
+}
+
+func (h *keyRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.record(cmd)
+		}
+		return h.reply
+	}
+}
+
+func (h *keyRecorder) record(cmd redis.Cmder) {
+	args := cmd.Args()
+	if h.reply != nil {
+		cmd.SetErr(h.reply)
+	}
+	if len(args) < 2 {
+		return // MULTI, EXEC
+	}
+	key := args[1]
+	if cmd.Name() == "xinfo" {
+		key = args[2] // XINFO GROUPS|STREAM <key>
+	}
+	h.keys[key.(string)] = true
+}
+
+func TestRedisCollector_StreamKeys(t *testing.T) {
+	ctx := context.Background()
+
+	routesFile := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(routesFile, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    parallelism: 1
+  - route_id: "analytics"
+    target_url: "https://example.com/analytics"
+    mode: "pubsub"
+    parallelism: 1
+  - route_id: "billing"
+    target_url: "https://example.com/billing"
+    mode: "pubsub"
+    parallelism: 1
+groups:
+  - group_id: "low-volume"
+    route_ids: ["analytics", "billing"]
+`), 0o644))
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(routesFile))
+
+	tests := []struct {
+		name    string
+		cluster bool
+		streams map[string]bool
+	}{
+		{
+			name:    "single node",
+			streams: map[string]bool{"webhooks:fifo:user-events": true, "webhooks:pubsub:low-volume": true},
+		},
+		{
+			name:    "cluster hash tags",
+			cluster: true,
+			streams: map[string]bool{"webhooks:fifo:{user-events}": true, "webhooks:pubsub:{low-volume}": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, tt.cluster, webhookredis.WithRouteGroups(loader.GroupOf))
+
+			// Record the keys the repository writes when storing a webhook of each route
+			recorder := &keyRecorder{keys: make(map[string]bool)}
+			repo.GetClient().AddHook(recorder)
+			for _, route := range loader.List() {
+				_, err := repo.Store(ctx, webhook.Webhook{
+					ID:           "evt-" + route.RouteID,
+					RouteID:      route.RouteID,
+					Payload:      []byte(`{}`),
+					Status:       webhook.Pending,
+					DeliveryMode: route.Mode,
+					CreatedAt:    time.Now(),
+					UpdatedAt:    time.Now(),
+				})
+				require.NoError(t, err)
+			}
+
+			written := recorder.keys
+			recorder.keys, recorder.reply = make(map[string]bool), errNotSent
+			collector := NewRedisCollector(repo, loader)
+			collector.GetQueueLengths(ctx)
+			collector.GetConsumerLags(ctx)
+			collector.GetOldestMessageAges(ctx)
+			collector.GetPayloadSummary(ctx)
+
+			// The collector reads the streams the repository writes
+			assert.Equal(t, tt.streams, recorder.keys)
+			for stream := range recorder.keys {
+				assert.True(t, written[stream], "the repository never wrote %s", stream)
+			}
+			assert.Equal(t, "webhook-workers-low-volume", repo.GroupName("billing"))
+		})
+	}
+}
+
+// newTestRepository connects a repository to a fake Redis, a single node or a one-node cluster
+func newTestRepository(t *testing.T, cluster bool, opts ...webhookredis.Option) *webhookredis.Repository {
+	t.Helper()
+
+	addr := fakeRedis(t)
+	var repo *webhookredis.Repository
+	var err error
+	if cluster {
+		repo, err = webhookredis.NewClusterRepository([]string{addr}, "", opts...)
+	} else {
+		repo, err = webhookredis.NewRepositoryWithContext(context.Background(), addr, "", 0, opts...)
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close(context.Background()) })
+	return repo
+}
+
+// fakeRedis speaks just enough RESP for a repository to connect: PING, AUTH, SELECT and CLUSTER SLOTS,
+// which maps every slot to itself. Every other command is rejected
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	slots := fmt.Sprintf("*1\r\n*3\r\n:0\r\n:16383\r\n*2\r\n$%d\r\n%s\r\n:%s\r\n", len(host), host, port)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := webhooktest.ReadRESPCommand(reader)
+					if err != nil {
+						return
+					}
+					reply := "-ERR unknown command\r\n"
+					switch {
+					case len(args) == 0:
+					case strings.EqualFold(args[0], "PING"):
+						reply = "+PONG\r\n"
+					case strings.EqualFold(args[0], "AUTH"), strings.EqualFold(args[0], "SELECT"):
+						reply = "+OK\r\n"
+					case strings.EqualFold(args[0], "CLUSTER") && len(args) > 1 && strings.EqualFold(args[1], "SLOTS"):
+						reply = slots
+					}
+					if _, err := conn.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
 // Note: Full integration tests that require Redis should be placed in
 // redis_collector_integration_test.go with build tag "integration"
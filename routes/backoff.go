@@ -0,0 +1,287 @@
+package routes
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/* Backoff expressions describe the retry delay in milliseconds
+ * Supported: numbers, the variable "retried", + - * / and parentheses,
+ * and the functions pow(x, y), min(a, b, ...) and max(a, b, ...)
+ * e.g. "pow(2, retried) * 1000" or "min(pow(2, retried) * 1000, 60000)"
+ */
+
+// Backoff returns the delay before the next attempt after `retried` retries
+// An empty expression means retrying immediately
+func (r *Route) Backoff(retried int) (time.Duration, error) {
+	if strings.TrimSpace(r.RetryBackoff) == "" {
+		return 0, nil
+	}
+
+	ms, err := evalBackoff(r.RetryBackoff, float64(retried))
+	if err != nil {
+		return 0, fmt.Errorf("evaluating retry_backoff for route %s: %w", r.RouteID, err)
+	}
+	if ms < 0 || math.IsNaN(ms) {
+		return 0, nil
+	}
+	if ms > float64(math.MaxInt64/int64(time.Millisecond)) {
+		return time.Duration(math.MaxInt64), nil
+	}
+
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// RetryDeadline returns when retries of a webhook created at createdAt stop (MaxRetryWindowHours)
+// ok is false when the route has no retry window
+func (r *Route) RetryDeadline(createdAt time.Time) (deadline time.Time, ok bool) {
+	if r.MaxRetryWindowHours <= 0 {
+		return time.Time{}, false
+	}
+	return createdAt.Add(time.Duration(r.MaxRetryWindowHours) * time.Hour), true
+}
+
+// JitteredBackoff returns Backoff spread by up to ±RetryJitter of the delay,
+// i.e. delay * (1 ± rnd*jitter), so webhooks failing together don't retry together
+// rnd is not safe for concurrent use; callers sharing it must serialize calls
+func (r *Route) JitteredBackoff(retried int, rnd *rand.Rand) (time.Duration, error) {
+	delay, err := r.Backoff(retried)
+	if err != nil || r.RetryJitter <= 0 || delay <= 0 {
+		return delay, err
+	}
+
+	factor := 1 + (2*rnd.Float64()-1)*r.RetryJitter
+	jittered := float64(delay) * factor
+	if jittered >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64), nil
+	}
+	return time.Duration(jittered), nil
+}
+
+// evalBackoff evaluates a backoff expression for the given retry count
+func evalBackoff(expr string, retried float64) (float64, error) {
+	p := &backoffParser{input: expr, retried: retried}
+	value, err := p.parseExpr()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+
+	return value, nil
+}
+
+// backoffParser is a small recursive-descent parser over a backoff expression
+type backoffParser struct {
+	input   string
+	pos     int
+	retried float64
+}
+
+// parseExpr handles addition and subtraction
+func (p *backoffParser) parseExpr() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		p.skipSpaces()
+		switch {
+		case p.consume('+'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case p.consume('-'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+// parseTerm handles multiplication and division
+func (p *backoffParser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		p.skipSpaces()
+		switch {
+		case p.consume('*'):
+			right, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			left *= right
+		case p.consume('/'):
+			right, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+// parseUnary handles a leading minus sign
+func (p *backoffParser) parseUnary() (float64, error) {
+	p.skipSpaces()
+	if p.consume('-') {
+		value, err := p.parseUnary()
+		return -value, err
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary handles numbers, identifiers, function calls and parentheses
+func (p *backoffParser) parsePrimary() (float64, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+
+	c := rune(p.input[p.pos])
+	switch {
+	case c == '(':
+		p.pos++
+		value, err := p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		p.skipSpaces()
+		if !p.consume(')') {
+			return 0, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		return value, nil
+	case unicode.IsDigit(c) || c == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(c):
+		return p.parseIdent()
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+	}
+}
+
+func (p *backoffParser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
+
+func (p *backoffParser) parseIdent() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+
+	p.skipSpaces()
+	if !p.consume('(') {
+		if name == "retried" {
+			return p.retried, nil
+		}
+		return 0, fmt.Errorf("unknown variable %q", name)
+	}
+
+	args, err := p.parseArgs()
+	if err != nil {
+		return 0, err
+	}
+
+	switch name {
+	case "pow":
+		if len(args) != 2 {
+			return 0, fmt.Errorf("pow expects 2 arguments, got %d", len(args))
+		}
+		return math.Pow(args[0], args[1]), nil
+	case "min", "max":
+		if len(args) == 0 {
+			return 0, fmt.Errorf("%s expects at least 1 argument", name)
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			if name == "min" {
+				result = math.Min(result, arg)
+			} else {
+				result = math.Max(result, arg)
+			}
+		}
+		return result, nil
+	default:
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+// parseArgs reads a comma-separated argument list after the opening parenthesis
+func (p *backoffParser) parseArgs() ([]float64, error) {
+	var args []float64
+
+	p.skipSpaces()
+	if p.consume(')') {
+		return args, nil
+	}
+
+	for {
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+
+		p.skipSpaces()
+		if p.consume(',') {
+			continue
+		}
+		if p.consume(')') {
+			return args, nil
+		}
+		return nil, fmt.Errorf("expected ',' or ')' at position %d", p.pos)
+	}
+}
+
+func (p *backoffParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *backoffParser) peekIs(c byte) bool {
+	return p.pos < len(p.input) && p.input[p.pos] == c
+}
+
+func (p *backoffParser) consume(c byte) bool {
+	if p.peekIs(c) {
+		p.pos++
+		return true
+	}
+	return false
+}
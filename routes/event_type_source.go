@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+)
+
+/* Event type sources tell ingestion where a route's event type comes from
+ * Senders that can't produce Standard Webhooks payloads post plain JSON; the type is read
+ * from a header or the body and the inbox wraps the body as the data of a Standard Webhooks payload
+ */
+const (
+	EventTypeSourcePayload  = "payload"   // The type field of a Standard Webhooks payload (default)
+	EventTypeSourceHeader   = "header:"   // Prefix of "header:<name>", e.g. "header:X-Event-Type"
+	EventTypeSourceJSONPath = "jsonpath:" // Prefix of "jsonpath:<expr>", e.g. "jsonpath:$.event.type"
+)
+
+// ErrNoEventType is returned by Route.EventType when the request doesn't carry an event type
+var ErrNoEventType = errors.New("event type not found")
+
+// DerivesEventType reports whether the route types plain JSON bodies from a header or JSON path
+// instead of expecting Standard Webhooks payloads
+func (r *Route) DerivesEventType() bool {
+	return r.EventTypeSource != "" && r.EventTypeSource != EventTypeSourcePayload
+}
+
+// EventType reads the event type of a request from the route's EventTypeSource
+// Only meaningful when DerivesEventType is true
+func (r *Route) EventType(header http.Header, body []byte) (string, error) {
+	if name, ok := strings.CutPrefix(r.EventTypeSource, EventTypeSourceHeader); ok {
+		eventType := header.Get(name)
+		if eventType == "" {
+			return "", fmt.Errorf("%w: missing %s header", ErrNoEventType, name)
+		}
+		return eventType, nil
+	}
+
+	path, err := r.eventTypePath()
+	if err != nil {
+		return "", err
+	}
+	eventType, err := path.Lookup(body)
+	if errors.Is(err, payload.ErrPathNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrNoEventType, err)
+	}
+	return eventType, err
+}
+
+// eventTypePath compiles the JSON path of a "jsonpath:<expr>" source
+func (r *Route) eventTypePath() (payload.JSONPath, error) {
+	expr, ok := strings.CutPrefix(r.EventTypeSource, EventTypeSourceJSONPath)
+	if !ok {
+		return payload.JSONPath{}, fmt.Errorf("event_type_source must be %q, %q or %q (got %q)",
+			EventTypeSourcePayload, EventTypeSourceHeader+"<name>", EventTypeSourceJSONPath+"<expr>", r.EventTypeSource)
+	}
+	return payload.ParseJSONPath(expr)
+}
+
+// validateEventTypeSource checks the event type source and the settings it can't be combined with
+func (r *Route) validateEventTypeSource() error {
+	if !r.DerivesEventType() {
+		return nil
+	}
+	// Derived types are wrapped into Standard Webhooks payloads
+	if r.PayloadFormat == PayloadFormatRaw || r.AcceptRaw {
+		return fmt.Errorf("event_type_source cannot be used with raw payloads for route %s", r.RouteID)
+	}
+	if name, ok := strings.CutPrefix(r.EventTypeSource, EventTypeSourceHeader); ok {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("event_type_source needs a header name for route %s", r.RouteID)
+		}
+		return nil
+	}
+	if _, err := r.eventTypePath(); err != nil {
+		return fmt.Errorf("invalid event_type_source for route %s: %w", r.RouteID, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+)
+
+/* eventMatcher is an event type filter compiled once when a route is loaded
+ * Matching follows payload.MatchesEventType, but walks the event type in place
+ * instead of splitting pattern and type on every message
+ */
+type eventMatcher struct {
+	pattern  string
+	segments []string // Pattern segments; nil when the pattern has no wildcard
+}
+
+func compileEventTypes(patterns []string) []eventMatcher {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	matchers := make([]eventMatcher, len(patterns))
+	for i, pattern := range patterns {
+		matchers[i] = eventMatcher{pattern: pattern}
+		if strings.Contains(pattern, "*") {
+			matchers[i].segments = strings.Split(pattern, ".")
+		}
+	}
+	return matchers
+}
+
+func (m eventMatcher) match(eventType string) bool {
+	if eventType == m.pattern {
+		return true
+	}
+	if m.segments == nil {
+		return false
+	}
+
+	// more reports whether rest still holds a segment (an empty one included)
+	rest, more := eventType, true
+	for i, segment := range m.segments {
+		last := i == len(m.segments)-1
+
+		switch {
+		case segment == "**" && last:
+			return true
+		case segment == "*" && last:
+			// Trailing wildcard keeps prefix semantics: one or more segments
+			return more
+		case !more:
+			return false
+		}
+
+		var typeSegment string
+		typeSegment, rest, more = strings.Cut(rest, ".")
+		if segment != "*" && segment != typeSegment {
+			return false
+		}
+	}
+
+	return !more
+}
+
+// matchAny reports whether eventType matches any of the matchers
+// patterns is the uncompiled form, used when the matchers are missing or stale
+// (e.g. routes built as struct literals rather than loaded)
+func matchAny(matchers []eventMatcher, patterns []string, eventType string) bool {
+	if len(matchers) != len(patterns) {
+		p := payload.StandardPayload{Type: eventType}
+		return p.MatchesEventType(patterns)
+	}
+
+	for _, m := range matchers {
+		if m.match(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileEventTypes precompiles the route's event type filters
+// Called when the route is loaded; routes built otherwise match without the cache
+func (r *Route) compileEventTypes() {
+	r.eventMatchers = compileEventTypes(r.EventTypes)
+	r.priorityMatchers = compileEventTypes(r.PriorityEventTypes)
+}
+
+// MatchesType reports whether the route subscribes to an event type
+// Semantics are those of payload.MatchesEventType: a route without EventTypes accepts every type
+func (r *Route) MatchesType(eventType string) bool {
+	if len(r.EventTypes) == 0 {
+		return true
+	}
+	return matchAny(r.eventMatchers, r.EventTypes, eventType)
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	matcherPatterns = []string{
+		"user", "user.created", "user.*", "user.**", "*", "**",
+		"*.created", "user.*.created", "*.admin.**", "us.*", "us.**",
+		"order.item.shipped", "user.**.created", "user.",
+	}
+	matcherTypes = []string{
+		"", "user", "user.", "user.created", "user.deleted", "user.admin.created",
+		"user.admin.team.created", "users.created", "us", "order.created",
+		"order.item.shipped", "user..created", ".created", "user.*", "user.**.created",
+	}
+)
+
+func TestEventMatcher_MatchesPayloadSemantics(t *testing.T) {
+	for _, m := range compileEventTypes(matcherPatterns) {
+		for _, eventType := range matcherTypes {
+			p := payload.StandardPayload{Type: eventType}
+			assert.Equal(t, p.MatchesEventType([]string{m.pattern}), m.match(eventType),
+				"pattern %q, type %q", m.pattern, eventType)
+		}
+	}
+}
+
+func TestRoute_MatchesType(t *testing.T) {
+	filters := [][]string{nil, {"user.created"}, {"order.*", "user.**"}, {"*.created", "invoice.paid"}}
+
+	for _, eventTypes := range filters {
+		loaded := &Route{EventTypes: eventTypes}
+		loaded.compileEventTypes()
+		literal := &Route{EventTypes: eventTypes}
+
+		for _, eventType := range matcherTypes {
+			p := payload.StandardPayload{Type: eventType}
+			want := p.MatchesEventType(eventTypes)
+			assert.Equal(t, want, loaded.MatchesType(eventType), "filters %q, type %q", eventTypes, eventType)
+			assert.Equal(t, want, literal.MatchesType(eventType), "uncompiled filters %q, type %q", eventTypes, eventType)
+		}
+	}
+}
+
+var benchmarkEventTypes = []string{"order.*", "invoice.**", "user.*.deleted", "user.created"}
+
+func BenchmarkMatchesEventType(b *testing.B) {
+	p := payload.StandardPayload{Type: "user.created"}
+	for b.Loop() {
+		p.MatchesEventType(benchmarkEventTypes)
+	}
+}
+
+func BenchmarkRoute_MatchesType(b *testing.B) {
+	route := &Route{EventTypes: benchmarkEventTypes}
+	route.compileEventTypes()
+	for b.Loop() {
+		route.MatchesType("user.created")
+	}
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/* Expected statuses describe which target responses count as a successful delivery
+ * Each entry is a single code ("204"), a class ("2xx") or an inclusive range ("200-204")
+ * Only 2xx codes can be expected; routes without expected statuses accept any 2xx
+ */
+
+// DefaultExpectedStatus is used when a route sets neither expected_status nor expected_statuses
+const DefaultExpectedStatus = "2xx"
+
+// statusRange is an inclusive range of HTTP status codes
+type statusRange struct {
+	min, max int
+}
+
+// IsExpectedStatus reports whether a target response status means the webhook was delivered
+// Priority: ExpectedStatuses > ExpectedStatus > any 2xx
+func (r *Route) IsExpectedStatus(code int) bool {
+	ranges, err := r.expectedStatusRanges()
+	if err != nil {
+		return false
+	}
+
+	for _, sr := range ranges {
+		if code >= sr.min && code <= sr.max {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryableStatus reports whether retrying a delivery rejected with this status may succeed
+// Client errors (4xx) mean the target refused the request itself, so they are final,
+// except 408 Request Timeout and 429 Too Many Requests, which are transient
+func (r *Route) IsRetryableStatus(code int) bool {
+	if code < 400 || code > 499 {
+		return true
+	}
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// AcceptedStatuses returns the expected statuses in effect for the route, as configured
+func (r *Route) AcceptedStatuses() []string {
+	switch {
+	case len(r.ExpectedStatuses) > 0:
+		return r.ExpectedStatuses
+	case r.ExpectedStatus != 0:
+		return []string{strconv.Itoa(r.ExpectedStatus)}
+	default:
+		return []string{DefaultExpectedStatus}
+	}
+}
+
+// PrimaryExpectedStatus returns the single code standing for the route's expected statuses
+// That is expected_status when set, otherwise the lowest code of the first accepted status
+// (200 for the default "2xx"); 0 only when the accepted statuses don't parse
+func (r *Route) PrimaryExpectedStatus() int {
+	if r.ExpectedStatus != 0 {
+		return r.ExpectedStatus
+	}
+	sr, err := parseStatusRange(r.AcceptedStatuses()[0])
+	if err != nil {
+		return 0
+	}
+	return sr.min
+}
+
+// expectedStatusRanges parses the route's accepted statuses
+func (r *Route) expectedStatusRanges() ([]statusRange, error) {
+	accepted := r.AcceptedStatuses()
+	ranges := make([]statusRange, 0, len(accepted))
+	for _, s := range accepted {
+		sr, err := parseStatusRange(s)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, sr)
+	}
+	return ranges, nil
+}
+
+// parseStatusRange parses "204", "2xx" or "200-204"
+func parseStatusRange(s string) (statusRange, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	var sr statusRange
+	switch {
+	case len(s) == 3 && strings.HasSuffix(s, "xx"):
+		class, err := strconv.Atoi(s[:1])
+		if err != nil {
+			return statusRange{}, fmt.Errorf("invalid status class %q", s)
+		}
+		sr = statusRange{min: class * 100, max: class*100 + 99}
+	case strings.Contains(s, "-"):
+		from, to, _ := strings.Cut(s, "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return statusRange{}, fmt.Errorf("invalid status range %q", s)
+		}
+		hi, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return statusRange{}, fmt.Errorf("invalid status range %q", s)
+		}
+		if lo > hi {
+			return statusRange{}, fmt.Errorf("invalid status range %q: %d is greater than %d", s, lo, hi)
+		}
+		sr = statusRange{min: lo, max: hi}
+	default:
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return statusRange{}, fmt.Errorf("invalid status %q", s)
+		}
+		sr = statusRange{min: code, max: code}
+	}
+
+	if sr.min < 200 || sr.max > 299 {
+		return statusRange{}, fmt.Errorf("status %q is outside 200-299", s)
+	}
+	return sr, nil
+}
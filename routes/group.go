@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* RouteGroup fans several low-volume routes into one stream and consumer group
+ * Webhooks keep their route ID, so each is still delivered with its own route's settings
+ * (target URL, retries, signing...) by the worker draining the group
+ */
+type RouteGroup struct {
+	GroupID     string
+	RouteIDs    []string
+	Mode        webhook.DeliveryMode // Delivery mode shared by every route of the group
+	Parallelism int                  // Concurrent deliveries of the group's worker (1 for FIFO)
+}
+
+// Route returns the route a group's worker is created with (see worker.WithRouteLookup)
+// It carries the group ID, mode and parallelism only; deliveries use the webhooks' own routes
+func (g *RouteGroup) Route() *Route {
+	return &Route{
+		RouteID:     g.GroupID,
+		Mode:        g.Mode,
+		Parallelism: g.Parallelism,
+	}
+}
+
+// toGroups validates the groups of a routes file against its routes
+// Group IDs name the shared streams, so they may not collide with route IDs
+func toGroups(configs []RouteGroupConfig, routes map[string]*Route) (map[string]*RouteGroup, error) {
+	groups := make(map[string]*RouteGroup, len(configs))
+	memberOf := make(map[string]string)
+
+	for _, gc := range configs {
+		if gc.GroupID == "" {
+			return nil, fmt.Errorf("group_id cannot be empty")
+		}
+		if _, exists := groups[gc.GroupID]; exists {
+			return nil, fmt.Errorf("duplicate group_id %s", gc.GroupID)
+		}
+		if _, exists := routes[gc.GroupID]; exists {
+			return nil, fmt.Errorf("group_id %s is already a route_id", gc.GroupID)
+		}
+		if len(gc.RouteIDs) == 0 {
+			return nil, fmt.Errorf("group %s has no route_ids", gc.GroupID)
+		}
+
+		group := &RouteGroup{GroupID: gc.GroupID, RouteIDs: gc.RouteIDs, Parallelism: gc.Parallelism}
+		if group.Parallelism == 0 {
+			group.Parallelism = 1
+		}
+		for i, routeID := range gc.RouteIDs {
+			route, exists := routes[routeID]
+			if !exists {
+				return nil, fmt.Errorf("group %s: route not found: %s", gc.GroupID, routeID)
+			}
+			if other, grouped := memberOf[routeID]; grouped {
+				return nil, fmt.Errorf("group %s: route %s already belongs to group %s", gc.GroupID, routeID, other)
+			}
+			if i == 0 {
+				group.Mode = route.Mode
+			} else if route.Mode != group.Mode {
+				return nil, fmt.Errorf("group %s: route %s is %s but the group is %s", gc.GroupID, routeID, route.Mode, group.Mode)
+			}
+			memberOf[routeID] = gc.GroupID
+		}
+
+		if group.Parallelism < 1 {
+			return nil, fmt.Errorf("parallelism must be at least 1 for group %s", gc.GroupID)
+		}
+		if group.Mode == webhook.FIFO && group.Parallelism > 1 {
+			return nil, fmt.Errorf("FIFO mode requires parallelism=1 for group %s (got %d)", gc.GroupID, group.Parallelism)
+		}
+		groups[gc.GroupID] = group
+	}
+
+	return groups, nil
+}
+
+// groupIndex maps every grouped route ID to its group ID
+func groupIndex(groups map[string]*RouteGroup) map[string]string {
+	index := make(map[string]string)
+	for _, group := range groups {
+		for _, routeID := range group.RouteIDs {
+			index[routeID] = group.GroupID
+		}
+	}
+	return index
+}
+
+// GroupOf returns the group a route belongs to, or "" when it has its own stream
+// Meant for redis.WithRouteGroups, so grouped routes are stored on their group's stream
+func (l *Loader) GroupOf(routeID string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.groupOf[routeID]
+}
+
+// Groups returns every loaded route group, sorted by group ID
+func (l *Loader) Groups() []*RouteGroup {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	groups := make([]*RouteGroup, 0, len(l.groups))
+	for _, group := range l.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+	return groups
+}
@@ -1,9 +1,16 @@
 package routes
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
+	"sync"
 
+	"github.com/marcelsud/webhook-inbox/config"
 	"github.com/marcelsud/webhook-inbox/webhook"
 	"gopkg.in/yaml.v3"
 )
@@ -14,82 +21,339 @@ import (
 
 // Config represents the structure of routes.yaml
 type Config struct {
-	Routes []RouteConfig `yaml:"routes"`
+	Routes []RouteConfig      `yaml:"routes"`
+	Groups []RouteGroupConfig `yaml:"groups,omitempty"` // Optional: routes sharing a stream and worker (see RouteGroup)
+}
+
+// RouteGroupConfig represents a route group in the YAML file
+type RouteGroupConfig struct {
+	GroupID     string   `yaml:"group_id"`
+	RouteIDs    []string `yaml:"route_ids"`
+	Parallelism int      `yaml:"parallelism"` // Optional: concurrent deliveries (default: 1)
 }
 
 // RouteConfig represents a single route in the YAML file
 type RouteConfig struct {
-	RouteID           string   `yaml:"route_id"`
-	TargetURL         string   `yaml:"target_url"`
-	Mode              string   `yaml:"mode"`
-	MaxRetries        int      `yaml:"max_retries"`
-	RetryBackoff      string   `yaml:"retry_backoff"`
-	Parallelism       int      `yaml:"parallelism"`
-	ExpectedStatus    int      `yaml:"expected_status"`     // Default: 202
-	DeliveredTTLHours *int     `yaml:"delivered_ttl_hours"` // Optional: override global default
-	FailedTTLHours    *int     `yaml:"failed_ttl_hours"`    // Optional: override global default
-	SigningSecret     string   `yaml:"signing_secret"`      // Standard Webhooks signing secret
-	EventTypes        []string `yaml:"event_types"`         // Event type filters
+	RouteID              string     `yaml:"route_id"`
+	TargetURL            string     `yaml:"target_url"`
+	Mode                 string     `yaml:"mode"`
+	MaxRetries           int        `yaml:"max_retries"`
+	RetryBackoff         string     `yaml:"retry_backoff"`
+	RetryJitter          float64    `yaml:"retry_jitter,omitempty"` // Optional: retry delay spread (0-1)
+	Parallelism          int        `yaml:"parallelism"`
+	ExpectedStatus       int        `yaml:"expected_status,omitempty"`        // Optional: single expected status code
+	ExpectedStatuses     statusList `yaml:"expected_statuses,omitempty"`      // Optional: codes, classes or ranges (default: "2xx")
+	DeliveredTTLHours    *int       `yaml:"delivered_ttl_hours,omitempty"`    // Optional: override global default
+	FailedTTLHours       *int       `yaml:"failed_ttl_hours,omitempty"`       // Optional: override global default
+	SigningSecret        string     `yaml:"signing_secret,omitempty"`         // Standard Webhooks signing secret
+	SigningSecrets       []string   `yaml:"signing_secrets,omitempty"`        // Optional: extra secrets signed during rotation
+	RequireSignature     bool       `yaml:"require_signature,omitempty"`      // Fail webhooks that cannot be signed
+	SignatureHeader      string     `yaml:"signature_header,omitempty"`       // Optional: header carrying the signature
+	SignatureFormat      string     `yaml:"signature_format,omitempty"`       // "standard" (default) or "hex"
+	SignatureAlgorithm   string     `yaml:"signature_algorithm,omitempty"`    // "sha256" (default) or "sha512"
+	HeaderStyle          string     `yaml:"header_style,omitempty"`           // "standard" (default) or "xprefixed"
+	EventTypes           []string   `yaml:"event_types,omitempty"`            // Event type filters
+	MaxStreamLen         int        `yaml:"max_stream_len,omitempty"`         // Optional: stream trimming threshold
+	MaxQueueDepth        int        `yaml:"max_queue_depth,omitempty"`        // Optional: backpressure threshold (429)
+	RejectUnsubscribed   bool       `yaml:"reject_unsubscribed,omitempty"`    // Reject unmatched event types at ingestion
+	AcceptRaw            bool       `yaml:"accept_raw,omitempty"`             // Store bodies as-is, skipping payload validation
+	PayloadFormat        string     `yaml:"payload_format,omitempty"`         // "standard" (default) or "raw"
+	ClientCertFile       string     `yaml:"client_cert_file,omitempty"`       // Optional: mTLS client certificate
+	ClientKeyFile        string     `yaml:"client_key_file,omitempty"`        // Optional: mTLS client key
+	CAFile               string     `yaml:"ca_file,omitempty"`                // Optional: custom root CAs for the target
+	ForwardHeaders       []string   `yaml:"forward_headers,omitempty"`        // Optional: inbound headers to forward (allow-list)
+	BodyTemplate         string     `yaml:"body_template,omitempty"`          // Optional: Go template reshaping the delivered body
+	IDPrefix             string     `yaml:"id_prefix,omitempty"`              // Optional: prefix of generated event IDs
+	MaxJSONDepth         int        `yaml:"max_json_depth,omitempty"`         // Optional: nesting limit of event data (422)
+	IsDefault            bool       `yaml:"is_default,omitempty"`             // Catch events posted to unconfigured route IDs
+	AckPolicy            string     `yaml:"ack_policy,omitempty"`             // "ack" (default) or "keep_pending"
+	PriorityEventTypes   []string   `yaml:"priority_event_types,omitempty"`   // Event types delivered ahead of the others
+	EventTypeSource      string     `yaml:"event_type_source,omitempty"`      // "payload" (default), "header:<name>" or "jsonpath:<expr>"
+	MaxRetryWindowHours  int        `yaml:"max_retry_window_hours,omitempty"` // Optional: stop retrying this long after creation
+	IngestAPIKeys        []string   `yaml:"ingest_api_keys,omitempty"`        // Optional: X-API-Key values accepted at ingestion
+	Archive              bool       `yaml:"archive,omitempty"`                // Archive delivered webhooks before they expire
+	Enabled              *bool      `yaml:"enabled,omitempty"`                // Optional: false pauses delivery (default: true)
+	InjectEventIDField   string     `yaml:"inject_event_id_field,omitempty"`  // Optional: body key set to the event ID on delivery
+	CaptureResponseBytes *int       `yaml:"capture_response_bytes,omitempty"` // Optional: response body bytes kept on failure (default: 1024)
+}
+
+// statusList accepts expected_statuses as a list ([200, 204]) or a single value ("2xx")
+type statusList []string
+
+// UnmarshalYAML decodes a scalar or a sequence of scalars
+func (l *statusList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = statusList{value.Value}
+		return nil
+	}
+
+	var statuses []string
+	if err := value.Decode(&statuses); err != nil {
+		return fmt.Errorf("expected_statuses must be a status or a list of statuses: %w", err)
+	}
+	*l = statuses
+	return nil
 }
 
 // Loader holds the loaded routes
+// Safe for concurrent use, so routes can be reloaded while requests are served
 type Loader struct {
-	routes map[string]*Route
+	mu      sync.RWMutex
+	routes  map[string]*Route
+	groups  map[string]*RouteGroup
+	groupOf map[string]string // Group ID of every grouped route
+	cfg     *config.Config    // Configuration routes are validated against
+}
+
+// LoaderOption configures a Loader
+type LoaderOption func(*Loader)
+
+// WithConfig validates routes against the configuration, e.g. its default signing secret
+func WithConfig(cfg *config.Config) LoaderOption {
+	return func(l *Loader) {
+		l.cfg = cfg
+	}
 }
 
 // NewLoader creates a new route loader
-func NewLoader() *Loader {
-	return &Loader{
-		routes: make(map[string]*Route),
+func NewLoader(opts ...LoaderOption) *Loader {
+	l := &Loader{
+		routes:  make(map[string]*Route),
+		groups:  make(map[string]*RouteGroup),
+		groupOf: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	return l
 }
 
 // Load reads and parses the routes.yaml file
+// Loading stops at the first invalid route (see LoadStrict to report every problem)
 func (l *Loader) Load(filePath string) error {
-	data, err := os.ReadFile(filePath)
+	config, err := readConfig(filePath)
 	if err != nil {
-		return fmt.Errorf("reading routes file: %w", err)
-	}
-
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("parsing routes YAML: %w", err)
+		return err
 	}
 
 	// Convert and validate routes
 	for _, rc := range config.Routes {
-		// Set default expected status to 202 if not specified
-		expectedStatus := rc.ExpectedStatus
-		if expectedStatus == 0 {
-			expectedStatus = 202
+		route := rc.toRoute()
+		if err := route.ValidateWithConfig(l.cfg); err != nil {
+			return fmt.Errorf("validating route: %w", err)
 		}
 
-		route := &Route{
-			RouteID:           rc.RouteID,
-			TargetURL:         rc.TargetURL,
-			Mode:              webhook.NewDeliveryMode(rc.Mode),
-			MaxRetries:        rc.MaxRetries,
-			RetryBackoff:      rc.RetryBackoff,
-			Parallelism:       rc.Parallelism,
-			ExpectedStatus:    expectedStatus,
-			DeliveredTTLHours: rc.DeliveredTTLHours,
-			FailedTTLHours:    rc.FailedTTLHours,
-			SigningSecret:     rc.SigningSecret,
-			EventTypes:        rc.EventTypes,
+		l.mu.Lock()
+		err := checkDefault(l.routes, route)
+		if err == nil {
+			l.routes[route.RouteID] = route
 		}
-
-		if err := route.Validate(); err != nil {
+		l.mu.Unlock()
+		if err != nil {
 			return fmt.Errorf("validating route: %w", err)
 		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	groups, err := toGroups(config.Groups, l.routes)
+	if err != nil {
+		return fmt.Errorf("validating groups: %w", err)
+	}
+	l.setGroups(groups)
+
+	return nil
+}
+
+// LoadStrict reads and parses the routes.yaml file, validating every route
+// All problems are returned together (joined with errors.Join) and no route is loaded if any is invalid
+func (l *Loader) LoadStrict(filePath string) error {
+	loaded, groups, err := loadAll(filePath, l.cfg)
+	if err != nil {
+		return err
+	}
 
-		l.routes[route.RouteID] = route
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, route := range loaded {
+		l.routes[id] = route
 	}
+	l.setGroups(groups)
 
 	return nil
 }
 
+// Reload replaces every loaded route with the routes of the file, returning how many were loaded
+// The file is fully validated first; on any error the current routes are left untouched
+// Routes removed from the file are dropped
+func (l *Loader) Reload(filePath string) (int, error) {
+	loaded, groups, err := loadAll(filePath, l.cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.routes = loaded
+	l.setGroups(groups)
+
+	return len(loaded), nil
+}
+
+// loadAll reads and validates every route and group of a routes file, reporting all route problems together
+func loadAll(filePath string, cfg *config.Config) (map[string]*Route, map[string]*RouteGroup, error) {
+	routesConfig, err := readConfig(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buildAll(routesConfig, cfg)
+}
+
+// buildAll converts and validates every route and group of a parsed routes file
+// Groups are only validated once every route is valid
+func buildAll(config Config, cfg *config.Config) (map[string]*Route, map[string]*RouteGroup, error) {
+	var errs []error
+	loaded := make(map[string]*Route, len(config.Routes))
+	for i, rc := range config.Routes {
+		route := rc.toRoute()
+		if err := route.ValidateWithConfig(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("validating route #%d: %w", i+1, err))
+			continue
+		}
+		if err := checkDefault(loaded, route); err != nil {
+			errs = append(errs, fmt.Errorf("validating route #%d: %w", i+1, err))
+			continue
+		}
+		loaded[route.RouteID] = route
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	groups, err := toGroups(config.Groups, loaded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("validating groups: %w", err)
+	}
+
+	return loaded, groups, nil
+}
+
+// setGroups replaces the loaded groups; callers hold the write lock
+func (l *Loader) setGroups(groups map[string]*RouteGroup) {
+	l.groups = groups
+	l.groupOf = groupIndex(groups)
+}
+
+// checkDefault rejects a second default route
+func checkDefault(loaded map[string]*Route, route *Route) error {
+	if !route.IsDefault {
+		return nil
+	}
+	for id, other := range loaded {
+		if other.IsDefault && id != route.RouteID {
+			return fmt.Errorf("route %s cannot be the default, route %s already is", route.RouteID, id)
+		}
+	}
+	return nil
+}
+
+// readConfig reads and parses a routes file
+func readConfig(filePath string) (Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading routes file: %w", err)
+	}
+	return parseConfig(data)
+}
+
+// parseConfig parses the contents of a routes file
+// Unknown keys are rejected, so a misspelled setting (e.g. paralelism) fails loading instead of being ignored
+func parseConfig(data []byte) (Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parsing routes YAML: %w", err)
+	}
+
+	return config, nil
+}
+
+// toRoute converts a YAML route entry into a Route, applying defaults
+func (rc RouteConfig) toRoute() *Route {
+	payloadFormat := rc.PayloadFormat
+	if payloadFormat == "" {
+		payloadFormat = PayloadFormatStandard
+	}
+	signatureFormat := rc.SignatureFormat
+	if signatureFormat == "" {
+		signatureFormat = SignatureFormatStandard
+	}
+	headerStyle := rc.HeaderStyle
+	if headerStyle == "" {
+		headerStyle = HeaderStyleStandard
+	}
+	ackPolicy := rc.AckPolicy
+	if ackPolicy == "" {
+		ackPolicy = AckPolicyAck
+	}
+	captureResponseBytes := DefaultCaptureResponseBytes
+	if rc.CaptureResponseBytes != nil {
+		captureResponseBytes = *rc.CaptureResponseBytes
+	}
+
+	route := &Route{
+		RouteID:              rc.RouteID,
+		TargetURL:            rc.TargetURL,
+		Mode:                 webhook.NewDeliveryMode(rc.Mode),
+		MaxRetries:           rc.MaxRetries,
+		RetryBackoff:         rc.RetryBackoff,
+		RetryJitter:          rc.RetryJitter,
+		Parallelism:          rc.Parallelism,
+		ExpectedStatus:       rc.ExpectedStatus,
+		ExpectedStatuses:     rc.ExpectedStatuses,
+		DeliveredTTLHours:    rc.DeliveredTTLHours,
+		FailedTTLHours:       rc.FailedTTLHours,
+		SigningSecret:        rc.SigningSecret,
+		SigningSecrets:       rc.SigningSecrets,
+		RequireSignature:     rc.RequireSignature,
+		SignatureHeader:      rc.SignatureHeader,
+		SignatureFormat:      signatureFormat,
+		SignatureAlgorithm:   rc.SignatureAlgorithm,
+		HeaderStyle:          headerStyle,
+		EventTypes:           rc.EventTypes,
+		MaxStreamLen:         rc.MaxStreamLen,
+		MaxQueueDepth:        rc.MaxQueueDepth,
+		RejectUnsubscribed:   rc.RejectUnsubscribed,
+		AcceptRaw:            rc.AcceptRaw,
+		PayloadFormat:        payloadFormat,
+		ClientCertFile:       rc.ClientCertFile,
+		ClientKeyFile:        rc.ClientKeyFile,
+		CAFile:               rc.CAFile,
+		ForwardHeaders:       rc.ForwardHeaders,
+		BodyTemplate:         rc.BodyTemplate,
+		IDPrefix:             rc.IDPrefix,
+		MaxJSONDepth:         rc.MaxJSONDepth,
+		IsDefault:            rc.IsDefault,
+		AckPolicy:            ackPolicy,
+		PriorityEventTypes:   rc.PriorityEventTypes,
+		EventTypeSource:      rc.EventTypeSource,
+		MaxRetryWindowHours:  rc.MaxRetryWindowHours,
+		IngestAPIKeys:        rc.IngestAPIKeys,
+		Archive:              rc.Archive,
+		Enabled:              rc.Enabled,
+		InjectEventIDField:   rc.InjectEventIDField,
+		CaptureResponseBytes: captureResponseBytes,
+	}
+	route.compileEventTypes()
+	return route
+}
+
 // Get retrieves a route by its ID
 func (l *Loader) Get(routeID string) (*Route, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	route, exists := l.routes[routeID]
 	if !exists {
 		return nil, fmt.Errorf("route not found: %s", routeID)
@@ -97,17 +361,118 @@ func (l *Loader) Get(routeID string) (*Route, error) {
 	return route, nil
 }
 
-// List returns all loaded routes
+// GetOrDefault retrieves a route by its ID, falling back to the default route (is_default) when none matches
+// The fallback is the default route itself, so webhooks are queued on its stream and delivered by its workers;
+// callers compare the returned RouteID with the requested one to tell a fallback apart
+func (l *Loader) GetOrDefault(routeID string) (*Route, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if route, exists := l.routes[routeID]; exists {
+		return route, nil
+	}
+	for _, route := range l.routes {
+		if route.IsDefault {
+			return route, nil
+		}
+	}
+	return nil, fmt.Errorf("route not found: %s", routeID)
+}
+
+// List returns all loaded routes, ordered by route ID
 func (l *Loader) List() []*Route {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	routes := make([]*Route, 0, len(l.routes))
-	for _, route := range l.routes {
-		routes = append(routes, route)
+	for _, id := range slices.Sorted(maps.Keys(l.routes)) {
+		routes = append(routes, l.routes[id])
 	}
 	return routes
 }
 
+// Warnings returns the warnings of every loaded route (see Route.Warnings), ordered by route ID
+// They never prevent loading; cmd/validate-routes prints them
+func (l *Loader) Warnings() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var warnings []string
+	for _, id := range slices.Sorted(maps.Keys(l.routes)) {
+		warnings = append(warnings, l.routes[id].Warnings()...)
+	}
+	return warnings
+}
+
+// IDPrefix returns the prefix of a route's generated event IDs ("" for unknown routes)
+// Meant for webhook.WithIDPrefix, so routes reloaded later get their new prefix
+func (l *Loader) IDPrefix(routeID string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if route, exists := l.routes[routeID]; exists {
+		return route.IDPrefix
+	}
+	return ""
+}
+
+// IsPriority reports whether a payload goes to its route's high-priority stream (false for unknown routes)
+// Meant for webhook.WithPriority, so reloaded priority_event_types apply to new webhooks
+func (l *Loader) IsPriority(routeID string, body []byte) bool {
+	l.mu.RLock()
+	route, exists := l.routes[routeID]
+	l.mu.RUnlock()
+
+	return exists && route.IsPriority(body)
+}
+
+// IsEnabled reports whether a route's webhooks are delivered (see Route.Enabled)
+// Meant for worker.WithRouteEnabled, so routes disabled at runtime pause their workers;
+// unknown routes, such as a group's shared route, count as enabled
+func (l *Loader) IsEnabled(routeID string) bool {
+	l.mu.RLock()
+	route, exists := l.routes[routeID]
+	l.mu.RUnlock()
+
+	return !exists || route.IsEnabled()
+}
+
+// SigningSecret returns the primary signing secret of a loaded route ("" when it has none)
+// Meant for worker.WithCurrentSecret, so a secret rotated by Reload applies to the next attempt;
+// ok is false for unknown routes, such as a group's shared route
+func (l *Loader) SigningSecret(routeID string) (secret string, ok bool) {
+	l.mu.RLock()
+	route, exists := l.routes[routeID]
+	l.mu.RUnlock()
+
+	if !exists {
+		return "", false
+	}
+	return route.SigningSecret, true
+}
+
+// SetEnabled enables or disables a loaded route, e.g. to pause its deliveries during an incident
+// The route is replaced by an updated copy, so callers holding the previous one never see it change;
+// the next Reload restores the routes file's setting
+func (l *Loader) SetEnabled(routeID string, enabled bool) (*Route, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	route, exists := l.routes[routeID]
+	if !exists {
+		return nil, fmt.Errorf("route not found: %s", routeID)
+	}
+	updated := *route
+	updated.Enabled = &enabled
+	l.routes[routeID] = &updated
+	return &updated, nil
+}
+
 // Exists checks if a route ID exists
 func (l *Loader) Exists(routeID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	_, exists := l.routes[routeID]
 	return exists
 }
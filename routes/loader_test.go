@@ -1,11 +1,18 @@
 package routes_test
 
 import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/marcelsud/webhook-inbox/config"
 	"github.com/marcelsud/webhook-inbox/routes"
 	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,6 +95,44 @@ routes:
 		assert.Contains(t, err.Error(), "parsing routes YAML")
 	})
 
+	t.Run("error - misspelled key", func(t *testing.T) {
+		content := `
+routes:
+  - route_id: "typo"
+    target_url: "https://example.com"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    paralelism: 1
+`
+		tmpFile, err := os.CreateTemp("", "routes-*.yaml")
+		require.NoError(t, err)
+		defer os.Remove(tmpFile.Name())
+
+		_, err = tmpFile.WriteString(content)
+		require.NoError(t, err)
+		tmpFile.Close()
+
+		loader := routes.NewLoader()
+		err = loader.Load(tmpFile.Name())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing routes YAML")
+		assert.Contains(t, err.Error(), "line 8: field paralelism not found")
+		assert.False(t, loader.Exists("typo"))
+	})
+
+	t.Run("success - empty file loads no routes", func(t *testing.T) {
+		tmpFile, err := os.CreateTemp("", "routes-*.yaml")
+		require.NoError(t, err)
+		defer os.Remove(tmpFile.Name())
+		tmpFile.Close()
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(tmpFile.Name()))
+		assert.Empty(t, loader.List())
+	})
+
 	t.Run("error - FIFO with parallelism > 1", func(t *testing.T) {
 		content := `
 routes:
@@ -125,6 +170,61 @@ func TestLoader_Get(t *testing.T) {
 	})
 }
 
+func TestLoader_GetOrDefault(t *testing.T) {
+	const routeYAML = `
+  - route_id: %q
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    is_default: %t
+`
+	writeRoutes := func(t *testing.T, routes ...string) string {
+		t.Helper()
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte("routes:"+strings.Join(routes, "")), 0o644))
+		return path
+	}
+
+	t.Run("falls back to the default route", func(t *testing.T) {
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(writeRoutes(t, fmt.Sprintf(routeYAML, "user-events", false), fmt.Sprintf(routeYAML, "catch-all", true))))
+
+		route, err := loader.GetOrDefault("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, "user-events", route.RouteID)
+
+		// Unknown routes get the default route itself, so they share its stream and workers
+		route, err = loader.GetOrDefault("tenant-42")
+		require.NoError(t, err)
+		assert.Equal(t, "catch-all", route.RouteID)
+		assert.True(t, route.IsDefault)
+		assert.False(t, loader.Exists("tenant-42"))
+	})
+
+	t.Run("route not found without a default route", func(t *testing.T) {
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(writeRoutes(t, fmt.Sprintf(routeYAML, "user-events", false))))
+
+		_, err := loader.GetOrDefault("tenant-42")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "route not found")
+	})
+
+	t.Run("error - more than one default route", func(t *testing.T) {
+		path := writeRoutes(t, fmt.Sprintf(routeYAML, "first", true), fmt.Sprintf(routeYAML, "second", true))
+
+		err := routes.NewLoader().Load(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "route second cannot be the default, route first already is")
+
+		err = routes.NewLoader().LoadStrict(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "route second cannot be the default")
+	})
+}
+
 func TestLoader_Exists(t *testing.T) {
 	content := `
 routes:
@@ -242,3 +342,1599 @@ func TestRoute_Validate(t *testing.T) {
 		assert.Contains(t, err.Error(), "parallelism must be at least 1")
 	})
 }
+
+func TestRoute_Validate_MaxStreamLen(t *testing.T) {
+	t.Run("error - negative max_stream_len", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "test",
+			TargetURL:      "https://example.com",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			ExpectedStatus: 202,
+			MaxStreamLen:   -1,
+		}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_stream_len cannot be negative")
+	})
+}
+
+func TestRoute_Validate_MaxQueueDepth(t *testing.T) {
+	t.Run("error - negative max_queue_depth", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:       "test",
+			TargetURL:     "https://example.com",
+			Mode:          webhook.FIFO,
+			Parallelism:   1,
+			MaxQueueDepth: -1,
+		}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_queue_depth cannot be negative")
+	})
+}
+
+func TestRoute_Validate_MaxJSONDepth(t *testing.T) {
+	newRoute := func(depth int) *routes.Route {
+		return &routes.Route{
+			RouteID:      "test",
+			TargetURL:    "https://example.com",
+			Mode:         webhook.FIFO,
+			Parallelism:  1,
+			MaxJSONDepth: depth,
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, newRoute(0).Validate())
+		require.NoError(t, newRoute(16).Validate())
+	})
+
+	t.Run("error - negative max_json_depth", func(t *testing.T) {
+		err := newRoute(-1).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_json_depth cannot be negative")
+	})
+
+	t.Run("error - accept_raw", func(t *testing.T) {
+		route := newRoute(16)
+		route.AcceptRaw = true
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_json_depth cannot be used with accept_raw")
+	})
+}
+
+func TestRoute_Validate_ClientCertificate(t *testing.T) {
+	t.Run("error - certificate without key", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "test",
+			TargetURL:      "https://example.com",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			ExpectedStatus: 202,
+			ClientCertFile: "client.pem",
+		}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "client_cert_file and client_key_file must be set together")
+	})
+
+	t.Run("success - CA file alone", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "test",
+			TargetURL:      "https://example.com",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			ExpectedStatus: 202,
+			CAFile:         "ca.pem",
+		}
+
+		require.NoError(t, route.Validate())
+	})
+}
+
+func TestLoader_LoadStrict(t *testing.T) {
+	writeRoutes := func(t *testing.T, content string) string {
+		t.Helper()
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("success - valid routes file", func(t *testing.T) {
+		path := writeRoutes(t, `
+routes:
+  - route_id: "valid"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`)
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.LoadStrict(path))
+		assert.True(t, loader.Exists("valid"))
+	})
+
+	t.Run("error - reports every invalid route", func(t *testing.T) {
+		path := writeRoutes(t, `
+routes:
+  - route_id: "valid"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "missing-url"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "bad-parallelism"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 4
+  - route_id: "bad-retries"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: -1
+    retry_backoff: "1000"
+    parallelism: 1
+`)
+
+		loader := routes.NewLoader()
+		err := loader.LoadStrict(path)
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "target_url cannot be empty for route missing-url")
+		assert.Contains(t, err.Error(), "FIFO mode requires parallelism=1 for route bad-parallelism")
+		assert.Contains(t, err.Error(), "max_retries cannot be negative for route bad-retries")
+		assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+
+		// Nothing is loaded when any route is invalid
+		assert.Empty(t, loader.List())
+	})
+
+	t.Run("error - file not found", func(t *testing.T) {
+		loader := routes.NewLoader()
+		err := loader.LoadStrict("/nonexistent/routes.yaml")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reading routes file")
+	})
+}
+
+func TestLoader_Reload(t *testing.T) {
+	const routeYAML = `
+  - route_id: %q
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: %d
+`
+	writeRoutes := func(t *testing.T, path string, routes ...string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(path, []byte("routes:"+strings.Join(routes, "")), 0o644))
+	}
+
+	path := t.TempDir() + "/routes.yaml"
+	writeRoutes(t, path, fmt.Sprintf(routeYAML, "first", 1), fmt.Sprintf(routeYAML, "second", 1))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	t.Run("error - invalid file leaves routes untouched", func(t *testing.T) {
+		writeRoutes(t, path, fmt.Sprintf(routeYAML, "third", 2))
+
+		loaded, err := loader.Reload(path)
+		require.Error(t, err)
+		assert.Zero(t, loaded)
+		assert.Contains(t, err.Error(), "FIFO mode requires parallelism=1 for route third")
+		assert.True(t, loader.Exists("first"))
+		assert.True(t, loader.Exists("second"))
+		assert.False(t, loader.Exists("third"))
+
+		_, err = loader.Reload("/nonexistent/routes.yaml")
+		require.Error(t, err)
+		assert.Len(t, loader.List(), 2)
+	})
+
+	t.Run("success - replaces every route", func(t *testing.T) {
+		writeRoutes(t, path, fmt.Sprintf(routeYAML, "second", 1), fmt.Sprintf(routeYAML, "third", 1))
+
+		loaded, err := loader.Reload(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, loaded)
+		assert.False(t, loader.Exists("first"))
+		assert.True(t, loader.Exists("second"))
+		assert.True(t, loader.Exists("third"))
+	})
+}
+
+func TestRoute_Validate_AcceptRaw(t *testing.T) {
+	t.Run("error - reject_unsubscribed with accept_raw", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:            "test",
+			TargetURL:          "https://example.com",
+			Mode:               webhook.FIFO,
+			Parallelism:        1,
+			ExpectedStatus:     202,
+			AcceptRaw:          true,
+			RejectUnsubscribed: true,
+		}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reject_unsubscribed cannot be used with accept_raw")
+	})
+}
+
+func TestRoute_Validate_PayloadFormat(t *testing.T) {
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:        "test",
+			TargetURL:      "https://example.com",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			ExpectedStatus: 202,
+			PayloadFormat:  routes.PayloadFormatRaw,
+		}
+	}
+
+	t.Run("success - raw route", func(t *testing.T) {
+		require.NoError(t, newRoute().Validate())
+	})
+
+	t.Run("error - unknown format", func(t *testing.T) {
+		route := newRoute()
+		route.PayloadFormat = "xml"
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "payload_format must be")
+	})
+
+	t.Run("error - event types on raw route", func(t *testing.T) {
+		route := newRoute()
+		route.EventTypes = []string{"user.*"}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be used with payload_format \"raw\"")
+	})
+
+	t.Run("loader defaults to standard", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "default-format"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("default-format")
+		require.NoError(t, err)
+		assert.Equal(t, routes.PayloadFormatStandard, route.PayloadFormat)
+	})
+}
+
+func TestRoute_Backoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		retried int
+		want    time.Duration
+	}{
+		{"empty", "", 3, 0},
+		{"constant", "1000", 3, time.Second},
+		{"exponential", "pow(2, retried) * 1000", 3, 8 * time.Second},
+		{"capped", "min(pow(2, retried) * 1000, 60000)", 10, time.Minute},
+		{"arithmetic", "(retried + 1) * 500 - 100 / 2", 1, 950 * time.Millisecond},
+		{"negative clamps to zero", "-1000", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &routes.Route{RouteID: "test", RetryBackoff: tt.expr}
+
+			got, err := route.Backoff(tt.retried)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("error - invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{"pow(2)", "retries * 1000", "1000 +", "sqrt(4)", "(1000", "1000 / 0"} {
+			route := &routes.Route{RouteID: "test", RetryBackoff: expr}
+
+			_, err := route.Backoff(1)
+			assert.Error(t, err, expr)
+		}
+	})
+}
+
+func TestRoute_JitteredBackoff(t *testing.T) {
+	t.Run("stays within bounds", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test", RetryBackoff: "1000", RetryJitter: 0.2}
+		rnd := rand.New(rand.NewPCG(1, 2))
+
+		var below, above bool
+		for i := 0; i < 10000; i++ {
+			got, err := route.JitteredBackoff(0, rnd)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, got, 800*time.Millisecond)
+			require.LessOrEqual(t, got, 1200*time.Millisecond)
+			below = below || got < time.Second
+			above = above || got > time.Second
+		}
+		assert.True(t, below, "some delays should be shortened")
+		assert.True(t, above, "some delays should be lengthened")
+	})
+
+	t.Run("same seed, same delays", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test", RetryBackoff: "pow(2, retried) * 1000", RetryJitter: 0.5}
+		first, second := rand.New(rand.NewPCG(7, 7)), rand.New(rand.NewPCG(7, 7))
+
+		for retried := 0; retried < 5; retried++ {
+			a, err := route.JitteredBackoff(retried, first)
+			require.NoError(t, err)
+			b, err := route.JitteredBackoff(retried, second)
+			require.NoError(t, err)
+			assert.Equal(t, a, b)
+		}
+	})
+
+	t.Run("no jitter keeps the backoff", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test", RetryBackoff: "pow(2, retried) * 1000"}
+
+		got, err := route.JitteredBackoff(3, rand.New(rand.NewPCG(1, 2)))
+		require.NoError(t, err)
+		assert.Equal(t, 8*time.Second, got)
+	})
+
+	t.Run("error - jitter out of range", func(t *testing.T) {
+		for _, jitter := range []float64{-0.1, 1.5} {
+			route := &routes.Route{
+				RouteID:     "test",
+				TargetURL:   "https://example.com",
+				Mode:        webhook.FIFO,
+				Parallelism: 1,
+				RetryJitter: jitter,
+			}
+
+			err := route.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "retry_jitter must be between 0 and 1")
+		}
+	})
+}
+
+func TestRoute_FilterHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Cookie", "session=abc")
+	header.Set("Proxy-Authorization", "Basic abc")
+	header.Set("Transfer-Encoding", "chunked")
+	header.Set("Connection", "close, X-Hop")
+	header.Set("X-Hop", "1")
+	header.Set("X-Request-Id", "req-1")
+
+	t.Run("default - drops credentials and hop-by-hop headers", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test"}
+
+		assert.Equal(t, map[string]string{
+			"Content-Type": "application/json",
+			"X-Request-Id": "req-1",
+		}, route.FilterHeaders(header))
+	})
+
+	t.Run("allow-list - keeps only listed headers", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test", ForwardHeaders: []string{"x-request-id", "Authorization", "X-Missing"}}
+
+		assert.Equal(t, map[string]string{
+			"X-Request-Id":  "req-1",
+			"Authorization": "Bearer secret",
+		}, route.FilterHeaders(header))
+	})
+
+	t.Run("loader reads forward_headers", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "allowlisted"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    forward_headers: ["X-Request-Id"]
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("allowlisted")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"X-Request-Id"}, route.ForwardHeaders)
+	})
+
+	t.Run("error - empty header name", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "test",
+			TargetURL:      "https://example.com",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			ExpectedStatus: 202,
+			ForwardHeaders: []string{" "},
+		}
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "forward_headers")
+	})
+}
+
+func TestRoute_ExpectedStatuses(t *testing.T) {
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:     "test",
+			TargetURL:   "https://example.com",
+			Mode:        webhook.FIFO,
+			Parallelism: 1,
+		}
+	}
+
+	t.Run("defaults to any 2xx", func(t *testing.T) {
+		route := newRoute()
+		require.NoError(t, route.Validate())
+
+		assert.Equal(t, []string{"2xx"}, route.AcceptedStatuses())
+		assert.True(t, route.IsExpectedStatus(200))
+		assert.True(t, route.IsExpectedStatus(299))
+		assert.False(t, route.IsExpectedStatus(301))
+		assert.False(t, route.IsExpectedStatus(500))
+	})
+
+	t.Run("single expected_status", func(t *testing.T) {
+		route := newRoute()
+		route.ExpectedStatus = 201
+		require.NoError(t, route.Validate())
+
+		assert.True(t, route.IsExpectedStatus(201))
+		assert.False(t, route.IsExpectedStatus(200))
+	})
+
+	t.Run("codes, classes and ranges", func(t *testing.T) {
+		route := newRoute()
+		route.ExpectedStatuses = []string{"200", "202-204"}
+		require.NoError(t, route.Validate())
+
+		assert.True(t, route.IsExpectedStatus(200))
+		assert.True(t, route.IsExpectedStatus(203))
+		assert.False(t, route.IsExpectedStatus(201))
+		assert.False(t, route.IsExpectedStatus(205))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			status   int
+			statuses []string
+			wantErr  string
+		}{
+			{"non-2xx expected_status", 404, nil, "expected_status must be a 2xx status code"},
+			{"both forms", 200, []string{"204"}, "cannot be used together"},
+			{"non-2xx class", 0, []string{"3xx"}, "outside 200-299"},
+			{"reversed range", 0, []string{"204-200"}, "invalid status range"},
+			{"not a status", 0, []string{"ok"}, "invalid status"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				route := newRoute()
+				route.ExpectedStatus = tt.status
+				route.ExpectedStatuses = tt.statuses
+
+				err := route.Validate()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("loader accepts a list, a single value or the legacy int", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "no-content"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_statuses: [204]
+  - route_id: "listed"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_statuses: [200, 201, 204]
+  - route_id: "class"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_statuses: "2xx"
+  - route_id: "legacy"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    expected_status: 200
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		noContent, err := loader.Get("no-content")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"204"}, noContent.AcceptedStatuses())
+		assert.Equal(t, 204, noContent.PrimaryExpectedStatus())
+		assert.True(t, noContent.IsExpectedStatus(204))
+		assert.False(t, noContent.IsExpectedStatus(200))
+
+		listed, err := loader.Get("listed")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"200", "201", "204"}, listed.AcceptedStatuses())
+		assert.Equal(t, 200, listed.PrimaryExpectedStatus())
+
+		class, err := loader.Get("class")
+		require.NoError(t, err)
+		assert.True(t, class.IsExpectedStatus(226))
+		assert.Equal(t, 200, class.PrimaryExpectedStatus())
+
+		legacy, err := loader.Get("legacy")
+		require.NoError(t, err)
+		assert.Equal(t, 200, legacy.ExpectedStatus)
+		assert.Equal(t, []string{"200"}, legacy.AcceptedStatuses())
+		assert.Equal(t, 200, legacy.PrimaryExpectedStatus())
+	})
+}
+
+func TestRoute_Validate_BodyTemplate(t *testing.T) {
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:      "slack",
+			TargetURL:    "https://hooks.slack.com/services/T000/B000/XXXX",
+			Mode:         webhook.FIFO,
+			Parallelism:  1,
+			BodyTemplate: `{"text": {{json .Type}}}`,
+		}
+	}
+
+	t.Run("success - valid template", func(t *testing.T) {
+		require.NoError(t, newRoute().Validate())
+	})
+
+	t.Run("error - template does not parse", func(t *testing.T) {
+		route := newRoute()
+		route.BodyTemplate = `{"text": {{json .Type}`
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid body_template for route slack")
+	})
+
+	t.Run("error - raw payload format", func(t *testing.T) {
+		route := newRoute()
+		route.PayloadFormat = routes.PayloadFormatRaw
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "body_template cannot be used with raw payloads")
+	})
+
+	t.Run("loader reads body_template", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "slack"
+    target_url: "https://hooks.slack.com/services/T000/B000/XXXX"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    body_template: |
+      {"text": {{json (printf "New event: %s" .Type)}}}
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("slack")
+		require.NoError(t, err)
+		assert.Contains(t, route.BodyTemplate, `{{json (printf "New event: %s" .Type)}}`)
+	})
+}
+
+func TestRoute_Validate_IngestAPIKeys(t *testing.T) {
+	route := &routes.Route{
+		RouteID:       "user-events",
+		TargetURL:     "https://example.com/users",
+		Mode:          webhook.FIFO,
+		Parallelism:   1,
+		IngestAPIKeys: []string{"key-current", "key-previous"},
+	}
+	assert.NoError(t, route.Validate())
+
+	route.IngestAPIKeys = []string{"key-current", " "}
+	err := route.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ingest_api_keys cannot contain empty keys for route user-events")
+}
+
+func TestRoute_Validate_IDPrefix(t *testing.T) {
+	newRoute := func(prefix string) *routes.Route {
+		return &routes.Route{
+			RouteID:     "user-events",
+			TargetURL:   "https://example.com/users",
+			Mode:        webhook.FIFO,
+			Parallelism: 1,
+			IDPrefix:    prefix,
+		}
+	}
+
+	for _, prefix := range []string{"", "user_", "evt-", "Orders2024_"} {
+		t.Run("valid "+prefix, func(t *testing.T) {
+			assert.NoError(t, newRoute(prefix).Validate())
+		})
+	}
+
+	for _, prefix := range []string{"user.", "user:", "user events", "usér_", "user/"} {
+		t.Run("invalid "+prefix, func(t *testing.T) {
+			err := newRoute(prefix).Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "id_prefix may only contain letters, digits, '_' and '-' for route user-events")
+		})
+	}
+
+	t.Run("loader reads id_prefix", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    id_prefix: "user_"
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, "user_", route.IDPrefix)
+
+		assert.Equal(t, "user_", loader.IDPrefix("user-events"))
+		assert.Equal(t, "", loader.IDPrefix("orders"))
+		assert.Equal(t, "", loader.IDPrefix("unknown"))
+	})
+}
+
+func TestRoute_Validate_InjectEventIDField(t *testing.T) {
+	newRoute := func(field string) *routes.Route {
+		return &routes.Route{
+			RouteID:            "user-events",
+			TargetURL:          "https://example.com/users",
+			Mode:               webhook.FIFO,
+			Parallelism:        1,
+			InjectEventIDField: field,
+		}
+	}
+
+	for _, field := range []string{"", "id", "event_id", "msgId"} {
+		t.Run("valid "+field, func(t *testing.T) {
+			assert.NoError(t, newRoute(field).Validate())
+		})
+	}
+
+	for _, field := range []string{"type", "timestamp", "data", " "} {
+		t.Run("invalid "+field, func(t *testing.T) {
+			err := newRoute(field).Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "inject_event_id_field cannot be blank or one of type, timestamp and data for route user-events")
+		})
+	}
+
+	t.Run("invalid with accept_raw", func(t *testing.T) {
+		route := newRoute("id")
+		route.AcceptRaw = true
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "inject_event_id_field cannot be used with accept_raw for route user-events")
+	})
+
+	t.Run("invalid with raw payload format", func(t *testing.T) {
+		route := newRoute("id")
+		route.PayloadFormat = routes.PayloadFormatRaw
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `inject_event_id_field cannot be used with payload_format "raw" for route user-events`)
+	})
+
+	t.Run("invalid with body_template", func(t *testing.T) {
+		route := newRoute("id")
+		route.BodyTemplate = `{"text": "{{.Type}}"}`
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "inject_event_id_field cannot be used with body_template for route user-events")
+	})
+
+	t.Run("loader reads inject_event_id_field", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    inject_event_id_field: "id"
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, "id", route.InjectEventIDField)
+	})
+}
+
+func TestRoute_AckPolicy(t *testing.T) {
+	newRoute := func(policy string) *routes.Route {
+		return &routes.Route{
+			RouteID:     "user-events",
+			TargetURL:   "https://example.com/users",
+			Mode:        webhook.FIFO,
+			Parallelism: 1,
+			AckPolicy:   policy,
+		}
+	}
+
+	for _, policy := range []string{"", routes.AckPolicyAck, routes.AckPolicyKeepPending} {
+		t.Run("valid "+policy, func(t *testing.T) {
+			assert.NoError(t, newRoute(policy).Validate())
+		})
+	}
+
+	t.Run("invalid policy", func(t *testing.T) {
+		err := newRoute("nack").Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `ack_policy must be "ack" or "keep_pending" for route user-events`)
+	})
+
+	t.Run("retryable statuses", func(t *testing.T) {
+		route := newRoute("")
+		for _, code := range []int{0, 302, 408, 429, 500, 503} {
+			assert.True(t, route.IsRetryableStatus(code), code)
+		}
+		for _, code := range []int{400, 401, 403, 404, 410, 422} {
+			assert.False(t, route.IsRetryableStatus(code), code)
+		}
+	})
+
+	t.Run("loader defaults to ack", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    ack_policy: "keep_pending"
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, routes.AckPolicyKeepPending, route.AckPolicy)
+
+		route, err = loader.Get("orders")
+		require.NoError(t, err)
+		assert.Equal(t, routes.AckPolicyAck, route.AckPolicy)
+	})
+}
+
+func TestRoute_Validate_RequireSignature(t *testing.T) {
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:          "user-events",
+			TargetURL:        "https://example.com/webhook",
+			Mode:             webhook.FIFO,
+			Parallelism:      1,
+			SigningSecret:    "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+			RequireSignature: true,
+		}
+	}
+
+	t.Run("success - secret configured", func(t *testing.T) {
+		require.NoError(t, newRoute().Validate())
+	})
+
+	t.Run("error - no signing secret", func(t *testing.T) {
+		route := newRoute()
+		route.SigningSecret = ""
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "require_signature needs a signing_secret or a default signing secret for route user-events")
+	})
+
+	t.Run("success - default signing secret configured", func(t *testing.T) {
+		route := newRoute()
+		route.SigningSecret = ""
+
+		cfg := &config.Config{WebhookDefaultSigningSecret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"}
+		require.NoError(t, route.ValidateWithConfig(cfg))
+	})
+
+	t.Run("error - loader rejects require_signature without any secret", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    require_signature: true
+`), 0o644))
+
+		err := routes.NewLoader().Load(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "require_signature needs a signing_secret")
+
+		// The configuration's default signing secret is enough, on every way of loading
+		cfg := &config.Config{WebhookDefaultSigningSecret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"}
+		require.NoError(t, routes.NewLoader(routes.WithConfig(cfg)).Load(path))
+		require.NoError(t, routes.NewLoader(routes.WithConfig(cfg)).LoadStrict(path))
+		_, err = routes.NewLoader(routes.WithConfig(cfg)).Reload(path)
+		require.NoError(t, err)
+	})
+
+	t.Run("loader reads require_signature", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    signing_secret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+    require_signature: true
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.True(t, route.RequireSignature)
+	})
+}
+
+func TestRoute_SignatureSettings(t *testing.T) {
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:       "github-style",
+			TargetURL:     "https://example.com/webhook",
+			Mode:          webhook.FIFO,
+			Parallelism:   1,
+			SigningSecret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+		}
+	}
+
+	t.Run("defaults to the Standard Webhooks header", func(t *testing.T) {
+		route := newRoute()
+		require.NoError(t, route.Validate())
+		assert.Equal(t, routes.DefaultSignatureHeader, route.GetSignatureHeader())
+	})
+
+	t.Run("success - custom header and hex format", func(t *testing.T) {
+		route := newRoute()
+		route.SignatureHeader = "X-Signature"
+		route.SignatureFormat = routes.SignatureFormatHex
+
+		require.NoError(t, route.Validate())
+		assert.Equal(t, "X-Signature", route.GetSignatureHeader())
+	})
+
+	t.Run("xprefixed header style renames every delivery header", func(t *testing.T) {
+		route := newRoute()
+		route.HeaderStyle = routes.HeaderStyleXPrefixed
+
+		require.NoError(t, route.Validate())
+		assert.Equal(t, "X-Webhook-Id", route.GetIDHeader())
+		assert.Equal(t, "X-Webhook-Timestamp", route.GetTimestampHeader())
+		assert.Equal(t, "X-Webhook-Signature", route.GetSignatureHeader())
+
+		// An explicit signature header still wins
+		route.SignatureHeader = "X-Signature"
+		assert.Equal(t, "X-Signature", route.GetSignatureHeader())
+	})
+
+	t.Run("error - unknown header style", func(t *testing.T) {
+		route := newRoute()
+		route.HeaderStyle = "legacy"
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `header_style must be "standard" or "xprefixed"`)
+	})
+
+	t.Run("error - unknown signature algorithm", func(t *testing.T) {
+		route := newRoute()
+		route.SignatureAlgorithm = "sha1"
+
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid signature_algorithm for route")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			header  string
+			format  string
+			wantErr string
+		}{
+			{"unknown format", "", "base64", `signature_format must be "standard" or "hex"`},
+			{"x-prefixed header set by the client", "X-Webhook-Timestamp", "", "signature_header cannot be X-Webhook-Timestamp"},
+			{"header with spaces", "X Signature", "", "is not a valid header name"},
+			{"header with colon", "X-Signature:", "", "is not a valid header name"},
+			{"header set by the client", "webhook-id", "", "signature_header cannot be webhook-id"},
+			{"content type", "Content-Type", "", "signature_header cannot be Content-Type"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				route := newRoute()
+				route.SignatureHeader = tt.header
+				route.SignatureFormat = tt.format
+
+				err := route.Validate()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("loader reads signature settings", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "github-style"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    signing_secret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+    signature_header: "X-Signature"
+    signature_format: "hex"
+    signature_algorithm: "sha512"
+  - route_id: "legacy"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    header_style: "xprefixed"
+  - route_id: "standard"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("github-style")
+		require.NoError(t, err)
+		assert.Equal(t, "X-Signature", route.GetSignatureHeader())
+		assert.Equal(t, routes.SignatureFormatHex, route.SignatureFormat)
+		assert.Equal(t, signature.SHA512, route.GetSignatureAlgorithm())
+
+		route, err = loader.Get("legacy")
+		require.NoError(t, err)
+		assert.Equal(t, routes.HeaderStyleXPrefixed, route.HeaderStyle)
+		assert.Equal(t, "X-Webhook-Signature", route.GetSignatureHeader())
+		assert.Equal(t, signature.SHA256, route.GetSignatureAlgorithm())
+
+		route, err = loader.Get("standard")
+		require.NoError(t, err)
+		assert.Equal(t, routes.DefaultSignatureHeader, route.GetSignatureHeader())
+		assert.Equal(t, routes.SignatureFormatStandard, route.SignatureFormat)
+		assert.Equal(t, routes.HeaderStyleStandard, route.HeaderStyle)
+	})
+}
+
+func TestRoute_SigningSecrets(t *testing.T) {
+	const (
+		current  = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+		previous = "whsec_ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7"
+	)
+	newRoute := func() *routes.Route {
+		return &routes.Route{
+			RouteID:        "user-events",
+			TargetURL:      "https://example.com/webhook",
+			Mode:           webhook.FIFO,
+			Parallelism:    1,
+			SigningSecret:  current,
+			SigningSecrets: []string{previous},
+		}
+	}
+
+	t.Run("success - current secret first", func(t *testing.T) {
+		route := newRoute()
+		require.NoError(t, route.Validate())
+		assert.Equal(t, []string{current, previous}, route.Secrets(nil))
+	})
+
+	t.Run("no secrets without a signing secret", func(t *testing.T) {
+		assert.Empty(t, (&routes.Route{}).Secrets(nil))
+		assert.Empty(t, (&routes.Route{}).Secrets(&config.Config{}))
+	})
+
+	t.Run("falls back to the config default", func(t *testing.T) {
+		cfg := &config.Config{WebhookDefaultSigningSecret: previous}
+		assert.Equal(t, []string{previous}, (&routes.Route{}).Secrets(cfg))
+	})
+
+	t.Run("route secrets take precedence over the config default", func(t *testing.T) {
+		cfg := &config.Config{WebhookDefaultSigningSecret: "whsec_ZGVmYXVsdGRlZmF1bHRkZWZhdWx0ZGVmYXVsdA=="}
+		assert.Equal(t, []string{current, previous}, newRoute().Secrets(cfg))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			modify  func(r *routes.Route)
+			wantErr string
+		}{
+			{"no signing secret", func(r *routes.Route) { r.SigningSecret = "" }, "signing_secrets needs a signing_secret for route user-events"},
+			{"hex format", func(r *routes.Route) { r.SignatureFormat = routes.SignatureFormatHex }, `signing_secrets cannot be used with signature_format "hex"`},
+			{"invalid secret", func(r *routes.Route) { r.SigningSecrets = []string{"not-a-secret"} }, "invalid signing_secrets entry for route user-events"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				route := newRoute()
+				tt.modify(route)
+
+				err := route.Validate()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("loader reads signing_secrets", func(t *testing.T) {
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/webhook"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    signing_secret: "`+current+`"
+    signing_secrets:
+      - "`+previous+`"
+`), 0o644))
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, []string{current, previous}, route.Secrets(nil))
+	})
+}
+
+func TestRoute_PriorityEventTypes(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "payments"
+    target_url: "https://example.com/payments"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    priority_event_types: ["payment.failed", "dispute.*"]
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	event := func(eventType string) []byte {
+		return []byte(`{"type":"` + eventType + `","timestamp":"2024-01-01T12:00:00Z","data":{}}`)
+	}
+
+	t.Run("matching event types are prioritized", func(t *testing.T) {
+		assert.True(t, loader.IsPriority("payments", event("payment.failed")))
+		assert.True(t, loader.IsPriority("payments", event("dispute.opened")))
+	})
+
+	t.Run("other payloads are not", func(t *testing.T) {
+		assert.False(t, loader.IsPriority("payments", event("payment.succeeded")))
+		assert.False(t, loader.IsPriority("payments", []byte(`not json`)))
+		// No priority_event_types means no priority, not "everything"
+		assert.False(t, loader.IsPriority("orders", event("payment.failed")))
+		assert.False(t, loader.IsPriority("unknown", event("payment.failed")))
+	})
+
+	t.Run("raw payloads are rejected", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:            "payments",
+			TargetURL:          "https://example.com/payments",
+			Mode:               webhook.FIFO,
+			Parallelism:        1,
+			PayloadFormat:      routes.PayloadFormatRaw,
+			PriorityEventTypes: []string{"payment.failed"},
+		}
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "priority_event_types cannot be used with raw payloads")
+	})
+
+	t.Run("invalid event type", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:            "payments",
+			TargetURL:          "https://example.com/payments",
+			Mode:               webhook.FIFO,
+			Parallelism:        1,
+			PriorityEventTypes: []string{""},
+		}
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid priority_event_type")
+	})
+}
+
+func TestRoute_EventTypeSource(t *testing.T) {
+	newRoute := func(source string) *routes.Route {
+		return &routes.Route{
+			RouteID:         "typed",
+			TargetURL:       "https://example.com/typed",
+			Mode:            webhook.FIFO,
+			Parallelism:     1,
+			EventTypeSource: source,
+		}
+	}
+
+	t.Run("valid sources", func(t *testing.T) {
+		for _, source := range []string{"", routes.EventTypeSourcePayload, "header:X-Event-Type", "jsonpath:$.event.type"} {
+			assert.NoError(t, newRoute(source).Validate(), source)
+		}
+		assert.False(t, newRoute("").DerivesEventType())
+		assert.False(t, newRoute(routes.EventTypeSourcePayload).DerivesEventType())
+		assert.True(t, newRoute("header:X-Event-Type").DerivesEventType())
+	})
+
+	t.Run("invalid sources", func(t *testing.T) {
+		for _, source := range []string{"body", "header:", "jsonpath:event.type", "jsonpath:"} {
+			assert.Error(t, newRoute(source).Validate(), source)
+		}
+	})
+
+	t.Run("raw payloads are rejected", func(t *testing.T) {
+		route := newRoute("header:X-Event-Type")
+		route.AcceptRaw = true
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "event_type_source cannot be used with raw payloads")
+	})
+
+	t.Run("type from header", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("X-Event-Type", "user.created")
+
+		eventType, err := newRoute("header:X-Event-Type").EventType(header, []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, "user.created", eventType)
+
+		_, err = newRoute("header:X-Event-Type").EventType(http.Header{}, []byte(`{}`))
+		assert.ErrorIs(t, err, routes.ErrNoEventType)
+	})
+
+	t.Run("type from JSON path", func(t *testing.T) {
+		route := newRoute("jsonpath:$.event.type")
+
+		eventType, err := route.EventType(http.Header{}, []byte(`{"event":{"type":"order.paid"}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "order.paid", eventType)
+
+		_, err = route.EventType(http.Header{}, []byte(`{"event":{}}`))
+		assert.ErrorIs(t, err, routes.ErrNoEventType)
+	})
+}
+
+func TestRoute_RetryDeadline(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("no window", func(t *testing.T) {
+		_, ok := (&routes.Route{}).RetryDeadline(created)
+		assert.False(t, ok)
+	})
+
+	t.Run("window counts from creation", func(t *testing.T) {
+		deadline, ok := (&routes.Route{MaxRetryWindowHours: 24}).RetryDeadline(created)
+		require.True(t, ok)
+		assert.Equal(t, created.Add(24*time.Hour), deadline)
+	})
+
+	t.Run("negative window is rejected", func(t *testing.T) {
+		route := &routes.Route{RouteID: "windowed", TargetURL: "https://example.com", Mode: webhook.FIFO, Parallelism: 1, MaxRetryWindowHours: -1}
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_retry_window_hours cannot be negative")
+	})
+}
+
+func TestLoader_SigningSecret(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    signing_secret: "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	secret, ok := loader.SigningSecret("user-events")
+	assert.True(t, ok)
+	assert.Equal(t, "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", secret)
+
+	secret, ok = loader.SigningSecret("orders")
+	assert.True(t, ok)
+	assert.Empty(t, secret)
+
+	_, ok = loader.SigningSecret("unknown")
+	assert.False(t, ok)
+}
+
+func TestLoader_Enabled(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    enabled: false
+`), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	t.Run("routes are enabled unless the file disables them", func(t *testing.T) {
+		assert.True(t, loader.IsEnabled("user-events"))
+		assert.False(t, loader.IsEnabled("orders"))
+		assert.True(t, loader.IsEnabled("unknown"))
+		assert.True(t, (&routes.Route{}).IsEnabled())
+	})
+
+	t.Run("SetEnabled replaces the route with a copy", func(t *testing.T) {
+		before, err := loader.Get("user-events")
+		require.NoError(t, err)
+
+		updated, err := loader.SetEnabled("user-events", false)
+		require.NoError(t, err)
+
+		assert.False(t, updated.IsEnabled())
+		assert.False(t, loader.IsEnabled("user-events"))
+		assert.True(t, before.IsEnabled())
+		assert.Equal(t, before.TargetURL, updated.TargetURL)
+	})
+
+	t.Run("Reload restores the file's setting", func(t *testing.T) {
+		_, err := loader.Reload(path)
+		require.NoError(t, err)
+
+		assert.True(t, loader.IsEnabled("user-events"))
+		assert.False(t, loader.IsEnabled("orders"))
+	})
+
+	t.Run("error - unknown route", func(t *testing.T) {
+		_, err := loader.SetEnabled("unknown", false)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoader_Groups(t *testing.T) {
+	const routesYAML = `
+routes:
+  - route_id: "billing"
+    target_url: "https://example.com/billing"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "crm"
+    target_url: "https://example.com/crm"
+    mode: "fifo"
+    max_retries: 5
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "events"
+    target_url: "https://example.com/events"
+    mode: "pubsub"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 4
+`
+	writeRoutes := func(t *testing.T, groups string) string {
+		t.Helper()
+		path := t.TempDir() + "/routes.yaml"
+		require.NoError(t, os.WriteFile(path, []byte(routesYAML+groups), 0o644))
+		return path
+	}
+
+	t.Run("success - routes share a group", func(t *testing.T) {
+		path := writeRoutes(t, `
+groups:
+  - group_id: "low-volume"
+    route_ids: ["billing", "crm"]
+`)
+
+		loader := routes.NewLoader()
+		require.NoError(t, loader.Load(path))
+
+		assert.Equal(t, "low-volume", loader.GroupOf("billing"))
+		assert.Equal(t, "low-volume", loader.GroupOf("crm"))
+		assert.Empty(t, loader.GroupOf("events"))
+
+		groups := loader.Groups()
+		require.Len(t, groups, 1)
+		assert.Equal(t, []string{"billing", "crm"}, groups[0].RouteIDs)
+		assert.Equal(t, webhook.FIFO, groups[0].Mode)
+		assert.Equal(t, 1, groups[0].Parallelism)
+
+		route := groups[0].Route()
+		assert.Equal(t, "low-volume", route.RouteID)
+		assert.Equal(t, webhook.FIFO, route.Mode)
+
+		// Grouped routes keep their own settings
+		billing, err := loader.Get("billing")
+		require.NoError(t, err)
+		assert.Equal(t, 3, billing.MaxRetries)
+	})
+
+	t.Run("reload replaces groups", func(t *testing.T) {
+		loader := routes.NewLoader()
+		require.NoError(t, loader.LoadStrict(writeRoutes(t, `
+groups:
+  - group_id: "low-volume"
+    route_ids: ["billing", "crm"]
+`)))
+
+		_, err := loader.Reload(writeRoutes(t, ""))
+		require.NoError(t, err)
+		assert.Empty(t, loader.Groups())
+		assert.Empty(t, loader.GroupOf("billing"))
+	})
+
+	t.Run("invalid groups", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			groups string
+			err    string
+		}{
+			{"empty group_id", `[{route_ids: ["billing"]}]`, "group_id cannot be empty"},
+			{"duplicate group_id", `[{group_id: "g", route_ids: ["billing"]}, {group_id: "g", route_ids: ["crm"]}]`, "duplicate group_id g"},
+			{"group_id is a route_id", `[{group_id: "crm", route_ids: ["billing"]}]`, "group_id crm is already a route_id"},
+			{"no routes", `[{group_id: "g"}]`, "group g has no route_ids"},
+			{"unknown route", `[{group_id: "g", route_ids: ["billing", "missing"]}]`, "route not found: missing"},
+			{"route in two groups", `[{group_id: "a", route_ids: ["billing"]}, {group_id: "b", route_ids: ["billing"]}]`, "route billing already belongs to group a"},
+			{"mixed modes", `[{group_id: "g", route_ids: ["billing", "events"]}]`, "route events is pubsub but the group is fifo"},
+			{"FIFO parallelism", `[{group_id: "g", route_ids: ["billing"], parallelism: 2}]`, "FIFO mode requires parallelism=1 for group g"},
+			{"negative parallelism", `[{group_id: "g", route_ids: ["events"], parallelism: -1}]`, "parallelism must be at least 1 for group g"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				loader := routes.NewLoader()
+				err := loader.LoadStrict(writeRoutes(t, "groups: "+tt.groups+"\n"))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				assert.Empty(t, loader.List())
+			})
+		}
+	})
+}
+
+func TestLoader_Warnings(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    event_types: ["user.*"]
+  - route_id: "analytics"
+    target_url: "https://example.com/analytics"
+    mode: "pubsub"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 2
+    event_types: ["user.*"]
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path), "warnings don't prevent loading")
+
+	warnings := loader.Warnings()
+	require.Len(t, warnings, 1, "only FIFO routes with event_types are warned about")
+	assert.Contains(t, warnings[0], "route user-events: event_types on a FIFO route")
+
+	assert.Empty(t, routes.NewLoader().Warnings())
+}
+
+func TestRoute_CaptureResponseBytes(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+  - route_id: "orders"
+    target_url: "https://example.com/orders"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+    capture_response_bytes: 0
+`), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	t.Run("defaults to 1KB", func(t *testing.T) {
+		route, err := loader.Get("user-events")
+		require.NoError(t, err)
+		assert.Equal(t, 1024, route.CaptureResponseBytes)
+	})
+
+	t.Run("zero disables capture", func(t *testing.T) {
+		route, err := loader.Get("orders")
+		require.NoError(t, err)
+		assert.Equal(t, 0, route.CaptureResponseBytes)
+	})
+
+	t.Run("error - negative capture_response_bytes", func(t *testing.T) {
+		route := &routes.Route{RouteID: "test", TargetURL: "https://example.com", Mode: webhook.FIFO, Parallelism: 1, CaptureResponseBytes: -1}
+		err := route.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "capture_response_bytes cannot be negative for route test")
+	})
+}
+
+func TestLoader_List(t *testing.T) {
+	path := t.TempDir() + "/routes.yaml"
+	var config strings.Builder
+	config.WriteString("routes:\n")
+	for _, id := range []string{"orders", "analytics", "user-events", "billing", "zeta", "alpha"} {
+		fmt.Fprintf(&config, `  - route_id: %q
+    target_url: "https://example.com/%s"
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1000"
+    parallelism: 1
+`, id, id)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(config.String()), 0o644))
+
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	listedIDs := func() []string {
+		var ids []string
+		for _, route := range loader.List() {
+			ids = append(ids, route.RouteID)
+		}
+		return ids
+	}
+
+	want := []string{"alpha", "analytics", "billing", "orders", "user-events", "zeta"}
+	for i := 0; i < 20; i++ {
+		require.Equal(t, want, listedIDs(), "call %d", i+1)
+	}
+}
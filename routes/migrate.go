@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
+	"gopkg.in/yaml.v3"
+)
+
+/* Migrate upgrades a routes file written for an older version of the inbox
+ * Settings that were replaced are rewritten in their current form and every
+ * setting left to its default is written out, so the file documents how its
+ * routes behave. Routes behave exactly as before: a value the loader would
+ * have read differently (e.g. mode "FIFO", read as fifo) is written as read.
+ * The migrated file is validated before it is returned
+ */
+
+// Migration is the result of migrating a routes file
+type Migration struct {
+	Output  []byte   // The normalized routes file
+	Changes []string // What changed, one line per setting, in file order
+}
+
+// Migrate migrates the contents of a routes file (see Migration)
+// Returns an error when the file cannot be parsed or the migrated routes are invalid
+func Migrate(data []byte) (Migration, error) {
+	config, err := parseConfig(data)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	var changes []string
+	for i := range config.Routes {
+		changes = append(changes, migrateRoute(&config.Routes[i])...)
+	}
+	for i := range config.Groups {
+		changes = append(changes, migrateGroup(&config.Groups[i])...)
+	}
+
+	if _, _, err := buildAll(config, nil); err != nil {
+		return Migration{}, fmt.Errorf("migrated routes are invalid: %w", err)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return Migration{}, fmt.Errorf("encoding routes YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return Migration{}, fmt.Errorf("encoding routes YAML: %w", err)
+	}
+
+	return Migration{Output: out.Bytes(), Changes: changes}, nil
+}
+
+// migrateRoute rewrites a route entry in the current schema, returning what changed
+func migrateRoute(rc *RouteConfig) []string {
+	var changes []string
+	changed := func(format string, args ...any) {
+		changes = append(changes, fmt.Sprintf("route %s: ", rc.RouteID)+fmt.Sprintf(format, args...))
+	}
+	setDefault := func(field *string, name, value string) {
+		if *field == "" {
+			*field = value
+			changed("%s set to the default %q", name, value)
+		}
+	}
+
+	// Unknown modes have always been read as fifo
+	if mode := webhook.NewDeliveryMode(rc.Mode).String(); rc.Mode != mode {
+		changed("mode %q written as %q, the mode it is read as", rc.Mode, mode)
+		rc.Mode = mode
+	}
+
+	// expected_status predates expected_statuses
+	switch {
+	case rc.ExpectedStatus != 0 && len(rc.ExpectedStatuses) == 0:
+		rc.ExpectedStatuses = statusList{strconv.Itoa(rc.ExpectedStatus)}
+		changed("expected_status %d replaced by expected_statuses [%d]", rc.ExpectedStatus, rc.ExpectedStatus)
+		rc.ExpectedStatus = 0
+	case len(rc.ExpectedStatuses) == 0:
+		rc.ExpectedStatuses = statusList{DefaultExpectedStatus}
+		changed("expected_statuses set to the default [%q]", DefaultExpectedStatus)
+	}
+
+	setDefault(&rc.SignatureFormat, "signature_format", SignatureFormatStandard)
+	setDefault(&rc.SignatureAlgorithm, "signature_algorithm", string(signature.SHA256))
+	setDefault(&rc.HeaderStyle, "header_style", HeaderStyleStandard)
+	setDefault(&rc.PayloadFormat, "payload_format", PayloadFormatStandard)
+	setDefault(&rc.AckPolicy, "ack_policy", AckPolicyAck)
+	setDefault(&rc.EventTypeSource, "event_type_source", EventTypeSourcePayload)
+
+	if rc.CaptureResponseBytes == nil {
+		captureResponseBytes := DefaultCaptureResponseBytes
+		rc.CaptureResponseBytes = &captureResponseBytes
+		changed("capture_response_bytes set to the default %d", captureResponseBytes)
+	}
+
+	if rc.Enabled == nil {
+		enabled := true
+		rc.Enabled = &enabled
+		changed("enabled set to the default true")
+	}
+
+	return changes
+}
+
+// migrateGroup rewrites a group entry in the current schema, returning what changed
+func migrateGroup(gc *RouteGroupConfig) []string {
+	if gc.Parallelism != 0 {
+		return nil
+	}
+	gc.Parallelism = 1
+	return []string{fmt.Sprintf("group %s: parallelism set to the default 1", gc.GroupID)}
+}
@@ -0,0 +1,76 @@
+package routes_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	old, err := os.ReadFile("testdata/routes_old.yaml")
+	require.NoError(t, err)
+	want, err := os.ReadFile("testdata/routes_migrated.yaml")
+	require.NoError(t, err)
+
+	t.Run("old file is normalized", func(t *testing.T) {
+		migration, err := routes.Migrate(old)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(want), string(migration.Output))
+		assert.Contains(t, migration.Changes, `route user-events: mode "FIFO" written as "fifo", the mode it is read as`)
+		assert.Contains(t, migration.Changes, "route user-events: expected_status 202 replaced by expected_statuses [202]")
+		assert.Contains(t, migration.Changes, `route analytics-events: expected_statuses set to the default ["2xx"]`)
+		assert.Contains(t, migration.Changes, "route orders: enabled set to the default true")
+		assert.Contains(t, migration.Changes, "route orders: capture_response_bytes set to the default 1024")
+		assert.Contains(t, migration.Changes, "group commerce: parallelism set to the default 1")
+	})
+
+	t.Run("routes load as before", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "old.yaml"), old, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "migrated.yaml"), want, 0o644))
+
+		before, after := routes.NewLoader(), routes.NewLoader()
+		require.NoError(t, before.Load(filepath.Join(dir, "old.yaml")))
+		require.NoError(t, after.Load(filepath.Join(dir, "migrated.yaml")))
+
+		for _, id := range []string{"user-events", "analytics-events", "orders"} {
+			b, err := before.Get(id)
+			require.NoError(t, err)
+			a, err := after.Get(id)
+			require.NoError(t, err)
+
+			assert.Equal(t, b.Mode, a.Mode, id)
+			assert.Equal(t, b.AcceptedStatuses(), a.AcceptedStatuses(), id)
+			assert.Equal(t, b.GetSignatureAlgorithm(), a.GetSignatureAlgorithm(), id)
+			assert.Equal(t, b.IsEnabled(), a.IsEnabled(), id)
+			assert.Equal(t, b.CaptureResponseBytes, a.CaptureResponseBytes, id)
+		}
+		assert.Equal(t, before.GroupOf("orders"), after.GroupOf("orders"))
+	})
+
+	t.Run("migrated file is left unchanged", func(t *testing.T) {
+		migration, err := routes.Migrate(want)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(want), string(migration.Output))
+		assert.Empty(t, migration.Changes)
+	})
+
+	t.Run("error - unknown settings", func(t *testing.T) {
+		_, err := routes.Migrate([]byte("routes:\n  - route_id: orders\n    paralelism: 1\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing routes YAML")
+	})
+
+	t.Run("error - invalid routes", func(t *testing.T) {
+		_, err := routes.Migrate([]byte("routes:\n  - route_id: orders\n    mode: fifo\n    parallelism: 1\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "migrated routes are invalid")
+		assert.Contains(t, err.Error(), "target_url cannot be empty for route orders")
+	})
+}
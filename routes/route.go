@@ -1,7 +1,12 @@
 package routes
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -9,6 +14,22 @@ import (
 	"github.com/marcelsud/webhook-inbox/webhook"
 	"github.com/marcelsud/webhook-inbox/webhook/payload"
 	"github.com/marcelsud/webhook-inbox/webhook/signature"
+	"github.com/marcelsud/webhook-inbox/webhook/template"
+)
+
+// Payload formats accepted on ingestion
+const (
+	PayloadFormatStandard = "standard" // Standard Webhooks payload with type, timestamp and data
+	PayloadFormatRaw      = "raw"      // Arbitrary JSON forwarded verbatim
+)
+
+// DefaultCaptureResponseBytes is how much of a failed delivery's response body is kept when capture_response_bytes is unset
+const DefaultCaptureResponseBytes = 1024
+
+// Ack policies for deliveries rejected with a non-retryable status (see IsRetryableStatus)
+const (
+	AckPolicyAck         = "ack"          // Mark failed (or dead-letter) and acknowledge the stream message
+	AckPolicyKeepPending = "keep_pending" // Mark failed but leave the message pending for manual intervention
 )
 
 /* Route represents a webhook destination configuration
@@ -20,16 +41,117 @@ type Route struct {
 	Mode              webhook.DeliveryMode
 	MaxRetries        int
 	RetryBackoff      string   // Expression like "pow(2, retried) * 1000"
+	RetryJitter       float64  // Optional: randomly spread retry delays by up to this fraction (0-1)
 	Parallelism       int      // 1 for FIFO, >1 for PubSub
-	ExpectedStatus    int      // Expected HTTP status code: 200, 201, or 202 (default: 202)
+	ExpectedStatus    int      // Optional: single expected 2xx status code (see ExpectedStatuses)
+	ExpectedStatuses  []string // Optional: expected codes, classes or ranges (e.g. ["200", "204"], ["2xx"])
 	DeliveredTTLHours *int     // Optional: TTL for delivered webhooks in hours
 	FailedTTLHours    *int     // Optional: TTL for failed webhooks in hours
 	SigningSecret     string   // Standard Webhooks signing secret (whsec_ prefix)
+	SigningSecrets    []string // Optional: extra secrets also signed during rotation (e.g. the previous secret)
+	RequireSignature  bool     // Fail webhooks that cannot be signed instead of sending them unsigned
+	SignatureHeader   string   // Optional: header carrying the signature (default: webhook-signature)
+	SignatureFormat   string   // "standard" (default when empty) or "hex"
+	HeaderStyle       string   // "standard" (default when empty) or "xprefixed" delivery header names
 	EventTypes        []string // Event types to filter (e.g., ["user.created", "user.*"])
+	MaxStreamLen      int      // Optional: trim acknowledged stream entries beyond this length (0 = unbounded)
+	MaxQueueDepth     int      // Optional: reject events with 429 while this many are unacknowledged (0 = unlimited)
+	// RejectUnsubscribed rejects events not matching EventTypes at ingestion (422)
+	// instead of storing them and skipping them at delivery time
+	RejectUnsubscribed bool
+	ClientCertFile     string // Optional: PEM client certificate for mutual TLS
+	ClientKeyFile      string // Optional: PEM private key matching ClientCertFile
+	CAFile             string // Optional: PEM root CAs used to verify the target instead of the system pool
+	// AcceptRaw stores request bodies as-is, whatever their Content-Type,
+	// without validating them as Standard Webhooks payloads
+	AcceptRaw bool
+	// PayloadFormat is "standard" (default when empty) or "raw"; raw routes accept
+	// any JSON body and skip Standard Webhooks validation and event-type filtering
+	PayloadFormat string
+	// ForwardHeaders allow-lists the inbound headers stored and forwarded to the target
+	// When empty, every header except credentials and hop-by-hop headers is forwarded
+	ForwardHeaders []string
+	// BodyTemplate is an optional Go text/template turning the Standard Webhooks payload
+	// into the body sent to the target (see the webhook/template package)
+	BodyTemplate string
+	// IDPrefix is prepended to the event IDs generated for this route (e.g. "user_")
+	// Only letters, digits, '_' and '-' are allowed, as IDs are signed as Standard Webhooks message IDs
+	IDPrefix string
+	// MaxJSONDepth rejects events at ingestion (422) whose data nests objects and arrays
+	// deeper than this (0 = unlimited); raw payload_format routes check the whole body
+	MaxJSONDepth int
+	// IsDefault accepts events posted to unconfigured route IDs with this route's settings
+	// (see Loader.GetOrDefault); at most one route may be the default
+	IsDefault bool
+	// AckPolicy is "ack" (default when empty) or "keep_pending"; keep_pending leaves messages
+	// rejected with a non-retryable status in the pending entries list instead of acknowledging them
+	AckPolicy string
+	// PriorityEventTypes are event types (patterns as in EventTypes) queued on the route's
+	// high-priority stream, which workers drain before the normal one (e.g. ["payment.failed"])
+	PriorityEventTypes []string
+	// SignatureAlgorithm is the HMAC hash of signatures: "sha256" (default when empty) or "sha512"
+	// SHA512 Standard Webhooks signatures are tagged v1-sha512 instead of v1
+	SignatureAlgorithm string
+	// EventTypeSource is where ingestion reads the event type: "payload" (default when empty),
+	// "header:<name>" or "jsonpath:<expr>"; the latter two accept plain JSON bodies and store
+	// them as the data of a synthesized Standard Webhooks payload
+	EventTypeSource string
+	// MaxRetryWindowHours stops retrying a webhook this many hours after it was created,
+	// even if retries remain (0 = no window); max_retries still applies within the window
+	MaxRetryWindowHours int
+	// IngestAPIKeys are the keys accepted in the X-API-Key header when posting events to the route
+	// (empty = the server-wide ingest key, if any); several keys allow rotating them
+	IngestAPIKeys []string
+	// Archive hands delivered webhooks to the worker's archiver (see webhook.Archiver) before their TTL is set,
+	// keeping a durable record after Redis expires them
+	Archive bool
+	// Enabled set to false pauses delivery: events are still accepted and stored, but workers stop
+	// reading the route's streams until it is enabled again (nil = enabled, see Loader.SetEnabled)
+	Enabled *bool
+	// InjectEventIDField is a top-level key the worker sets to the webhook ID in the delivered JSON body,
+	// before signing it, for receivers reading the ID from the body rather than the headers (e.g. "id")
+	InjectEventIDField string
+	// CaptureResponseBytes is how much of the target's response body is kept when a delivery fails with
+	// an unexpected status, in the DeliveryError and the attempt history (0 = none; routes.yaml defaults to 1KB)
+	CaptureResponseBytes int
+
+	eventMatchers    []eventMatcher // EventTypes compiled at load time (see MatchesType)
+	priorityMatchers []eventMatcher // PriorityEventTypes compiled at load time
+}
+
+// reservedPayloadFields are the Standard Webhooks payload fields inject_event_id_field cannot overwrite
+var reservedPayloadFields = map[string]bool{"type": true, "timestamp": true, "data": true}
+
+// idPrefixPattern matches the characters allowed in id_prefix
+var idPrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]*$`)
+
+/* strippedHeaders are never forwarded unless explicitly allow-listed
+ * Credentials would leak to receivers; hop-by-hop headers (RFC 7230 6.1)
+ * only apply to the connection the webhook arrived on
+ */
+var strippedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Proxy-Authenticate":  true,
+	"X-Api-Key":           true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
 }
 
 // Validate checks if the route configuration is valid
+// require_signature is only accepted with the route's own signing_secret (see ValidateWithConfig)
 func (r *Route) Validate() error {
+	return r.ValidateWithConfig(nil)
+}
+
+// ValidateWithConfig checks if the route configuration is valid against the configuration
+// require_signature is also accepted when cfg provides a default signing secret
+func (r *Route) ValidateWithConfig(cfg *config.Config) error {
 	if r.RouteID == "" {
 		return fmt.Errorf("route_id cannot be empty")
 	}
@@ -49,9 +171,15 @@ func (r *Route) Validate() error {
 	if r.Mode == webhook.FIFO && r.Parallelism > 1 {
 		return fmt.Errorf("FIFO mode requires parallelism=1 for route %s (got %d)", r.RouteID, r.Parallelism)
 	}
-	// Validate expected status code (only 200, 201, 202 allowed)
-	if r.ExpectedStatus != 200 && r.ExpectedStatus != 201 && r.ExpectedStatus != 202 {
-		return fmt.Errorf("expected_status must be 200, 201, or 202 for route %s (got %d)", r.RouteID, r.ExpectedStatus)
+	// Validate expected statuses (only 2xx codes allowed)
+	if r.ExpectedStatus != 0 && (r.ExpectedStatus < 200 || r.ExpectedStatus > 299) {
+		return fmt.Errorf("expected_status must be a 2xx status code for route %s (got %d)", r.RouteID, r.ExpectedStatus)
+	}
+	if r.ExpectedStatus != 0 && len(r.ExpectedStatuses) > 0 {
+		return fmt.Errorf("expected_status and expected_statuses cannot be used together for route %s", r.RouteID)
+	}
+	if _, err := r.expectedStatusRanges(); err != nil {
+		return fmt.Errorf("invalid expected_statuses for route %s: %w", r.RouteID, err)
 	}
 	// Validate TTL values if provided
 	if r.DeliveredTTLHours != nil && *r.DeliveredTTLHours < 0 {
@@ -60,6 +188,96 @@ func (r *Route) Validate() error {
 	if r.FailedTTLHours != nil && *r.FailedTTLHours < 0 {
 		return fmt.Errorf("failed_ttl_hours cannot be negative for route %s", r.RouteID)
 	}
+	if r.RetryJitter < 0 || r.RetryJitter > 1 {
+		return fmt.Errorf("retry_jitter must be between 0 and 1 for route %s (got %g)", r.RouteID, r.RetryJitter)
+	}
+	if r.MaxStreamLen < 0 {
+		return fmt.Errorf("max_stream_len cannot be negative for route %s", r.RouteID)
+	}
+	if r.MaxQueueDepth < 0 {
+		return fmt.Errorf("max_queue_depth cannot be negative for route %s", r.RouteID)
+	}
+	if r.MaxRetryWindowHours < 0 {
+		return fmt.Errorf("max_retry_window_hours cannot be negative for route %s", r.RouteID)
+	}
+	if r.MaxJSONDepth < 0 {
+		return fmt.Errorf("max_json_depth cannot be negative for route %s", r.RouteID)
+	}
+	if r.CaptureResponseBytes < 0 {
+		return fmt.Errorf("capture_response_bytes cannot be negative for route %s", r.RouteID)
+	}
+	// Raw bodies are not necessarily JSON
+	if r.AcceptRaw && r.MaxJSONDepth > 0 {
+		return fmt.Errorf("max_json_depth cannot be used with accept_raw for route %s", r.RouteID)
+	}
+	// Raw bodies have no event type to check against
+	if r.AcceptRaw && r.RejectUnsubscribed {
+		return fmt.Errorf("reject_unsubscribed cannot be used with accept_raw for route %s", r.RouteID)
+	}
+	if r.AckPolicy != "" && r.AckPolicy != AckPolicyAck && r.AckPolicy != AckPolicyKeepPending {
+		return fmt.Errorf("ack_policy must be %q or %q for route %s (got %q)", AckPolicyAck, AckPolicyKeepPending, r.RouteID, r.AckPolicy)
+	}
+	if r.PayloadFormat != "" && r.PayloadFormat != PayloadFormatStandard && r.PayloadFormat != PayloadFormatRaw {
+		return fmt.Errorf("payload_format must be %q or %q for route %s (got %q)", PayloadFormatStandard, PayloadFormatRaw, r.RouteID, r.PayloadFormat)
+	}
+	// Filtering needs the Standard Webhooks type field
+	if r.PayloadFormat == PayloadFormatRaw && (len(r.EventTypes) > 0 || r.RejectUnsubscribed) {
+		return fmt.Errorf("event_types and reject_unsubscribed cannot be used with payload_format %q for route %s", PayloadFormatRaw, r.RouteID)
+	}
+	// Priority is decided by the Standard Webhooks type field
+	if len(r.PriorityEventTypes) > 0 && (r.PayloadFormat == PayloadFormatRaw || r.AcceptRaw) {
+		return fmt.Errorf("priority_event_types cannot be used with raw payloads for route %s", r.RouteID)
+	}
+	// Client certificate and key only make sense together
+	if (r.ClientCertFile == "") != (r.ClientKeyFile == "") {
+		return fmt.Errorf("client_cert_file and client_key_file must be set together for route %s", r.RouteID)
+	}
+	if r.BodyTemplate != "" {
+		// Templates render the parsed Standard Webhooks payload
+		if r.PayloadFormat == PayloadFormatRaw || r.AcceptRaw {
+			return fmt.Errorf("body_template cannot be used with raw payloads for route %s", r.RouteID)
+		}
+		if err := template.Validate(r.BodyTemplate); err != nil {
+			return fmt.Errorf("invalid body_template for route %s: %w", r.RouteID, err)
+		}
+	}
+	if !idPrefixPattern.MatchString(r.IDPrefix) {
+		return fmt.Errorf("id_prefix may only contain letters, digits, '_' and '-' for route %s (got %q)", r.RouteID, r.IDPrefix)
+	}
+	if r.InjectEventIDField != "" {
+		if strings.TrimSpace(r.InjectEventIDField) == "" || reservedPayloadFields[r.InjectEventIDField] {
+			return fmt.Errorf("inject_event_id_field cannot be blank or one of type, timestamp and data for route %s (got %q)", r.RouteID, r.InjectEventIDField)
+		}
+		// Raw and templated bodies are not necessarily JSON objects, so the field could never be set
+		if r.AcceptRaw {
+			return fmt.Errorf("inject_event_id_field cannot be used with accept_raw for route %s", r.RouteID)
+		}
+		if r.PayloadFormat == PayloadFormatRaw {
+			return fmt.Errorf("inject_event_id_field cannot be used with payload_format %q for route %s", PayloadFormatRaw, r.RouteID)
+		}
+		if r.BodyTemplate != "" {
+			return fmt.Errorf("inject_event_id_field cannot be used with body_template for route %s", r.RouteID)
+		}
+	}
+	for _, key := range r.IngestAPIKeys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("ingest_api_keys cannot contain empty keys for route %s", r.RouteID)
+		}
+	}
+	for _, name := range r.ForwardHeaders {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("forward_headers cannot contain empty header names for route %s", r.RouteID)
+		}
+	}
+	if err := r.validateSignatureSettings(); err != nil {
+		return err
+	}
+	if err := r.validateEventTypeSource(); err != nil {
+		return err
+	}
+	if r.RequireSignature && len(r.Secrets(cfg)) == 0 {
+		return fmt.Errorf("require_signature needs a signing_secret or a default signing secret for route %s", r.RouteID)
+	}
 	// Validate signing secret if provided (Standard Webhooks)
 	if r.SigningSecret != "" {
 		if !strings.HasPrefix(r.SigningSecret, signature.SecretPrefix) {
@@ -71,14 +289,76 @@ func (r *Route) Validate() error {
 		}
 	}
 	// Validate event types if provided
-	for _, eventType := range r.EventTypes {
-		if err := payload.ValidateEventType(eventType); err != nil {
-			return fmt.Errorf("invalid event_type '%s' for route %s: %w", eventType, r.RouteID, err)
-		}
+	if err := payload.ValidateEventTypes(r.EventTypes); err != nil {
+		return fmt.Errorf("invalid event_types for route %s: %w", r.RouteID, err)
+	}
+	if err := payload.ValidateEventTypes(r.PriorityEventTypes); err != nil {
+		return fmt.Errorf("invalid priority_event_types for route %s: %w", r.RouteID, err)
 	}
 	return nil
 }
 
+// Warnings returns the valid but risky settings of the route, one line each
+func (r *Route) Warnings() []string {
+	var warnings []string
+	// Unsubscribed events are skipped at delivery (or rejected at ingestion), leaving gaps in the sequence
+	if r.Mode == webhook.FIFO && len(r.EventTypes) > 0 {
+		warnings = append(warnings, fmt.Sprintf("route %s: event_types on a FIFO route leave gaps in its sequence: events of other types are skipped, so the target only sees the subscribed events in order", r.RouteID))
+	}
+	return warnings
+}
+
+// IsPriority reports whether a Standard Webhooks payload's event type is one of PriorityEventTypes
+// Payloads that cannot be parsed are never prioritized
+func (r *Route) IsPriority(body []byte) bool {
+	if len(r.PriorityEventTypes) == 0 {
+		return false
+	}
+	p, err := payload.Parse(body)
+	if err != nil {
+		return false
+	}
+	return matchAny(r.priorityMatchers, r.PriorityEventTypes, p.Type)
+}
+
+// FilterHeaders selects the inbound headers to store with a webhook
+// Uses the ForwardHeaders allow-list when set, otherwise drops credentials,
+// hop-by-hop headers and any header named in Connection
+func (r *Route) FilterHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+
+	if len(r.ForwardHeaders) > 0 {
+		for _, name := range r.ForwardHeaders {
+			if value := header.Get(name); value != "" {
+				headers[http.CanonicalHeaderKey(name)] = value
+			}
+		}
+		return headers
+	}
+
+	connectionHeaders := make(map[string]bool)
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			connectionHeaders[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		if len(values) == 0 || strippedHeaders[key] || connectionHeaders[key] {
+			continue
+		}
+		headers[key] = values[0]
+	}
+
+	return headers
+}
+
+// IsEnabled reports whether the route's webhooks are delivered (true unless Enabled is false)
+func (r *Route) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
 // GetDeliveredTTL returns the TTL for delivered webhooks
 // Priority: route-specific > config > default (1 hour)
 func (r *Route) GetDeliveredTTL(cfg *config.Config) time.Duration {
@@ -104,3 +384,35 @@ func (r *Route) GetFailedTTL(cfg *config.Config) time.Duration {
 	}
 	return time.Duration(hours) * time.Hour
 }
+
+// TLSConfig builds the TLS configuration for deliveries to this route
+// Returns nil when the route uses neither a client certificate nor custom root CAs
+func (r *Route) TLSConfig() (*tls.Config, error) {
+	if r.ClientCertFile == "" && r.CAFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if r.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.ClientCertFile, r.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate for route %s: %w", r.RouteID, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if r.CAFile != "" {
+		caPEM, err := os.ReadFile(r.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file for route %s: %w", r.RouteID, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file for route %s", r.RouteID)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
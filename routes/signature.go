@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/marcelsud/webhook-inbox/config"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
+)
+
+// Signature formats for the signature header sent with deliveries
+const (
+	SignatureFormatStandard = "standard" // Standard Webhooks: v1,<base64 HMAC of {id}.{timestamp}.{body}>
+	SignatureFormatHex      = "hex"      // Hex HMAC of the body alone (GitHub-style receivers)
+)
+
+// DefaultSignatureHeader is the Standard Webhooks signature header
+const DefaultSignatureHeader = "webhook-signature"
+
+/* Header styles name the id, timestamp and signature headers sent with deliveries
+ * Only the names change: the signed content is the same whatever the style
+ */
+const (
+	HeaderStyleStandard  = "standard"  // webhook-id, webhook-timestamp, webhook-signature
+	HeaderStyleXPrefixed = "xprefixed" // X-Webhook-Id, X-Webhook-Timestamp, X-Webhook-Signature (legacy receivers)
+)
+
+// reservedHeaders are set by the delivery client and cannot carry the signature
+var reservedHeaders = map[string]bool{
+	"Host":                true,
+	"Content-Type":        true,
+	"Content-Length":      true,
+	"Webhook-Id":          true,
+	"Webhook-Timestamp":   true,
+	"X-Webhook-Id":        true,
+	"X-Webhook-Timestamp": true,
+}
+
+// GetIDHeader returns the header carrying the webhook ID (webhook-id, or X-Webhook-Id for the xprefixed style)
+func (r *Route) GetIDHeader() string {
+	if r.HeaderStyle == HeaderStyleXPrefixed {
+		return "X-Webhook-Id"
+	}
+	return "webhook-id"
+}
+
+// GetTimestampHeader returns the header carrying the signing timestamp (webhook-timestamp, or X-Webhook-Timestamp for the xprefixed style)
+func (r *Route) GetTimestampHeader() string {
+	if r.HeaderStyle == HeaderStyleXPrefixed {
+		return "X-Webhook-Timestamp"
+	}
+	return "webhook-timestamp"
+}
+
+// GetSignatureHeader returns the header carrying the delivery signature
+// Defaults to webhook-signature, or X-Webhook-Signature for the xprefixed style
+func (r *Route) GetSignatureHeader() string {
+	if r.SignatureHeader != "" {
+		return r.SignatureHeader
+	}
+	if r.HeaderStyle == HeaderStyleXPrefixed {
+		return "X-Webhook-Signature"
+	}
+	return DefaultSignatureHeader
+}
+
+// GetSignatureAlgorithm returns the HMAC algorithm deliveries are signed with (default: SHA256)
+func (r *Route) GetSignatureAlgorithm() signature.Algorithm {
+	if r.SignatureAlgorithm == string(signature.SHA512) {
+		return signature.SHA512
+	}
+	return signature.SHA256
+}
+
+// Secrets returns every secret deliveries are signed with: SigningSecret first, then SigningSecrets
+// Priority: route-specific > config default > none (deliveries are unsigned)
+func (r *Route) Secrets(cfg *config.Config) []string {
+	if r.SigningSecret != "" {
+		return append([]string{r.SigningSecret}, r.SigningSecrets...)
+	}
+	if cfg != nil && cfg.WebhookDefaultSigningSecret != "" {
+		return []string{cfg.WebhookDefaultSigningSecret}
+	}
+	return nil
+}
+
+// validateSignatureSettings checks the header style, signature header name, format and rotation secrets
+func (r *Route) validateSignatureSettings() error {
+	if r.HeaderStyle != "" && r.HeaderStyle != HeaderStyleStandard && r.HeaderStyle != HeaderStyleXPrefixed {
+		return fmt.Errorf("header_style must be %q or %q for route %s (got %q)", HeaderStyleStandard, HeaderStyleXPrefixed, r.RouteID, r.HeaderStyle)
+	}
+	if r.SignatureFormat != "" && r.SignatureFormat != SignatureFormatStandard && r.SignatureFormat != SignatureFormatHex {
+		return fmt.Errorf("signature_format must be %q or %q for route %s (got %q)", SignatureFormatStandard, SignatureFormatHex, r.RouteID, r.SignatureFormat)
+	}
+	if _, err := signature.ParseAlgorithm(r.SignatureAlgorithm); err != nil {
+		return fmt.Errorf("invalid signature_algorithm for route %s: %w", r.RouteID, err)
+	}
+	if len(r.SigningSecrets) > 0 {
+		if r.SigningSecret == "" {
+			return fmt.Errorf("signing_secrets needs a signing_secret for route %s", r.RouteID)
+		}
+		// A hex signature header carries a single signature
+		if r.SignatureFormat == SignatureFormatHex {
+			return fmt.Errorf("signing_secrets cannot be used with signature_format %q for route %s", SignatureFormatHex, r.RouteID)
+		}
+		for _, secret := range r.SigningSecrets {
+			if _, err := signature.ParseSecret(secret); err != nil {
+				return fmt.Errorf("invalid signing_secrets entry for route %s: %w", r.RouteID, err)
+			}
+		}
+	}
+	if r.SignatureHeader == "" {
+		return nil
+	}
+	if strings.ContainsAny(r.SignatureHeader, " \t\r\n:") {
+		return fmt.Errorf("signature_header %q is not a valid header name for route %s", r.SignatureHeader, r.RouteID)
+	}
+	if reservedHeaders[http.CanonicalHeaderKey(r.SignatureHeader)] {
+		return fmt.Errorf("signature_header cannot be %s for route %s", r.SignatureHeader, r.RouteID)
+	}
+	return nil
+}
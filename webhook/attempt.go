@@ -0,0 +1,15 @@
+package webhook
+
+import "time"
+
+/* Attempt records the outcome of a single delivery attempt
+ * Attempts are append-only and kept alongside the webhook for diagnostics
+ */
+type Attempt struct {
+	Timestamp    time.Time         `json:"timestamp"`
+	StatusCode   int               `json:"status_code,omitempty"` // 0 when no response was received
+	Error        string            `json:"error,omitempty"`
+	ErrorKind    DeliveryErrorKind `json:"error_kind,omitempty"`    // Set with Error (see DeliveryError)
+	ResponseBody string            `json:"response_body,omitempty"` // Start of the target's response to a failed attempt (see DeliveryError)
+	LatencyMs    int64             `json:"latency_ms"`
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+)
+
+// DeliveryErrorKind classifies why a delivery attempt failed
+type DeliveryErrorKind string
+
+const (
+	DeliveryErrorNetwork DeliveryErrorKind = "network" // Connection refused or reset, DNS failures, ...
+	DeliveryErrorTimeout DeliveryErrorKind = "timeout" // The attempt ran out of time
+	DeliveryErrorTLS     DeliveryErrorKind = "tls"     // Handshake or certificate verification failed
+	DeliveryErrorStatus  DeliveryErrorKind = "status"  // The target answered with a status the route doesn't expect
+	DeliveryErrorOther   DeliveryErrorKind = "other"   // Anything else, e.g. the webhook could not be rendered or signed
+)
+
+/* DeliveryError is returned by a failed delivery attempt
+ * Kind tells timeouts, DNS, TLS and status failures apart without matching messages;
+ * StatusCode and ResponseBody are set for DeliveryErrorStatus. Err is the underlying
+ * error, so errors.Is and errors.As see through a DeliveryError
+ */
+type DeliveryError struct {
+	Kind         DeliveryErrorKind
+	StatusCode   int
+	ResponseBody string // Start of the target's response body, up to the route's capture_response_bytes
+	Err          error
+}
+
+// Error returns the underlying error's message
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// NewDeliveryError wraps err in a DeliveryError classified by ClassifyDeliveryError
+// Returns err unchanged when it is nil or already carries a DeliveryError
+func NewDeliveryError(err error) error {
+	var deliveryErr *DeliveryError
+	if err == nil || errors.As(err, &deliveryErr) {
+		return err
+	}
+	return &DeliveryError{Kind: ClassifyDeliveryError(err), Err: err}
+}
+
+// ResponseBodyOf returns the response body captured by the DeliveryError in err's chain, or ""
+func ResponseBodyOf(err error) string {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.ResponseBody
+	}
+	return ""
+}
+
+// DeliveryErrorKindOf returns the kind of the DeliveryError in err's chain, or DeliveryErrorOther
+func DeliveryErrorKindOf(err error) DeliveryErrorKind {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.Kind
+	}
+	return DeliveryErrorOther
+}
+
+// ClassifyDeliveryError returns the kind of a failure to send a request, e.g. from http.Client.Do
+// Timeouts win over the other kinds, so a TLS handshake that times out is a timeout
+func ClassifyDeliveryError(err error) DeliveryErrorKind {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return DeliveryErrorTimeout
+	}
+	if isTLSError(err) {
+		return DeliveryErrorTLS
+	}
+	if errors.As(err, &netErr) {
+		return DeliveryErrorNetwork
+	}
+	return DeliveryErrorOther
+}
+
+// isTLSError reports whether err comes from the TLS handshake or certificate verification
+func isTLSError(err error) bool {
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		opErr       *net.OpError
+	)
+	switch {
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &unknownAuth),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return true
+	case errors.As(err, &opErr):
+		// crypto/tls reports alerts sent by the peer, e.g. a rejected client certificate, this way
+		return opErr.Op == "remote error"
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyDeliveryError(t *testing.T) {
+	// sendErr wraps err the way http.Client.Do reports a failed request
+	sendErr := func(err error) error {
+		return fmt.Errorf("sending webhook: %w", &url.Error{Op: "Post", URL: "https://example.com", Err: err})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want webhook.DeliveryErrorKind
+	}{
+		{"connection refused", sendErr(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), webhook.DeliveryErrorNetwork},
+		{"DNS failure", sendErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}), webhook.DeliveryErrorNetwork},
+		{"DNS timeout", sendErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}}), webhook.DeliveryErrorTimeout},
+		{"deadline exceeded", sendErr(context.DeadlineExceeded), webhook.DeliveryErrorTimeout},
+		{"unknown certificate authority", sendErr(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), webhook.DeliveryErrorTLS},
+		{"hostname mismatch", sendErr(x509.HostnameError{Host: "example.com"}), webhook.DeliveryErrorTLS},
+		{"plain HTTP server", sendErr(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), webhook.DeliveryErrorTLS},
+		{"alert from the peer", sendErr(&net.OpError{Op: "remote error", Err: errors.New("tls: certificate required")}), webhook.DeliveryErrorTLS},
+		{"unsignable webhook", errors.New("signing webhook: invalid secret"), webhook.DeliveryErrorOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, webhook.ClassifyDeliveryError(tt.err))
+		})
+	}
+}
+
+func TestNewDeliveryError(t *testing.T) {
+	t.Run("classifies and wraps the error", func(t *testing.T) {
+		cause := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+		err := webhook.NewDeliveryError(cause)
+
+		var deliveryErr *webhook.DeliveryError
+		require.ErrorAs(t, err, &deliveryErr)
+		assert.Equal(t, webhook.DeliveryErrorNetwork, deliveryErr.Kind)
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+		assert.Equal(t, cause.Error(), err.Error())
+	})
+
+	t.Run("keeps an existing classification", func(t *testing.T) {
+		statusErr := &webhook.DeliveryError{Kind: webhook.DeliveryErrorStatus, StatusCode: 503, Err: errors.New("status 503")}
+
+		assert.Same(t, statusErr, webhook.NewDeliveryError(statusErr))
+		assert.Equal(t, webhook.DeliveryErrorStatus, webhook.DeliveryErrorKindOf(fmt.Errorf("delivering: %w", statusErr)))
+	})
+
+	t.Run("nil stays nil", func(t *testing.T) {
+		assert.NoError(t, webhook.NewDeliveryError(nil))
+	})
+
+	t.Run("unclassified errors are other", func(t *testing.T) {
+		assert.Equal(t, webhook.DeliveryErrorOther, webhook.DeliveryErrorKindOf(errors.New("boom")))
+	})
+}
@@ -0,0 +1,47 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Archiver is an autogenerated mock type for the Archiver type
+type Archiver struct {
+	mock.Mock
+}
+
+// Archive provides a mock function with given fields: ctx, _a1
+func (_m *Archiver) Archive(ctx context.Context, _a1 webhook.Webhook) error {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Archive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, webhook.Webhook) error); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewArchiver creates a new instance of Archiver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewArchiver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Archiver {
+	mock := &Archiver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,77 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AttemptLog is an autogenerated mock type for the AttemptLog type
+type AttemptLog struct {
+	mock.Mock
+}
+
+// GetAttempts provides a mock function with given fields: ctx, id
+func (_m *AttemptLog) GetAttempts(ctx context.Context, id string) ([]webhook.Attempt, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAttempts")
+	}
+
+	var r0 []webhook.Attempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]webhook.Attempt, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []webhook.Attempt); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Attempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordAttempt provides a mock function with given fields: ctx, id, attempt
+func (_m *AttemptLog) RecordAttempt(ctx context.Context, id string, attempt webhook.Attempt) error {
+	ret := _m.Called(ctx, id, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.Attempt) error); ok {
+		r0 = rf(ctx, id, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAttemptLog creates a new instance of AttemptLog. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttemptLog(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttemptLog {
+	mock := &AttemptLog{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
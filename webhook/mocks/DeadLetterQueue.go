@@ -0,0 +1,123 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DeadLetterQueue is an autogenerated mock type for the DeadLetterQueue type
+type DeadLetterQueue struct {
+	mock.Mock
+}
+
+// GetDLQ provides a mock function with given fields: ctx, routeID, id
+func (_m *DeadLetterQueue) GetDLQ(ctx context.Context, routeID string, id string) (webhook.Webhook, error) {
+	ret := _m.Called(ctx, routeID, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDLQ")
+	}
+
+	var r0 webhook.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (webhook.Webhook, error)); ok {
+		return rf(ctx, routeID, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) webhook.Webhook); ok {
+		r0 = rf(ctx, routeID, id)
+	} else {
+		r0 = ret.Get(0).(webhook.Webhook)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, routeID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDLQ provides a mock function with given fields: ctx, routeID, offset, limit
+func (_m *DeadLetterQueue) ListDLQ(ctx context.Context, routeID string, offset int, limit int) ([]webhook.Webhook, error) {
+	ret := _m.Called(ctx, routeID, offset, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDLQ")
+	}
+
+	var r0 []webhook.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) ([]webhook.Webhook, error)); ok {
+		return rf(ctx, routeID, offset, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []webhook.Webhook); ok {
+		r0 = rf(ctx, routeID, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
+		r1 = rf(ctx, routeID, offset, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MoveToDLQ provides a mock function with given fields: ctx, _a1
+func (_m *DeadLetterQueue) MoveToDLQ(ctx context.Context, _a1 webhook.Webhook) error {
+	ret := _m.Called(ctx, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveToDLQ")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, webhook.Webhook) error); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RemoveFromDLQ provides a mock function with given fields: ctx, routeID, id
+func (_m *DeadLetterQueue) RemoveFromDLQ(ctx context.Context, routeID string, id string) error {
+	ret := _m.Called(ctx, routeID, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFromDLQ")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, routeID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDeadLetterQueue creates a new instance of DeadLetterQueue. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeadLetterQueue(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeadLetterQueue {
+	mock := &DeadLetterQueue{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
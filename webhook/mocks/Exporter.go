@@ -0,0 +1,49 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// Exporter is an autogenerated mock type for the Exporter type
+type Exporter struct {
+	mock.Mock
+}
+
+// ExportEach provides a mock function with given fields: ctx, routeID, from, to, fn
+func (_m *Exporter) ExportEach(ctx context.Context, routeID string, from time.Time, to time.Time, fn func(webhook.Webhook) error) error {
+	ret := _m.Called(ctx, routeID, from, to, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportEach")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time, func(webhook.Webhook) error) error); ok {
+		r0 = rf(ctx, routeID, from, to, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewExporter creates a new instance of Exporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewExporter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Exporter {
+	mock := &Exporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,47 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// GroupPositioner is an autogenerated mock type for the GroupPositioner type
+type GroupPositioner struct {
+	mock.Mock
+}
+
+// SetGroupPosition provides a mock function with given fields: ctx, routeID, deliveryMode, id
+func (_m *GroupPositioner) SetGroupPosition(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, id string) error {
+	ret := _m.Called(ctx, routeID, deliveryMode, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetGroupPosition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode, string) error); ok {
+		r0 = rf(ctx, routeID, deliveryMode, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewGroupPositioner creates a new instance of GroupPositioner. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewGroupPositioner(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *GroupPositioner {
+	mock := &GroupPositioner{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
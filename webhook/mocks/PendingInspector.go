@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PendingInspector is an autogenerated mock type for the PendingInspector type
+type PendingInspector struct {
+	mock.Mock
+}
+
+// PendingSummary provides a mock function with given fields: ctx, routeID, deliveryMode
+func (_m *PendingInspector) PendingSummary(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode) (webhook.PendingInfo, error) {
+	ret := _m.Called(ctx, routeID, deliveryMode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PendingSummary")
+	}
+
+	var r0 webhook.PendingInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode) (webhook.PendingInfo, error)); ok {
+		return rf(ctx, routeID, deliveryMode)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode) webhook.PendingInfo); ok {
+		r0 = rf(ctx, routeID, deliveryMode)
+	} else {
+		r0 = ret.Get(0).(webhook.PendingInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, webhook.DeliveryMode) error); ok {
+		r1 = rf(ctx, routeID, deliveryMode)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPendingInspector creates a new instance of PendingInspector. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPendingInspector(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PendingInspector {
+	mock := &PendingInspector{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// QueueInspector is an autogenerated mock type for the QueueInspector type
+type QueueInspector struct {
+	mock.Mock
+}
+
+// QueueDepth provides a mock function with given fields: ctx, routeID, deliveryMode
+func (_m *QueueInspector) QueueDepth(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode) (int64, error) {
+	ret := _m.Called(ctx, routeID, deliveryMode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueueDepth")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode) (int64, error)); ok {
+		return rf(ctx, routeID, deliveryMode)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode) int64); ok {
+		r0 = rf(ctx, routeID, deliveryMode)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, webhook.DeliveryMode) error); ok {
+		r1 = rf(ctx, routeID, deliveryMode)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewQueueInspector creates a new instance of QueueInspector. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewQueueInspector(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *QueueInspector {
+	mock := &QueueInspector{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Searcher is an autogenerated mock type for the Searcher type
+type Searcher struct {
+	mock.Mock
+}
+
+// Search provides a mock function with given fields: ctx, routeID, opts
+func (_m *Searcher) Search(ctx context.Context, routeID string, opts webhook.SearchOptions) ([]webhook.Webhook, error) {
+	ret := _m.Called(ctx, routeID, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 []webhook.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.SearchOptions) ([]webhook.Webhook, error)); ok {
+		return rf(ctx, routeID, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.SearchOptions) []webhook.Webhook); ok {
+		r0 = rf(ctx, routeID, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, webhook.SearchOptions) error); ok {
+		r1 = rf(ctx, routeID, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSearcher creates a new instance of Searcher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSearcher(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Searcher {
+	mock := &Searcher{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
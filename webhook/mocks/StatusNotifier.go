@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// StatusNotifier is an autogenerated mock type for the StatusNotifier type
+type StatusNotifier struct {
+	mock.Mock
+}
+
+// SubscribeStatus provides a mock function with given fields: ctx, routeID
+func (_m *StatusNotifier) SubscribeStatus(ctx context.Context, routeID string) (<-chan webhook.StatusEvent, error) {
+	ret := _m.Called(ctx, routeID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubscribeStatus")
+	}
+
+	var r0 <-chan webhook.StatusEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (<-chan webhook.StatusEvent, error)); ok {
+		return rf(ctx, routeID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan webhook.StatusEvent); ok {
+		r0 = rf(ctx, routeID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan webhook.StatusEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, routeID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewStatusNotifier creates a new instance of StatusNotifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStatusNotifier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *StatusNotifier {
+	mock := &StatusNotifier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
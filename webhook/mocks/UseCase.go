@@ -4,9 +4,11 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
-	webhook "github.com/marcelsud/webhook-inbox/webhook"
 	mock "github.com/stretchr/testify/mock"
+
+	webhook "github.com/marcelsud/webhook-inbox/webhook"
 )
 
 // UseCase is an autogenerated mock type for the UseCase type
@@ -14,9 +16,9 @@ type UseCase struct {
 	mock.Mock
 }
 
-// Get provides a mock function with given fields: ctx, id
-func (_m *UseCase) Get(ctx context.Context, id string) (webhook.Webhook, error) {
-	ret := _m.Called(ctx, id)
+// Get provides a mock function with given fields: ctx, routeID, eventID
+func (_m *UseCase) Get(ctx context.Context, routeID string, eventID string) (webhook.Webhook, error) {
+	ret := _m.Called(ctx, routeID, eventID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
@@ -24,17 +26,17 @@ func (_m *UseCase) Get(ctx context.Context, id string) (webhook.Webhook, error)
 
 	var r0 webhook.Webhook
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (webhook.Webhook, error)); ok {
-		return rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (webhook.Webhook, error)); ok {
+		return rf(ctx, routeID, eventID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) webhook.Webhook); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) webhook.Webhook); ok {
+		r0 = rf(ctx, routeID, eventID)
 	} else {
 		r0 = ret.Get(0).(webhook.Webhook)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, id)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, routeID, eventID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -88,6 +90,62 @@ func (_m *UseCase) Receive(ctx context.Context, routeID string, deliveryMode web
 	return r0, r1
 }
 
+// ReceiveAt provides a mock function with given fields: ctx, routeID, deliveryMode, payload, headers, maxRetries, deliverAt
+func (_m *UseCase) ReceiveAt(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, payload []byte, headers map[string]string, maxRetries int, deliverAt time.Time) (string, error) {
+	ret := _m.Called(ctx, routeID, deliveryMode, payload, headers, maxRetries, deliverAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReceiveAt")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode, []byte, map[string]string, int, time.Time) (string, error)); ok {
+		return rf(ctx, routeID, deliveryMode, payload, headers, maxRetries, deliverAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, webhook.DeliveryMode, []byte, map[string]string, int, time.Time) string); ok {
+		r0 = rf(ctx, routeID, deliveryMode, payload, headers, maxRetries, deliverAt)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, webhook.DeliveryMode, []byte, map[string]string, int, time.Time) error); ok {
+		r1 = rf(ctx, routeID, deliveryMode, payload, headers, maxRetries, deliverAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Replay provides a mock function with given fields: ctx, routeID, eventID
+func (_m *UseCase) Replay(ctx context.Context, routeID string, eventID string) (string, error) {
+	ret := _m.Called(ctx, routeID, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Replay")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, routeID, eventID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, routeID, eventID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, routeID, eventID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateStatus provides a mock function with given fields: ctx, id, status
 func (_m *UseCase) UpdateStatus(ctx context.Context, id string, status webhook.Status) error {
 	ret := _m.Called(ctx, id, status)
@@ -0,0 +1,107 @@
+package payload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is returned by JSONPath.Lookup when the document has no value at the path
+var ErrPathNotFound = errors.New("no value at JSON path")
+
+/* JSONPath is a minimal JSONPath expression selecting a single value
+ * Only the root ($), child keys (.key) and array indexes ([0]) are supported,
+ * e.g. "$.event.type" or "$.events[0].kind"
+ */
+type JSONPath struct {
+	expr  string
+	steps []pathStep
+}
+
+// pathStep is an object key or, when index >= 0, an array index
+type pathStep struct {
+	key   string
+	index int
+}
+
+// ParseJSONPath compiles a JSONPath expression
+func ParseJSONPath(expr string) (JSONPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return JSONPath{}, fmt.Errorf("JSON path must start with $: %q", expr)
+	}
+
+	path := JSONPath{expr: expr}
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			key := rest[1:end]
+			if key == "" {
+				return JSONPath{}, fmt.Errorf("empty key in JSON path %q", expr)
+			}
+			path.steps = append(path.steps, pathStep{key: key, index: -1})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return JSONPath{}, fmt.Errorf("unterminated index in JSON path %q", expr)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return JSONPath{}, fmt.Errorf("invalid index %q in JSON path %q", rest[1:end], expr)
+			}
+			path.steps = append(path.steps, pathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return JSONPath{}, fmt.Errorf("unexpected %q in JSON path %q", rest[0], expr)
+		}
+	}
+
+	if len(path.steps) == 0 {
+		return JSONPath{}, fmt.Errorf("JSON path %q selects the whole document", expr)
+	}
+	return path, nil
+}
+
+// String returns the expression the path was parsed from
+func (p JSONPath) String() string {
+	return p.expr
+}
+
+// Lookup returns the string at the path in a JSON document
+// Values that exist but aren't strings are an error, not ErrPathNotFound
+func (p JSONPath) Lookup(data []byte) (string, error) {
+	current := json.RawMessage(data)
+	for _, step := range p.steps {
+		if step.index >= 0 {
+			var array []json.RawMessage
+			if json.Unmarshal(current, &array) != nil || step.index >= len(array) {
+				return "", fmt.Errorf("%w: %s", ErrPathNotFound, p.expr)
+			}
+			current = array[step.index]
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if json.Unmarshal(current, &object) != nil {
+			return "", fmt.Errorf("%w: %s", ErrPathNotFound, p.expr)
+		}
+		value, ok := object[step.key]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrPathNotFound, p.expr)
+		}
+		current = value
+	}
+
+	var value string
+	if err := json.Unmarshal(current, &value); err != nil {
+		return "", fmt.Errorf("value at JSON path %s is not a string", p.expr)
+	}
+	return value, nil
+}
@@ -0,0 +1,60 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	t.Run("success - valid expressions", func(t *testing.T) {
+		for _, expr := range []string{"$.type", "$.event.type", "$.events[0].kind", "$[1]", "$.a[0][2].b"} {
+			path, err := ParseJSONPath(expr)
+			require.NoError(t, err, expr)
+			assert.Equal(t, expr, path.String())
+		}
+	})
+
+	t.Run("error - invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{"", "type", "$", "$.", "$..type", "$.events[", "$.events[-1]", "$.events[x]", "$type"} {
+			_, err := ParseJSONPath(expr)
+			assert.Error(t, err, expr)
+		}
+	})
+}
+
+func TestJSONPath_Lookup(t *testing.T) {
+	body := []byte(`{"event":{"type":"user.created","count":2},"events":[{"kind":"order.shipped"}]}`)
+
+	lookup := func(expr string) (string, error) {
+		path, err := ParseJSONPath(expr)
+		require.NoError(t, err)
+		return path.Lookup(body)
+	}
+
+	t.Run("success - nested key", func(t *testing.T) {
+		value, err := lookup("$.event.type")
+		require.NoError(t, err)
+		assert.Equal(t, "user.created", value)
+	})
+
+	t.Run("success - array index", func(t *testing.T) {
+		value, err := lookup("$.events[0].kind")
+		require.NoError(t, err)
+		assert.Equal(t, "order.shipped", value)
+	})
+
+	t.Run("error - missing values", func(t *testing.T) {
+		for _, expr := range []string{"$.type", "$.event.name", "$.events[1].kind", "$.event[0]", "$.events.kind"} {
+			_, err := lookup(expr)
+			assert.ErrorIs(t, err, ErrPathNotFound, expr)
+		}
+	})
+
+	t.Run("error - value is not a string", func(t *testing.T) {
+		_, err := lookup("$.event.count")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrPathNotFound)
+	})
+}
@@ -1,15 +1,46 @@
 package payload
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
 )
 
 // eventTypePattern validates event types: hierarchical, full-stop delimited, [a-zA-Z0-9_.]
 var eventTypePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+(\.[a-zA-Z0-9_]+)*$`)
 
+// eventTypeSegmentPattern validates a single full-stop delimited segment of an event type
+var eventTypeSegmentPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// minTimestamp is the earliest event timestamp accepted by NewWithTimestamp
+var minTimestamp = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxTimestampSkew is how far in the future an event timestamp may be, to tolerate clock drift
+const maxTimestampSkew = 5 * time.Minute
+
+// utf8BOM is the byte order mark some clients prepend to UTF-8 bodies
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DefaultMaxBytes is the default limit on request bodies accepted at ingestion (1 MiB)
+const DefaultMaxBytes = 1 << 20
+
+// Errors returned by Parse and ParseWithLimit before the payload is decoded
+var (
+	ErrInvalidEncoding = errors.New("payload is not valid UTF-8 JSON")
+	ErrTooLarge        = errors.New("payload is too large")
+)
+
+// ErrTooDeep is returned by CheckDepth when JSON nests objects and arrays too deeply
+var ErrTooDeep = errors.New("payload is too deeply nested")
+
 // StandardPayload represents a Standard Webhooks compliant payload
 type StandardPayload struct {
 	// Type is a full-stop delimited type associated with the event
@@ -91,6 +122,27 @@ func (p *StandardPayload) UnmarshalJSON(data []byte) error {
 
 // New creates a new StandardPayload with the given type and data
 func New(eventType string, data interface{}) (StandardPayload, error) {
+	return NewWithClock(eventType, clock.System, data)
+}
+
+// NewWithClock creates a new StandardPayload timestamped with the clock's current time
+func NewWithClock(eventType string, c clock.Clock, data interface{}) (StandardPayload, error) {
+	now := clock.OrSystem(c).Now()
+	return newPayload(eventType, now, now, data)
+}
+
+// NewWithTimestamp creates a new StandardPayload with the given type, event time and data
+// Use it to ingest historical events while preserving their original timestamp
+func NewWithTimestamp(eventType string, ts time.Time, data interface{}) (StandardPayload, error) {
+	return newPayload(eventType, ts, time.Now(), data)
+}
+
+// newPayload creates a payload with timestamp ts, validated against the current time now
+func newPayload(eventType string, ts, now time.Time, data interface{}) (StandardPayload, error) {
+	if err := validateTimestamp(ts, now); err != nil {
+		return StandardPayload{}, fmt.Errorf("validating timestamp: %w", err)
+	}
+
 	// Marshal data to JSON
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
@@ -99,7 +151,7 @@ func New(eventType string, data interface{}) (StandardPayload, error) {
 
 	payload := StandardPayload{
 		Type:      eventType,
-		Timestamp: time.Now().UTC(),
+		Timestamp: ts.UTC(),
 		Data:      dataBytes,
 	}
 
@@ -110,8 +162,31 @@ func New(eventType string, data interface{}) (StandardPayload, error) {
 	return payload, nil
 }
 
+// validateTimestamp checks that an event timestamp is set and within a sane range of now
+func validateTimestamp(ts, now time.Time) error {
+	if ts.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+
+	if ts.Before(minTimestamp) {
+		return fmt.Errorf("timestamp %s is before %s", ts.Format(time.RFC3339), minTimestamp.Format(time.RFC3339))
+	}
+
+	if ts.After(now.Add(maxTimestampSkew)) {
+		return fmt.Errorf("timestamp %s is too far in the future", ts.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
 // Parse parses a JSON payload into a StandardPayload
+// A leading UTF-8 byte order mark is ignored; other encodings are rejected
 func Parse(data []byte) (StandardPayload, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) || !json.Valid(data) {
+		return StandardPayload{}, ErrInvalidEncoding
+	}
+
 	var payload StandardPayload
 	if err := json.Unmarshal(data, &payload); err != nil {
 		return StandardPayload{}, fmt.Errorf("unmarshaling payload: %w", err)
@@ -124,6 +199,49 @@ func Parse(data []byte) (StandardPayload, error) {
 	return payload, nil
 }
 
+// ParseWithLimit parses a payload of at most max bytes (0 = unlimited)
+// Use it on untrusted input so huge or deeply nested documents are rejected before decoding
+func ParseWithLimit(data []byte, max int) (StandardPayload, error) {
+	if max > 0 && len(data) > max {
+		return StandardPayload{}, fmt.Errorf("%w: %d bytes, limit is %d", ErrTooLarge, len(data), max)
+	}
+	return Parse(data)
+}
+
+// CheckDepth returns ErrTooDeep when objects and arrays in a JSON document nest deeper than max (0 = unlimited)
+// Scalars have depth 0 and {"a":[1]} has depth 2. The document is scanned token by token and
+// rejected as soon as the limit is crossed, without decoding it into memory
+func CheckDepth(data []byte, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && depth > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("scanning JSON: %w", err)
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				return fmt.Errorf("%w: more than %d levels", ErrTooDeep, max)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
 // Bytes returns the JSON-encoded payload as bytes
 // The returned bytes are minified (no extra whitespace)
 func (p StandardPayload) Bytes() ([]byte, error) {
@@ -131,44 +249,89 @@ func (p StandardPayload) Bytes() ([]byte, error) {
 }
 
 // MatchesEventType checks if the payload's type matches any of the given event types
-// Supports exact matching and prefix matching (e.g., "user.*" matches "user.created")
+// Supports exact matching and segment wildcards:
+//   - "*" matches exactly one segment (e.g., "user.*.created" matches "user.admin.created")
+//   - a trailing "*" matches one or more segments (e.g., "user.*" matches "user.created")
+//   - a trailing "**" matches zero or more segments (e.g., "user.**" matches "user")
 func (p StandardPayload) MatchesEventType(eventTypes []string) bool {
+	_, ok := p.MatchingFilter(eventTypes)
+	return ok
+}
+
+// MatchingFilter returns the first of the given event types the payload's type matches
+// An empty filter list accepts every type and reports "" as the matching filter
+func (p StandardPayload) MatchingFilter(eventTypes []string) (string, bool) {
 	if len(eventTypes) == 0 {
 		// No filter means accept all
-		return true
+		return "", true
 	}
 
 	for _, eventType := range eventTypes {
-		// Exact match
-		if p.Type == eventType {
-			return true
+		if matchEventType(eventType, p.Type) {
+			return eventType, true
 		}
+	}
 
-		// Prefix match (e.g., "user.*" matches "user.created", "user.updated")
-		if len(eventType) > 2 && eventType[len(eventType)-2:] == ".*" {
-			prefix := eventType[:len(eventType)-2]
-			if len(p.Type) > len(prefix) && p.Type[:len(prefix)] == prefix && p.Type[len(prefix)] == '.' {
-				return true
-			}
+	return "", false
+}
+
+// matchEventType matches an event type against a single, possibly wildcarded, pattern
+func matchEventType(pattern, eventType string) bool {
+	if pattern == eventType {
+		return true
+	}
+
+	patternSegments := strings.Split(pattern, ".")
+	typeSegments := strings.Split(eventType, ".")
+
+	for i, segment := range patternSegments {
+		last := i == len(patternSegments)-1
+
+		switch {
+		case segment == "**" && last:
+			return true
+		case segment == "*" && last:
+			// Trailing wildcard keeps prefix semantics: one or more segments
+			return len(typeSegments) > i
+		case i >= len(typeSegments):
+			return false
+		case segment != "*" && segment != typeSegments[i]:
+			return false
 		}
 	}
 
-	return false
+	return len(patternSegments) == len(typeSegments)
+}
+
+// ValidateEventTypes validates every event type of a filter list, returning the first error
+func ValidateEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if err := ValidateEventType(eventType); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ValidateEventType validates an event type format
+// Event type filters may use "*" in place of any segment and "**" as the last segment
 func ValidateEventType(eventType string) error {
 	if eventType == "" {
 		return fmt.Errorf("event type cannot be empty")
 	}
 
-	// Allow wildcard suffix for filtering
-	if len(eventType) > 2 && eventType[len(eventType)-2:] == ".*" {
-		eventType = eventType[:len(eventType)-2]
-	}
-
-	if !eventTypePattern.MatchString(eventType) {
-		return fmt.Errorf("event type must be hierarchical and contain only [a-zA-Z0-9_.]: %s", eventType)
+	segments := strings.Split(eventType, ".")
+	for i, segment := range segments {
+		switch {
+		case segment == "*":
+			// Single-segment wildcard is allowed anywhere
+		case segment == "**":
+			if i != len(segments)-1 {
+				return fmt.Errorf("event type wildcard \"**\" is only allowed as the last segment: %s", eventType)
+			}
+		case !eventTypeSegmentPattern.MatchString(segment):
+			return fmt.Errorf("event type must be hierarchical and contain only [a-zA-Z0-9_.]: %s", eventType)
+		}
 	}
 
 	return nil
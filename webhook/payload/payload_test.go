@@ -2,9 +2,11 @@ package payload
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,6 +50,51 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNewWithClock(t *testing.T) {
+	now := time.Date(2024, 3, 10, 8, 0, 0, 0, time.FixedZone("BRT", -3*60*60))
+	clk := clock.NewFake(now)
+
+	payload, err := NewWithClock("user.created", clk, map[string]string{"id": "123"})
+	require.NoError(t, err)
+	assert.True(t, now.Equal(payload.Timestamp))
+	assert.Equal(t, time.UTC, payload.Timestamp.Location())
+
+	clk.Advance(time.Minute)
+	next, err := NewWithClock("user.created", clk, map[string]string{"id": "123"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, next.Timestamp.Sub(payload.Timestamp))
+}
+
+func TestNewWithTimestamp(t *testing.T) {
+	t.Run("success - preserves past timestamp", func(t *testing.T) {
+		ts := time.Date(2023, 6, 15, 10, 30, 0, 0, time.FixedZone("BRT", -3*60*60))
+
+		payload, err := NewWithTimestamp("user.created", ts, map[string]string{"id": "123"})
+		require.NoError(t, err)
+		assert.Equal(t, "user.created", payload.Type)
+		assert.True(t, ts.Equal(payload.Timestamp))
+		assert.Equal(t, time.UTC, payload.Timestamp.Location())
+	})
+
+	t.Run("error - zero timestamp", func(t *testing.T) {
+		_, err := NewWithTimestamp("user.created", time.Time{}, map[string]string{"id": "123"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timestamp is required")
+	})
+
+	t.Run("error - timestamp too old", func(t *testing.T) {
+		_, err := NewWithTimestamp("user.created", time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), map[string]string{"id": "123"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "validating timestamp")
+	})
+
+	t.Run("error - timestamp in the future", func(t *testing.T) {
+		_, err := NewWithTimestamp("user.created", time.Now().Add(time.Hour), map[string]string{"id": "123"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "too far in the future")
+	})
+}
+
 func TestParse(t *testing.T) {
 	t.Run("success - valid payload", func(t *testing.T) {
 		data := []byte(`{
@@ -75,10 +122,33 @@ func TestParse(t *testing.T) {
 		assert.NotZero(t, payload.Timestamp.Nanosecond())
 	})
 
+	t.Run("success - UTF-8 byte order mark", func(t *testing.T) {
+		data := append([]byte("\xEF\xBB\xBF"), `{"type": "user.created", "timestamp": "2024-01-01T12:00:00Z", "data": {"name": "Zoë"}}`...)
+
+		payload, err := Parse(data)
+		require.NoError(t, err)
+		assert.Equal(t, "user.created", payload.Type)
+		assert.JSONEq(t, `{"name": "Zoë"}`, string(payload.Data))
+	})
+
 	t.Run("error - invalid JSON", func(t *testing.T) {
 		data := []byte(`{invalid json}`)
 		_, err := Parse(data)
-		require.Error(t, err)
+		require.ErrorIs(t, err, ErrInvalidEncoding)
+		assert.EqualError(t, err, "payload is not valid UTF-8 JSON")
+	})
+
+	t.Run("error - not UTF-8", func(t *testing.T) {
+		// "Zoë" in ISO-8859-1
+		data := []byte("{\"type\": \"user.created\", \"timestamp\": \"2024-01-01T12:00:00Z\", \"data\": {\"name\": \"Zo\xEB\"}}")
+		_, err := Parse(data)
+		require.ErrorIs(t, err, ErrInvalidEncoding)
+	})
+
+	t.Run("error - UTF-16 byte order mark", func(t *testing.T) {
+		data := append([]byte("\xFF\xFE"), `{"type": "user.created"}`...)
+		_, err := Parse(data)
+		require.ErrorIs(t, err, ErrInvalidEncoding)
 	})
 
 	t.Run("error - missing type", func(t *testing.T) {
@@ -127,6 +197,34 @@ func TestParse(t *testing.T) {
 	})
 }
 
+func TestParseWithLimit(t *testing.T) {
+	data := []byte(`{"type": "user.created", "timestamp": "2024-01-01T12:00:00Z", "data": {}}`)
+
+	t.Run("success - within limit", func(t *testing.T) {
+		payload, err := ParseWithLimit(data, len(data))
+		require.NoError(t, err)
+		assert.Equal(t, "user.created", payload.Type)
+	})
+
+	t.Run("success - no limit", func(t *testing.T) {
+		_, err := ParseWithLimit(data, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("error - oversized input", func(t *testing.T) {
+		_, err := ParseWithLimit(data, len(data)-1)
+		require.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("error - deeply nested input", func(t *testing.T) {
+		nested := `{"type": "user.created", "timestamp": "2024-01-01T12:00:00Z", "data": ` +
+			strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + `}`
+
+		_, err := ParseWithLimit([]byte(nested), 64*1024)
+		require.ErrorIs(t, err, ErrTooLarge)
+	})
+}
+
 func TestValidate(t *testing.T) {
 	t.Run("success - valid payload", func(t *testing.T) {
 		payload := StandardPayload{
@@ -258,6 +356,70 @@ func TestMatchesEventType(t *testing.T) {
 	})
 }
 
+func TestMatchingFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventType   string
+		filters     []string
+		wantFilter  string
+		wantMatched bool
+	}{
+		{"exact match", "order.created", []string{"user.*", "order.created"}, "order.created", true},
+		{"wildcard match", "user.admin.created", []string{"order.created", "user.*.created"}, "user.*.created", true},
+		{"first matching filter wins", "user.created", []string{"user.**", "user.created"}, "user.**", true},
+		{"no match", "invoice.paid", []string{"user.*", "order.created"}, "", false},
+		{"empty filter accepts all", "invoice.paid", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := StandardPayload{Type: tt.eventType}
+
+			filter, matched := p.MatchingFilter(tt.filters)
+
+			assert.Equal(t, tt.wantFilter, filter)
+			assert.Equal(t, tt.wantMatched, matched)
+		})
+	}
+}
+
+func TestMatchesEventType_Wildcards(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		eventType string
+		want      bool
+	}{
+		// Single-segment wildcard
+		{"single wildcard matches middle segment", "user.*.created", "user.admin.created", true},
+		{"single wildcard matches first segment", "*.created", "user.created", true},
+		{"single wildcard requires a segment", "user.*.created", "user.created", false},
+		{"single wildcard matches only one segment", "user.*.created", "user.admin.team.created", false},
+		{"single wildcard with mismatched suffix", "user.*.created", "user.admin.deleted", false},
+
+		// Trailing wildcard keeps prefix behavior
+		{"trailing wildcard matches one segment", "user.*", "user.created", true},
+		{"trailing wildcard matches nested segments", "user.*", "user.admin.created", true},
+		{"trailing wildcard requires a segment", "user.*", "user", false},
+
+		// Multi-segment wildcard
+		{"double wildcard matches zero segments", "user.**", "user", true},
+		{"double wildcard matches one segment", "user.**", "user.created", true},
+		{"double wildcard matches many segments", "user.**", "user.admin.team.created", true},
+		{"double wildcard after single wildcard", "*.admin.**", "user.admin.team.created", true},
+		{"double wildcard with mismatched prefix", "user.**", "order.created", false},
+		{"double wildcard with partial prefix", "us.**", "user.created", false},
+		{"bare double wildcard matches everything", "**", "order.item.shipped", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := StandardPayload{Type: tt.eventType}
+			assert.Equal(t, tt.want, p.MatchesEventType([]string{tt.pattern}))
+		})
+	}
+}
+
 func TestValidateEventType(t *testing.T) {
 	t.Run("success - simple type", func(t *testing.T) {
 		err := ValidateEventType("user")
@@ -284,6 +446,22 @@ func TestValidateEventType(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("success - wildcard patterns", func(t *testing.T) {
+		for _, eventType := range []string{"user.*.created", "*.created", "user.**", "*.admin.**", "**"} {
+			assert.NoError(t, ValidateEventType(eventType), eventType)
+		}
+	})
+
+	t.Run("error - double wildcard not last", func(t *testing.T) {
+		err := ValidateEventType("user.**.created")
+		require.Error(t, err)
+	})
+
+	t.Run("error - partial segment wildcard", func(t *testing.T) {
+		err := ValidateEventType("user.crea*")
+		require.Error(t, err)
+	})
+
 	t.Run("error - empty type", func(t *testing.T) {
 		err := ValidateEventType("")
 		require.Error(t, err)
@@ -315,6 +493,19 @@ func TestValidateEventType(t *testing.T) {
 	})
 }
 
+func TestValidateEventTypes(t *testing.T) {
+	t.Run("success - every type valid", func(t *testing.T) {
+		require.NoError(t, ValidateEventTypes([]string{"user.created", "order.*", "**"}))
+		require.NoError(t, ValidateEventTypes(nil))
+	})
+
+	t.Run("error - first invalid type", func(t *testing.T) {
+		err := ValidateEventTypes([]string{"user.created", "user-deleted", "user@updated"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "user-deleted")
+	})
+}
+
 func TestMarshalUnmarshal(t *testing.T) {
 	t.Run("round-trip - preserves data", func(t *testing.T) {
 		original := StandardPayload{
@@ -355,3 +546,46 @@ func TestMarshalUnmarshal(t *testing.T) {
 		assert.Contains(t, raw["timestamp"], "2024-01-01T12:00:00")
 	})
 }
+
+func TestCheckDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		max   int
+		valid bool
+	}{
+		{"scalar", `42`, 1, true},
+		{"empty object", `{}`, 1, true},
+		{"flat object", `{"id":1,"name":"a"}`, 1, true},
+		{"object in array", `[{"id":1}]`, 1, false},
+		{"at the limit", `{"a":{"b":[1,2]}}`, 3, true},
+		{"over the limit", `{"a":{"b":[[1]]}}`, 3, false},
+		{"siblings don't add up", `{"a":{"b":1},"c":{"d":1},"e":[1]}`, 2, true},
+		{"brackets inside strings are ignored", `{"a":"[[[[{{{{"}`, 1, true},
+		{"unlimited", strings.Repeat(`[`, 1000) + strings.Repeat(`]`, 1000), 0, true},
+		{"deeply nested", strings.Repeat(`{"a":`, 1000) + `1` + strings.Repeat(`}`, 1000), 32, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDepth([]byte(tt.data), tt.max)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrTooDeep)
+			}
+		})
+	}
+
+	t.Run("stops at the first level over the limit", func(t *testing.T) {
+		// The unterminated document is never read to the end
+		err := CheckDepth([]byte(`[[[[`+strings.Repeat(`1,`, 1000)), 3)
+		assert.ErrorIs(t, err, ErrTooDeep)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		err := CheckDepth([]byte(`{"a":`), 3)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTooDeep)
+	})
+}
@@ -0,0 +1,11 @@
+package webhook
+
+/* PendingInfo summarizes a route's webhooks read by workers but not yet acknowledged
+ * Entries stay pending while they are being delivered, or forever when their worker crashed
+ */
+type PendingInfo struct {
+	Count     int64            `json:"count"`
+	LowestID  string           `json:"lowest_id,omitempty"`  // Oldest pending stream entry ID
+	HighestID string           `json:"highest_id,omitempty"` // Newest pending stream entry ID
+	Consumers map[string]int64 `json:"consumers"`            // Pending entries per consumer name
+}
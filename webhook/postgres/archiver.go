@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* Postgres implementation of webhook.Archiver
+ * Keeps a durable copy of delivered webhooks, e.g. of a Redis repository where they expire with their TTL
+ * Archiving is an upsert keyed by webhook ID, so a webhook archived twice (the worker failing between
+ * archiving and acknowledging it) keeps one row holding its latest state
+ *
+ * Like the Repository it uses database/sql and no particular driver; call Migrate once
+ */
+type Archiver struct {
+	db *sql.DB
+}
+
+// NewArchiver creates a Postgres archiver on db, checking the connection with the caller's context
+// The ping is additionally bounded by DefaultPingTimeout
+func NewArchiver(ctx context.Context, db *sql.DB) (*Archiver, error) {
+	pingCtx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(pingCtx); err != nil {
+		return nil, fmt.Errorf("connecting to Postgres: %w", err)
+	}
+
+	return &Archiver{db: db}, nil
+}
+
+// Archive stores a delivered webhook, replacing the state of a previous copy
+func (a *Archiver) Archive(ctx context.Context, wh webhook.Webhook) error {
+	headersJSON, err := json.Marshal(wh.Headers)
+	if err != nil {
+		return fmt.Errorf("marshaling headers: %w", err)
+	}
+
+	var deliverAt sql.NullTime
+	if !wh.DeliverAt.IsZero() {
+		deliverAt = sql.NullTime{Time: wh.DeliverAt, Valid: true}
+	}
+
+	_, err = a.db.ExecContext(ctx, `
+		INSERT INTO webhook_archive (id, route_id, payload, payload_size, headers, status, retry_count, max_retries,
+			delivery_mode, priority, traceparent, request_id, deliver_at, created_at, updated_at, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now())
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status, retry_count = EXCLUDED.retry_count,
+			updated_at = EXCLUDED.updated_at, archived_at = EXCLUDED.archived_at`,
+		wh.ID, wh.RouteID, wh.Payload, len(wh.Payload), string(headersJSON), wh.Status.String(), wh.RetryCount, wh.MaxRetries,
+		wh.DeliveryMode.String(), wh.Priority, wh.TraceParent, wh.RequestID, deliverAt, wh.CreatedAt, wh.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("archiving webhook: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves an archived webhook by ID
+// Returns webhook.ErrNotFound if the webhook was never archived
+func (a *Archiver) Get(ctx context.Context, id string) (webhook.Webhook, error) {
+	row := a.db.QueryRowContext(ctx, `
+		SELECT id, route_id, payload, payload_size, headers, status, retry_count, max_retries,
+			delivery_mode, priority, traceparent, request_id, deliver_at, created_at, updated_at
+		FROM webhook_archive WHERE id = $1`, id)
+
+	wh, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook.Webhook{}, fmt.Errorf("%w: %s", webhook.ErrNotFound, id)
+	}
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("getting archived webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// Close closes the database handle
+func (a *Archiver) Close(ctx context.Context) error {
+	return a.db.Close()
+}
@@ -0,0 +1,69 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiver_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	postgresContainer, cleanup := SetupPostgresContainer(t, ctx)
+	defer cleanup()
+
+	archiver, err := postgres.NewArchiver(ctx, OpenDB(t, postgresContainer.DSN))
+	require.NoError(t, err)
+	defer archiver.Close(ctx)
+	require.NoError(t, archiver.Migrate(ctx))
+	require.NoError(t, archiver.Migrate(ctx), "migrating again is a no-op")
+
+	t.Run("archive and retrieve webhook", func(t *testing.T) {
+		wh := NewWebhook("archive-route", webhook.FIFO)
+		wh.Status = webhook.Delivered
+		wh.RequestID = "req-123"
+		require.NoError(t, archiver.Archive(ctx, wh))
+
+		archived, err := archiver.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wh.RouteID, archived.RouteID)
+		assert.Equal(t, wh.Payload, archived.Payload)
+		assert.Equal(t, wh.Headers, archived.Headers)
+		assert.Equal(t, webhook.Delivered, archived.Status)
+		assert.Equal(t, "req-123", archived.RequestID)
+	})
+
+	t.Run("archiving twice keeps one row with the latest state", func(t *testing.T) {
+		wh := NewWebhook("archive-route", webhook.FIFO)
+		wh.Status = webhook.Delivering
+		require.NoError(t, archiver.Archive(ctx, wh))
+
+		wh.Status = webhook.Delivered
+		wh.RetryCount = 2
+		wh.UpdatedAt = time.Now()
+		require.NoError(t, archiver.Archive(ctx, wh))
+
+		archived, err := archiver.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.Delivered, archived.Status)
+		assert.Equal(t, 2, archived.RetryCount)
+
+		db := OpenDB(t, postgresContainer.DSN)
+		defer db.Close()
+		var rows int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT count(*) FROM webhook_archive WHERE id = $1`, wh.ID).Scan(&rows))
+		assert.Equal(t, 1, rows)
+	})
+
+	t.Run("webhooks never archived are not found", func(t *testing.T) {
+		_, err := archiver.Get(ctx, "never-archived")
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+}
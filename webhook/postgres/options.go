@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultPingTimeout bounds the connection check when WithPingTimeout is not given
+const DefaultPingTimeout = 5 * time.Second
+
+/* DefaultBlockTimeout is how long Consume polls for new webhooks before returning empty
+ * Matches the Redis repository, so workers notice cancellation equally fast on both backends
+ */
+const DefaultBlockTimeout = 1 * time.Second
+
+/* DefaultPollInterval is how often Consume queries the queue while blocking
+ * Postgres has no blocking read like XREADGROUP, so new webhooks are picked up
+ * up to one interval after they are stored
+ */
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Option configures optional Repository behavior
+type Option func(*Repository)
+
+// WithConsumer sets the name identifying this process on the queue entries it claims (default: "{hostname}-{pid}")
+func WithConsumer(consumer string) Option {
+	return func(r *Repository) {
+		if consumer != "" {
+			r.consumer = consumer
+		}
+	}
+}
+
+// WithPingTimeout sets how long NewRepository waits for Postgres to answer the initial ping
+func WithPingTimeout(timeout time.Duration) Option {
+	return func(r *Repository) {
+		if timeout > 0 {
+			r.pingTimeout = timeout
+		}
+	}
+}
+
+// WithBlockTimeout sets how long Consume waits for new webhooks before returning empty (default: 1s)
+func WithBlockTimeout(timeout time.Duration) Option {
+	return func(r *Repository) {
+		if timeout > 0 {
+			r.blockTimeout = timeout
+		}
+	}
+}
+
+// WithPollInterval sets how often Consume queries the queue while waiting (default: 100ms)
+func WithPollInterval(interval time.Duration) Option {
+	return func(r *Repository) {
+		if interval > 0 {
+			r.pollInterval = interval
+		}
+	}
+}
+
+// WithLogger sets the logger reporting queue entries Consume skips (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Repository) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
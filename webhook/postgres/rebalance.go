@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* Rebalance hands the queue entries claimed by a consumer that went away back to the route
+ * A claimed entry is never claimed again, so without it the webhooks a crashed consumer was
+ * delivering would stay pending forever; released entries keep their place in the queue
+ * and are consumed by any live consumer
+ *
+ * Entries whose webhook already failed (ack_policy keep_pending) stay claimed for manual
+ * intervention, so a webhook rejected by its target is never delivered again
+ */
+
+// notFailed excludes the queue entries of failed webhooks from a release
+const notFailed = `NOT EXISTS (SELECT 1 FROM webhooks w WHERE w.id = webhook_queue.webhook_id AND w.status = 'failed')`
+
+// Rebalance releases every entry of a route claimed by deadConsumer, returning how many were released
+// The consumer must really be gone: webhooks it is still delivering would be delivered again
+func (r *Repository) Rebalance(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, deadConsumer string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_queue SET consumer = NULL, claimed_at = NULL
+		WHERE route_id = $1 AND delivery_mode = $2 AND consumer = $3 AND `+notFailed,
+		routeID, deliveryMode.String(), deadConsumer)
+	if err != nil {
+		return 0, fmt.Errorf("releasing entries of consumer %s: %w", deadConsumer, err)
+	}
+	return released(res)
+}
+
+// RebalanceIdle releases every entry of a route claimed by another consumer at least minIdle ago
+// minIdle should comfortably exceed the longest delivery, so entries of live consumers are left alone
+func (r *Repository) RebalanceIdle(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, minIdle time.Duration) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_queue SET consumer = NULL, claimed_at = NULL
+		WHERE route_id = $1 AND delivery_mode = $2 AND consumer IS NOT NULL AND consumer <> $3
+			AND claimed_at <= now() - make_interval(secs => $4) AND `+notFailed,
+		routeID, deliveryMode.String(), r.consumer, minIdle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("releasing idle entries: %w", err)
+	}
+	return released(res)
+}
+
+// released returns how many entries a release updated
+func released(res sql.Result) (int, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("reading affected rows: %w", err)
+	}
+	return int(n), nil
+}
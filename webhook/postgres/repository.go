@@ -0,0 +1,426 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* Postgres implementation of webhook.Repository
+ * For teams that can't run Redis: webhooks are rows and the streams become a queue table
+ * consumed with SELECT ... FOR UPDATE SKIP LOCKED, so concurrent workers never claim the same entry
+ *
+ * The repository uses database/sql and no particular driver: open db with the driver of
+ * your choice (e.g. pgx's "pgx" or lib/pq's "postgres") and call Migrate once
+ */
+type Repository struct {
+	db *sql.DB
+	// consumer identifies this process on the queue entries it claims
+	consumer string
+	// pingTimeout bounds the connection check done by NewRepository
+	pingTimeout time.Duration
+	// blockTimeout is how long Consume polls waiting for new webhooks
+	blockTimeout time.Duration
+	// pollInterval is how often Consume queries the queue while waiting
+	pollInterval time.Duration
+	// logger reports queue entries Consume skips
+	logger *slog.Logger
+}
+
+// NewRepository creates a Postgres repository on db, checking the connection with the caller's context
+// The ping is additionally bounded by the ping timeout (see WithPingTimeout)
+func NewRepository(ctx context.Context, db *sql.DB, opts ...Option) (*Repository, error) {
+	r := &Repository{
+		db:           db,
+		consumer:     webhook.DefaultConsumerName(),
+		pingTimeout:  DefaultPingTimeout,
+		blockTimeout: DefaultBlockTimeout,
+		pollInterval: DefaultPollInterval,
+		logger:       slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, r.pingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(pingCtx); err != nil {
+		return nil, fmt.Errorf("connecting to Postgres: %w", err)
+	}
+
+	return r, nil
+}
+
+// Store inserts the webhook and queues it, in one transaction
+// Webhooks with a future DeliverAt are queued but only consumed once due
+func (r *Repository) Store(ctx context.Context, wh webhook.Webhook) (string, error) {
+	headersJSON, err := json.Marshal(wh.Headers)
+	if err != nil {
+		return "", fmt.Errorf("marshaling headers: %w", err)
+	}
+
+	var deliverAt sql.NullTime
+	if !wh.DeliverAt.IsZero() {
+		deliverAt = sql.NullTime{Time: wh.DeliverAt, Valid: true}
+	}
+	availableAt := time.Now()
+	if !wh.Due(availableAt) {
+		availableAt = wh.DeliverAt
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhooks (id, route_id, payload, payload_size, headers, status, retry_count, max_retries,
+			delivery_mode, priority, traceparent, request_id, deliver_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		wh.ID, wh.RouteID, wh.Payload, len(wh.Payload), string(headersJSON), wh.Status.String(), wh.RetryCount, wh.MaxRetries,
+		wh.DeliveryMode.String(), wh.Priority, wh.TraceParent, wh.RequestID, deliverAt, wh.CreatedAt, wh.UpdatedAt)
+	if err != nil {
+		return "", fmt.Errorf("storing webhook: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_queue (webhook_id, route_id, delivery_mode, priority, available_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		wh.ID, wh.RouteID, wh.DeliveryMode.String(), wh.Priority, availableAt)
+	if err != nil {
+		return "", fmt.Errorf("queueing webhook: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("committing webhook: %w", err)
+	}
+
+	return wh.ID, nil
+}
+
+// selectWebhook lists the webhooks columns in the order scanWebhook reads them
+const selectWebhook = `
+	SELECT id, route_id, payload, payload_size, headers, status, retry_count, max_retries,
+		delivery_mode, priority, traceparent, request_id, deliver_at, created_at, updated_at
+	FROM webhooks`
+
+// notExpired filters out webhooks whose TTL has elapsed
+const notExpired = `(expires_at IS NULL OR expires_at > now())`
+
+// Get retrieves a webhook by ID
+// Webhooks whose TTL has elapsed are reported as webhook.ErrNotFound
+func (r *Repository) Get(ctx context.Context, id string) (webhook.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, selectWebhook+` WHERE id = $1 AND `+notExpired, id)
+
+	wh, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook.Webhook{}, fmt.Errorf("%w: %s", webhook.ErrNotFound, id)
+	}
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("getting webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// GetByRouteID retrieves up to limit webhooks of a route, oldest first (limit <= 0: all of them)
+func (r *Repository) GetByRouteID(ctx context.Context, routeID string, limit int) ([]webhook.Webhook, error) {
+	query := selectWebhook + ` WHERE route_id = $1 AND ` + notExpired + ` ORDER BY created_at, id`
+	args := []any{routeID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []webhook.Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading webhook: %w", err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// UpdateStatus updates the status of a webhook
+func (r *Repository) UpdateStatus(ctx context.Context, id string, status webhook.Status) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE webhooks SET status = $2, updated_at = now() WHERE id = $1 AND `+notExpired,
+		id, status.String())
+	if err != nil {
+		return fmt.Errorf("updating status: %w", err)
+	}
+	return expectRow(res, id)
+}
+
+// IncrementRetry increments the retry count for a webhook
+// Returns webhook.ErrRetryLimit once the count reached max_retries + 1
+func (r *Repository) IncrementRetry(ctx context.Context, id string) error {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE webhooks SET retry_count = retry_count + 1, updated_at = now()
+		WHERE id = $1 AND `+notExpired+` AND retry_count < max_retries + 1
+		RETURNING retry_count`, id).Scan(&count)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Either the webhook is gone or the cap was hit
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: webhook %s", webhook.ErrRetryLimit, id)
+	}
+	if err != nil {
+		return fmt.Errorf("incrementing retry count: %w", err)
+	}
+
+	return nil
+}
+
+// Consume claims the route's next due webhook, polling up to the repository's block timeout
+// Returns as soon as a webhook is due, or an empty slice once the timeout elapses
+func (r *Repository) Consume(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode) ([]webhook.Webhook, error) {
+	return r.consume(ctx, routeID, deliveryMode, 1)
+}
+
+// ConsumeBatch claims up to count due webhooks in a single call
+// Polls up to the repository's block timeout and returns whatever is available
+func (r *Repository) ConsumeBatch(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, count int) ([]webhook.Webhook, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("batch size must be at least 1 (got %d)", count)
+	}
+	return r.consume(ctx, routeID, deliveryMode, count)
+}
+
+// consume polls for due queue entries until some are claimed or the block timeout elapses
+func (r *Repository) consume(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, count int) ([]webhook.Webhook, error) {
+	deadline := time.NewTimer(r.blockTimeout)
+	defer deadline.Stop()
+
+	for {
+		webhooks, err := r.claim(ctx, routeID, deliveryMode, count)
+		if err != nil || len(webhooks) > 0 {
+			return webhooks, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return []webhook.Webhook{}, nil
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// claim assigns up to count due, unclaimed queue entries to this consumer
+// Priority entries come first, then entries in the order they were stored; entries
+// locked by another consumer's claim are skipped rather than waited on
+func (r *Repository) claim(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, count int) ([]webhook.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_queue SET consumer = $1, claimed_at = now()
+		WHERE seq IN (
+			SELECT seq FROM webhook_queue
+			WHERE route_id = $2 AND delivery_mode = $3 AND consumer IS NULL AND available_at <= now()
+			ORDER BY priority DESC, seq
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING seq, webhook_id, priority`,
+		r.consumer, routeID, deliveryMode.String(), count)
+	if err != nil {
+		return nil, fmt.Errorf("claiming webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	type entry struct {
+		seq       int64
+		webhookID string
+		priority  bool
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.seq, &e.webhookID, &e.priority); err != nil {
+			return nil, fmt.Errorf("reading claimed entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claiming webhooks: %w", err)
+	}
+
+	// RETURNING has no defined order
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].priority != entries[j].priority {
+			return entries[i].priority
+		}
+		return entries[i].seq < entries[j].seq
+	})
+
+	webhooks := []webhook.Webhook{}
+	for _, e := range entries {
+		if wh, ok := r.claimed(ctx, e.seq, e.webhookID); ok {
+			webhooks = append(webhooks, wh)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// claimed returns the webhook a claimed queue entry points at
+// Entries that can never be delivered are logged and deleted: those whose webhook expired or
+// whose row can't be decoded (kept for inspection). On other errors the claim is released,
+// so the entry is consumed again
+func (r *Repository) claimed(ctx context.Context, seq int64, webhookID string) (webhook.Webhook, bool) {
+	wh, err := r.Get(ctx, webhookID)
+	switch {
+	case err == nil:
+		return wh, true
+	case errors.Is(err, webhook.ErrNotFound):
+		r.logger.Warn("deleting queue entry of a missing webhook", "seq", seq, "event_id", webhookID)
+		r.deleteEntry(ctx, seq)
+	case errors.Is(err, webhook.ErrCorrupted):
+		r.logger.Error("deleting queue entry of a corrupted webhook", "seq", seq, "event_id", webhookID, "error", err)
+		r.deleteEntry(ctx, seq)
+	default:
+		r.logger.Error("reading claimed webhook, releasing it", "seq", seq, "event_id", webhookID, "error", err)
+		r.releaseEntry(ctx, seq)
+	}
+	return webhook.Webhook{}, false
+}
+
+// deleteEntry deletes a queue entry Consume skips, logging failures
+func (r *Repository) deleteEntry(ctx context.Context, seq int64) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webhook_queue WHERE seq = $1`, seq); err != nil {
+		r.logger.Error("deleting skipped queue entry", "seq", seq, "error", err)
+	}
+}
+
+// releaseEntry hands a claimed queue entry back to the route, logging failures
+// Entries whose release fails stay claimed until RebalanceIdle releases them
+func (r *Repository) releaseEntry(ctx context.Context, seq int64) {
+	if _, err := r.db.ExecContext(ctx, `UPDATE webhook_queue SET consumer = NULL, claimed_at = NULL WHERE seq = $1`, seq); err != nil {
+		r.logger.Error("releasing claimed queue entry", "seq", seq, "error", err)
+	}
+}
+
+// Acknowledge removes a claimed webhook's queue entry
+// Acknowledging a webhook that isn't claimed is a no-op
+func (r *Repository) Acknowledge(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, eventID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM webhook_queue
+		WHERE webhook_id = $1 AND route_id = $2 AND delivery_mode = $3 AND consumer IS NOT NULL`,
+		eventID, routeID, deliveryMode.String())
+	if err != nil {
+		return fmt.Errorf("acknowledging webhook: %w", err)
+	}
+	return nil
+}
+
+// SetTTL makes a webhook expire after ttl
+// Expired rows are hidden from reads and removed by DeleteExpired
+func (r *Repository) SetTTL(ctx context.Context, id string, ttl time.Duration) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE webhooks SET expires_at = $2 WHERE id = $1 AND `+notExpired,
+		id, time.Now().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("setting TTL on webhook: %w", err)
+	}
+	return expectRow(res, id)
+}
+
+// DeleteExpired removes webhooks whose TTL has elapsed, with their queue entries
+// Postgres doesn't expire rows on its own, so run it periodically; returns the number of webhooks removed
+func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired webhooks: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired webhooks: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteMessageID is a no-op: queue entries carry their webhook ID, so there is no separate message ID to clean up
+func (r *Repository) DeleteMessageID(ctx context.Context, id string) error {
+	return nil
+}
+
+// Close closes the database handle
+func (r *Repository) Close(ctx context.Context) error {
+	return r.db.Close()
+}
+
+// ConsumerName returns the name this repository records on the queue entries it claims
+func (r *Repository) ConsumerName() string {
+	return r.consumer
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanWebhook reads a row selected with selectWebhook
+func scanWebhook(row scanner) (webhook.Webhook, error) {
+	var (
+		wh        webhook.Webhook
+		headers   []byte
+		status    string
+		mode      string
+		deliverAt sql.NullTime
+	)
+	err := row.Scan(&wh.ID, &wh.RouteID, &wh.Payload, &wh.PayloadSize, &headers, &status, &wh.RetryCount, &wh.MaxRetries,
+		&mode, &wh.Priority, &wh.TraceParent, &wh.RequestID, &deliverAt, &wh.CreatedAt, &wh.UpdatedAt)
+	if err != nil {
+		return webhook.Webhook{}, err
+	}
+
+	wh.Headers = make(map[string]string)
+	if err := json.Unmarshal(headers, &wh.Headers); err != nil {
+		return webhook.Webhook{}, fmt.Errorf("%w: unmarshaling headers of webhook %s: %w", webhook.ErrCorrupted, wh.ID, err)
+	}
+	// A status no Status maps to means the row was written by an incompatible version
+	if wh.Status, err = webhook.NewStatusStrict(status); err != nil {
+		return webhook.Webhook{}, fmt.Errorf("%w: parsing status of webhook %s: %w", webhook.ErrCorrupted, wh.ID, err)
+	}
+	wh.DeliveryMode = webhook.NewDeliveryMode(mode)
+	if deliverAt.Valid {
+		wh.DeliverAt = deliverAt.Time
+	}
+
+	return wh, nil
+}
+
+// expectRow reports webhook.ErrNotFound when an update matched no webhook
+func expectRow(res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("reading affected rows: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", webhook.ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/postgres"
+	"github.com/marcelsud/webhook-inbox/webhook/webhooktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Conformance_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	postgresContainer, cleanup := SetupPostgresContainer(t, ctx)
+	defer cleanup()
+
+	webhooktest.RunRepositorySuite(t, func() webhook.Repository {
+		return CreateTestRepository(t, postgresContainer.DSN)
+	})
+}
+
+func TestRepository_Consume_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	postgresContainer, cleanup := SetupPostgresContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, postgresContainer.DSN, postgres.WithBlockTimeout(200*time.Millisecond))
+	defer repo.Close(ctx)
+
+	store := func(t *testing.T, wh webhook.Webhook) webhook.Webhook {
+		t.Helper()
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		return wh
+	}
+
+	t.Run("priority webhooks are consumed first", func(t *testing.T) {
+		routeID := "priority-route"
+		normal := store(t, NewWebhook(routeID, webhook.FIFO))
+		urgent := NewWebhook(routeID, webhook.FIFO)
+		urgent.Priority = true
+		store(t, urgent)
+
+		webhooks, err := repo.ConsumeBatch(ctx, routeID, webhook.FIFO, 2)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+		assert.Equal(t, urgent.ID, webhooks[0].ID)
+		assert.Equal(t, normal.ID, webhooks[1].ID)
+	})
+
+	t.Run("scheduled webhooks wait until due", func(t *testing.T) {
+		routeID := "scheduled-route"
+		wh := NewWebhook(routeID, webhook.FIFO)
+		wh.DeliverAt = time.Now().Add(time.Second)
+		store(t, wh)
+
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+
+		time.Sleep(time.Until(wh.DeliverAt))
+		webhooks, err = repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+	})
+
+	t.Run("concurrent consumers never claim the same webhook", func(t *testing.T) {
+		routeID := "concurrent-route"
+		for i := 0; i < 10; i++ {
+			store(t, NewWebhook(routeID, webhook.PubSub))
+		}
+		other := CreateTestRepository(t, postgresContainer.DSN, postgres.WithConsumer("other-consumer"), postgres.WithBlockTimeout(200*time.Millisecond))
+		defer other.Close(ctx)
+
+		results := make(chan []webhook.Webhook, 2)
+		for _, consumer := range []*postgres.Repository{repo, other} {
+			go func() {
+				webhooks, err := consumer.ConsumeBatch(ctx, routeID, webhook.PubSub, 10)
+				assert.NoError(t, err)
+				results <- webhooks
+			}()
+		}
+
+		seen := make(map[string]bool)
+		for i := 0; i < 2; i++ {
+			for _, wh := range <-results {
+				assert.False(t, seen[wh.ID], "claimed twice: %s", wh.ID)
+				seen[wh.ID] = true
+			}
+		}
+		assert.Len(t, seen, 10)
+	})
+
+	t.Run("consume deletes entries it can't deliver", func(t *testing.T) {
+		routeID := "undeliverable-route"
+		db := OpenDB(t, postgresContainer.DSN)
+		defer db.Close()
+
+		expired := store(t, NewWebhook(routeID, webhook.FIFO))
+		require.NoError(t, repo.SetTTL(ctx, expired.ID, time.Millisecond))
+		corrupted := store(t, NewWebhook(routeID, webhook.FIFO))
+		_, err := db.ExecContext(ctx, `UPDATE webhooks SET status = 'bogus' WHERE id = $1`, corrupted.ID)
+		require.NoError(t, err)
+		valid := store(t, NewWebhook(routeID, webhook.FIFO))
+		time.Sleep(10 * time.Millisecond)
+
+		webhooks, err := repo.ConsumeBatch(ctx, routeID, webhook.FIFO, 3)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, valid.ID, webhooks[0].ID)
+
+		// Only the delivered webhook is still queued
+		var queued int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT count(*) FROM webhook_queue WHERE route_id = $1`, routeID).Scan(&queued))
+		assert.Equal(t, 1, queued)
+
+		// The corrupted row is kept for inspection
+		_, err = repo.Get(ctx, corrupted.ID)
+		assert.ErrorIs(t, err, webhook.ErrCorrupted)
+	})
+}
+
+func TestRepository_Rebalance_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	postgresContainer, cleanup := SetupPostgresContainer(t, ctx)
+	defer cleanup()
+
+	newConsumer := func(name string) *postgres.Repository {
+		repo := CreateTestRepository(t, postgresContainer.DSN, postgres.WithConsumer(name), postgres.WithBlockTimeout(200*time.Millisecond))
+		t.Cleanup(func() { repo.Close(ctx) })
+		return repo
+	}
+	dead := newConsumer("dead-consumer")
+	live := newConsumer("live-consumer")
+
+	// claimByDead stores a webhook the dead consumer claims but never acknowledges
+	claimByDead := func(t *testing.T, routeID string) webhook.Webhook {
+		t.Helper()
+		wh := NewWebhook(routeID, webhook.FIFO)
+		_, err := dead.Store(ctx, wh)
+		require.NoError(t, err)
+		webhooks, err := dead.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		return wh
+	}
+
+	t.Run("entries of a dead consumer are consumed again", func(t *testing.T) {
+		routeID := "rebalance-route"
+		wh := claimByDead(t, routeID)
+
+		webhooks, err := live.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks, "claimed entries are not consumed again")
+
+		released, err := live.Rebalance(ctx, routeID, webhook.FIFO, "dead-consumer")
+		require.NoError(t, err)
+		assert.Equal(t, 1, released)
+
+		webhooks, err = live.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+		require.NoError(t, live.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+	})
+
+	t.Run("only entries claimed at least minIdle ago are released", func(t *testing.T) {
+		routeID := "rebalance-idle-route"
+		wh := claimByDead(t, routeID)
+
+		released, err := live.RebalanceIdle(ctx, routeID, webhook.FIFO, time.Hour)
+		require.NoError(t, err)
+		assert.Zero(t, released)
+
+		time.Sleep(100 * time.Millisecond)
+		released, err = live.RebalanceIdle(ctx, routeID, webhook.FIFO, 50*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 1, released)
+
+		webhooks, err := live.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+
+		// Its own entries are never released
+		released, err = live.RebalanceIdle(ctx, routeID, webhook.FIFO, 0)
+		require.NoError(t, err)
+		assert.Zero(t, released)
+	})
+
+	t.Run("entries of failed webhooks stay claimed", func(t *testing.T) {
+		// The dead consumer rejected the webhook with ack_policy keep_pending
+		routeID := "rebalance-keep-pending-route"
+		wh := claimByDead(t, routeID)
+		require.NoError(t, dead.UpdateStatus(ctx, wh.ID, webhook.Failed))
+
+		released, err := live.Rebalance(ctx, routeID, webhook.FIFO, "dead-consumer")
+		require.NoError(t, err)
+		assert.Zero(t, released)
+		released, err = live.RebalanceIdle(ctx, routeID, webhook.FIFO, 0)
+		require.NoError(t, err)
+		assert.Zero(t, released)
+
+		webhooks, err := live.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks, "a failed webhook must not be delivered again")
+	})
+}
+
+func TestRepository_Migrate_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	postgresContainer, cleanup := SetupPostgresContainer(t, ctx)
+	defer cleanup()
+
+	// Tables created by a version without the request_id column
+	db := OpenDB(t, postgresContainer.DSN)
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE SCHEMA legacy`,
+		`CREATE TABLE legacy.webhooks (
+			id            TEXT PRIMARY KEY,
+			route_id      TEXT NOT NULL,
+			payload       BYTEA NOT NULL,
+			payload_size  INTEGER NOT NULL,
+			headers       JSONB NOT NULL,
+			status        TEXT NOT NULL,
+			retry_count   INTEGER NOT NULL DEFAULT 0,
+			max_retries   INTEGER NOT NULL DEFAULT 0,
+			delivery_mode TEXT NOT NULL,
+			priority      BOOLEAN NOT NULL DEFAULT FALSE,
+			traceparent   TEXT NOT NULL DEFAULT '',
+			deliver_at    TIMESTAMPTZ,
+			created_at    TIMESTAMPTZ NOT NULL,
+			updated_at    TIMESTAMPTZ NOT NULL,
+			expires_at    TIMESTAMPTZ
+		)`,
+		`INSERT INTO legacy.webhooks (id, route_id, payload, payload_size, headers, status, delivery_mode, created_at, updated_at)
+			VALUES ('legacy-webhook', 'legacy-route', '\x7b7d', 2, '{}', 'pending', 'fifo', now(), now())`,
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	repo := CreateTestRepository(t, postgresContainer.DSN+"&search_path=legacy")
+	defer repo.Close(ctx)
+	require.NoError(t, repo.Migrate(ctx))
+	require.NoError(t, repo.Migrate(ctx), "migrating again is a no-op")
+
+	t.Run("existing rows get an empty request ID", func(t *testing.T) {
+		wh, err := repo.Get(ctx, "legacy-webhook")
+		require.NoError(t, err)
+		assert.Empty(t, wh.RequestID)
+	})
+
+	t.Run("new webhooks store their request ID", func(t *testing.T) {
+		wh := NewWebhook("legacy-route", webhook.FIFO)
+		wh.RequestID = "req-123"
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		got, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "req-123", got.RequestID)
+	})
+}
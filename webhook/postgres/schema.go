@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+/* schema creates the tables backing the repository
+ * webhooks holds one row per webhook, like the Redis hashes; expires_at emulates key TTLs
+ * webhook_queue holds the entries workers consume, like the Redis streams: an entry is
+ * claimed by a consumer (pending) and deleted once acknowledged
+ */
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id            TEXT PRIMARY KEY,
+		route_id      TEXT NOT NULL,
+		payload       BYTEA NOT NULL,
+		payload_size  INTEGER NOT NULL,
+		headers       JSONB NOT NULL,
+		status        TEXT NOT NULL,
+		retry_count   INTEGER NOT NULL DEFAULT 0,
+		max_retries   INTEGER NOT NULL DEFAULT 0,
+		delivery_mode TEXT NOT NULL,
+		priority      BOOLEAN NOT NULL DEFAULT FALSE,
+		traceparent   TEXT NOT NULL DEFAULT '',
+		request_id    TEXT NOT NULL DEFAULT '',
+		deliver_at    TIMESTAMPTZ,
+		created_at    TIMESTAMPTZ NOT NULL,
+		updated_at    TIMESTAMPTZ NOT NULL,
+		expires_at    TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhooks_route_created ON webhooks (route_id, created_at, id)`,
+	`CREATE TABLE IF NOT EXISTS webhook_queue (
+		seq           BIGSERIAL PRIMARY KEY,
+		webhook_id    TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		route_id      TEXT NOT NULL,
+		delivery_mode TEXT NOT NULL,
+		priority      BOOLEAN NOT NULL DEFAULT FALSE,
+		available_at  TIMESTAMPTZ NOT NULL,
+		consumer      TEXT,
+		claimed_at    TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhook_queue_ready ON webhook_queue (route_id, delivery_mode, priority DESC, seq) WHERE consumer IS NULL`,
+	`CREATE INDEX IF NOT EXISTS webhook_queue_webhook ON webhook_queue (webhook_id)`,
+}
+
+/* upgrades add the columns introduced since the tables were first created
+ * CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so every column added
+ * to schema later must also be added here
+ */
+var upgrades = []string{
+	`ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT ''`,
+}
+
+/* archiveSchema creates the table backing the Archiver
+ * webhook_archive holds one row per archived webhook; rows never expire
+ */
+var archiveSchema = []string{
+	`CREATE TABLE IF NOT EXISTS webhook_archive (
+		id            TEXT PRIMARY KEY,
+		route_id      TEXT NOT NULL,
+		payload       BYTEA NOT NULL,
+		payload_size  INTEGER NOT NULL,
+		headers       JSONB NOT NULL,
+		status        TEXT NOT NULL,
+		retry_count   INTEGER NOT NULL DEFAULT 0,
+		max_retries   INTEGER NOT NULL DEFAULT 0,
+		delivery_mode TEXT NOT NULL,
+		priority      BOOLEAN NOT NULL DEFAULT FALSE,
+		traceparent   TEXT NOT NULL DEFAULT '',
+		request_id    TEXT NOT NULL DEFAULT '',
+		deliver_at    TIMESTAMPTZ,
+		created_at    TIMESTAMPTZ NOT NULL,
+		updated_at    TIMESTAMPTZ NOT NULL,
+		archived_at   TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhook_archive_route_created ON webhook_archive (route_id, created_at, id)`,
+}
+
+// Migrate creates the repository's tables and indexes if they don't exist yet, and adds missing columns to existing ones
+// Safe to run on every start
+func (r *Repository) Migrate(ctx context.Context) error {
+	return migrate(ctx, r.db, append(schema, upgrades...))
+}
+
+// Migrate creates the archive table and its index if they don't exist yet
+// Safe to run on every start
+func (a *Archiver) Migrate(ctx context.Context) error {
+	return migrate(ctx, a.db, archiveSchema)
+}
+
+// migrate runs schema statements in order
+func migrate(ctx context.Context, db *sql.DB, stmts []string) error {
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrating schema: %w", err)
+		}
+	}
+	return nil
+}
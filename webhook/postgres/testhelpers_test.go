@@ -0,0 +1,106 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/postgres"
+	"github.com/stretchr/testify/require"
+	testcontainerspostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+/* Test Helpers for Postgres Integration Tests
+ * Following the pattern from: https://eltonminetto.dev/post/2024-02-15-using-test-helpers/
+ */
+
+// PostgresContainer holds the Postgres testcontainer and connection details
+type PostgresContainer struct {
+	Container *testcontainerspostgres.PostgresContainer
+	DSN       string
+}
+
+// SetupPostgresContainer creates and starts a Postgres testcontainer with the repository's tables
+func SetupPostgresContainer(t testing.TB, ctx context.Context) (*PostgresContainer, func()) {
+	t.Helper()
+
+	// Start Postgres container
+	postgresContainer, err := testcontainerspostgres.Run(ctx,
+		"postgres:16-alpine",
+		testcontainerspostgres.WithDatabase("webhooks"),
+		testcontainerspostgres.WithUsername("webhooks"),
+		testcontainerspostgres.WithPassword("webhooks"),
+		testcontainerspostgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err, "failed to start Postgres container")
+
+	// Get connection string
+	dsn, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err, "failed to get Postgres connection string")
+
+	pc := &PostgresContainer{
+		Container: postgresContainer,
+		DSN:       dsn,
+	}
+
+	// Cleanup function
+	cleanup := func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate Postgres container: %v", err)
+		}
+	}
+
+	// Create the tables once, as a deployment would
+	repo := CreateTestRepository(t, dsn)
+	require.NoError(t, repo.Migrate(ctx), "failed to migrate schema")
+	repo.Close(ctx)
+
+	return pc, cleanup
+}
+
+// OpenDB opens a database handle on the test container
+func OpenDB(t testing.TB, dsn string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err, "failed to open Postgres database")
+
+	return db
+}
+
+// CreateTestRepository creates a Postgres repository on its own database handle
+// Closing the repository closes the handle, so every repository gets one
+func CreateTestRepository(t testing.TB, dsn string, opts ...postgres.Option) *postgres.Repository {
+	t.Helper()
+
+	repo, err := postgres.NewRepository(context.Background(), OpenDB(t, dsn), opts...)
+	require.NoError(t, err, "failed to create Postgres repository")
+
+	return repo
+}
+
+// webhookSeq keeps webhook IDs unique within a test run
+var webhookSeq atomic.Int64
+
+// NewWebhook builds a pending webhook with a unique ID
+func NewWebhook(routeID string, mode webhook.DeliveryMode) webhook.Webhook {
+	now := time.Now()
+	return webhook.Webhook{
+		ID:           fmt.Sprintf("test-webhook-%d-%d", webhookSeq.Add(1), now.UnixNano()),
+		RouteID:      routeID,
+		Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		Headers:      map[string]string{"Content-Type": "application/json"},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: mode,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Delivery attempt history stored in a Redis list per webhook
+ * Key: webhook:{webhook_id}:attempts, one JSON-encoded webhook.Attempt per entry
+ * The list follows the webhook hash TTL (see SetTTL and MoveToDLQ)
+ */
+
+// MaxAttempts is the number of most recent attempts kept per webhook
+const MaxAttempts = 50
+
+// RecordAttempt appends an attempt to a webhook's history, keeping the latest MaxAttempts
+func (r *Repository) RecordAttempt(ctx context.Context, id string, attempt webhook.Attempt) error {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return fmt.Errorf("marshaling attempt: %w", err)
+	}
+
+	hashKey := fmt.Sprintf("%s:%s", hashPrefix, id)
+	attemptsKey := attemptsKey(id)
+
+	// Inherit the webhook's remaining TTL, if any
+	ttl, err := r.client.PTTL(ctx, hashKey).Result()
+	if err != nil {
+		return fmt.Errorf("reading webhook TTL: %w", err)
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, attemptsKey, data)
+		pipe.LTrim(ctx, attemptsKey, -MaxAttempts, -1)
+		if ttl > 0 {
+			pipe.PExpire(ctx, attemptsKey, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetAttempts returns a webhook's delivery attempts, oldest first
+func (r *Repository) GetAttempts(ctx context.Context, id string) ([]webhook.Attempt, error) {
+	entries, err := r.client.LRange(ctx, attemptsKey(id), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("listing attempts: %w", err)
+	}
+
+	attempts := make([]webhook.Attempt, 0, len(entries))
+	for _, entry := range entries {
+		var attempt webhook.Attempt
+		if err := json.Unmarshal([]byte(entry), &attempt); err != nil {
+			return nil, fmt.Errorf("unmarshaling attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+// attemptsKey builds the key webhook:{webhook_id}:attempts
+func attemptsKey(id string) string {
+	return fmt.Sprintf("%s:%s:attempts", hashPrefix, id)
+}
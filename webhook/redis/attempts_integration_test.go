@@ -0,0 +1,105 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Attempts_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	newWebhook := func(index int) webhook.Webhook {
+		wh := webhook.Webhook{
+			ID:           GenerateID(t, index),
+			RouteID:      "attempts-route",
+			Payload:      []byte(`{}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		return wh
+	}
+
+	t.Run("attempts are returned oldest first", func(t *testing.T) {
+		wh := newWebhook(1)
+
+		first := webhook.Attempt{Timestamp: time.Now().Add(-time.Second).UTC().Truncate(time.Millisecond), StatusCode: 503, Error: "webhook delivery failed with status: 503", LatencyMs: 120}
+		second := webhook.Attempt{Timestamp: time.Now().UTC().Truncate(time.Millisecond), StatusCode: 202, LatencyMs: 45}
+		require.NoError(t, repo.RecordAttempt(ctx, wh.ID, first))
+		require.NoError(t, repo.RecordAttempt(ctx, wh.ID, second))
+
+		attempts, err := repo.GetAttempts(ctx, wh.ID)
+		require.NoError(t, err)
+		require.Len(t, attempts, 2)
+		assert.Equal(t, first.StatusCode, attempts[0].StatusCode)
+		assert.Equal(t, first.Error, attempts[0].Error)
+		assert.True(t, first.Timestamp.Equal(attempts[0].Timestamp))
+		assert.Equal(t, second.StatusCode, attempts[1].StatusCode)
+		assert.Equal(t, int64(45), attempts[1].LatencyMs)
+	})
+
+	t.Run("no attempts recorded", func(t *testing.T) {
+		attempts, err := repo.GetAttempts(ctx, "never-attempted")
+		require.NoError(t, err)
+		assert.Empty(t, attempts)
+	})
+
+	t.Run("history is capped to the latest attempts", func(t *testing.T) {
+		wh := newWebhook(2)
+
+		for i := 0; i < redis.MaxAttempts+5; i++ {
+			require.NoError(t, repo.RecordAttempt(ctx, wh.ID, webhook.Attempt{Timestamp: time.Now(), StatusCode: 500 + i%2, Error: fmt.Sprintf("attempt %d", i)}))
+		}
+
+		attempts, err := repo.GetAttempts(ctx, wh.ID)
+		require.NoError(t, err)
+		require.Len(t, attempts, redis.MaxAttempts)
+		assert.Equal(t, "attempt 5", attempts[0].Error)
+		assert.Equal(t, fmt.Sprintf("attempt %d", redis.MaxAttempts+4), attempts[len(attempts)-1].Error)
+	})
+
+	t.Run("history expires with the webhook", func(t *testing.T) {
+		wh := newWebhook(3)
+		require.NoError(t, repo.RecordAttempt(ctx, wh.ID, webhook.Attempt{Timestamp: time.Now(), StatusCode: 202}))
+
+		require.NoError(t, repo.SetTTL(ctx, wh.ID, time.Hour))
+		ttl := GetKeyTTL(t, redisContainer.Addr, fmt.Sprintf("webhook:%s:attempts", wh.ID))
+		assert.InDelta(t, 3600, ttl, 5)
+
+		// Attempts recorded after the TTL was set inherit it
+		require.NoError(t, repo.RecordAttempt(ctx, wh.ID, webhook.Attempt{Timestamp: time.Now(), StatusCode: 202}))
+		ttl = GetKeyTTL(t, redisContainer.Addr, fmt.Sprintf("webhook:%s:attempts", wh.ID))
+		assert.InDelta(t, 3600, ttl, 5)
+	})
+
+	t.Run("dead-lettered webhooks keep their history", func(t *testing.T) {
+		wh := newWebhook(4)
+		require.NoError(t, repo.RecordAttempt(ctx, wh.ID, webhook.Attempt{Timestamp: time.Now(), StatusCode: 500, Error: "failed"}))
+		require.NoError(t, repo.SetTTL(ctx, wh.ID, time.Hour))
+
+		require.NoError(t, repo.MoveToDLQ(ctx, wh))
+
+		ttl := GetKeyTTL(t, redisContainer.Addr, fmt.Sprintf("webhook:%s:attempts", wh.ID))
+		assert.Equal(t, int64(-1), ttl)
+	})
+}
@@ -0,0 +1,194 @@
+//go:build integration && cluster
+
+package redis_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/* Cluster tests need a running Redis Cluster, e.g.:
+ *   docker run -d -p 7000-7005:7000-7005 -e IP=0.0.0.0 grokzen/redis-cluster:7.0.10
+ *   REDIS_CLUSTER_ADDRS=localhost:7000,localhost:7001,localhost:7002 \
+ *     go test -tags=integration,cluster ./webhook/redis/...
+ */
+
+func TestClusterRepository_Integration(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS not set")
+	}
+
+	ctx := context.Background()
+
+	repo, err := redis.NewClusterRepository(strings.Split(addrs, ","), "")
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	routeID := GenerateID(t, 0)
+	wh := webhook.Webhook{
+		ID:           GenerateID(t, 1),
+		RouteID:      routeID,
+		Payload:      []byte(`{"test": "cluster"}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	t.Run("store, consume and acknowledge", func(t *testing.T) {
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+	})
+
+	t.Run("route keys share a slot", func(t *testing.T) {
+		require.NoError(t, repo.MoveToDLQ(ctx, wh))
+
+		client := repo.GetClient()
+		keys := []string{
+			"webhooks:fifo:{" + routeID + "}",
+			"webhooks:index:{" + routeID + "}",
+			"webhooks:dlq:{" + routeID + "}",
+		}
+
+		var slot int64 = -1
+		for _, key := range keys {
+			exists, err := client.Exists(ctx, key).Result()
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), exists, key)
+
+			keySlot, err := client.ClusterKeySlot(ctx, key).Result()
+			require.NoError(t, err)
+			if slot == -1 {
+				slot = keySlot
+			}
+			assert.Equal(t, slot, keySlot, key)
+		}
+	})
+
+	t.Run("dead-letter and acknowledge a consumed webhook", func(t *testing.T) {
+		consumed := wh
+		consumed.ID = GenerateID(t, 2)
+		_, err := repo.Store(ctx, consumed)
+		require.NoError(t, err)
+
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+
+		require.NoError(t, repo.MoveToDLQAndAcknowledge(ctx, webhooks[0], webhook.FIFO))
+
+		_, err = repo.GetDLQ(ctx, routeID, consumed.ID)
+		require.NoError(t, err)
+		pending, err := repo.GetClient().XPending(ctx, "webhooks:fifo:{"+routeID+"}", "webhook-workers-"+routeID).Result()
+		require.NoError(t, err)
+		assert.Zero(t, pending.Count)
+	})
+
+	t.Run("heartbeats are found across masters", func(t *testing.T) {
+		for _, workerID := range []string{"worker-a", "worker-b", "worker-c"} {
+			require.NoError(t, repo.SetWorkerHeartbeat(ctx, workerID, routeID, "idle"))
+		}
+
+		workers, err := repo.GetActiveWorkers(ctx, routeID)
+		require.NoError(t, err)
+		assert.Len(t, workers, 3)
+	})
+
+	t.Run("statuses are scanned across masters", func(t *testing.T) {
+		// Webhook hashes aren't hash tagged, so enough of them land on every master
+		for i := 3; i < 23; i++ {
+			stored := wh
+			stored.ID = GenerateID(t, i)
+			_, err := repo.Store(ctx, stored)
+			require.NoError(t, err)
+		}
+
+		scanned := 0
+		require.NoError(t, repo.ScanStatuses(ctx, func(scannedRoute, status string) {
+			if scannedRoute == routeID {
+				scanned++
+			}
+		}))
+		assert.GreaterOrEqual(t, scanned, 20)
+	})
+}
+
+func TestClusterRepository_Store_Integration(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS not set")
+	}
+
+	ctx := context.Background()
+
+	groupID := GenerateID(t, 0)
+	groupedRoute := GenerateID(t, 1)
+	repo, err := redis.NewClusterRepository(strings.Split(addrs, ","), "",
+		redis.WithRouteGroups(func(routeID string) string {
+			if routeID == groupedRoute {
+				return groupID
+			}
+			return ""
+		}))
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	newWebhook := func(routeID string, n int) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           GenerateID(t, n),
+			RouteID:      routeID,
+			Payload:      []byte(`{"test": "cluster store"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	t.Run("webhook of a grouped route is queued on its group's stream", func(t *testing.T) {
+		wh := newWebhook(groupedRoute, 2)
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		stored, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wh.ID, stored.ID)
+
+		webhooks, err := repo.Consume(ctx, groupedRoute, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+	})
+
+	t.Run("delayed webhook is scheduled", func(t *testing.T) {
+		routeID := GenerateID(t, 3)
+		wh := newWebhook(routeID, 4)
+		wh.DeliverAt = time.Now().Add(time.Hour)
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		scheduled, err := repo.GetClient().ZScore(ctx, "webhooks:scheduled:{"+routeID+"}", wh.ID).Result()
+		require.NoError(t, err)
+		assert.Equal(t, float64(wh.DeliverAt.UnixMilli()), scheduled)
+	})
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+/* Payload compression (see WithPayloadCompression)
+ * Compressed hashes carry payload_encoding: gzip next to the gzipped payload;
+ * hashes without the field hold the payload verbatim, so both kinds coexist and
+ * Get decodes either whatever the repository's own setting
+ * payload_size always records the uncompressed size
+ */
+
+const payloadEncodingGzip = "gzip"
+
+// encodePayload gzips payloads larger than the repository's compression threshold
+// Returns the bytes to store and their encoding ("" when stored verbatim)
+func (r *Repository) encodePayload(payload []byte) ([]byte, string, error) {
+	if !r.compressPayloads || len(payload) <= r.compressThreshold {
+		return payload, "", nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("compressing payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", fmt.Errorf("compressing payload: %w", err)
+	}
+	return buf.Bytes(), payloadEncodingGzip, nil
+}
+
+// decodePayload reverses encodePayload for a stored payload and its payload_encoding
+func decodePayload(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case payloadEncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing payload: %w", err)
+		}
+		defer zr.Close()
+
+		payload, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing payload: %w", err)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("unknown payload encoding %q", encoding)
+	}
+}
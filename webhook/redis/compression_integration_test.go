@@ -0,0 +1,117 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_PayloadCompression_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	compressing, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0, redis.WithPayloadCompression(1024))
+	require.NoError(t, err)
+	defer compressing.Close(ctx)
+
+	plain := CreateTestRepository(t, redisContainer.Addr)
+	defer plain.Close(ctx)
+
+	client := compressing.GetClient()
+	largePayload := []byte(`{"type":"user.created","data":{"bio":"` + strings.Repeat("lorem ipsum ", 500) + `"}}`)
+	smallPayload := []byte(`{"type":"user.created","data":{}}`)
+
+	store := func(t *testing.T, repo *redis.Repository, index int, payload []byte) string {
+		t.Helper()
+		id, err := repo.Store(ctx, webhook.Webhook{
+			ID:           GenerateID(t, index),
+			RouteID:      "compressed-route",
+			Payload:      payload,
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	t.Run("payloads above the threshold are gzipped", func(t *testing.T) {
+		id := store(t, compressing, 0, largePayload)
+
+		fields, err := client.HMGet(ctx, "webhook:"+id, "payload", "payload_encoding").Result()
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", fields[1])
+		assert.Less(t, len(fields[0].(string)), len(largePayload))
+
+		wh, err := compressing.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, largePayload, wh.Payload)
+		assert.Equal(t, len(largePayload), wh.PayloadSize)
+
+		// The stream entry points at the hash instead of keeping an uncompressed copy
+		entries, err := client.XRevRangeN(ctx, "webhooks:fifo:compressed-route", "+", "-", 1).Result()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, id, entries[0].Values["event_id"])
+		assert.NotContains(t, entries[0].Values, "payload")
+		assert.Equal(t, strconv.Itoa(len(largePayload)), entries[0].Values["payload_size"])
+		assert.Equal(t, "user.created", entries[0].Values["event_type"])
+	})
+
+	t.Run("payloads below the threshold are stored verbatim", func(t *testing.T) {
+		id := store(t, compressing, 1, smallPayload)
+
+		fields, err := client.HMGet(ctx, "webhook:"+id, "payload", "payload_encoding").Result()
+		require.NoError(t, err)
+		assert.Equal(t, string(smallPayload), fields[0])
+		assert.Nil(t, fields[1])
+
+		wh, err := compressing.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, smallPayload, wh.Payload)
+	})
+
+	t.Run("compressed and uncompressed payloads coexist", func(t *testing.T) {
+		compressed := store(t, compressing, 2, largePayload)
+		uncompressed := store(t, plain, 3, largePayload)
+
+		// Either repository reads both, whatever its own setting
+		for _, repo := range []*redis.Repository{compressing, plain} {
+			for _, id := range []string{compressed, uncompressed} {
+				wh, err := repo.Get(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, largePayload, wh.Payload)
+			}
+		}
+	})
+
+	t.Run("consumed webhooks are decompressed", func(t *testing.T) {
+		webhooks, err := compressing.Consume(ctx, "compressed-route", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, largePayload, webhooks[0].Payload)
+	})
+
+	t.Run("unknown encodings are rejected", func(t *testing.T) {
+		id := store(t, plain, 4, smallPayload)
+		require.NoError(t, client.HSet(ctx, "webhook:"+id, "payload_encoding", "br").Err())
+
+		_, err := plain.Get(ctx, id)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown payload encoding "br"`)
+	})
+}
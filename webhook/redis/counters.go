@@ -0,0 +1,207 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Per-route status counters kept alongside the webhook hashes
+ * Key: metrics:{route_id}:status:{status}, value: number of webhooks in that status
+ * Updated on every Store and status transition so metrics never scan the keyspace
+ * Counters are not decremented when a webhook hash expires; metrics.RedisCollector
+ * backfills them from a scan when none exists and, when enabled, reconciles them (see BackfillStatusCounters)
+ *
+ * Deliveries are also recorded in a sorted set per route for throughput metrics
+ * Key: metrics:{route_id}:deliveries, member: webhook ID, score: delivered at (unix seconds)
+ */
+
+const counterPrefix = "metrics" // Counter naming: metrics:{route_id}:status:{status}
+
+// countedStatuses are the statuses with a counter per route
+var countedStatuses = []webhook.Status{webhook.Pending, webhook.Delivering, webhook.Delivered, webhook.Failed, webhook.Retrying}
+
+// DeliveryRetention is how long deliveries stay in the throughput time series
+// Matches the widest throughput window reported by the metrics
+const DeliveryRetention = 15 * time.Minute
+
+// StatusCounterKey returns the counter key tracking a route's webhooks in a status
+// The route ID is always wrapped in {} so a route's counters share a Cluster slot
+func StatusCounterKey(routeID, status string) string {
+	return fmt.Sprintf("%s:{%s}:status:%s", counterPrefix, routeID, status)
+}
+
+// DeliveriesKey returns the sorted set recording a route's recent deliveries
+func DeliveriesKey(routeID string) string {
+	return fmt.Sprintf("%s:{%s}:deliveries", counterPrefix, routeID)
+}
+
+// CountByStatus returns how many of a route's webhooks are in each status, read from its counters
+// Every status is present in the result; counters that don't exist yet count as zero
+func (r *Repository) CountByStatus(ctx context.Context, routeID string) (map[string]int64, error) {
+	keys := make([]string, len(countedStatuses))
+	for i, status := range countedStatuses {
+		keys[i] = StatusCounterKey(routeID, status.String())
+	}
+
+	// The counters of a route share a Cluster slot, so a single MGET reads them all
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading status counters: %w", err)
+	}
+
+	counts := make(map[string]int64, len(countedStatuses))
+	for i, status := range countedStatuses {
+		value, _ := values[i].(string)
+		// Counters can drift below zero when webhooks expire between transitions
+		counts[status.String()] = max(parseInt64(value), 0)
+	}
+	return counts, nil
+}
+
+// ScanStatuses calls fn with the route_id and status of every stored webhook
+// Scans webhook:* keys (every master on Redis Cluster) and reads the fields in a single pipeline per batch;
+// the scan isn't atomic, so webhooks changing status meanwhile may be reported with either status
+func (r *Repository) ScanStatuses(ctx context.Context, fn func(routeID, status string)) error {
+	err := r.scanKeys(ctx, hashPrefix+":*", func(keys []string) error {
+		// Skip auxiliary keys (webhook:*:msgid, webhook:*:attempts)
+		var hashKeys []string
+		for _, key := range keys {
+			if strings.HasSuffix(key, ":msgid") || strings.HasSuffix(key, ":attempts") {
+				continue
+			}
+			hashKeys = append(hashKeys, key)
+		}
+		if len(hashKeys) == 0 {
+			return nil
+		}
+
+		cmds := make([]*redis.SliceCmd, len(hashKeys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range hashKeys {
+				cmds[i] = pipe.HMGet(ctx, key, "route_id", "status")
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		for _, cmd := range cmds {
+			values, err := cmd.Result()
+			if err != nil || len(values) < 2 {
+				continue
+			}
+			routeID, ok1 := values[0].(string)
+			status, ok2 := values[1].(string)
+			if !ok1 || !ok2 {
+				continue
+			}
+			fn(routeID, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scanning webhook statuses: %w", err)
+	}
+	return nil
+}
+
+// RecordDelivery adds a successful delivery to the route's throughput time series
+// Entries older than DeliveryRetention are pruned on every write
+func (r *Repository) RecordDelivery(ctx context.Context, routeID, id string, at time.Time) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		recordDelivery(ctx, pipe, routeID, id, at)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recording delivery: %w", err)
+	}
+	return nil
+}
+
+// recordDelivery queues the ZADD and pruning for a delivery on a pipeline
+func recordDelivery(ctx context.Context, pipe redis.Pipeliner, routeID, id string, at time.Time) {
+	key := DeliveriesKey(routeID)
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.Unix()), Member: id})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", time.Now().Add(-DeliveryRetention).Unix()))
+}
+
+// setStatusScript writes a webhook's status and fields, and moves it between its route's status counters
+// KEYS[1] is the webhook hash, KEYS[2..] the route's counters with ARGV[2..#KEYS] naming their statuses
+// (none on Redis Cluster, where the counters live in the route's slot). ARGV[1] is the new status,
+// followed by field/value pairs. Returns the previous status, "" for a missing hash
+var setStatusScript = redis.NewScript(`
+local previous = redis.call('HGET', KEYS[1], 'status') or ''
+redis.call('HSET', KEYS[1], 'status', ARGV[1], unpack(ARGV, #KEYS + 1))
+if previous ~= ARGV[1] then
+	for i = 2, #KEYS do
+		if ARGV[i] == previous then
+			redis.call('DECR', KEYS[i])
+		elseif ARGV[i] == ARGV[1] then
+			redis.call('INCR', KEYS[i])
+		end
+	end
+end
+return previous
+`)
+
+// setStatus writes a webhook's new status, moves it between status counters and publishes the transition
+// The status is read and replaced in one script, so concurrent transitions each move the counters once.
+// On a single node the counters are updated by the same script; on Redis Cluster the hash and the
+// counters live in different slots, so the counters follow in a second round-trip
+// Counters and events are left untouched when the status doesn't change or the webhook has no route
+// Transitions to delivered are also recorded in the route's throughput time series
+func (r *Repository) setStatus(ctx context.Context, id string, fields map[string]interface{}, status webhook.Status) error {
+	hashKey := fmt.Sprintf("%s:%s", hashPrefix, id)
+
+	// A webhook's route never changes, so it can be read ahead of the transition
+	routeID, err := r.client.HGet(ctx, hashKey, "route_id").Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("reading route: %w", err)
+	}
+
+	keys := []string{hashKey}
+	args := []interface{}{status.String()}
+	if routeID != "" && !r.hashTags {
+		for _, counted := range countedStatuses {
+			keys = append(keys, StatusCounterKey(routeID, counted.String()))
+			args = append(args, counted.String())
+		}
+	}
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+
+	reply, err := setStatusScript.Run(ctx, r.client, keys, args...).Result()
+	if err != nil {
+		return fmt.Errorf("updating status: %w", err)
+	}
+	previous, _ := reply.(string)
+	if routeID == "" || previous == status.String() {
+		return nil
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if r.hashTags {
+			if previous != "" {
+				pipe.Decr(ctx, StatusCounterKey(routeID, previous))
+			}
+			pipe.Incr(ctx, StatusCounterKey(routeID, status.String()))
+		}
+		if status == webhook.Delivered {
+			recordDelivery(ctx, pipe, routeID, id, time.Now())
+		}
+		publishStatus(ctx, pipe, routeID, id, status, time.Now())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("updating status counters: %w", err)
+	}
+
+	return nil
+}
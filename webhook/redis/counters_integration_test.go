@@ -0,0 +1,176 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_StatusCounters_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	client := repo.GetClient()
+	counter := func(routeID string, status webhook.Status) int64 {
+		t.Helper()
+		count, err := client.Get(ctx, redis.StatusCounterKey(routeID, status.String())).Int64()
+		if err != nil {
+			return 0
+		}
+		return count
+	}
+
+	newWebhook := func(index int, routeID string) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           GenerateID(t, index),
+			RouteID:      routeID,
+			Payload:      []byte(`{}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	first := newWebhook(1, "counters-a")
+	second := newWebhook(2, "counters-a")
+	other := newWebhook(3, "counters-b")
+
+	t.Run("store counts pending webhooks per route", func(t *testing.T) {
+		for _, wh := range []webhook.Webhook{first, second, other} {
+			_, err := repo.Store(ctx, wh)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, int64(2), counter("counters-a", webhook.Pending))
+		assert.Equal(t, int64(1), counter("counters-b", webhook.Pending))
+	})
+
+	t.Run("transitions move webhooks between counters", func(t *testing.T) {
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivering))
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Retrying))
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivering))
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivered))
+
+		assert.Equal(t, int64(1), counter("counters-a", webhook.Pending))
+		assert.Equal(t, int64(0), counter("counters-a", webhook.Delivering))
+		assert.Equal(t, int64(0), counter("counters-a", webhook.Retrying))
+		assert.Equal(t, int64(1), counter("counters-a", webhook.Delivered))
+	})
+
+	t.Run("repeating a status doesn't double count", func(t *testing.T) {
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivered))
+
+		assert.Equal(t, int64(1), counter("counters-a", webhook.Delivered))
+	})
+
+	t.Run("dead-lettering counts as failed", func(t *testing.T) {
+		require.NoError(t, repo.MoveToDLQ(ctx, second))
+
+		assert.Equal(t, int64(0), counter("counters-a", webhook.Pending))
+		assert.Equal(t, int64(1), counter("counters-a", webhook.Failed))
+		assert.Equal(t, int64(1), counter("counters-b", webhook.Pending))
+	})
+
+	t.Run("concurrent transitions move the counters once", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.UpdateStatus(ctx, other.ID, webhook.Delivering))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(0), counter("counters-b", webhook.Pending))
+		assert.Equal(t, int64(1), counter("counters-b", webhook.Delivering))
+	})
+}
+
+func TestRepository_CountByStatus_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	routeID := GenerateID(t, 0)
+	newWebhook := func(index int) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           GenerateID(t, index),
+			RouteID:      routeID,
+			Payload:      []byte(`{}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+	zero := map[string]int64{"pending": 0, "delivering": 0, "delivered": 0, "failed": 0, "retrying": 0}
+
+	t.Run("unknown routes count zero for every status", func(t *testing.T) {
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, zero, counts)
+	})
+
+	first, second, third := newWebhook(1), newWebhook(2), newWebhook(3)
+
+	t.Run("counts track stores", func(t *testing.T) {
+		for _, wh := range []webhook.Webhook{first, second, third} {
+			_, err := repo.Store(ctx, wh)
+			require.NoError(t, err)
+		}
+
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), counts["pending"])
+	})
+
+	t.Run("counts track status updates", func(t *testing.T) {
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivering))
+		require.NoError(t, repo.UpdateStatus(ctx, first.ID, webhook.Delivered))
+		require.NoError(t, repo.UpdateStatus(ctx, second.ID, webhook.Delivering))
+		require.NoError(t, repo.MoveToDLQ(ctx, third))
+
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"pending": 0, "delivering": 1, "delivered": 1, "failed": 1, "retrying": 0}, counts)
+	})
+
+	t.Run("negative drift reads as zero", func(t *testing.T) {
+		require.NoError(t, repo.GetClient().Set(ctx, redis.StatusCounterKey(routeID, "retrying"), -2, 0).Err())
+
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), counts["retrying"])
+	})
+
+	t.Run("counts are reset when the route is purged", func(t *testing.T) {
+		require.NoError(t, repo.PurgeRoute(ctx, routeID))
+
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, zero, counts)
+	})
+}
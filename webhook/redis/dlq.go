@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Dead letter queue backed by a Redis sorted set per route
+ * Key: webhooks:dlq:{route_id}, member: webhook ID, score: dead-lettered at (unix seconds)
+ * The webhook hash itself stays at webhook:{webhook_id} without a TTL until removed from the DLQ
+ */
+
+const dlqPrefix = "webhooks:dlq" // DLQ naming: webhooks:dlq:{route_id}
+
+// MoveToDLQ marks a webhook as failed and adds it to its route's dead letter queue
+func (r *Repository) MoveToDLQ(ctx context.Context, wh webhook.Webhook) error {
+	return r.moveToDLQ(ctx, wh, "", "")
+}
+
+// MoveToDLQAndAcknowledge dead-letters a webhook and acknowledges its stream message in one MULTI/EXEC
+// The DLQ and the stream share the route's slot, so the DLQ entry and the XACK apply together on Redis
+// Cluster too. A grouped route's stream is in its group's slot instead; there the XACK follows the transaction
+func (r *Repository) MoveToDLQAndAcknowledge(ctx context.Context, wh webhook.Webhook, deliveryMode webhook.DeliveryMode) error {
+	streamKey, msgID, err := r.consumedMessage(ctx, wh.RouteID, deliveryMode, wh.ID)
+	if err != nil {
+		return err
+	}
+
+	return r.moveToDLQ(ctx, wh, streamKey, msgID)
+}
+
+// moveToDLQ marks a webhook as failed and parks it in the DLQ
+// A non-empty msgID is the webhook's stream message, acknowledged together with the DLQ entry
+func (r *Repository) moveToDLQ(ctx context.Context, wh webhook.Webhook, streamKey, msgID string) error {
+	now := time.Now()
+
+	err := r.setStatus(ctx, wh.ID, map[string]interface{}{
+		"updated_at": now.Unix(),
+	}, webhook.Failed)
+	if err != nil {
+		return err
+	}
+
+	// Dead-lettered webhooks must outlive the failed TTL
+	// The hash and attempts list are outside the route's slot, so they are kept out of the transaction
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Persist(ctx, fmt.Sprintf("%s:%s", hashPrefix, wh.ID))
+		pipe.Persist(ctx, attemptsKey(wh.ID))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("persisting dead-lettered webhook: %w", err)
+	}
+
+	acknowledge := func(pipe redis.Pipeliner) {
+		if msgID != "" {
+			pipe.XAck(ctx, streamKey, r.GroupName(wh.RouteID), msgID)
+		}
+	}
+	sameSlot := !r.hashTags || r.streamRoute(wh.RouteID) == wh.RouteID
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, r.DLQKey(wh.RouteID), redis.Z{
+			Score:  float64(now.Unix()),
+			Member: wh.ID,
+		})
+		if sameSlot {
+			acknowledge(pipe)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("adding to DLQ: %w", err)
+	}
+
+	if msgID == "" {
+		// Not consumed, or already acknowledged
+		return nil
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if !sameSlot {
+			acknowledge(pipe)
+		}
+		pipe.Del(ctx, fmt.Sprintf("%s:%s:msgid", hashPrefix, wh.ID))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("acknowledging dead-lettered webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListDLQ returns dead-lettered webhooks for a route, oldest first
+func (r *Repository) ListDLQ(ctx context.Context, routeID string, offset, limit int) ([]webhook.Webhook, error) {
+	if offset < 0 || limit < 1 {
+		return nil, fmt.Errorf("invalid pagination: offset=%d limit=%d", offset, limit)
+	}
+
+	dlqKey := r.DLQKey(routeID)
+	ids, err := r.client.ZRange(ctx, dlqKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("listing DLQ: %w", err)
+	}
+
+	webhooks := make([]webhook.Webhook, 0, len(ids))
+	for _, id := range ids {
+		wh, err := r.Get(ctx, id)
+		if errors.Is(err, webhook.ErrNotFound) {
+			// Hash was removed out of band, drop the dangling entry
+			r.client.ZRem(ctx, dlqKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting DLQ webhook: %w", err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	return webhooks, nil
+}
+
+// GetDLQ returns a dead-lettered webhook, or webhook.ErrNotFound if it is not in the queue
+func (r *Repository) GetDLQ(ctx context.Context, routeID string, id string) (webhook.Webhook, error) {
+	err := r.client.ZScore(ctx, r.DLQKey(routeID), id).Err()
+	if err == redis.Nil {
+		return webhook.Webhook{}, fmt.Errorf("%w: %s", webhook.ErrNotFound, id)
+	}
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("checking DLQ membership: %w", err)
+	}
+
+	return r.Get(ctx, id)
+}
+
+// RemoveFromDLQ removes a webhook from its route's dead letter queue and deletes it
+// Dead-lettered webhooks don't expire, so the hash and attempts list would otherwise be kept forever
+// They are deleted first, outside the route's slot: a crash in between leaves a DLQ entry that
+// ListDLQ prunes, never a hash nothing points at
+func (r *Repository) RemoveFromDLQ(ctx context.Context, routeID string, id string) error {
+	hashKey := fmt.Sprintf("%s:%s", hashPrefix, id)
+	status, err := r.client.HGet(ctx, hashKey, "status").Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("reading dead-lettered webhook: %w", err)
+	}
+
+	var deleted *redis.IntCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, hashKey)
+		pipe.Del(ctx, attemptsKey(id), fmt.Sprintf("%s:%s:msgid", hashPrefix, id))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleting dead-lettered webhook: %w", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, r.DLQKey(routeID), id)
+		pipe.ZRem(ctx, r.IndexKey(routeID), id)
+		if deleted.Val() > 0 && status != "" {
+			pipe.Decr(ctx, StatusCounterKey(routeID, status))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("removing from DLQ: %w", err)
+	}
+	return nil
+}
+
+// DLQKey returns the sorted set holding a route's dead-lettered webhooks
+// It takes the route ID as is; Repository.DLQKey applies hash tags
+func DLQKey(routeID string) string {
+	return fmt.Sprintf("%s:%s", dlqPrefix, routeID)
+}
+
+// DLQKey returns the sorted set this repository dead-letters a route's webhooks to, hash-tagged on Redis Cluster
+func (r *Repository) DLQKey(routeID string) string {
+	return DLQKey(r.routeTag(routeID))
+}
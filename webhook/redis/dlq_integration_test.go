@@ -0,0 +1,93 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_DLQ_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("move, list, get and remove", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "dlq-route"
+		var stored []webhook.Webhook
+		for i := 0; i < 3; i++ {
+			wh := webhook.Webhook{
+				ID:           fmt.Sprintf("dlq-webhook-%d", i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"test": "dlq"}`),
+				Headers:      map[string]string{},
+				Status:       webhook.Retrying,
+				RetryCount:   3,
+				MaxRetries:   3,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+			_, err := repo.Store(ctx, wh)
+			require.NoError(t, err)
+			require.NoError(t, repo.SetTTL(ctx, wh.ID, time.Hour))
+			require.NoError(t, repo.MoveToDLQ(ctx, wh))
+			stored = append(stored, wh)
+		}
+
+		// Dead-lettered webhooks are failed and no longer expire
+		wh, err := repo.GetDLQ(ctx, routeID, stored[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.Failed, wh.Status)
+		assert.Equal(t, int64(-1), GetKeyTTL(t, redisContainer.Addr, "webhook:"+stored[0].ID))
+
+		page, err := repo.ListDLQ(ctx, routeID, 1, 10)
+		require.NoError(t, err)
+		assert.Len(t, page, 2)
+
+		require.NoError(t, repo.RecordAttempt(ctx, stored[0].ID, webhook.Attempt{Timestamp: time.Now(), StatusCode: 500}))
+		require.NoError(t, repo.RemoveFromDLQ(ctx, routeID, stored[0].ID))
+		_, err = repo.GetDLQ(ctx, routeID, stored[0].ID)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+
+		// Removed webhooks are deleted rather than kept without a TTL
+		_, err = repo.Get(ctx, stored[0].ID)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+		exists, err := repo.GetClient().Exists(ctx, "webhook:"+stored[0].ID+":attempts").Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists)
+		counts, err := repo.CountByStatus(ctx, routeID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), counts["failed"])
+
+		all, err := repo.ListDLQ(ctx, routeID, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("get from another route's DLQ is not found", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		wh := webhook.Webhook{ID: "dlq-other", RouteID: "route-a", DeliveryMode: webhook.FIFO}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		require.NoError(t, repo.MoveToDLQ(ctx, wh))
+
+		_, err = repo.GetDLQ(ctx, "route-b", wh.ID)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+}
@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* Dump and Load move every webhook record between Redis instances as newline-delimited JSON
+ * Records keep the payload as base64 and timestamps at full precision, unlike the canonical
+ * webhook JSON, so payloads (and their signatures) survive the round trip byte for byte
+ * Streams, consumer groups, delivery attempts and DLQ membership are not part of a dump
+ */
+
+// dumpRecord is a single line of a dump
+type dumpRecord struct {
+	ID           string            `json:"id"`
+	RouteID      string            `json:"route_id"`
+	Payload      []byte            `json:"payload"`
+	Headers      map[string]string `json:"headers"`
+	Status       string            `json:"status"`
+	RetryCount   int               `json:"retry_count"`
+	MaxRetries   int               `json:"max_retries"`
+	DeliveryMode string            `json:"delivery_mode"`
+	Priority     bool              `json:"priority,omitempty"`
+	TraceParent  string            `json:"traceparent,omitempty"`
+	RequestID    string            `json:"request_id,omitempty"`
+	DeliverAt    time.Time         `json:"deliver_at,omitzero"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Dump writes every stored webhook to w, one JSON record per line
+func (r *Repository) Dump(ctx context.Context, w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	err := r.scanKeys(ctx, hashPrefix+":*", func(keys []string) error {
+		for _, key := range keys {
+			// Skip auxiliary keys (webhook:*:msgid, webhook:*:attempts)
+			if strings.HasSuffix(key, ":msgid") || strings.HasSuffix(key, ":attempts") {
+				continue
+			}
+			id := strings.TrimPrefix(key, hashPrefix+":")
+
+			wh, err := r.Get(ctx, id)
+			if errors.Is(err, webhook.ErrNotFound) {
+				// Expired since the scan returned it
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := enc.Encode(newDumpRecord(wh)); err != nil {
+				return fmt.Errorf("writing webhook %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("dumping webhooks: %w", err)
+	}
+
+	return nil
+}
+
+// Load stores every webhook record read from rd, as written by Dump
+// Webhooks not in a final status are enqueued again; delivered and failed ones are only stored and indexed
+// Webhooks that already exist are skipped, so an interrupted Load can be run again
+func (r *Repository) Load(ctx context.Context, rd io.Reader) error {
+	dec := json.NewDecoder(bufio.NewReader(rd))
+
+	for line := 1; ; line++ {
+		var record dumpRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading record %d: %w", line, err)
+		}
+
+		wh, err := record.webhook()
+		if err != nil {
+			return fmt.Errorf("reading record %d: %w", line, err)
+		}
+
+		exists, err := r.client.Exists(ctx, fmt.Sprintf("%s:%s", hashPrefix, wh.ID)).Result()
+		if err != nil {
+			return fmt.Errorf("checking webhook %s: %w", wh.ID, err)
+		}
+		if exists > 0 {
+			continue
+		}
+
+		if err := r.store(ctx, wh, !wh.Status.IsFinal()); err != nil {
+			return fmt.Errorf("loading webhook %s: %w", wh.ID, err)
+		}
+	}
+}
+
+func newDumpRecord(wh webhook.Webhook) dumpRecord {
+	return dumpRecord{
+		ID:           wh.ID,
+		RouteID:      wh.RouteID,
+		Payload:      wh.Payload,
+		Headers:      wh.Headers,
+		Status:       wh.Status.String(),
+		RetryCount:   wh.RetryCount,
+		MaxRetries:   wh.MaxRetries,
+		DeliveryMode: wh.DeliveryMode.String(),
+		Priority:     wh.Priority,
+		TraceParent:  wh.TraceParent,
+		RequestID:    wh.RequestID,
+		DeliverAt:    wh.DeliverAt,
+		CreatedAt:    wh.CreatedAt,
+		UpdatedAt:    wh.UpdatedAt,
+	}
+}
+
+// webhook validates the record and converts it back to a webhook
+func (d dumpRecord) webhook() (webhook.Webhook, error) {
+	if d.ID == "" || d.RouteID == "" {
+		return webhook.Webhook{}, fmt.Errorf("missing id or route_id")
+	}
+	status, err := webhook.NewStatusStrict(d.Status)
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("webhook %s: %w", d.ID, err)
+	}
+	mode := webhook.NewDeliveryMode(d.DeliveryMode)
+	if mode.String() != d.DeliveryMode {
+		return webhook.Webhook{}, fmt.Errorf("webhook %s: invalid delivery mode: %q", d.ID, d.DeliveryMode)
+	}
+
+	return webhook.Webhook{
+		ID:           d.ID,
+		RouteID:      d.RouteID,
+		Payload:      d.Payload,
+		PayloadSize:  len(d.Payload),
+		Headers:      d.Headers,
+		Status:       status,
+		RetryCount:   d.RetryCount,
+		MaxRetries:   d.MaxRetries,
+		DeliverAt:    d.DeliverAt,
+		DeliveryMode: mode,
+		Priority:     d.Priority,
+		TraceParent:  d.TraceParent,
+		RequestID:    d.RequestID,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,100 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_DumpLoad_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	sourceContainer, cleanupSource := SetupRedisContainer(t, ctx)
+	defer cleanupSource()
+	targetContainer, cleanupTarget := SetupRedisContainer(t, ctx)
+	defer cleanupTarget()
+
+	source := CreateTestRepository(t, sourceContainer.Addr)
+	defer source.Close(ctx)
+	target := CreateTestRepository(t, targetContainer.Addr)
+	defer target.Close(ctx)
+
+	routeID := "dump-route"
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	statuses := []webhook.Status{webhook.Pending, webhook.Retrying, webhook.Delivered, webhook.Failed}
+
+	for i, status := range statuses {
+		_, err := source.Store(ctx, webhook.Webhook{
+			ID:           fmt.Sprintf("dump-%s", status),
+			RouteID:      routeID,
+			Payload:      []byte(fmt.Sprintf(`{"n": %d}`, i)),
+			Headers:      map[string]string{"X-Index": fmt.Sprint(i)},
+			Status:       status,
+			RetryCount:   i,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    createdAt.Add(time.Duration(i) * time.Second),
+			UpdatedAt:    createdAt.Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+	// Auxiliary keys are not dumped
+	require.NoError(t, source.RecordAttempt(ctx, "dump-retrying", webhook.Attempt{Timestamp: time.Now(), StatusCode: 500}))
+
+	var dump bytes.Buffer
+	require.NoError(t, source.Dump(ctx, &dump))
+	assert.Equal(t, len(statuses), strings.Count(dump.String(), "\n"))
+
+	require.NoError(t, target.Load(ctx, bytes.NewReader(dump.Bytes())))
+
+	t.Run("records are restored as dumped", func(t *testing.T) {
+		for _, status := range statuses {
+			id := fmt.Sprintf("dump-%s", status)
+			want, err := source.Get(ctx, id)
+			require.NoError(t, err)
+			got, err := target.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		}
+	})
+
+	t.Run("records are indexed", func(t *testing.T) {
+		webhooks, err := target.GetByRouteID(ctx, routeID, 0)
+		require.NoError(t, err)
+		require.Len(t, webhooks, len(statuses))
+		assert.Equal(t, "dump-pending", webhooks[0].ID)
+	})
+
+	t.Run("only webhooks awaiting delivery are enqueued", func(t *testing.T) {
+		consumed, err := target.ConsumeBatch(ctx, routeID, webhook.FIFO, 10)
+		require.NoError(t, err)
+
+		ids := make([]string, 0, len(consumed))
+		for _, wh := range consumed {
+			ids = append(ids, wh.ID)
+		}
+		assert.ElementsMatch(t, []string{"dump-pending", "dump-retrying"}, ids)
+	})
+
+	t.Run("loading again skips existing webhooks", func(t *testing.T) {
+		require.NoError(t, target.Load(ctx, bytes.NewReader(dump.Bytes())))
+
+		depth, err := target.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), depth)
+	})
+
+	t.Run("error - malformed record", func(t *testing.T) {
+		err := target.Load(ctx, strings.NewReader(`{"id": "x", "route_id": "r", "status": "bogus", "delivery_mode": "fifo"}`+"\n"))
+		assert.ErrorIs(t, err, webhook.ErrUnknownStatus)
+	})
+}
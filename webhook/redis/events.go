@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Status events are published with Redis Pub/Sub on every status transition
+ * Channel: webhooks:events:{route_id}, message: JSON-encoded webhook.StatusEvent
+ * Pub/Sub is fire-and-forget, so events published while nobody listens are lost
+ */
+
+const eventsPrefix = "webhooks:events" // Channel naming: webhooks:events:{route_id}
+
+// StatusEventsChannel returns the Pub/Sub channel carrying a route's status events
+func StatusEventsChannel(routeID string) string {
+	return fmt.Sprintf("%s:%s", eventsPrefix, routeID)
+}
+
+// publishStatus queues the PUBLISH of a status transition on a pipeline
+func publishStatus(ctx context.Context, pipe redis.Pipeliner, routeID, id string, status webhook.Status, at time.Time) {
+	message, err := json.Marshal(webhook.StatusEvent{
+		ID:      id,
+		RouteID: routeID,
+		Status:  status.String(),
+		At:      at,
+	})
+	if err != nil {
+		return
+	}
+	pipe.Publish(ctx, StatusEventsChannel(routeID), message)
+}
+
+// SubscribeStatus streams the status transitions of a route's webhooks until ctx is cancelled
+// Returns once Redis has confirmed the subscription, so no later transition is missed
+func (r *Repository) SubscribeStatus(ctx context.Context, routeID string) (<-chan webhook.StatusEvent, error) {
+	sub := r.client.Subscribe(ctx, StatusEventsChannel(routeID))
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("subscribing to status events: %w", err)
+	}
+
+	events := make(chan webhook.StatusEvent)
+	go func() {
+		defer close(events)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event webhook.StatusEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,93 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_SubscribeStatus_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	routeID := GenerateID(t, 0)
+	wh := webhook.Webhook{
+		ID:           GenerateID(t, 1),
+		RouteID:      routeID,
+		Payload:      []byte(`{}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	_, err := repo.Store(ctx, wh)
+	require.NoError(t, err)
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := repo.SubscribeStatus(subCtx, routeID)
+	require.NoError(t, err)
+
+	receive := func(t *testing.T) webhook.StatusEvent {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "subscription closed")
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no status event received")
+			return webhook.StatusEvent{}
+		}
+	}
+
+	t.Run("transitions are published", func(t *testing.T) {
+		require.NoError(t, repo.UpdateStatus(ctx, wh.ID, webhook.Delivering))
+		require.NoError(t, repo.UpdateStatus(ctx, wh.ID, webhook.Delivered))
+
+		event := receive(t)
+		assert.Equal(t, wh.ID, event.ID)
+		assert.Equal(t, routeID, event.RouteID)
+		assert.Equal(t, "delivering", event.Status)
+		assert.False(t, event.At.IsZero())
+
+		assert.Equal(t, "delivered", receive(t).Status)
+	})
+
+	t.Run("other routes are not received", func(t *testing.T) {
+		other := wh
+		other.ID = GenerateID(t, 2)
+		other.RouteID = GenerateID(t, 3)
+		_, err := repo.Store(ctx, other)
+		require.NoError(t, err)
+		require.NoError(t, repo.UpdateStatus(ctx, other.ID, webhook.Delivering))
+
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected event %+v", event)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("cancelling closes the channel", func(t *testing.T) {
+		cancel()
+		select {
+		case _, ok := <-events:
+			assert.False(t, ok)
+		case <-time.After(5 * time.Second):
+			t.Fatal("channel was not closed")
+		}
+	})
+}
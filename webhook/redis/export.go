@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Secondary index of webhooks per route, backed by a Redis sorted set
+ * Key: webhooks:index:{route_id}, member: webhook ID, score: created_at (unix seconds)
+ * Entries whose hash has expired are pruned lazily when the index is read
+ */
+
+const (
+	indexPrefix     = "webhooks:index" // Index naming: webhooks:index:{route_id}
+	exportBatchSize = 100              // Webhooks loaded per round trip while exporting
+)
+
+// Export returns every webhook of a route created within [from, to], oldest first
+// Prefer ExportEach for large ranges, as Export holds the whole result in memory
+func (r *Repository) Export(ctx context.Context, routeID string, from, to time.Time) ([]webhook.Webhook, error) {
+	var webhooks []webhook.Webhook
+	err := r.ExportEach(ctx, routeID, from, to, func(wh webhook.Webhook) error {
+		webhooks = append(webhooks, wh)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// ExportEach calls fn for every webhook of a route created within [from, to], oldest first
+func (r *Repository) ExportEach(ctx context.Context, routeID string, from, to time.Time, fn func(webhook.Webhook) error) error {
+	if to.Before(from) {
+		return fmt.Errorf("invalid time range: from %s is after to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	indexKey := r.IndexKey(routeID)
+	var dangling []interface{}
+
+	for offset := int64(0); ; offset += exportBatchSize {
+		ids, err := r.client.ZRangeByScore(ctx, indexKey, &redis.ZRangeBy{
+			Min:    strconv.FormatInt(from.Unix(), 10),
+			Max:    strconv.FormatInt(to.Unix(), 10),
+			Offset: offset,
+			Count:  exportBatchSize,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("reading route index: %w", err)
+		}
+
+		for _, id := range ids {
+			wh, err := r.Get(ctx, id)
+			if errors.Is(err, webhook.ErrNotFound) {
+				// Hash expired through its TTL, prune once iteration is done
+				dangling = append(dangling, id)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("getting indexed webhook: %w", err)
+			}
+			if err := fn(wh); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) < exportBatchSize {
+			break
+		}
+	}
+
+	if len(dangling) > 0 {
+		r.client.ZRem(ctx, indexKey, dangling...)
+	}
+
+	return nil
+}
+
+// IndexKey returns the sorted set indexing a route's webhooks by creation time
+// It takes the route ID as is; Repository.IndexKey applies hash tags
+func IndexKey(routeID string) string {
+	return fmt.Sprintf("%s:%s", indexPrefix, routeID)
+}
+
+// IndexKey returns the sorted set this repository indexes a route's webhooks in, hash-tagged on Redis Cluster
+func (r *Repository) IndexKey(routeID string) string {
+	return IndexKey(r.routeTag(routeID))
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Export_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	routeID := "export-route"
+
+	// One webhook per day, plus one on another route on the same days
+	for i := 0; i < 5; i++ {
+		createdAt := base.AddDate(0, 0, i)
+		for _, route := range []string{routeID, "other-route"} {
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           fmt.Sprintf("%s-%d", route, i),
+				RouteID:      route,
+				Payload:      []byte(fmt.Sprintf(`{"day": %d}`, i)),
+				Headers:      map[string]string{},
+				Status:       webhook.Delivered,
+				MaxRetries:   3,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    createdAt,
+				UpdatedAt:    createdAt,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	ids := func(webhooks []webhook.Webhook) []string {
+		result := make([]string, 0, len(webhooks))
+		for _, wh := range webhooks {
+			result = append(result, wh.ID)
+		}
+		return result
+	}
+
+	t.Run("range is inclusive and ordered by creation time", func(t *testing.T) {
+		webhooks, err := repo.Export(ctx, routeID, base.AddDate(0, 0, 1), base.AddDate(0, 0, 3))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"export-route-1", "export-route-2", "export-route-3"}, ids(webhooks))
+		assert.Equal(t, webhook.Delivered, webhooks[0].Status)
+		assert.Equal(t, `{"day": 1}`, string(webhooks[0].Payload))
+	})
+
+	t.Run("range outside stored webhooks is empty", func(t *testing.T) {
+		webhooks, err := repo.Export(ctx, routeID, base.AddDate(1, 0, 0), base.AddDate(2, 0, 0))
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+	})
+
+	t.Run("expired webhooks are skipped and pruned", func(t *testing.T) {
+		require.NoError(t, repo.SetTTL(ctx, "export-route-0", time.Millisecond))
+		time.Sleep(10 * time.Millisecond)
+
+		webhooks, err := repo.Export(ctx, routeID, base, base.AddDate(0, 0, 4))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"export-route-1", "export-route-2", "export-route-3", "export-route-4"}, ids(webhooks))
+
+		client := createRedisClient(redisContainer.Addr)
+		defer client.Close()
+		score := client.ZScore(ctx, "webhooks:index:"+routeID, "export-route-0")
+		assert.Error(t, score.Err())
+	})
+
+	t.Run("ExportEach stops at the first callback error", func(t *testing.T) {
+		stop := fmt.Errorf("stop")
+		calls := 0
+		err := repo.ExportEach(ctx, routeID, base, base.AddDate(0, 0, 4), func(webhook.Webhook) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := repo.Export(ctx, routeID, base.AddDate(0, 0, 1), base)
+		assert.Error(t, err)
+	})
+}
@@ -13,7 +13,7 @@ import (
 type WorkerHeartbeat struct {
 	WorkerID      string    `json:"worker_id"`
 	RouteID       string    `json:"route_id"`
-	Status        string    `json:"status"` // "idle", "processing"
+	Status        string    `json:"status"` // "idle", "processing", "paused"
 	LastHeartbeat time.Time `json:"last_heartbeat"`
 }
 
@@ -21,7 +21,7 @@ type WorkerHeartbeat struct {
 // The heartbeat key has a TTL of 60 seconds - if a worker doesn't send a heartbeat
 // within that time, it's considered inactive
 func (r *Repository) SetWorkerHeartbeat(ctx context.Context, workerID, routeID, status string) error {
-	key := fmt.Sprintf("worker:heartbeat:%s:%s", routeID, workerID)
+	key := heartbeatKey(workerID, routeID)
 
 	heartbeat := WorkerHeartbeat{
 		WorkerID:      workerID,
@@ -44,18 +44,21 @@ func (r *Repository) SetWorkerHeartbeat(ctx context.Context, workerID, routeID,
 	return nil
 }
 
+// DeleteWorkerHeartbeat removes a worker's heartbeat so it stops counting as active right away
+// Workers call this on shutdown instead of waiting for the TTL to expire
+func (r *Repository) DeleteWorkerHeartbeat(ctx context.Context, workerID, routeID string) error {
+	if err := r.client.Del(ctx, heartbeatKey(workerID, routeID)).Err(); err != nil {
+		return fmt.Errorf("deleting heartbeat: %w", err)
+	}
+	return nil
+}
+
 // GetActiveWorkers retrieves all active workers for a given route
 func (r *Repository) GetActiveWorkers(ctx context.Context, routeID string) ([]WorkerHeartbeat, error) {
 	pattern := fmt.Sprintf("worker:heartbeat:%s:*", routeID)
 	var workers []WorkerHeartbeat
 
-	var cursor uint64
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			return nil, fmt.Errorf("scanning worker keys: %w", err)
-		}
-
+	err := r.scanKeys(ctx, pattern, func(keys []string) error {
 		for _, key := range keys {
 			data, err := r.client.Get(ctx, key).Result()
 			if err == redis.Nil {
@@ -63,7 +66,7 @@ func (r *Repository) GetActiveWorkers(ctx context.Context, routeID string) ([]Wo
 				continue
 			}
 			if err != nil {
-				return nil, fmt.Errorf("getting worker heartbeat: %w", err)
+				return fmt.Errorf("getting worker heartbeat: %w", err)
 			}
 
 			var heartbeat WorkerHeartbeat
@@ -73,11 +76,10 @@ func (r *Repository) GetActiveWorkers(ctx context.Context, routeID string) ([]Wo
 
 			workers = append(workers, heartbeat)
 		}
-
-		cursor = nextCursor
-		if cursor == 0 {
-			break
-		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning worker keys: %w", err)
 	}
 
 	return workers, nil
@@ -88,13 +90,7 @@ func (r *Repository) GetAllActiveWorkers(ctx context.Context) (map[string][]Work
 	pattern := "worker:heartbeat:*"
 	workersByRoute := make(map[string][]WorkerHeartbeat)
 
-	var cursor uint64
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			return nil, fmt.Errorf("scanning worker keys: %w", err)
-		}
-
+	err := r.scanKeys(ctx, pattern, func(keys []string) error {
 		for _, key := range keys {
 			data, err := r.client.Get(ctx, key).Result()
 			if err == redis.Nil {
@@ -102,7 +98,7 @@ func (r *Repository) GetAllActiveWorkers(ctx context.Context) (map[string][]Work
 				continue
 			}
 			if err != nil {
-				return nil, fmt.Errorf("getting worker heartbeat: %w", err)
+				return fmt.Errorf("getting worker heartbeat: %w", err)
 			}
 
 			var heartbeat WorkerHeartbeat
@@ -112,12 +108,16 @@ func (r *Repository) GetAllActiveWorkers(ctx context.Context) (map[string][]Work
 
 			workersByRoute[heartbeat.RouteID] = append(workersByRoute[heartbeat.RouteID], heartbeat)
 		}
-
-		cursor = nextCursor
-		if cursor == 0 {
-			break
-		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning worker keys: %w", err)
 	}
 
 	return workersByRoute, nil
 }
+
+// heartbeatKey builds the key worker:heartbeat:{route_id}:{worker_id}
+func heartbeatKey(workerID, routeID string) string {
+	return fmt.Sprintf("worker:heartbeat:%s:%s", routeID, workerID)
+}
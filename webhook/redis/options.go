@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// DefaultPingTimeout bounds the connection check when WithPingTimeout is not given
+const DefaultPingTimeout = 5 * time.Second
+
+/* DefaultBlockTimeout is how long Consume blocks in XREADGROUP waiting for new webhooks
+ * Shorter blocks let workers notice cancellation sooner but poll Redis more often
+ * when streams are idle; longer blocks reduce Redis load at the cost of slower shutdown.
+ * New webhooks are returned as soon as they arrive whatever the block timeout
+ */
+const DefaultBlockTimeout = 1 * time.Second
+
+// DefaultMaxRedeliveries is how many times Rebalance requeues an entry before dead-lettering it
+const DefaultMaxRedeliveries = 5
+
+// Consumer group start positions (see WithGroupStart)
+const (
+	GroupStartBeginning = "0" // A new group delivers every entry already in the stream (default)
+	GroupStartNew       = "$" // A new group only delivers entries added after it was created
+)
+
+// Option configures optional Repository behavior
+type Option func(*Repository)
+
+// WithPingTimeout sets how long the constructors wait for Redis to answer the initial PING
+func WithPingTimeout(timeout time.Duration) Option {
+	return func(r *Repository) {
+		if timeout > 0 {
+			r.pingTimeout = timeout
+		}
+	}
+}
+
+// WithBlockTimeout sets how long Consume waits for new webhooks before returning empty (default: 1s)
+func WithBlockTimeout(timeout time.Duration) Option {
+	return func(r *Repository) {
+		if timeout > 0 {
+			r.blockTimeout = timeout
+		}
+	}
+}
+
+// WithMaxRedeliveries sets how many times Rebalance requeues an entry before moving its webhook to the DLQ
+// An entry that keeps getting stranded is likely what takes its consumers down; n < 1 removes the limit
+func WithMaxRedeliveries(n int) Option {
+	return func(r *Repository) {
+		r.maxRedeliveries = max(n, 0)
+	}
+}
+
+// WithLogger sets the logger reporting stream entries Consume acknowledges without returning them (default: slog.Default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Repository) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// WithTLSConfig connects to Redis over TLS (e.g. managed services requiring rediss://)
+// The server name is taken from the dialed address unless tlsConfig sets ServerName
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(r *Repository) {
+		r.tlsConfig = tlsConfig
+	}
+}
+
+// WithGroupStart sets where consumer groups created for routes of the given mode begin reading
+// Use GroupStartNew for PubSub routes that shouldn't replay history when their group is (re)created
+// Only applies when a group is created; existing groups keep their position
+func WithGroupStart(mode webhook.DeliveryMode, start string) Option {
+	return func(r *Repository) {
+		if r.groupStart == nil {
+			r.groupStart = make(map[webhook.DeliveryMode]string)
+		}
+		r.groupStart[mode] = start
+	}
+}
+
+// WithRetryPolicy sets how Store, UpdateStatus and IncrementRetry retry transient Redis errors
+// (default: DefaultRetryPolicy); Attempts below 1 are treated as 1
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(r *Repository) {
+		policy.Attempts = max(policy.Attempts, 1)
+		r.retryPolicy = policy
+	}
+}
+
+// WithPayloadCompression gzips stored payloads larger than threshold bytes, trading CPU for Redis memory
+// Payloads are decompressed transparently by Get; stream entries keep the payload uncompressed
+func WithPayloadCompression(threshold int) Option {
+	return func(r *Repository) {
+		r.compressPayloads = true
+		r.compressThreshold = max(threshold, 0)
+	}
+}
+
+// WithRouteGroups stores and consumes grouped routes on their group's streams (e.g. with routes.Loader.GroupOf)
+// groupOf returns a route's group ID, or "" for routes with their own streams. The group's consumer
+// group, streams and schedule are shared by its routes; hashes, indexes and DLQs stay per route
+func WithRouteGroups(groupOf func(routeID string) string) Option {
+	return func(r *Repository) {
+		r.groupOf = groupOf
+	}
+}
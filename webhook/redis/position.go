@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* SetGroupPosition moves a route's consumer group with XGROUP SETID
+ * "$" and "0" move the group on both the normal and the priority stream; an entry ID
+ * belongs to one of them, and only that stream's position changes
+ * Entries pending on consumers stay pending: moving the position only changes what ">" reads next
+ */
+
+// SetGroupPosition makes the route's consumer group read the entries after id next
+func (r *Repository) SetGroupPosition(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, id string) error {
+	streamKeys := []string{r.StreamKey(routeID, deliveryMode), r.priorityStreamKey(routeID, deliveryMode)}
+
+	if id != GroupStartNew && id != GroupStartBeginning {
+		streamKey, err := r.entryStream(ctx, streamKeys, id)
+		if err != nil {
+			return err
+		}
+		streamKeys = []string{streamKey}
+	}
+
+	groupName := r.GroupName(routeID)
+	for _, streamKey := range streamKeys {
+		if err := r.createGroup(ctx, streamKey, routeID, deliveryMode); err != nil {
+			return err
+		}
+		if err := r.client.XGroupSetID(ctx, streamKey, groupName, id).Err(); err != nil {
+			return fmt.Errorf("setting group position: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// entryStream returns which of the streams holds the entry id
+func (r *Repository) entryStream(ctx context.Context, streamKeys []string, id string) (string, error) {
+	for _, streamKey := range streamKeys {
+		messages, err := r.client.XRangeN(ctx, streamKey, id, id, 1).Result()
+		if isInvalidStreamID(err) {
+			return "", fmt.Errorf("%w: %q is not a stream entry ID", webhook.ErrInvalidPosition, id)
+		}
+		if err != nil {
+			return "", fmt.Errorf("looking up entry %s: %w", id, err)
+		}
+		if len(messages) > 0 {
+			return streamKey, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no entry %s in the route's streams", webhook.ErrInvalidPosition, id)
+}
+
+// isInvalidStreamID reports whether Redis rejected an argument as a malformed stream ID
+func isInvalidStreamID(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Invalid stream ID")
+}
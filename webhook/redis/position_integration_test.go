@@ -0,0 +1,90 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_SetGroupPosition_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0, redis.WithBlockTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	routeID := "position-route"
+	var ids []string
+	for i := 0; i < 4; i++ {
+		id, err := repo.Store(ctx, webhook.Webhook{
+			ID:           GenerateID(t, i),
+			RouteID:      routeID,
+			Payload:      []byte(`{"test":"position"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	entries, err := repo.GetClient().XRange(ctx, "webhooks:fifo:"+routeID, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	// consumeIDs reads and acknowledges every webhook available to the group
+	consumeIDs := func(t *testing.T) []string {
+		t.Helper()
+		var consumed []string
+		for {
+			webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+			require.NoError(t, err)
+			if len(webhooks) == 0 {
+				return consumed
+			}
+			for _, wh := range webhooks {
+				consumed = append(consumed, wh.ID)
+				require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+			}
+		}
+	}
+
+	t.Run("consumption resumes after the given entry", func(t *testing.T) {
+		require.NoError(t, repo.SetGroupPosition(ctx, routeID, webhook.FIFO, entries[1].ID))
+
+		assert.Equal(t, ids[2:], consumeIDs(t))
+	})
+
+	t.Run("0 reprocesses the whole stream", func(t *testing.T) {
+		require.NoError(t, repo.SetGroupPosition(ctx, routeID, webhook.FIFO, "0"))
+
+		assert.Equal(t, ids, consumeIDs(t))
+	})
+
+	t.Run("$ skips the backlog", func(t *testing.T) {
+		require.NoError(t, repo.SetGroupPosition(ctx, routeID, webhook.FIFO, entries[0].ID))
+		require.NoError(t, repo.SetGroupPosition(ctx, routeID, webhook.FIFO, "$"))
+
+		assert.Empty(t, consumeIDs(t))
+	})
+
+	t.Run("invalid positions are rejected", func(t *testing.T) {
+		for _, id := range []string{"not-an-id", "1-0", "+"} {
+			err := repo.SetGroupPosition(ctx, routeID, webhook.FIFO, id)
+			assert.ErrorIs(t, err, webhook.ErrInvalidPosition, id)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* High-priority webhooks (webhook.Webhook.Priority) are queued on a second stream per route,
+ * webhooks:{mode}:{route_id}:priority, read by the same consumer group as the normal stream
+ * Consumers drain it before reading the normal stream; each stream keeps its own FIFO order
+ */
+
+const prioritySuffix = "priority" // Priority stream naming: webhooks:{mode}:{route_id}:priority
+
+func (r *Repository) priorityStreamKey(routeID string, mode webhook.DeliveryMode) string {
+	return fmt.Sprintf("%s:%s", r.StreamKey(routeID, mode), prioritySuffix)
+}
+
+// queueKey returns the stream a webhook is queued on
+func (r *Repository) queueKey(wh webhook.Webhook) string {
+	if wh.Priority {
+		return r.priorityStreamKey(wh.RouteID, wh.DeliveryMode)
+	}
+	return r.StreamKey(wh.RouteID, wh.DeliveryMode)
+}
+
+// consumedMessage returns the stream and message ID a webhook was consumed from
+// msgID is empty when the message was already acknowledged or its ID expired
+func (r *Repository) consumedMessage(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, eventID string) (streamKey, msgID string, err error) {
+	var msgIDCmd *redis.StringCmd
+	var priorityCmd *redis.StringCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		msgIDCmd = pipe.Get(ctx, fmt.Sprintf("%s:%s:msgid", hashPrefix, eventID))
+		priorityCmd = pipe.HGet(ctx, fmt.Sprintf("%s:%s", hashPrefix, eventID), "priority")
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return "", "", fmt.Errorf("getting message ID: %w", err)
+	}
+
+	streamKey = r.StreamKey(routeID, deliveryMode)
+	if priorityCmd.Val() == "1" {
+		streamKey = r.priorityStreamKey(routeID, deliveryMode)
+	}
+	return streamKey, msgIDCmd.Val(), nil
+}
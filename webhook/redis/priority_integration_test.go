@@ -0,0 +1,141 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_PriorityStream_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	routeID := "priority-route"
+	newWebhook := func(id string, priority bool) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(fmt.Sprintf(`{"id":%q}`, id)),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			Priority:     priority,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+	store := func(t *testing.T, id string, priority bool) {
+		t.Helper()
+		_, err := repo.Store(ctx, newWebhook(id, priority))
+		require.NoError(t, err)
+	}
+
+	// Normal webhooks are queued first
+	store(t, "normal-1", false)
+	store(t, "normal-2", false)
+	store(t, "urgent-1", true)
+	store(t, "normal-3", false)
+	store(t, "urgent-2", true)
+
+	t.Run("priority is stored", func(t *testing.T) {
+		wh, err := repo.Get(ctx, "urgent-1")
+		require.NoError(t, err)
+		assert.True(t, wh.Priority)
+
+		wh, err = repo.Get(ctx, "normal-1")
+		require.NoError(t, err)
+		assert.False(t, wh.Priority)
+	})
+
+	t.Run("priority stream is drained first, FIFO within each tier", func(t *testing.T) {
+		var order []string
+		for i := 0; i < 5; i++ {
+			webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			order = append(order, webhooks[0].ID)
+			require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+		}
+		assert.Equal(t, []string{"urgent-1", "urgent-2", "normal-1", "normal-2", "normal-3"}, order)
+	})
+
+	t.Run("both streams are acknowledged", func(t *testing.T) {
+		for _, stream := range []string{"webhooks:fifo:priority-route", "webhooks:fifo:priority-route:priority"} {
+			pending, err := repo.GetClient().XPending(ctx, stream, "webhook-workers-priority-route").Result()
+			require.NoError(t, err)
+			assert.Zero(t, pending.Count, stream)
+		}
+
+		depth, err := repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Zero(t, depth)
+	})
+
+	t.Run("a blocked consumer wakes up for priority webhooks", func(t *testing.T) {
+		stored := make(chan error, 1)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_, err := repo.Store(ctx, newWebhook("urgent-3", true))
+			stored <- err
+		}()
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 5*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, <-stored)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "urgent-3", webhooks[0].ID)
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, "urgent-3"))
+	})
+
+	t.Run("a read returns at most one webhook, keeping the other for the next read", func(t *testing.T) {
+		// Consume and acknowledge both webhooks, so their hashes exist but the streams are drained
+		store(t, "urgent-4", true)
+		store(t, "normal-4", false)
+		for i := 0; i < 2; i++ {
+			webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+		}
+
+		// Both streams receive an entry at once while the consumer is blocked
+		added := make(chan error, 1)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_, err := repo.GetClient().TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+				pipe.XAdd(ctx, &goredis.XAddArgs{Stream: "webhooks:fifo:priority-route:priority", Values: map[string]any{"event_id": "urgent-4", "route_id": routeID}})
+				pipe.XAdd(ctx, &goredis.XAddArgs{Stream: "webhooks:fifo:priority-route", Values: map[string]any{"event_id": "normal-4", "route_id": routeID}})
+				return nil
+			})
+			added <- err
+		}()
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 5*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, <-added)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "urgent-4", webhooks[0].ID)
+
+		webhooks, err = repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "normal-4", webhooks[0].ID)
+
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, "urgent-4"))
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, "normal-4"))
+	})
+}
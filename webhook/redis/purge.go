@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+// purgeBatchSize is how many stream entries are read, and keys deleted, per round trip while purging
+const purgeBatchSize = 100
+
+/* PurgeRoute deletes everything stored for a route being decommissioned:
+ * its FIFO and PubSub streams with their consumer group, the route index, DLQ and schedule,
+ * the status counters and every webhook hash, attempt log and message ID of the route
+ * Webhooks are found through the index, the DLQ and the streams, so hashes whose
+ * index entry was pruned are still removed. Purging a missing route is a no-op
+ * Workers still consuming the route must be stopped first, or they recreate its streams
+ * The streams and schedule of a grouped route belong to its group and are kept (see WithRouteGroups)
+ */
+func (r *Repository) PurgeRoute(ctx context.Context, routeID string) error {
+	ids := make(map[string]struct{})
+
+	shared := r.streamRoute(routeID) != routeID
+	sourceKeys := []string{r.IndexKey(routeID), r.DLQKey(routeID)}
+	var streamKeys []string
+	if !shared {
+		sourceKeys = append(sourceKeys, r.scheduledKey(routeID))
+		streamKeys = []string{
+			r.StreamKey(routeID, webhook.FIFO), r.priorityStreamKey(routeID, webhook.FIFO),
+			r.StreamKey(routeID, webhook.PubSub), r.priorityStreamKey(routeID, webhook.PubSub),
+		}
+	}
+
+	for _, key := range sourceKeys {
+		members, err := r.client.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", key, err)
+		}
+		for _, id := range members {
+			ids[id] = struct{}{}
+		}
+	}
+
+	for _, streamKey := range streamKeys {
+		if err := r.streamEventIDs(ctx, streamKey, ids); err != nil {
+			return err
+		}
+	}
+
+	// Keys of a webhook live in different Cluster slots, so each gets its own DEL
+	var keys []string
+	for id := range ids {
+		keys = append(keys, fmt.Sprintf("%s:%s", hashPrefix, id), attemptsKey(id), fmt.Sprintf("%s:%s:msgid", hashPrefix, id))
+	}
+	if err := r.deleteKeys(ctx, keys); err != nil {
+		return fmt.Errorf("deleting webhooks: %w", err)
+	}
+
+	// Deleting a stream also destroys its consumer group
+	routeKeys := append(streamKeys, r.IndexKey(routeID), r.DLQKey(routeID), DeliveriesKey(routeID))
+	if !shared {
+		routeKeys = append(routeKeys, r.scheduledKey(routeID))
+	}
+	for _, status := range countedStatuses {
+		routeKeys = append(routeKeys, StatusCounterKey(routeID, status.String()))
+	}
+	if err := r.deleteKeys(ctx, routeKeys); err != nil {
+		return fmt.Errorf("deleting route keys: %w", err)
+	}
+
+	return nil
+}
+
+// streamEventIDs adds the webhook ID of every entry in a stream to ids
+func (r *Repository) streamEventIDs(ctx context.Context, streamKey string, ids map[string]struct{}) error {
+	start := "-"
+	for {
+		entries, err := r.client.XRangeN(ctx, streamKey, start, "+", purgeBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("reading stream %s: %w", streamKey, err)
+		}
+
+		for _, entry := range entries {
+			if id, ok := entry.Values["event_id"].(string); ok {
+				ids[id] = struct{}{}
+			}
+		}
+
+		if len(entries) < purgeBatchSize {
+			return nil
+		}
+		// Continue after the last entry read
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+// deleteKeys deletes keys one per DEL, pipelined in batches
+func (r *Repository) deleteKeys(ctx context.Context, keys []string) error {
+	for len(keys) > 0 {
+		n := min(len(keys), purgeBatchSize)
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys[:n] {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
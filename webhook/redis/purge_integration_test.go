@@ -0,0 +1,113 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_PurgeRoute_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	newWebhook := func(id, routeID string, mode webhook.DeliveryMode) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(`{"test": "purge"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: mode,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	t.Run("removes every key of the route", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "purge-route"
+		var ids []string
+		for i := 0; i < 5; i++ {
+			wh := newWebhook(fmt.Sprintf("purge-webhook-%d", i), routeID, webhook.FIFO)
+			_, err := repo.Store(ctx, wh)
+			require.NoError(t, err)
+			ids = append(ids, wh.ID)
+		}
+		pubsub := newWebhook("purge-webhook-pubsub", routeID, webhook.PubSub)
+		_, err := repo.Store(ctx, pubsub)
+		require.NoError(t, err)
+		ids = append(ids, pubsub.ID)
+
+		// Leave a consumed message ID, an attempt log, a dead letter and a delivery behind
+		consumed, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, consumed, 1)
+		require.NoError(t, repo.RecordAttempt(ctx, ids[0], webhook.Attempt{Timestamp: time.Now(), StatusCode: 503}))
+		dead, err := repo.Get(ctx, ids[1])
+		require.NoError(t, err)
+		require.NoError(t, repo.MoveToDLQ(ctx, dead))
+		require.NoError(t, repo.RecordDelivery(ctx, routeID, ids[2], time.Now()))
+
+		// Another route is untouched
+		other := newWebhook("other-webhook", "other-route", webhook.FIFO)
+		_, err = repo.Store(ctx, other)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.PurgeRoute(ctx, routeID))
+
+		for _, id := range ids {
+			_, err := repo.Get(ctx, id)
+			assert.ErrorIs(t, err, webhook.ErrNotFound, id)
+			assert.False(t, KeyExists(t, redisContainer.Addr, "webhook:"+id+":attempts"), id)
+			assert.False(t, KeyExists(t, redisContainer.Addr, "webhook:"+id+":msgid"), id)
+		}
+		for _, key := range []string{
+			"webhooks:fifo:" + routeID,
+			"webhooks:pubsub:" + routeID,
+			"webhooks:index:" + routeID,
+			"webhooks:dlq:" + routeID,
+			"metrics:{" + routeID + "}:deliveries",
+			"metrics:{" + routeID + "}:status:pending",
+			"metrics:{" + routeID + "}:status:failed",
+		} {
+			assert.False(t, KeyExists(t, redisContainer.Addr, key), key)
+		}
+
+		client := createRedisClient(redisContainer.Addr)
+		defer client.Close()
+		remaining, err := client.Keys(ctx, "*"+routeID+"*").Result()
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+
+		_, err = repo.Get(ctx, other.ID)
+		require.NoError(t, err)
+		assert.True(t, KeyExists(t, redisContainer.Addr, "webhooks:fifo:other-route"))
+	})
+
+	t.Run("purging twice or an unknown route is a no-op", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		_, err := repo.Store(ctx, newWebhook("purge-twice", "purge-twice-route", webhook.FIFO))
+		require.NoError(t, err)
+
+		require.NoError(t, repo.PurgeRoute(ctx, "purge-twice-route"))
+		require.NoError(t, repo.PurgeRoute(ctx, "purge-twice-route"))
+		require.NoError(t, repo.PurgeRoute(ctx, "never-stored"))
+	})
+}
@@ -0,0 +1,238 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Rebalance hands the pending entries of a consumer that went away back to its group
+ * Consumers only read new entries (">"), so an entry pending on a dead consumer is never
+ * delivered again; requeueing appends a copy to the end of the stream, where any live
+ * consumer picks it up, and acknowledges the original
+ *
+ * Copies carry a redeliveries count; an entry stranded again once it reached the repository's
+ * limit (see WithMaxRedeliveries) is not requeued but acknowledged and its webhook dead-lettered,
+ * so a webhook that keeps crashing its consumers cannot cycle through them forever
+ *
+ * Entries whose webhook already failed (ack_policy keep_pending) are left pending for manual
+ * intervention: they are neither requeued nor dead-lettered but claimed by ParkedConsumer, which
+ * no consumer reads from and which is never rebalanced as idle
+ */
+
+// redeliveriesField counts how many times a stream entry was requeued by Rebalance
+const redeliveriesField = "redeliveries"
+
+// ParkedConsumer owns the pending entries of failed webhooks moved off their consumer by Rebalance
+const ParkedConsumer = "parked"
+
+// requeueScript acknowledges a pending entry and appends a copy of it to the stream
+// Only the call that acknowledged the entry requeues it, so concurrent rebalances don't duplicate it
+// KEYS[1] = stream, ARGV[1] = group, ARGV[2] = entry ID, ARGV[3...] = entry fields and values
+var requeueScript = redis.NewScript(`
+if redis.call('XACK', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('XADD', KEYS[1], '*', unpack(ARGV, 3))
+return 1
+`)
+
+// Rebalance requeues every entry pending on deadConsumer in a route's streams and removes the consumer
+// Returns the number of entries requeued, not counting those dead-lettered for exceeding the redelivery
+// limit or parked for a failed webhook. Requeued entries land behind those added since, and the consumer
+// must really be gone: webhooks it is still delivering would be delivered again
+// Rebalancing ParkedConsumer is a no-op, its entries wait for manual intervention
+func (r *Repository) Rebalance(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, deadConsumer string) (int, error) {
+	if deadConsumer == ParkedConsumer {
+		return 0, nil
+	}
+	groupName := r.GroupName(routeID)
+
+	total := 0
+	for _, streamKey := range []string{r.StreamKey(routeID, deliveryMode), r.priorityStreamKey(routeID, deliveryMode)} {
+		requeued, err := r.requeuePending(ctx, streamKey, groupName, deadConsumer)
+		total += requeued
+		if err != nil {
+			return total, err
+		}
+
+		err = r.client.XGroupDelConsumer(ctx, streamKey, groupName, deadConsumer).Err()
+		if err != nil && !isNoSuchKey(err) && !isNoGroup(err) {
+			return total, fmt.Errorf("removing consumer %s: %w", deadConsumer, err)
+		}
+	}
+
+	return total, nil
+}
+
+// requeuePending requeues the entries pending on a consumer in one stream, oldest first
+func (r *Repository) requeuePending(ctx context.Context, streamKey, groupName, consumer string) (int, error) {
+	requeued := 0
+	for {
+		pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream:   streamKey,
+			Group:    groupName,
+			Start:    "-",
+			End:      "+",
+			Count:    purgeBatchSize,
+			Consumer: consumer,
+		}).Result()
+		if isNoSuchKey(err) || isNoGroup(err) {
+			return requeued, nil
+		}
+		if err != nil {
+			return requeued, fmt.Errorf("getting pending entries of %s: %w", consumer, err)
+		}
+		if len(pending) == 0 {
+			return requeued, nil
+		}
+
+		for _, entry := range pending {
+			messages, err := r.client.XRangeN(ctx, streamKey, entry.ID, entry.ID, 1).Result()
+			if err != nil {
+				return requeued, fmt.Errorf("reading entry %s: %w", entry.ID, err)
+			}
+			if len(messages) == 0 {
+				// Deleted from the stream while pending: nothing left to deliver
+				if err := r.client.XAck(ctx, streamKey, groupName, entry.ID).Err(); err != nil {
+					return requeued, fmt.Errorf("acknowledging deleted entry %s: %w", entry.ID, err)
+				}
+				continue
+			}
+
+			parked, err := r.parkFailed(ctx, streamKey, groupName, messages[0])
+			if err != nil {
+				return requeued, err
+			}
+			if parked {
+				continue
+			}
+
+			values := messages[0].Values
+			redeliveries, _ := strconv.Atoi(fmt.Sprint(values[redeliveriesField]))
+			if r.maxRedeliveries > 0 && redeliveries >= r.maxRedeliveries {
+				if err := r.deadLetterPending(ctx, streamKey, groupName, messages[0]); err != nil {
+					return requeued, err
+				}
+				continue
+			}
+
+			values[redeliveriesField] = redeliveries + 1
+			args := []interface{}{groupName, entry.ID}
+			for field, value := range values {
+				args = append(args, field, value)
+			}
+			moved, err := requeueScript.Run(ctx, r.client, []string{streamKey}, args...).Int()
+			if err != nil {
+				return requeued, fmt.Errorf("requeueing entry %s: %w", entry.ID, err)
+			}
+			requeued += moved
+		}
+	}
+}
+
+// parkFailed hands a pending entry to ParkedConsumer if its webhook failed, reporting whether it did
+// Left on its consumer, the entry would be dropped from the pending entries list with it
+func (r *Repository) parkFailed(ctx context.Context, streamKey, groupName string, msg redis.XMessage) (bool, error) {
+	eventID, _ := msg.Values["event_id"].(string)
+	status, err := r.client.HGet(ctx, fmt.Sprintf("%s:%s", hashPrefix, eventID), "status").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting status of entry %s: %w", msg.ID, err)
+	}
+	if status != webhook.Failed.String() {
+		return false, nil
+	}
+
+	err = r.client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   streamKey,
+		Group:    groupName,
+		Consumer: ParkedConsumer,
+		Messages: []string{msg.ID},
+	}).Err()
+	if err != nil {
+		return false, fmt.Errorf("parking entry %s: %w", msg.ID, err)
+	}
+	return true, nil
+}
+
+// deadLetterPending acknowledges a pending entry and moves its webhook to the DLQ
+// Entries whose webhook is gone are only acknowledged
+func (r *Repository) deadLetterPending(ctx context.Context, streamKey, groupName string, msg redis.XMessage) error {
+	eventID, _ := msg.Values["event_id"].(string)
+	wh, err := r.Get(ctx, eventID)
+	if errors.Is(err, webhook.ErrNotFound) {
+		if err := r.client.XAck(ctx, streamKey, groupName, msg.ID).Err(); err != nil {
+			return fmt.Errorf("acknowledging entry %s: %w", msg.ID, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting webhook of entry %s: %w", msg.ID, err)
+	}
+
+	if err := r.moveToDLQ(ctx, wh, streamKey, msg.ID); err != nil {
+		return fmt.Errorf("dead-lettering entry %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// IdleConsumers returns the consumers of a route's group idle for at least minIdle, sorted by name
+// A consumer counts as idle only if it has not read any of the route's streams for that long;
+// this repository's own consumer and ParkedConsumer are never returned
+func (r *Repository) IdleConsumers(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, minIdle time.Duration) ([]string, error) {
+	groupName := r.GroupName(routeID)
+
+	// Shortest idle time of each consumer across the streams
+	idle := make(map[string]time.Duration)
+	for _, streamKey := range []string{r.StreamKey(routeID, deliveryMode), r.priorityStreamKey(routeID, deliveryMode)} {
+		consumers, err := r.client.XInfoConsumers(ctx, streamKey, groupName).Result()
+		if isNoSuchKey(err) || isNoGroup(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting consumers: %w", err)
+		}
+		for _, consumer := range consumers {
+			if current, ok := idle[consumer.Name]; !ok || consumer.Idle < current {
+				idle[consumer.Name] = consumer.Idle
+			}
+		}
+	}
+
+	var names []string
+	for name, d := range idle {
+		if name != r.consumer && name != ParkedConsumer && d >= minIdle {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RebalanceIdle rebalances every consumer of a route idle for at least minIdle (see IdleConsumers)
+// minIdle should comfortably exceed the block timeout, as a consumer waiting in XREADGROUP ages until the read returns
+func (r *Repository) RebalanceIdle(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, minIdle time.Duration) (int, error) {
+	consumers, err := r.IdleConsumers(ctx, routeID, deliveryMode, minIdle)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, consumer := range consumers {
+		requeued, err := r.Rebalance(ctx, routeID, deliveryMode, consumer)
+		total += requeued
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
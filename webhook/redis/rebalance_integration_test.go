@@ -0,0 +1,233 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Rebalance_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	dead, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-dead")
+	require.NoError(t, err)
+	defer dead.Close(ctx)
+
+	live, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-live")
+	require.NoError(t, err)
+	defer live.Close(ctx)
+
+	routeID := "rebalance-route"
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := live.Store(ctx, webhook.Webhook{
+			ID:           GenerateID(t, i),
+			RouteID:      routeID,
+			Payload:      []byte(`{"test":"rebalance"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// The dead consumer reads two webhooks and never acknowledges them
+	webhooks, err := dead.ConsumeBatch(ctx, routeID, webhook.FIFO, 2)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+
+	// The live consumer handles the third one
+	webhooks, err = live.Consume(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.NoError(t, live.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+
+	t.Run("idle consumers are detected", func(t *testing.T) {
+		time.Sleep(200 * time.Millisecond)
+
+		idle, err := live.IdleConsumers(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"worker-dead"}, idle)
+
+		idle, err = live.IdleConsumers(ctx, routeID, webhook.FIFO, time.Hour)
+		require.NoError(t, err)
+		assert.Empty(t, idle)
+	})
+
+	t.Run("pending entries are requeued", func(t *testing.T) {
+		requeued, err := live.RebalanceIdle(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 2, requeued)
+
+		summary, err := live.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Zero(t, summary.Count)
+
+		// The live consumer gets them back in their original order
+		webhooks, err := live.ConsumeBatch(ctx, routeID, webhook.FIFO, 10)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+		assert.Equal(t, ids[0], webhooks[0].ID)
+		assert.Equal(t, ids[1], webhooks[1].ID)
+
+		for _, wh := range webhooks {
+			require.NoError(t, live.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+		}
+		summary, err = live.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Zero(t, summary.Count)
+	})
+
+	t.Run("the dead consumer is removed", func(t *testing.T) {
+		consumers, err := live.GetClient().XInfoConsumers(ctx, "webhooks:fifo:rebalance-route", "webhook-workers-rebalance-route").Result()
+		require.NoError(t, err)
+		for _, consumer := range consumers {
+			assert.NotEqual(t, "worker-dead", consumer.Name)
+		}
+	})
+
+	t.Run("rebalancing an unknown consumer is a no-op", func(t *testing.T) {
+		requeued, err := live.Rebalance(ctx, routeID, webhook.FIFO, "worker-unknown")
+		require.NoError(t, err)
+		assert.Zero(t, requeued)
+	})
+}
+
+func TestRepository_Rebalance_RedeliveryLimit_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	crashing, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-crashing")
+	require.NoError(t, err)
+	defer crashing.Close(ctx)
+
+	live, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-live", redis.WithMaxRedeliveries(2))
+	require.NoError(t, err)
+	defer live.Close(ctx)
+
+	routeID := "redelivery-route"
+	id, err := live.Store(ctx, webhook.Webhook{
+		ID:           GenerateID(t, 0),
+		RouteID:      routeID,
+		Payload:      []byte(`{"test":"poison"}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	})
+	require.NoError(t, err)
+
+	// Every consumer that reads the webhook dies before acknowledging it
+	for _, want := range []int{1, 1, 0} {
+		webhooks, err := crashing.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, id, webhooks[0].ID)
+
+		requeued, err := live.Rebalance(ctx, routeID, webhook.FIFO, "worker-crashing")
+		require.NoError(t, err)
+		assert.Equal(t, want, requeued)
+	}
+
+	// The third time it is dead-lettered instead of requeued
+	dead, err := live.GetDLQ(ctx, routeID, id)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Failed, dead.Status)
+
+	summary, err := live.PendingSummary(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	assert.Zero(t, summary.Count)
+
+	webhooks, err := crashing.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
+
+func TestRepository_Rebalance_KeepPending_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	dead, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-dead")
+	require.NoError(t, err)
+	defer dead.Close(ctx)
+
+	live, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-live", redis.WithMaxRedeliveries(1))
+	require.NoError(t, err)
+	defer live.Close(ctx)
+
+	// The dead consumer rejected the webhook with ack_policy keep_pending: failed, but still pending
+	routeID := "keep-pending-route"
+	id, err := live.Store(ctx, webhook.Webhook{
+		ID:           GenerateID(t, 0),
+		RouteID:      routeID,
+		Payload:      []byte(`{"test":"keep_pending"}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	})
+	require.NoError(t, err)
+	webhooks, err := dead.Consume(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.NoError(t, dead.UpdateStatus(ctx, id, webhook.Failed))
+	time.Sleep(200 * time.Millisecond)
+
+	t.Run("failed entries are parked, not requeued", func(t *testing.T) {
+		requeued, err := live.RebalanceIdle(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Zero(t, requeued)
+
+		webhooks, err := live.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks, "a failed webhook must not be delivered again")
+
+		summary, err := live.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{redis.ParkedConsumer: 1}, summary.Consumers)
+	})
+
+	t.Run("parked entries are neither rebalanced nor dead-lettered", func(t *testing.T) {
+		time.Sleep(200 * time.Millisecond)
+		idle, err := live.IdleConsumers(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Empty(t, idle)
+
+		requeued, err := live.Rebalance(ctx, routeID, webhook.FIFO, redis.ParkedConsumer)
+		require.NoError(t, err)
+		assert.Zero(t, requeued)
+
+		summary, err := live.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), summary.Count)
+
+		_, err = live.GetDLQ(ctx, routeID, id)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+
+		wh, err := live.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.Failed, wh.Status)
+	})
+}
@@ -4,10 +4,14 @@ package redis_test
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/marcelsud/webhook-inbox/webhook/webhooktest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -80,6 +84,103 @@ func TestRepository_Store_Integration(t *testing.T) {
 		assert.Equal(t, wh.MaxRetries, retrieved.MaxRetries)
 		assert.Equal(t, wh.DeliveryMode, retrieved.DeliveryMode)
 		assert.Equal(t, "user.created", retrieved.Headers["X-Event-Type"])
+		assert.Equal(t, len(wh.Payload), retrieved.PayloadSize)
+	})
+
+	t.Run("stores payload size", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		wh := webhook.Webhook{
+			ID:           "test-webhook-3",
+			RouteID:      "analytics",
+			Payload:      []byte(`{"event": "user.created", "user_id": 123}`),
+			Status:       webhook.Pending,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		size, err := repo.GetClient().HGet(ctx, "webhook:test-webhook-3", "payload_size").Int()
+		require.NoError(t, err)
+		assert.Equal(t, len(wh.Payload), size)
+
+		// Webhooks stored without the field report their payload length
+		require.NoError(t, repo.GetClient().HDel(ctx, "webhook:test-webhook-3", "payload_size").Err())
+		retrieved, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, len(wh.Payload), retrieved.PayloadSize)
+	})
+
+	t.Run("unknown stored status is reported", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		wh := webhook.Webhook{
+			ID:           "test-webhook-corrupt",
+			RouteID:      "analytics",
+			Payload:      []byte(`{"event": "user.created"}`),
+			Status:       webhook.Failed,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		for _, stored := range []string{"canceled", ""} {
+			require.NoError(t, repo.GetClient().HSet(ctx, "webhook:test-webhook-corrupt", "status", stored).Err())
+
+			_, err = repo.Get(ctx, wh.ID)
+			assert.ErrorIs(t, err, webhook.ErrUnknownStatus)
+			assert.ErrorIs(t, err, webhook.ErrCorrupted)
+			assert.NotErrorIs(t, err, webhook.ErrNotFound)
+		}
+	})
+
+	t.Run("consume acknowledges entries it can't deliver", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		client := repo.GetClient()
+		for _, id := range []string{"consume-corrupt", "consume-missing", "consume-valid"} {
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           id,
+				RouteID:      "skipped-route",
+				Payload:      []byte(`{}`),
+				Status:       webhook.Pending,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+		}
+		require.NoError(t, client.HSet(ctx, "webhook:consume-corrupt", "status", "canceled").Err())
+		require.NoError(t, client.Del(ctx, "webhook:consume-missing").Err())
+
+		webhooks, err := repo.Consume(ctx, "skipped-route", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "consume-valid", webhooks[0].ID)
+
+		// Only the delivered webhook is left pending; the corrupted hash is kept for inspection
+		pending, err := client.XPending(ctx, "webhooks:fifo:skipped-route", "webhook-workers-skipped-route").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), pending.Count)
+		exists, err := client.Exists(ctx, "webhook:consume-corrupt").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists)
 	})
 }
 
@@ -177,6 +278,41 @@ func TestRepository_IncrementRetry_Integration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 3, retrieved.RetryCount)
 	})
+
+	t.Run("stops at max retries plus one", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		wh := webhook.Webhook{
+			ID:           "test-webhook-runaway",
+			RouteID:      "test-route",
+			Payload:      []byte(`{"test": "retry"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   2,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, repo.IncrementRetry(ctx, wh.ID))
+		}
+
+		for i := 0; i < 2; i++ {
+			err = repo.IncrementRetry(ctx, wh.ID)
+			assert.ErrorIs(t, err, webhook.ErrRetryLimit)
+		}
+
+		retrieved, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, retrieved.RetryCount)
+	})
 }
 
 func TestRepository_Consume_Integration(t *testing.T) {
@@ -265,6 +401,331 @@ func TestRepository_Consume_Integration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Empty(t, webhooks)
 	})
+
+	t.Run("consume a batch", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "batch-route"
+		var stored []string
+		for i := 0; i < 5; i++ {
+			wh := webhook.Webhook{
+				ID:           webhook.GenerateID(t, i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"type":"test.event"}`),
+				Status:       webhook.Pending,
+				DeliveryMode: webhook.PubSub,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+			_, err := repo.Store(ctx, wh)
+			require.NoError(t, err)
+			stored = append(stored, wh.ID)
+		}
+
+		// Up to count webhooks, oldest first
+		webhooks, err := repo.ConsumeBatch(ctx, routeID, webhook.PubSub, 3)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 3)
+		for i, wh := range webhooks {
+			assert.Equal(t, stored[i], wh.ID)
+		}
+
+		// Only what is left when fewer are available
+		webhooks, err = repo.ConsumeBatch(ctx, routeID, webhook.PubSub, 3)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+
+		// Each webhook is acknowledged on its own
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.PubSub, webhooks[0].ID))
+		pending, err := repo.GetClient().XPending(ctx, "webhooks:pubsub:"+routeID, "webhook-workers-"+routeID).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), pending.Count)
+
+		_, err = repo.ConsumeBatch(ctx, routeID, webhook.PubSub, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("peek shows the next webhooks without consuming them", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "peek-route"
+		peeked, err := repo.Peek(ctx, routeID, webhook.FIFO, 10)
+		require.NoError(t, err)
+		assert.Empty(t, peeked, "missing stream is empty")
+
+		var stored []string
+		for i := 0; i < 3; i++ {
+			id := webhook.GenerateID(t, i)
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           id,
+				RouteID:      routeID,
+				Payload:      []byte(`{"type":"test.event"}`),
+				Status:       webhook.Pending,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+			stored = append(stored, id)
+		}
+
+		peekIDs := func(count int) []string {
+			t.Helper()
+			peeked, err := repo.Peek(ctx, routeID, webhook.FIFO, count)
+			require.NoError(t, err)
+			ids := make([]string, 0, len(peeked))
+			for _, wh := range peeked {
+				ids = append(ids, wh.ID)
+			}
+			return ids
+		}
+
+		assert.Equal(t, stored, peekIDs(10))
+		assert.Equal(t, stored[:2], peekIDs(2))
+		// Peeking twice returns the same webhooks
+		assert.Equal(t, stored, peekIDs(10))
+
+		depth, err := repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), depth, "peeking must not claim webhooks")
+
+		// Consumption still sees every webhook, in order
+		for _, id := range stored {
+			webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			assert.Equal(t, id, webhooks[0].ID)
+			require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, id))
+
+			// Consumed webhooks are no longer next in line
+			assert.NotContains(t, peekIDs(10), id)
+		}
+		assert.Empty(t, peekIDs(10))
+
+		_, err = repo.Peek(ctx, routeID, webhook.FIFO, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("queue depth counts unacknowledged webhooks", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "depth-route"
+		depth, err := repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), depth, "missing stream is empty")
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           webhook.GenerateID(t, i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"type":"test.event"}`),
+				Status:       webhook.Pending,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+		}
+
+		depth, err = repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), depth)
+
+		// Read but unacknowledged webhooks still count
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		depth, err = repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), depth)
+
+		// Acknowledged entries stay in the stream but leave the queue
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+		depth, err = repo.QueueDepth(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), depth)
+	})
+
+	t.Run("group lag counts webhooks not yet read", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "lag-route"
+		lag, err := repo.GroupLag(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), lag, "missing stream has no lag")
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           webhook.GenerateID(t, i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"type":"test.event"}`),
+				Status:       webhook.Pending,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+		}
+
+		lag, err = repo.GroupLag(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), lag)
+
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		lag, err = repo.GroupLag(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), lag)
+
+		// Acknowledged entries stay in the stream but are not lag
+		require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+		lag, err = repo.GroupLag(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), lag)
+
+		length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:"+routeID).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), length)
+	})
+}
+
+func TestRepository_ConsumerName_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	t.Run("distinct consumers share the route group", func(t *testing.T) {
+		repoA, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-a")
+		require.NoError(t, err)
+		defer repoA.Close(ctx)
+
+		repoB, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-b")
+		require.NoError(t, err)
+		defer repoB.Close(ctx)
+
+		routeID := "shared-route"
+		for i := 0; i < 2; i++ {
+			_, err := repoA.Store(ctx, webhook.Webhook{
+				ID:           GenerateID(t, i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"test": "consumer"}`),
+				Headers:      map[string]string{},
+				Status:       webhook.Pending,
+				MaxRetries:   3,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+		}
+
+		webhooksA, err := repoA.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooksA, 1)
+
+		webhooksB, err := repoB.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooksB, 1)
+		assert.NotEqual(t, webhooksA[0].ID, webhooksB[0].ID)
+
+		client := createRedisClient(redisContainer.Addr)
+		defer client.Close()
+
+		consumers, err := client.XInfoConsumers(ctx, "webhooks:fifo:"+routeID, "webhook-workers-"+routeID).Result()
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(consumers))
+		for _, consumer := range consumers {
+			names = append(names, consumer.Name)
+			assert.Equal(t, int64(1), consumer.Pending)
+		}
+		assert.ElementsMatch(t, []string{"worker-a", "worker-b"}, names)
+	})
+
+	t.Run("pending summary counts unacknowledged webhooks per consumer", func(t *testing.T) {
+		repoA, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-a")
+		require.NoError(t, err)
+		defer repoA.Close(ctx)
+
+		repoB, err := redis.NewRepositoryWithConsumer(redisContainer.Addr, "", 0, "worker-b")
+		require.NoError(t, err)
+		defer repoB.Close(ctx)
+
+		routeID := "pending-route"
+		summary, err := repoA.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), summary.Count, "missing stream has nothing pending")
+
+		for i := 0; i < 3; i++ {
+			_, err := repoA.Store(ctx, webhook.Webhook{
+				ID:           GenerateID(t, i),
+				RouteID:      routeID,
+				Payload:      []byte(`{"test": "pending"}`),
+				Headers:      map[string]string{},
+				Status:       webhook.Pending,
+				MaxRetries:   3,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			})
+			require.NoError(t, err)
+		}
+
+		summary, err = repoA.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), summary.Count, "stored webhooks are not pending until read")
+
+		// Read without acknowledging, as a crashed worker would
+		var consumed []webhook.Webhook
+		for _, repo := range []*redis.Repository{repoA, repoA, repoB} {
+			webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			consumed = append(consumed, webhooks[0])
+		}
+
+		summary, err = repoA.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), summary.Count)
+		assert.Equal(t, map[string]int64{"worker-a": 2, "worker-b": 1}, summary.Consumers)
+		assert.NotEmpty(t, summary.LowestID)
+		assert.NotEmpty(t, summary.HighestID)
+		assert.NotEqual(t, summary.LowestID, summary.HighestID)
+
+		require.NoError(t, repoB.Acknowledge(ctx, routeID, webhook.FIFO, consumed[2].ID))
+
+		summary, err = repoA.PendingSummary(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), summary.Count)
+		assert.Equal(t, map[string]int64{"worker-a": 2}, summary.Consumers)
+	})
+
+	t.Run("defaults to hostname and pid", func(t *testing.T) {
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		hostname, err := os.Hostname()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s-%d", hostname, os.Getpid()), repo.ConsumerName())
+	})
 }
 
 func TestRepository_Acknowledge_Integration(t *testing.T) {
@@ -386,6 +847,46 @@ func TestRepository_ErrorCases_Integration(t *testing.T) {
 	})
 }
 
+func TestRepository_Replay_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	service := webhook.NewService(repo)
+
+	routeID := "replay-route"
+	originalID, err := service.Receive(ctx, routeID, webhook.FIFO, []byte(`{"test": "replay"}`), map[string]string{"X-Source": "test"}, 3)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateStatus(ctx, originalID, webhook.Delivered))
+
+	replayedID, err := service.Replay(ctx, routeID, originalID)
+	require.NoError(t, err)
+	assert.NotEqual(t, originalID, replayedID)
+
+	replayed, err := repo.Get(ctx, replayedID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Pending, replayed.Status)
+	assert.Equal(t, `{"test": "replay"}`, string(replayed.Payload))
+	assert.Equal(t, "test", replayed.Headers["X-Source"])
+
+	// Both the original and the replayed webhook are in the stream
+	consumed := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		consumed = append(consumed, webhooks[0].ID)
+	}
+	assert.Equal(t, []string{originalID, replayedID}, consumed)
+
+	_, err = service.Replay(ctx, routeID, "non-existent-id")
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+}
+
 func TestRepository_TTL_Integration(t *testing.T) {
 	ctx := context.Background()
 
@@ -567,3 +1068,238 @@ func TestRepository_TTL_Integration(t *testing.T) {
 		assert.False(t, msgIDExists, "Message ID key should be deleted")
 	})
 }
+
+func TestRepository_TrimStream_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	newWebhook := func(t *testing.T, routeID string, i int) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           webhook.GenerateID(t, i),
+			RouteID:      routeID,
+			Payload:      []byte(`{"test": "trim"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	t.Run("stream length stays bounded after many acknowledged adds", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "trim-route"
+		maxLen := int64(10)
+
+		for i := 0; i < 50; i++ {
+			_, err := repo.Store(ctx, newWebhook(t, routeID, i))
+			require.NoError(t, err)
+
+			webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+			require.NoError(t, err)
+			require.Len(t, webhooks, 1)
+			require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, webhooks[0].ID))
+
+			_, err = repo.TrimStream(ctx, routeID, webhook.FIFO, maxLen)
+			require.NoError(t, err)
+		}
+
+		length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:"+routeID).Result()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, length, maxLen)
+	})
+
+	t.Run("never trims pending or unread entries", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		routeID := "trim-pending-route"
+
+		for i := 0; i < 20; i++ {
+			_, err := repo.Store(ctx, newWebhook(t, routeID, i))
+			require.NoError(t, err)
+		}
+
+		// Consume one without acknowledging - it stays in the PEL
+		webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+
+		trimmed, err := repo.TrimStream(ctx, routeID, webhook.FIFO, 5)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), trimmed)
+
+		length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:"+routeID).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), length)
+	})
+
+	t.Run("error - max length below 1", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		_, err := repo.TrimStream(ctx, "any-route", webhook.FIFO, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max length must be at least 1")
+	})
+}
+
+func TestRepository_Conformance_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	webhooktest.RunRepositorySuite(t, func() webhook.Repository {
+		return CreateTestRepository(t, redisContainer.Addr)
+	})
+}
+
+func TestRepository_BlockTimeout_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0, redis.WithBlockTimeout(10*time.Second))
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	newWebhook := func(id, routeID string) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(`{"test": "block"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	t.Run("long block returns as soon as a webhook arrives", func(t *testing.T) {
+		routeID := "block-route"
+
+		type result struct {
+			webhooks []webhook.Webhook
+			err      error
+		}
+		done := make(chan result, 1)
+		start := time.Now()
+		go func() {
+			webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+			done <- result{webhooks, err}
+		}()
+
+		// Arrive mid-block
+		time.Sleep(500 * time.Millisecond)
+		_, err := repo.Store(ctx, newWebhook("block-webhook-1", routeID))
+		require.NoError(t, err)
+
+		select {
+		case res := <-done:
+			require.NoError(t, res.err)
+			require.Len(t, res.webhooks, 1)
+			assert.Equal(t, "block-webhook-1", res.webhooks[0].ID)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Consume did not return after a webhook arrived")
+		}
+	})
+
+	t.Run("short block returns empty once the timeout elapses", func(t *testing.T) {
+		start := time.Now()
+		webhooks, err := repo.ConsumeWithTimeout(ctx, "idle-block-route", webhook.FIFO, 200*time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("error - non-positive block timeout", func(t *testing.T) {
+		_, err := repo.ConsumeWithTimeout(ctx, "idle-block-route", webhook.FIFO, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "block timeout must be positive")
+	})
+}
+
+func TestRepository_GroupStart_Integration(t *testing.T) {
+	ctx := context.Background()
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	newWebhook := func(id, routeID string) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(`{"type":"test.event"}`),
+			Status:       webhook.Pending,
+			DeliveryMode: webhook.PubSub,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	// seed fills a route's stream, then drops its consumer group as if the route were new to this repository
+	seed := func(t *testing.T, routeID string) {
+		t.Helper()
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		for i := 0; i < 2; i++ {
+			_, err := repo.Store(ctx, newWebhook(GenerateID(t, i), routeID))
+			require.NoError(t, err)
+		}
+
+		client := createRedisClient(redisContainer.Addr)
+		defer client.Close()
+		require.NoError(t, client.XGroupDestroy(ctx, "webhooks:pubsub:"+routeID, "webhook-workers-"+routeID).Err())
+	}
+
+	t.Run("new-messages group ignores pre-existing entries", func(t *testing.T) {
+		routeID := "group-start-new"
+		seed(t, routeID)
+
+		repo, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0, redis.WithGroupStart(webhook.PubSub, redis.GroupStartNew))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.PubSub, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks, "history must not be replayed")
+
+		fresh := newWebhook(GenerateID(t, 99), routeID)
+		_, err = repo.Store(ctx, fresh)
+		require.NoError(t, err)
+
+		webhooks, err = repo.ConsumeWithTimeout(ctx, routeID, webhook.PubSub, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, fresh.ID, webhooks[0].ID)
+	})
+
+	t.Run("default group replays pre-existing entries", func(t *testing.T) {
+		routeID := "group-start-beginning"
+		seed(t, routeID)
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		webhooks, err := repo.ConsumeBatch(ctx, routeID, webhook.PubSub, 10)
+		require.NoError(t, err)
+		assert.Len(t, webhooks, 2)
+	})
+}
@@ -0,0 +1,361 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/config"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/marcelsud/webhook-inbox/webhook/webhooktest"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// silentListener accepts connections but never answers, simulating an unresponsive Redis
+func silentListener(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestNewRepositoryWithContext(t *testing.T) {
+	t.Run("cancelled context fails promptly", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		repo, err := redis.NewRepositoryWithContext(ctx, silentListener(t), "", 0)
+
+		require.Error(t, err)
+		assert.Nil(t, repo)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "connecting to Redis")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("ping timeout option bounds the connection check", func(t *testing.T) {
+		start := time.Now()
+		_, err := redis.NewRepositoryWithContext(context.Background(), silentListener(t), "", 0,
+			redis.WithPingTimeout(100*time.Millisecond))
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+// pingServer is a plaintext listener speaking just enough RESP for the connection check
+func pingServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return serveListener(t, listener)
+}
+
+// tlsPingServer is a TLS listener speaking just enough RESP for the connection check
+// Returns its address and a PEM file with the certificate to trust
+func tlsPingServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	// Reuse httptest's certificate, valid for 127.0.0.1
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := certServer.TLS.Certificates[0]
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certServer.Certificate().Raw})
+	certServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "redis-ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o644))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+
+	return serveListener(t, listener), caFile
+}
+
+// serveListener answers RESP commands on every accepted connection until the test ends
+func serveListener(t *testing.T, listener net.Listener) string {
+	t.Helper()
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRESP(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// serveRESP answers PING with PONG, accepts AUTH and SELECT, and rejects every other command
+func serveRESP(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := webhooktest.ReadRESPCommand(reader)
+		if err != nil {
+			return
+		}
+		reply := "-ERR unknown command\r\n"
+		switch {
+		case len(args) == 0:
+		case strings.EqualFold(args[0], "PING"):
+			reply = "+PONG\r\n"
+		case strings.EqualFold(args[0], "AUTH"), strings.EqualFold(args[0], "SELECT"):
+			reply = "+OK\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func TestNewRepository_TLS(t *testing.T) {
+	ctx := context.Background()
+	addr, caFile := tlsPingServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		RedisHost:       host,
+		RedisPort:       port,
+		RedisTLSEnabled: true,
+		RedisTLSCAFile:  caFile,
+	}
+	tlsConfig, err := cfg.RedisTLSConfig()
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig)
+
+	t.Run("success - TLS config from environment settings", func(t *testing.T) {
+		repo, err := redis.NewRepositoryWithContext(ctx, cfg.RedisAddr(), "", 0,
+			redis.WithTLSConfig(tlsConfig), redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		client, ok := repo.GetClient().(*goredis.Client)
+		require.True(t, ok)
+		assert.NotNil(t, client.Options().TLSConfig)
+	})
+
+	t.Run("success - rediss URL", func(t *testing.T) {
+		repo, err := redis.NewRepositoryFromURL("rediss://"+addr+"/0",
+			redis.WithTLSConfig(tlsConfig), redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		client, ok := repo.GetClient().(*goredis.Client)
+		require.True(t, ok)
+		assert.NotNil(t, client.Options().TLSConfig)
+	})
+
+	t.Run("error - untrusted certificate", func(t *testing.T) {
+		_, err := redis.NewRepositoryWithContext(ctx, addr, "", 0,
+			redis.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}), redis.WithPingTimeout(2*time.Second))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connecting to Redis")
+	})
+
+	t.Run("error - rediss URL with an untrusted certificate", func(t *testing.T) {
+		_, err := redis.NewRepositoryFromURL("rediss://"+addr+"/0", redis.WithPingTimeout(2*time.Second))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connecting to Redis")
+	})
+
+	t.Run("error - plaintext connection to a TLS server", func(t *testing.T) {
+		_, err := redis.NewRepositoryWithContext(ctx, addr, "", 0, redis.WithPingTimeout(500*time.Millisecond))
+		require.Error(t, err)
+	})
+
+	t.Run("error - invalid URL", func(t *testing.T) {
+		_, err := redis.NewRepositoryFromURL("http://" + addr)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing Redis URL")
+	})
+}
+
+func TestNewRepositoryFromURL(t *testing.T) {
+	addr := pingServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		url          string
+		wantAddr     string
+		wantUsername string
+		wantPassword string
+		wantDB       int
+	}{
+		{"host and port", "redis://" + addr, addr, "", "", 0},
+		{"password only", "redis://:secret@" + addr, addr, "", "secret", 0},
+		{"user, password and database", "redis://user:pass@" + addr + "/2", addr, "user", "pass", 2},
+		{"trailing slash", "redis://" + host + ":" + port + "/", addr, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := redis.NewRepositoryFromURL(tt.url, redis.WithPingTimeout(2*time.Second))
+			require.NoError(t, err)
+			defer repo.Close(context.Background())
+
+			client, ok := repo.GetClient().(*goredis.Client)
+			require.True(t, ok)
+			options := client.Options()
+			assert.Equal(t, tt.wantAddr, options.Addr)
+			assert.Equal(t, tt.wantUsername, options.Username)
+			assert.Equal(t, tt.wantPassword, options.Password)
+			assert.Equal(t, tt.wantDB, options.DB)
+			assert.Nil(t, options.TLSConfig)
+		})
+	}
+
+	t.Run("query options are applied", func(t *testing.T) {
+		repo, err := redis.NewRepositoryFromURL("redis://"+addr+"?pool_size=4&dial_timeout=3s", redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(context.Background())
+
+		options := repo.GetClient().(*goredis.Client).Options()
+		assert.Equal(t, 4, options.PoolSize)
+		assert.Equal(t, 3*time.Second, options.DialTimeout)
+	})
+
+	t.Run("error - invalid database", func(t *testing.T) {
+		_, err := redis.NewRepositoryFromURL("redis://" + addr + "/not-a-number")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing Redis URL")
+	})
+
+	t.Run("error - unreachable server", func(t *testing.T) {
+		_, err := redis.NewRepositoryFromURL("redis://"+silentListener(t), redis.WithPingTimeout(100*time.Millisecond))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connecting to Redis")
+	})
+}
+
+func TestNewRepositoryWithPool(t *testing.T) {
+	addr := pingServer(t)
+
+	t.Run("pool settings from config", func(t *testing.T) {
+		cfg := &config.Config{RedisPoolSize: 20, RedisMinIdleConns: 2, RedisPoolTimeout: 2 * time.Second}
+
+		repo, err := redis.NewRepositoryWithPool(addr, "", 0, redis.PoolConfig{
+			Size:         cfg.GetRedisPoolSize(),
+			MinIdleConns: cfg.GetRedisMinIdleConns(),
+			Timeout:      cfg.GetRedisPoolTimeout(),
+		}, redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(context.Background())
+
+		options := repo.GetClient().(*goredis.Client).Options()
+		assert.Equal(t, 20, options.PoolSize)
+		assert.Equal(t, 2, options.MinIdleConns)
+		assert.Equal(t, 2*time.Second, options.PoolTimeout)
+	})
+
+	t.Run("zero values keep go-redis defaults", func(t *testing.T) {
+		repo, err := redis.NewRepositoryWithPool(addr, "", 0, redis.PoolConfig{}, redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(context.Background())
+
+		options := repo.GetClient().(*goredis.Client).Options()
+		assert.Equal(t, 10*runtime.GOMAXPROCS(0), options.PoolSize)
+		assert.Equal(t, 0, options.MinIdleConns)
+		assert.Positive(t, options.PoolTimeout)
+	})
+}
+
+func TestNewRepositoryFromConfig(t *testing.T) {
+	ctx := context.Background()
+	addr := pingServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	t.Run("success - host, port and pool settings", func(t *testing.T) {
+		cfg := &config.Config{RedisHost: host, RedisPort: port, RedisDB: 3, RedisPoolSize: 20, RedisMinIdleConns: 2, RedisPoolTimeout: 2 * time.Second}
+
+		repo, err := redis.NewRepositoryFromConfig(ctx, cfg, redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		options := repo.GetClient().(*goredis.Client).Options()
+		assert.Equal(t, addr, options.Addr)
+		assert.Equal(t, 3, options.DB)
+		assert.Equal(t, 20, options.PoolSize)
+		assert.Equal(t, 2, options.MinIdleConns)
+		assert.Equal(t, 2*time.Second, options.PoolTimeout)
+		assert.Nil(t, options.TLSConfig)
+	})
+
+	t.Run("success - REDIS_URL takes precedence", func(t *testing.T) {
+		cfg := &config.Config{RedisURL: "redis://" + addr + "/2?pool_size=7", RedisHost: "unreachable.invalid", RedisMinIdleConns: 1}
+
+		repo, err := redis.NewRepositoryFromConfig(ctx, cfg, redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		options := repo.GetClient().(*goredis.Client).Options()
+		assert.Equal(t, addr, options.Addr)
+		assert.Equal(t, 2, options.DB)
+		assert.Equal(t, 7, options.PoolSize, "unset pool settings keep the URL's")
+		assert.Equal(t, 1, options.MinIdleConns)
+	})
+
+	t.Run("success - TLS settings", func(t *testing.T) {
+		tlsAddr, caFile := tlsPingServer(t)
+		tlsHost, tlsPort, err := net.SplitHostPort(tlsAddr)
+		require.NoError(t, err)
+		cfg := &config.Config{RedisHost: tlsHost, RedisPort: tlsPort, RedisTLSEnabled: true, RedisTLSCAFile: caFile}
+
+		repo, err := redis.NewRepositoryFromConfig(ctx, cfg, redis.WithPingTimeout(2*time.Second))
+		require.NoError(t, err)
+		defer repo.Close(ctx)
+
+		assert.NotNil(t, repo.GetClient().(*goredis.Client).Options().TLSConfig)
+	})
+
+	t.Run("error - unreadable CA file", func(t *testing.T) {
+		cfg := &config.Config{RedisHost: host, RedisPort: port, RedisTLSEnabled: true, RedisTLSCAFile: filepath.Join(t.TempDir(), "missing.pem")}
+
+		_, err := redis.NewRepositoryFromConfig(ctx, cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "building Redis TLS config")
+	})
+
+	t.Run("error - invalid URL", func(t *testing.T) {
+		_, err := redis.NewRepositoryFromConfig(ctx, &config.Config{RedisURL: "http://" + addr})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing Redis URL")
+	})
+}
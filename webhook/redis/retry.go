@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/* Writes on the ingestion and delivery paths are retried on transient Redis errors proving the
+ * command was not applied, e.g. READONLY replies while a replica is promoted during failover
+ * or a refused connection. A connection dropped after a command was sent may hide a write that
+ * went through, so it is not retried: replaying Store's transaction could queue a webhook twice
+ * Logical errors (redis.Nil, WRONGTYPE, ...) and cancelled contexts are returned immediately
+ */
+
+// RetryPolicy bounds the retries of a write failing with a transient error
+type RetryPolicy struct {
+	Attempts       int           // Total attempts, including the first one (1: never retry)
+	InitialBackoff time.Duration // Wait before the first retry, doubled before every further retry
+	MaxBackoff     time.Duration // Upper bound of the wait between attempts
+}
+
+// DefaultRetryPolicy is used when WithRetryPolicy is not given
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:       3,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     time.Second,
+}
+
+// transientPrefixes are Redis error replies to commands that were rejected, mostly seen during failover or resharding
+var transientPrefixes = []string{"LOADING", "READONLY", "MASTERDOWN", "TRYAGAIN", "CLUSTERDOWN"}
+
+// retry runs fn until it succeeds, fails with a non-transient error or the policy's attempts are exhausted
+// Returns the last error of fn, or the context's error if ctx ends while backing off
+func (r *Repository) retry(ctx context.Context, fn func() error) error {
+	backoff := r.retryPolicy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.retryPolicy.Attempts || !isTransient(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, r.retryPolicy.MaxBackoff)
+	}
+}
+
+// isTransient reports whether err proves the command was not applied, so sending it again is safe
+func isTransient(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, redis.ErrPoolTimeout):
+		// No connection was free, so nothing was sent
+		return true
+	}
+
+	// Commands are only written once connected
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	for _, prefix := range transientPrefixes {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package redis_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replyError is a Redis error reply, as returned by the server
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+func (replyError) RedisError()     {}
+
+/* flakyClient stands in for Redis once the connection check is done:
+ * the first failures commands fail with err, every later one succeeds with an empty reply
+ */
+type flakyClient struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    map[string]int
+}
+
+func (c *flakyClient) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (c *flakyClient) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		return c.reply(cmd.Name(), []goredis.Cmder{cmd})
+	}
+}
+
+func (c *flakyClient) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		return c.reply("pipeline", cmds)
+	}
+}
+
+func (c *flakyClient) reply(name string, cmds []goredis.Cmder) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls[name]++
+	if c.failures == 0 {
+		for _, cmd := range cmds {
+			switch cmd := cmd.(type) {
+			case *goredis.SliceCmd:
+				// HMGET replies with one value per field, nil for missing fields
+				if cmd.Name() == "hmget" {
+					cmd.SetVal(make([]interface{}, len(cmd.Args())-2))
+				}
+			case *goredis.Cmd:
+				// Scripts reply with a number
+				cmd.SetVal(int64(1))
+			}
+		}
+		return nil
+	}
+	c.failures--
+	for _, cmd := range cmds {
+		cmd.SetErr(c.err)
+	}
+	return c.err
+}
+
+// newFlakyRepository returns a repository whose first failures commands fail with err
+func newFlakyRepository(t *testing.T, failures int, err error, opts ...redis.Option) (*redis.Repository, *flakyClient) {
+	t.Helper()
+
+	opts = append([]redis.Option{redis.WithRetryPolicy(redis.RetryPolicy{
+		Attempts:       3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})}, opts...)
+	repo, newErr := redis.NewRepositoryWithContext(context.Background(), pingServer(t), "", 0, opts...)
+	require.NoError(t, newErr)
+	t.Cleanup(func() { repo.Close(context.Background()) })
+
+	client := &flakyClient{failures: failures, err: err, calls: make(map[string]int)}
+	repo.GetClient().AddHook(client)
+	return repo, client
+}
+
+func TestRepository_RetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "route-1",
+		Payload:      []byte(`{}`),
+		Status:       webhook.Pending,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	connRefused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	loading := replyError("LOADING Redis is loading the dataset in memory")
+
+	t.Run("store succeeds after two failures", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 2, connRefused)
+
+		id, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		assert.Equal(t, "evt-1", id)
+		assert.Equal(t, 3, client.calls["xgroup"])
+		assert.Equal(t, 1, client.calls["pipeline"])
+	})
+
+	t.Run("store retries its transaction", func(t *testing.T) {
+		// Scheduled webhooks need no consumer group, so the transaction is the first command
+		scheduled := wh
+		scheduled.DeliverAt = time.Now().Add(time.Hour)
+		repo, client := newFlakyRepository(t, 2, connRefused)
+
+		_, err := repo.Store(ctx, scheduled)
+		require.NoError(t, err)
+		assert.Equal(t, 3, client.calls["pipeline"])
+	})
+
+	t.Run("update status succeeds after two failures", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 2, replyError("READONLY You can't write against a read only replica."))
+
+		require.NoError(t, repo.UpdateStatus(ctx, "evt-1", webhook.Delivered))
+		assert.Equal(t, 3, client.calls["hget"])
+		assert.Equal(t, 1, client.calls["evalsha"])
+	})
+
+	t.Run("increment retry succeeds after two failures", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 2, loading)
+
+		require.NoError(t, repo.IncrementRetry(ctx, "evt-1"))
+		assert.Equal(t, 3, client.calls["evalsha"])
+	})
+
+	t.Run("gives up after the configured attempts", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 10, loading)
+
+		_, err := repo.Store(ctx, wh)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, loading)
+		assert.Equal(t, 3, client.calls["xgroup"])
+		assert.Zero(t, client.calls["pipeline"])
+	})
+
+	t.Run("attempts can be configured", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 10, loading, redis.WithRetryPolicy(redis.RetryPolicy{Attempts: 1}))
+
+		require.Error(t, repo.IncrementRetry(ctx, "evt-1"))
+		assert.Equal(t, 1, client.calls["evalsha"])
+	})
+
+	t.Run("logical errors are not retried", func(t *testing.T) {
+		for _, logical := range []error{goredis.Nil, replyError("WRONGTYPE Operation against a key holding the wrong kind of value")} {
+			repo, client := newFlakyRepository(t, 10, logical)
+
+			err := repo.IncrementRetry(ctx, "evt-1")
+			require.Error(t, err)
+			assert.ErrorContains(t, err, logical.Error())
+			assert.Equal(t, 1, client.calls["evalsha"])
+		}
+	})
+
+	t.Run("errors that may follow an applied command are not retried", func(t *testing.T) {
+		connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		for _, dropped := range []error{io.EOF, io.ErrUnexpectedEOF, connReset} {
+			scheduled := wh
+			scheduled.DeliverAt = time.Now().Add(time.Hour)
+			repo, client := newFlakyRepository(t, 10, dropped)
+
+			_, err := repo.Store(ctx, scheduled)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, dropped)
+			assert.Equal(t, 1, client.calls["pipeline"], "the transaction may have been applied")
+
+			require.Error(t, repo.IncrementRetry(ctx, "evt-1"))
+			assert.Equal(t, 1, client.calls["evalsha"], "the retry count may have been incremented")
+		}
+	})
+
+	t.Run("cancelled context stops retrying", func(t *testing.T) {
+		repo, client := newFlakyRepository(t, 10, loading, redis.WithRetryPolicy(redis.RetryPolicy{
+			Attempts:       5,
+			InitialBackoff: time.Hour,
+			MaxBackoff:     time.Hour,
+		}))
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		err := repo.IncrementRetry(ctx, "evt-1")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, client.calls["evalsha"])
+	})
+}
+
+// groupErrorClient fails XGROUP commands with err; every other command succeeds as on flakyClient
+type groupErrorClient struct {
+	*flakyClient
+}
+
+func (c *groupErrorClient) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		if cmd.Name() == "xgroup" {
+			c.mu.Lock()
+			c.calls["xgroup"]++
+			c.mu.Unlock()
+			cmd.SetErr(c.err)
+			return c.err
+		}
+		return c.reply(cmd.Name(), []goredis.Cmder{cmd})
+	}
+}
+
+func TestRepository_CreateGroupErrors(t *testing.T) {
+	ctx := context.Background()
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "route-1",
+		Payload:      []byte(`{}`),
+		Status:       webhook.Pending,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	newRepository := func(t *testing.T, err error) (*redis.Repository, *groupErrorClient) {
+		repo, newErr := redis.NewRepositoryWithContext(ctx, pingServer(t), "", 0)
+		require.NoError(t, newErr)
+		t.Cleanup(func() { repo.Close(ctx) })
+
+		client := &groupErrorClient{&flakyClient{err: err, calls: make(map[string]int)}}
+		repo.GetClient().AddHook(client)
+		return repo, client
+	}
+	noPerm := replyError("NOPERM User default has no permissions to run the 'xgroup|create' command")
+
+	t.Run("existing group is not an error", func(t *testing.T) {
+		repo, client := newRepository(t, replyError("BUSYGROUP Consumer Group name already exists"))
+
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		assert.Equal(t, 1, client.calls["xgroup"])
+		assert.Equal(t, 1, client.calls["pipeline"])
+	})
+
+	t.Run("store returns other errors", func(t *testing.T) {
+		repo, client := newRepository(t, noPerm)
+
+		_, err := repo.Store(ctx, wh)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, noPerm)
+		assert.ErrorContains(t, err, "creating consumer group webhook-workers-route-1")
+		assert.Zero(t, client.calls["pipeline"], "nothing is stored without a consumer group")
+	})
+
+	t.Run("consume returns other errors", func(t *testing.T) {
+		repo, client := newRepository(t, noPerm)
+
+		_, err := repo.Consume(ctx, "route-1", webhook.FIFO)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, noPerm)
+		assert.Zero(t, client.calls["xreadgroup"])
+	})
+}
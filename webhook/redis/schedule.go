@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/redis/go-redis/v9"
+)
+
+/* Scheduled webhooks wait in a sorted set scored by their delivery time (Unix ms)
+ * instead of the stream. Every Consume first moves the route's due webhooks to
+ * the stream, so they are delivered within one block timeout of becoming due
+ * A script removes a due webhook from the set and adds it to the stream in one step:
+ * when several consumers promote at once, only the one whose ZREM succeeded adds it,
+ * and a crash can't lose it between the two
+ */
+
+const scheduledPrefix = "webhooks:scheduled" // Schedule naming: webhooks:scheduled:{route_id}
+
+// promoteBatchSize is how many due webhooks are moved to the stream per Consume
+const promoteBatchSize = 100
+
+// Schedule parks a consumed webhook until its DeliverAt time and acknowledges its stream entry
+func (r *Repository) Schedule(ctx context.Context, wh webhook.Webhook) error {
+	if err := r.schedule(ctx, wh); err != nil {
+		return err
+	}
+	if err := r.Acknowledge(ctx, wh.RouteID, wh.DeliveryMode, wh.ID); err != nil {
+		return fmt.Errorf("acknowledging scheduled webhook: %w", err)
+	}
+	return nil
+}
+
+// schedule adds a webhook to its route's schedule
+func (r *Repository) schedule(ctx context.Context, wh webhook.Webhook) error {
+	err := r.client.ZAdd(ctx, r.scheduledKey(wh.RouteID), redis.Z{
+		Score:  float64(wh.DeliverAt.UnixMilli()),
+		Member: wh.ID,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("scheduling webhook: %w", err)
+	}
+	return nil
+}
+
+// promoteScript claims a due webhook by removing it from the schedule and appends it to the stream
+// Only the call that removed it adds it, so concurrent consumers don't duplicate it, and it never
+// leaves the schedule without reaching the stream. Both keys share the route's Cluster slot
+// KEYS[1] = schedule, KEYS[2] = stream, ARGV[1] = webhook ID, ARGV[2...] = entry fields and values
+var promoteScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('XADD', KEYS[2], '*', unpack(ARGV, 2))
+return 1
+`)
+
+// promoteDue moves the route's webhooks whose delivery time has come to the stream
+func (r *Repository) promoteDue(ctx context.Context, routeID string) error {
+	key := r.scheduledKey(routeID)
+	ids, err := r.client.ZRangeArgs(ctx, redis.ZRangeArgs{
+		Key:     key,
+		ByScore: true,
+		Start:   "-inf",
+		Stop:    strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Count:   promoteBatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("reading scheduled webhooks: %w", err)
+	}
+
+	for _, id := range ids {
+		wh, err := r.Get(ctx, id)
+		if errors.Is(err, webhook.ErrNotFound) {
+			// Expired or purged while scheduled
+			if err := r.client.ZRem(ctx, key, id).Err(); err != nil {
+				return fmt.Errorf("removing expired scheduled webhook: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.promote(ctx, key, wh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promote moves a scheduled webhook to its route's stream, or its priority stream
+// Does nothing when another consumer promoted it first
+func (r *Repository) promote(ctx context.Context, scheduledKey string, wh webhook.Webhook) error {
+	streamKey := r.queueKey(wh)
+	if err := r.createGroup(ctx, streamKey, wh.RouteID, wh.DeliveryMode); err != nil {
+		return err
+	}
+
+	streamData, err := streamValues(wh)
+	if err != nil {
+		return err
+	}
+	args := []interface{}{wh.ID}
+	for field, value := range streamData {
+		args = append(args, field, value)
+	}
+
+	if err := promoteScript.Run(ctx, r.client, []string{scheduledKey, streamKey}, args...).Err(); err != nil {
+		return fmt.Errorf("promoting scheduled webhook: %w", err)
+	}
+	return nil
+}
+
+// scheduledKey returns the schedule promoted by the consumers of a route's streams
+func (r *Repository) scheduledKey(routeID string) string {
+	return fmt.Sprintf("%s:%s", scheduledPrefix, r.routeTag(r.streamRoute(routeID)))
+}
@@ -0,0 +1,146 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Schedule_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	newWebhook := func(id, routeID string, deliverAt time.Time) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           id,
+			RouteID:      routeID,
+			Payload:      []byte(`{"test": "schedule"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			DeliverAt:    deliverAt,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+	}
+
+	t.Run("future webhooks are not consumed until due", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		deliverAt := time.Now().Add(2 * time.Second)
+		_, err := repo.Store(ctx, newWebhook("scheduled-1", "scheduled-route", deliverAt))
+		require.NoError(t, err)
+
+		stored, err := repo.Get(ctx, "scheduled-1")
+		require.NoError(t, err)
+		assert.Equal(t, deliverAt.UnixMilli(), stored.DeliverAt.UnixMilli())
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, "scheduled-route", webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks, "webhook should wait until its delivery time")
+
+		var consumed []webhook.Webhook
+		require.Eventually(t, func() bool {
+			consumed, err = repo.ConsumeWithTimeout(ctx, "scheduled-route", webhook.FIFO, 100*time.Millisecond)
+			require.NoError(t, err)
+			return len(consumed) > 0
+		}, 5*time.Second, 100*time.Millisecond)
+		assert.False(t, time.Now().Before(deliverAt), "webhook was consumed before its delivery time")
+		assert.Equal(t, "scheduled-1", consumed[0].ID)
+	})
+
+	t.Run("schedule parks a consumed webhook", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		_, err := repo.Store(ctx, newWebhook("scheduled-2", "reschedule-route", time.Time{}))
+		require.NoError(t, err)
+
+		webhooks, err := repo.Consume(ctx, "reschedule-route", webhook.FIFO)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+
+		wh := webhooks[0]
+		wh.DeliverAt = time.Now().Add(time.Second)
+		require.NoError(t, repo.Schedule(ctx, wh))
+
+		depth, err := repo.QueueDepth(ctx, "reschedule-route", webhook.FIFO)
+		require.NoError(t, err)
+		assert.Zero(t, depth, "the stream entry should be acknowledged")
+
+		require.Eventually(t, func() bool {
+			webhooks, err := repo.ConsumeWithTimeout(ctx, "reschedule-route", webhook.FIFO, 100*time.Millisecond)
+			require.NoError(t, err)
+			return len(webhooks) == 1 && webhooks[0].ID == "scheduled-2"
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	t.Run("concurrent consumers promote a due webhook once", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		_, err := repo.Store(ctx, newWebhook("scheduled-3", "promote-route", time.Now().Add(200*time.Millisecond)))
+		require.NoError(t, err)
+		time.Sleep(300 * time.Millisecond)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var consumed []webhook.Webhook
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				webhooks, err := repo.ConsumeWithTimeout(ctx, "promote-route", webhook.FIFO, 100*time.Millisecond)
+				assert.NoError(t, err)
+				mu.Lock()
+				consumed = append(consumed, webhooks...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, consumed, 1)
+		assert.Equal(t, "scheduled-3", consumed[0].ID)
+		length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:promote-route").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), length, "the webhook should be added to the stream once")
+	})
+
+	t.Run("webhooks gone while scheduled leave the schedule", func(t *testing.T) {
+		redisContainer, cleanup := SetupRedisContainer(t, ctx)
+		defer cleanup()
+
+		repo := CreateTestRepository(t, redisContainer.Addr)
+		defer repo.Close(ctx)
+
+		_, err := repo.Store(ctx, newWebhook("scheduled-4", "expired-route", time.Now().Add(100*time.Millisecond)))
+		require.NoError(t, err)
+		require.NoError(t, repo.GetClient().Del(ctx, "webhook:scheduled-4").Err())
+		time.Sleep(200 * time.Millisecond)
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, "expired-route", webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+
+		scheduled, err := repo.GetClient().ZCard(ctx, "webhooks:scheduled:expired-route").Result()
+		require.NoError(t, err)
+		assert.Zero(t, scheduled)
+	})
+}
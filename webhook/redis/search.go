@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+// errSearchDone stops iterating the route index once a search has collected its page
+var errSearchDone = errors.New("search done")
+
+// Search returns the webhooks of a route matching opts, oldest first
+// Candidates are read from the route index within the creation time bounds, then filtered
+// by status, so a selective status filter over a long time range reads many hashes
+func (r *Repository) Search(ctx context.Context, routeID string, opts webhook.SearchOptions) ([]webhook.Webhook, error) {
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, fmt.Errorf("invalid search page: limit %d, offset %d", opts.Limit, opts.Offset)
+	}
+
+	from := time.Unix(0, 0)
+	if !opts.CreatedAfter.IsZero() {
+		from = opts.CreatedAfter
+	}
+	to := time.Now()
+	if !opts.CreatedBefore.IsZero() {
+		to = opts.CreatedBefore
+	}
+
+	webhooks := []webhook.Webhook{}
+	skipped := 0
+	err := r.ExportEach(ctx, routeID, from, to, func(wh webhook.Webhook) error {
+		if !opts.Matches(wh) {
+			return nil
+		}
+		if skipped < opts.Offset {
+			skipped++
+			return nil
+		}
+		webhooks = append(webhooks, wh)
+		if opts.Limit > 0 && len(webhooks) == opts.Limit {
+			return errSearchDone
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSearchDone) {
+		return nil, fmt.Errorf("searching webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_Search_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo := CreateTestRepository(t, redisContainer.Addr)
+	defer repo.Close(ctx)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	routeID := "search-route"
+
+	// One webhook per day alternating between failed and delivered, plus the same on another route
+	statuses := []webhook.Status{webhook.Failed, webhook.Delivered}
+	for i := 0; i < 6; i++ {
+		for _, route := range []string{routeID, "other-route"} {
+			_, err := repo.Store(ctx, webhook.Webhook{
+				ID:           fmt.Sprintf("%s-%d", route, i),
+				RouteID:      route,
+				Payload:      []byte(fmt.Sprintf(`{"day": %d}`, i)),
+				Headers:      map[string]string{},
+				Status:       statuses[i%2],
+				MaxRetries:   3,
+				DeliveryMode: webhook.FIFO,
+				CreatedAt:    base.AddDate(0, 0, i),
+				UpdatedAt:    base.AddDate(0, 0, i),
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	ids := func(webhooks []webhook.Webhook) []string {
+		result := make([]string, 0, len(webhooks))
+		for _, wh := range webhooks {
+			result = append(result, wh.ID)
+		}
+		return result
+	}
+	day := func(i int) string { return fmt.Sprintf("%s-%d", routeID, i) }
+
+	tests := []struct {
+		name string
+		opts webhook.SearchOptions
+		want []string
+	}{
+		{
+			name: "no filters",
+			opts: webhook.SearchOptions{},
+			want: []string{day(0), day(1), day(2), day(3), day(4), day(5)},
+		},
+		{
+			name: "status",
+			opts: webhook.SearchOptions{Status: webhook.Failed},
+			want: []string{day(0), day(2), day(4)},
+		},
+		{
+			name: "status without matches",
+			opts: webhook.SearchOptions{Status: webhook.Retrying},
+			want: []string{},
+		},
+		{
+			name: "created after",
+			opts: webhook.SearchOptions{CreatedAfter: base.AddDate(0, 0, 3)},
+			want: []string{day(3), day(4), day(5)},
+		},
+		{
+			name: "created before",
+			opts: webhook.SearchOptions{CreatedBefore: base.AddDate(0, 0, 2)},
+			want: []string{day(0), day(1)},
+		},
+		{
+			name: "time range",
+			opts: webhook.SearchOptions{CreatedAfter: base.AddDate(0, 0, 1), CreatedBefore: base.AddDate(0, 0, 4)},
+			want: []string{day(1), day(2), day(3)},
+		},
+		{
+			name: "status and time range",
+			opts: webhook.SearchOptions{Status: webhook.Delivered, CreatedAfter: base.AddDate(0, 0, 1), CreatedBefore: base.AddDate(0, 0, 4)},
+			want: []string{day(1), day(3)},
+		},
+		{
+			name: "limit",
+			opts: webhook.SearchOptions{Limit: 2},
+			want: []string{day(0), day(1)},
+		},
+		{
+			name: "offset",
+			opts: webhook.SearchOptions{Offset: 4},
+			want: []string{day(4), day(5)},
+		},
+		{
+			name: "offset and limit apply to matching webhooks",
+			opts: webhook.SearchOptions{Status: webhook.Failed, Offset: 1, Limit: 1},
+			want: []string{day(2)},
+		},
+		{
+			name: "every filter",
+			opts: webhook.SearchOptions{Status: webhook.Failed, CreatedAfter: base.AddDate(0, 0, 1), CreatedBefore: base.AddDate(0, 0, 6), Offset: 1, Limit: 5},
+			want: []string{day(4)},
+		},
+		{
+			name: "offset past the end",
+			opts: webhook.SearchOptions{Offset: 10},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhooks, err := repo.Search(ctx, routeID, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(webhooks))
+		})
+	}
+
+	t.Run("GetByRouteID lists the oldest webhooks", func(t *testing.T) {
+		webhooks, err := repo.GetByRouteID(ctx, routeID, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []string{day(0), day(1), day(2)}, ids(webhooks))
+	})
+
+	t.Run("expired webhooks are skipped", func(t *testing.T) {
+		_, err := repo.GetClient().Del(ctx, "webhook:"+day(0)).Result()
+		require.NoError(t, err)
+
+		webhooks, err := repo.Search(ctx, routeID, webhook.SearchOptions{Status: webhook.Failed})
+		require.NoError(t, err)
+		assert.Equal(t, []string{day(2), day(4)}, ids(webhooks))
+	})
+
+	t.Run("invalid page", func(t *testing.T) {
+		_, err := repo.Search(ctx, routeID, webhook.SearchOptions{Limit: -1})
+		assert.Error(t, err)
+	})
+}
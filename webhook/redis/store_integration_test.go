@@ -0,0 +1,157 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/* crashBeforeExec simulates a process dying in the middle of Store
+ * Once armed, the next transaction is sent to Redis on a separate connection
+ * up to its EXEC, then the connection is closed and the caller sees an error
+ */
+type crashBeforeExec struct {
+	addr  string
+	armed atomic.Bool
+}
+
+func (h *crashBeforeExec) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (h *crashBeforeExec) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return next
+}
+
+func (h *crashBeforeExec) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		if len(cmds) == 0 || cmds[0].Name() != "multi" || !h.armed.CompareAndSwap(true, false) {
+			return next(ctx, cmds)
+		}
+
+		client := createRedisClient(h.addr)
+		defer client.Close()
+		conn := client.Conn()
+		defer conn.Close()
+
+		// Everything but EXEC: the commands are queued, never executed
+		for _, cmd := range cmds[:len(cmds)-1] {
+			if err := conn.Do(ctx, cmd.Args()...).Err(); err != nil {
+				return err
+			}
+		}
+		return io.ErrUnexpectedEOF
+	}
+}
+
+func TestRepository_Store_Atomic_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0,
+		redis.WithRetryPolicy(redis.RetryPolicy{Attempts: 1}))
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	crash := &crashBeforeExec{addr: redisContainer.Addr}
+	repo.GetClient().AddHook(crash)
+
+	client := createRedisClient(redisContainer.Addr)
+	defer client.Close()
+
+	routeID := "atomic-store"
+	wh := webhook.Webhook{
+		ID:           GenerateID(t, 1),
+		RouteID:      routeID,
+		Payload:      []byte(`{"test": "atomic"}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	t.Run("a store interrupted before EXEC writes nothing", func(t *testing.T) {
+		crash.armed.Store(true)
+
+		_, err := repo.Store(ctx, wh)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		_, err = repo.Get(ctx, wh.ID)
+		assert.ErrorIs(t, err, webhook.ErrNotFound, "hash must not exist")
+
+		length, err := client.XLen(ctx, "webhooks:fifo:"+routeID).Result()
+		require.NoError(t, err)
+		assert.Zero(t, length, "stream must not have an entry")
+
+		indexed, err := client.ZCard(ctx, "webhooks:index:"+routeID).Result()
+		require.NoError(t, err)
+		assert.Zero(t, indexed, "index must not list the webhook")
+
+		count, err := client.Get(ctx, redis.StatusCounterKey(routeID, webhook.Pending.String())).Int64()
+		require.ErrorIs(t, err, goredis.Nil, "status counter must not be incremented")
+		assert.Zero(t, count)
+	})
+
+	t.Run("storing again delivers the webhook exactly once", func(t *testing.T) {
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		webhooks, err := repo.ConsumeWithTimeout(ctx, routeID, webhook.FIFO, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, wh.ID, webhooks[0].ID)
+
+		count, err := client.Get(ctx, redis.StatusCounterKey(routeID, webhook.Pending.String())).Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+/* Measure the round trips of Store against a live Redis:
+ *   go test -tags=integration -bench=Store -run=^$ ./webhook/redis/
+ */
+func BenchmarkRepository_Store_Integration(b *testing.B) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(b, ctx)
+	defer cleanup()
+
+	repo, err := redis.NewRepositoryWithContext(ctx, redisContainer.Addr, "", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer repo.Close(ctx)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wh := webhook.Webhook{
+			ID:           fmt.Sprintf("bench-store-%d-%d", i, time.Now().UnixNano()),
+			RouteID:      "bench-store",
+			Payload:      []byte(`{"test": "bench"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		if _, err := repo.Store(ctx, wh); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+)
+
+/* StreamInfo describes a route's stream for capacity planning
+ * Entries stay in the stream after they are acknowledged, until it is trimmed (see max_stream_len),
+ * so the first entry is the oldest one retained, not necessarily one still waiting for delivery
+ */
+type StreamInfo struct {
+	Length       int64
+	FirstEntryID string        // Empty when the stream is empty or doesn't exist
+	LastEntryID  string        // Empty when the stream is empty or doesn't exist
+	OldestAge    time.Duration // Time since the first entry was added (0 when empty)
+}
+
+// StreamInfo returns the length, first and last entry IDs of a route's stream, and the age of its first entry
+// A stream that doesn't exist yet is reported empty
+func (r *Repository) StreamInfo(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode) (StreamInfo, error) {
+	streamKey := r.StreamKey(routeID, deliveryMode)
+
+	xinfo, err := r.client.XInfoStream(ctx, streamKey).Result()
+	if isNoSuchKey(err) {
+		return StreamInfo{}, nil
+	}
+	if err != nil {
+		return StreamInfo{}, fmt.Errorf("getting stream info: %w", err)
+	}
+
+	info := StreamInfo{
+		Length:       xinfo.Length,
+		FirstEntryID: xinfo.FirstEntry.ID,
+		LastEntryID:  xinfo.LastEntry.ID,
+	}
+	if info.FirstEntryID != "" {
+		added, err := EntryTime(info.FirstEntryID)
+		if err != nil {
+			return StreamInfo{}, err
+		}
+		info.OldestAge = max(time.Since(added), 0)
+	}
+	return info, nil
+}
+
+// EntryTime returns when a stream entry was added, read from the milliseconds part of its ID ("<ms>-<seq>")
+func EntryTime(entryID string) (time.Time, error) {
+	ms, _, _ := strings.Cut(entryID, "-")
+	millis, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid stream entry ID %q", entryID)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// OldestUndeliveredAge returns the age of the oldest entry of a route's streams its consumer group hasn't acknowledged
+// Acknowledged entries stay until the stream is trimmed, so this is the oldest of the smallest pending ID (XPENDING)
+// and the first entry after the group's last delivered ID; 0 when every entry was acknowledged
+func (r *Repository) OldestUndeliveredAge(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode) (time.Duration, error) {
+	groupName := r.GroupName(routeID)
+
+	var oldest time.Time
+	for _, streamKey := range []string{r.StreamKey(routeID, deliveryMode), r.priorityStreamKey(routeID, deliveryMode)} {
+		ids, err := r.undeliveredBounds(ctx, streamKey, groupName)
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range ids {
+			added, err := EntryTime(id)
+			if err != nil {
+				return 0, err
+			}
+			if oldest.IsZero() || added.Before(oldest) {
+				oldest = added
+			}
+		}
+	}
+
+	if oldest.IsZero() {
+		return 0, nil
+	}
+	return max(time.Since(oldest), 0), nil
+}
+
+// undeliveredBounds returns the IDs of the oldest pending entry and the oldest unread entry of a stream, when there are any
+// Reading starts at the beginning of the stream while the group doesn't exist yet, like Peek
+func (r *Repository) undeliveredBounds(ctx context.Context, streamKey, groupName string) ([]string, error) {
+	start, hasGroup := "-", false
+	groups, err := r.client.XInfoGroups(ctx, streamKey).Result()
+	if isNoSuchKey(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting consumer groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.Name == groupName {
+			hasGroup = true
+			if group.LastDeliveredID != "0-0" {
+				start = "(" + group.LastDeliveredID
+			}
+		}
+	}
+
+	var ids []string
+	if hasGroup {
+		pending, err := r.client.XPending(ctx, streamKey, groupName).Result()
+		if err != nil && !isNoGroup(err) {
+			return nil, fmt.Errorf("getting pending entries: %w", err)
+		}
+		if err == nil && pending.Count > 0 {
+			ids = append(ids, pending.Lower)
+		}
+	}
+
+	unread, err := r.client.XRangeN(ctx, streamKey, start, "+", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading stream: %w", err)
+	}
+	if len(unread) > 0 {
+		ids = append(ids, unread[0].ID)
+	}
+	return ids, nil
+}
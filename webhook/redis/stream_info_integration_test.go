@@ -0,0 +1,76 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_StreamInfo_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, cleanup := SetupRedisContainer(t, ctx)
+	defer cleanup()
+
+	repo, err := redis.NewRepository(redisContainer.Addr, "", 0)
+	require.NoError(t, err)
+	defer repo.Close(ctx)
+
+	routeID := "info-route"
+	store := func(i int) {
+		_, err := repo.Store(ctx, webhook.Webhook{
+			ID:           GenerateID(t, i),
+			RouteID:      routeID,
+			Payload:      []byte(`{"test":"info"}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("missing stream is empty", func(t *testing.T) {
+		info, err := repo.StreamInfo(ctx, routeID, webhook.FIFO)
+		require.NoError(t, err)
+		assert.Equal(t, redis.StreamInfo{}, info)
+	})
+
+	const gap = 500 * time.Millisecond
+	store(0)
+	time.Sleep(gap)
+	store(1)
+
+	info, err := repo.StreamInfo(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Length)
+	require.NotEmpty(t, info.FirstEntryID)
+	require.NotEqual(t, info.FirstEntryID, info.LastEntryID)
+
+	first, err := redis.EntryTime(info.FirstEntryID)
+	require.NoError(t, err)
+	last, err := redis.EntryTime(info.LastEntryID)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, last.Sub(first), gap)
+
+	// The oldest message is at least as old as the gap between the two
+	assert.GreaterOrEqual(t, info.OldestAge, gap)
+	assert.Less(t, info.OldestAge, gap+5*time.Second)
+
+	// Once the first entry is gone, the second one is the oldest
+	require.NoError(t, repo.GetClient().XDel(ctx, "webhooks:fifo:"+routeID, info.FirstEntryID).Err())
+	trimmed, err := repo.StreamInfo(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), trimmed.Length)
+	assert.Equal(t, info.LastEntryID, trimmed.FirstEntryID)
+	assert.Less(t, trimmed.OldestAge, info.OldestAge)
+}
@@ -25,7 +25,7 @@ type RedisContainer struct {
 }
 
 // SetupRedisContainer creates and starts a Redis testcontainer
-func SetupRedisContainer(t *testing.T, ctx context.Context) (*RedisContainer, func()) {
+func SetupRedisContainer(t testing.TB, ctx context.Context) (*RedisContainer, func()) {
 	t.Helper()
 
 	// Start Redis container
@@ -2,9 +2,22 @@ package webhook
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned when a webhook does not exist (or has expired)
+var ErrNotFound = errors.New("webhook not found")
+
+// ErrCorrupted is returned when a stored webhook can't be decoded, e.g. written by an incompatible version
+var ErrCorrupted = errors.New("corrupted webhook")
+
+// ErrRetryLimit is returned by IncrementRetry when the retry count already exceeds max_retries
+var ErrRetryLimit = errors.New("retry limit reached")
+
+// ErrInvalidPosition is returned by SetGroupPosition for IDs that are not entries of the route's streams
+var ErrInvalidPosition = errors.New("invalid stream position")
+
 /* Small, focused interfaces following "The Go Way"
  * Interfaces abstract behavior, not things
  * Written for users of the API, not just for testing
@@ -16,16 +29,21 @@ type Reader interface {
 	 * This allows for cancellation, timeouts, and shared values
 	 */
 	Get(ctx context.Context, id string) (Webhook, error)
+	// GetByRouteID lists a route's webhooks oldest first; webhooks created at the same time are ordered by ID
 	GetByRouteID(ctx context.Context, routeID string, limit int) ([]Webhook, error)
 }
 
 // Writer provides write operations for webhooks
 type Writer interface {
 	/* Store adds a webhook to the appropriate stream (FIFO or PubSub)
+	 * Webhooks with a future DeliverAt are only added once due
 	 * Returns the webhook ID and any error
 	 */
 	Store(ctx context.Context, webhook Webhook) (string, error)
 	UpdateStatus(ctx context.Context, id string, status Status) error
+	/* IncrementRetry never raises the retry count above max_retries + 1
+	 * Returns ErrRetryLimit instead, so runaway retries end in the failed state
+	 */
 	IncrementRetry(ctx context.Context, id string) error
 	/* SetTTL sets an expiration time on a webhook
 	 * Used to automatically clean up delivered and failed webhooks
@@ -49,6 +67,129 @@ type StreamConsumer interface {
 	Acknowledge(ctx context.Context, routeID string, deliveryMode DeliveryMode, eventID string) error
 }
 
+// BatchConsumer reads several webhooks per call so they can be delivered concurrently
+type BatchConsumer interface {
+	/* ConsumeBatch reads up to count webhooks from the stream for a given route
+	 * Each returned webhook must still be acknowledged individually
+	 */
+	ConsumeBatch(ctx context.Context, routeID string, deliveryMode DeliveryMode, count int) ([]Webhook, error)
+}
+
+// QueueInspector reports how far consumers are behind on a route
+type QueueInspector interface {
+	/* QueueDepth returns the number of webhooks of a route not yet acknowledged
+	 * Counts both webhooks waiting to be read and webhooks being delivered
+	 */
+	QueueDepth(ctx context.Context, routeID string, deliveryMode DeliveryMode) (int64, error)
+}
+
+// PendingInspector reports webhooks delivered to consumers but never acknowledged
+type PendingInspector interface {
+	/* PendingSummary returns how many of a route's webhooks are pending and which consumers hold them
+	 * A consumer holding entries while no worker with its name runs points at a crashed worker
+	 */
+	PendingSummary(ctx context.Context, routeID string, deliveryMode DeliveryMode) (PendingInfo, error)
+}
+
+// GroupPositioner moves where a route's consumers resume reading
+type GroupPositioner interface {
+	/* SetGroupPosition makes the route's consumer group read the entries after id next
+	 * id is a stream entry ID, "$" to skip everything queued or "0" to reprocess the whole stream
+	 * Returns ErrInvalidPosition when id is none of these
+	 */
+	SetGroupPosition(ctx context.Context, routeID string, deliveryMode DeliveryMode, id string) error
+}
+
+// Scheduler holds webhooks back until their DeliverAt time
+type Scheduler interface {
+	/* Schedule parks a consumed webhook that is not due yet
+	 * Its stream entry is acknowledged and the webhook is re-queued once due
+	 */
+	Schedule(ctx context.Context, webhook Webhook) error
+}
+
+// DeadLetterQueue provides operations for webhooks that exhausted their delivery attempts
+type DeadLetterQueue interface {
+	/* MoveToDLQ marks a webhook as failed and parks it in its route's dead letter queue
+	 * Dead-lettered webhooks are kept until replayed or removed
+	 */
+	MoveToDLQ(ctx context.Context, webhook Webhook) error
+	/* ListDLQ returns dead-lettered webhooks for a route, oldest first
+	 * Supports pagination through offset and limit
+	 */
+	ListDLQ(ctx context.Context, routeID string, offset, limit int) ([]Webhook, error)
+	/* GetDLQ returns a single dead-lettered webhook
+	 * Returns ErrNotFound if the webhook is not in the route's queue
+	 */
+	GetDLQ(ctx context.Context, routeID string, id string) (Webhook, error)
+	/* RemoveFromDLQ removes a webhook from its route's dead letter queue and deletes it
+	 * Replaying stores a new copy, so the original isn't kept
+	 */
+	RemoveFromDLQ(ctx context.Context, routeID string, id string) error
+}
+
+// DeadLetterAcknowledger dead-letters webhooks and acknowledges their stream message as a single operation
+type DeadLetterAcknowledger interface {
+	/* MoveToDLQAndAcknowledge is MoveToDLQ followed by Acknowledge, applied atomically
+	 * The message never leaves the pending entries list without the webhook being dead-lettered
+	 */
+	MoveToDLQAndAcknowledge(ctx context.Context, webhook Webhook, deliveryMode DeliveryMode) error
+}
+
+// StatusCounter reads per-route status counts without scanning webhooks
+type StatusCounter interface {
+	/* CountByStatus returns how many of a route's webhooks are in each status, keyed by status name
+	 * Every status is present, with zero when the route has no webhook in it
+	 */
+	CountByStatus(ctx context.Context, routeID string) (map[string]int64, error)
+}
+
+// StatusNotifier streams webhook status transitions as they happen
+type StatusNotifier interface {
+	/* SubscribeStatus returns the status events of a route's webhooks until ctx is cancelled
+	 * The channel is closed when the subscription ends; earlier events are not replayed
+	 */
+	SubscribeStatus(ctx context.Context, routeID string) (<-chan StatusEvent, error)
+}
+
+// Exporter provides bulk reads of a route's webhooks for auditing
+type Exporter interface {
+	/* ExportEach calls fn for every webhook of a route created within [from, to], oldest first
+	 * Webhooks are loaded in batches so callers can stream results without buffering them all
+	 * Iteration stops at the first error returned by fn
+	 */
+	ExportEach(ctx context.Context, routeID string, from, to time.Time, fn func(Webhook) error) error
+}
+
+// Searcher finds a route's webhooks by status and creation time
+type Searcher interface {
+	/* Search returns the webhooks of a route matching opts, oldest first
+	 * Webhooks whose TTL has expired are never returned
+	 */
+	Search(ctx context.Context, routeID string, opts SearchOptions) ([]Webhook, error)
+}
+
+// AttemptLog provides the delivery attempt history of webhooks
+type AttemptLog interface {
+	/* RecordAttempt appends an attempt to a webhook's history
+	 * Only the most recent attempts are kept, and they expire with the webhook
+	 */
+	RecordAttempt(ctx context.Context, id string, attempt Attempt) error
+	/* GetAttempts returns a webhook's attempts, oldest first
+	 * Returns an empty slice when the webhook has no recorded attempts
+	 */
+	GetAttempts(ctx context.Context, id string) ([]Attempt, error)
+}
+
+// Archiver keeps a durable copy of delivered webhooks outside Redis, where they expire with their TTL
+type Archiver interface {
+	/* Archive stores a delivered webhook
+	 * Called before the webhook's TTL is set, so it must be idempotent: a webhook can be archived again
+	 * when the worker fails between archiving and acknowledging it
+	 */
+	Archive(ctx context.Context, webhook Webhook) error
+}
+
 /* Interface composition - combining small interfaces into larger ones
  * This is preferred over large monolithic interfaces
  */
@@ -0,0 +1,21 @@
+package webhook
+
+import "context"
+
+/* Request IDs link a sender's HTTP request to the stored webhook and its delivery logs
+ * The HTTP layer puts the request's X-Request-ID in the context, and Receive stores it
+ */
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns ctx carrying the ID of the request that received a webhook
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID in ctx, or "" when there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
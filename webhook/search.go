@@ -0,0 +1,29 @@
+package webhook
+
+import "time"
+
+/* SearchOptions filters the webhooks of a route
+ * Zero values disable a filter: any status, no time bound, no limit
+ */
+type SearchOptions struct {
+	Status        Status    // Only webhooks currently in this status
+	CreatedAfter  time.Time // Only webhooks created at or after this time
+	CreatedBefore time.Time // Only webhooks created before this time
+	Limit         int       // Maximum number of webhooks returned
+	Offset        int       // Matching webhooks skipped before the first one returned
+}
+
+// Matches reports whether a webhook passes the status and creation time filters
+// Limit and Offset are applied by the caller
+func (o SearchOptions) Matches(wh Webhook) bool {
+	if o.Status != 0 && wh.Status != o.Status {
+		return false
+	}
+	if !o.CreatedAfter.IsZero() && wh.CreatedAt.Before(o.CreatedAfter) {
+		return false
+	}
+	if !o.CreatedBefore.IsZero() && !wh.CreatedAt.Before(o.CreatedBefore) {
+		return false
+	}
+	return true
+}
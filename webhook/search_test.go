@@ -0,0 +1,39 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchOptions_Matches(t *testing.T) {
+	noon := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	failedAtNoon := webhook.Webhook{ID: "evt-1", Status: webhook.Failed, CreatedAt: noon}
+
+	tests := []struct {
+		name string
+		opts webhook.SearchOptions
+		want bool
+	}{
+		{"no filters", webhook.SearchOptions{}, true},
+		{"matching status", webhook.SearchOptions{Status: webhook.Failed}, true},
+		{"other status", webhook.SearchOptions{Status: webhook.Delivered}, false},
+		{"created after is inclusive", webhook.SearchOptions{CreatedAfter: noon}, true},
+		{"created before created after", webhook.SearchOptions{CreatedAfter: noon.Add(time.Second)}, false},
+		{"created before is exclusive", webhook.SearchOptions{CreatedBefore: noon}, false},
+		{"created before created before", webhook.SearchOptions{CreatedBefore: noon.Add(time.Second)}, true},
+		{"within time range", webhook.SearchOptions{CreatedAfter: noon.Add(-time.Hour), CreatedBefore: noon.Add(time.Hour)}, true},
+		{"status and time range", webhook.SearchOptions{Status: webhook.Failed, CreatedAfter: noon.Add(-time.Hour), CreatedBefore: noon.Add(time.Hour)}, true},
+		{"status matches, outside time range", webhook.SearchOptions{Status: webhook.Failed, CreatedAfter: noon.Add(time.Hour)}, false},
+		{"within time range, other status", webhook.SearchOptions{Status: webhook.Pending, CreatedBefore: noon.Add(time.Hour)}, false},
+		{"paging is ignored", webhook.SearchOptions{Limit: 1, Offset: 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.opts.Matches(failedAtNoon))
+		})
+	}
+}
@@ -3,9 +3,14 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /* Service represents the business logic layer
@@ -15,56 +20,197 @@ import (
 // UseCase defines the business operations for webhook management
 type UseCase interface {
 	Receive(ctx context.Context, routeID string, deliveryMode DeliveryMode, payload []byte, headers map[string]string, maxRetries int) (string, error)
+	ReceiveAt(ctx context.Context, routeID string, deliveryMode DeliveryMode, payload []byte, headers map[string]string, maxRetries int, deliverAt time.Time) (string, error)
 	UpdateStatus(ctx context.Context, id string, status Status) error
 	IncrementRetry(ctx context.Context, id string) error
+	Replay(ctx context.Context, routeID string, eventID string) (string, error)
+	Get(ctx context.Context, routeID string, eventID string) (Webhook, error)
 }
 
 type Service struct {
 	Repo Repository
+	// IDGenerator returns the ID of each received webhook (default: random UUID)
+	IDGenerator func() string
+	// IDPrefix returns the prefix prepended to generated IDs of a route (optional)
+	IDPrefix func(routeID string) string
+	// Priority reports whether a route's payload jumps ahead of its routine webhooks (optional)
+	Priority func(routeID string, payload []byte) bool
+	// PayloadSizes records the size of each stored payload (optional)
+	PayloadSizes PayloadSizeRecorder
+	// TracerProvider creates the webhook.ingest spans (default: the global tracer provider)
+	TracerProvider trace.TracerProvider
+	// Clock timestamps received webhooks (default: clock.System)
+	Clock clock.Clock
+}
+
+// PayloadSizeRecorder observes the size of received payloads, e.g. in a histogram
+type PayloadSizeRecorder interface {
+	RecordPayloadSize(ctx context.Context, routeID string, size int)
+}
+
+// ServiceOption configures optional Service behavior
+type ServiceOption func(*Service)
+
+// WithIDGenerator sets the function generating webhook IDs
+// Generated IDs must be non-empty and must not contain '.', which Standard Webhooks signing forbids
+func WithIDGenerator(generate func() string) ServiceOption {
+	return func(s *Service) {
+		s.IDGenerator = generate
+	}
+}
+
+// WithIDPrefix prepends a per-route prefix to generated webhook IDs, e.g. routes.Loader.IDPrefix
+// Prefixes are subject to the same rules as generated IDs
+func WithIDPrefix(prefix func(routeID string) string) ServiceOption {
+	return func(s *Service) {
+		s.IDPrefix = prefix
+	}
+}
+
+// WithPriority queues webhooks whose payload matches on their route's high-priority stream,
+// e.g. routes.Loader.IsPriority
+func WithPriority(priority func(routeID string, payload []byte) bool) ServiceOption {
+	return func(s *Service) {
+		s.Priority = priority
+	}
+}
+
+// WithPayloadSizeRecorder records the size of every stored payload
+func WithPayloadSizeRecorder(recorder PayloadSizeRecorder) ServiceOption {
+	return func(s *Service) {
+		s.PayloadSizes = recorder
+	}
+}
+
+// WithTracerProvider sets the tracer provider used for ingestion spans
+func WithTracerProvider(provider trace.TracerProvider) ServiceOption {
+	return func(s *Service) {
+		s.TracerProvider = provider
+	}
+}
+
+// WithClock sets the clock timestamping received webhooks, e.g. a clock.Fake in tests
+func WithClock(c clock.Clock) ServiceOption {
+	return func(s *Service) {
+		s.Clock = c
+	}
 }
 
 // NewService creates a new webhook service with dependency injection
-func NewService(repo Repository) *Service {
-	return &Service{
-		Repo: repo,
+func NewService(repo Repository, opts ...ServiceOption) *Service {
+	s := &Service{
+		Repo:        repo,
+		IDGenerator: newUUID,
+		Clock:       clock.System,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Receive accepts a new webhook and stores it in the appropriate stream
 func (s *Service) Receive(ctx context.Context, routeID string, deliveryMode DeliveryMode, payload []byte, headers map[string]string, maxRetries int) (string, error) {
+	return s.ReceiveAt(ctx, routeID, deliveryMode, payload, headers, maxRetries, time.Time{})
+}
+
+// ReceiveAt accepts a new webhook to be delivered no earlier than deliverAt (zero: immediately)
+// The webhook.ingest span is a child of any span in ctx and its traceparent is stored with the webhook
+func (s *Service) ReceiveAt(ctx context.Context, routeID string, deliveryMode DeliveryMode, payload []byte, headers map[string]string, maxRetries int, deliverAt time.Time) (id string, err error) {
+	ctx, span := tracerFrom(s.TracerProvider).Start(ctx, "webhook.ingest",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("webhook.route_id", routeID)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	if err := deliveryMode.Validate(); err != nil {
 		return "", fmt.Errorf("validating delivery mode: %w", err)
 	}
 
+	id, err = s.newID(routeID)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(attribute.String("webhook.event_id", id))
+
+	now := clock.OrSystem(s.Clock).Now()
 	webhook := Webhook{
-		ID:           uuid.New().String(),
+		ID:           id,
 		RouteID:      routeID,
 		Payload:      payload,
+		PayloadSize:  len(payload),
 		Headers:      headers,
 		Status:       Pending,
 		RetryCount:   0,
 		MaxRetries:   maxRetries,
+		DeliverAt:    deliverAt,
 		DeliveryMode: deliveryMode,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		Priority:     s.Priority != nil && s.Priority(routeID, payload),
+		TraceParent:  TraceParent(ctx),
+		RequestID:    RequestID(ctx),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 
-	id, err := s.Repo.Store(ctx, webhook)
+	id, err = s.Repo.Store(ctx, webhook)
 	if err != nil {
 		return "", fmt.Errorf("storing webhook: %w", err)
 	}
 
+	if s.PayloadSizes != nil {
+		s.PayloadSizes.RecordPayloadSize(ctx, routeID, webhook.PayloadSize)
+	}
+
 	return id, nil
 }
 
+// newID generates a route's webhook ID and checks it can be used as a Standard Webhooks message ID
+func (s *Service) newID(routeID string) (string, error) {
+	generate := s.IDGenerator
+	if generate == nil {
+		generate = newUUID
+	}
+
+	id := generate()
+	if id == "" {
+		return "", fmt.Errorf("generating webhook ID: empty ID")
+	}
+	if s.IDPrefix != nil {
+		id = s.IDPrefix(routeID) + id
+	}
+	if strings.Contains(id, ".") {
+		return "", fmt.Errorf("generating webhook ID: %q must not contain '.'", id)
+	}
+	return id, nil
+}
+
+// newUUID is the default webhook ID generator
+func newUUID() string {
+	return uuid.New().String()
+}
+
 // UpdateStatus updates the status of a webhook
+// Returns ErrInvalidTransition if the webhook's current status cannot move to status
 func (s *Service) UpdateStatus(ctx context.Context, id string, status Status) error {
 	if err := status.Validate(); err != nil {
 		return fmt.Errorf("validating status: %w", err)
 	}
 
-	err := s.Repo.UpdateStatus(ctx, id, status)
+	current, err := s.Repo.Get(ctx, id)
 	if err != nil {
+		return fmt.Errorf("getting webhook: %w", err)
+	}
+	if !current.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, current.Status, status)
+	}
+
+	if err := s.Repo.UpdateStatus(ctx, id, status); err != nil {
 		return fmt.Errorf("updating webhook status: %w", err)
 	}
 	return nil
@@ -78,3 +224,34 @@ func (s *Service) IncrementRetry(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// Get returns a stored webhook of the given route
+// Returns ErrNotFound if the webhook does not exist or belongs to another route
+func (s *Service) Get(ctx context.Context, routeID string, eventID string) (Webhook, error) {
+	wh, err := s.Repo.Get(ctx, eventID)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("getting webhook: %w", err)
+	}
+	if wh.RouteID != routeID {
+		return Webhook{}, fmt.Errorf("%w: %s", ErrNotFound, eventID)
+	}
+
+	return wh, nil
+}
+
+// Replay re-enqueues a previously received webhook under a fresh ID
+// The payload, headers, delivery mode and max retries are copied from the original
+// Returns ErrNotFound if the webhook does not exist or belongs to another route
+func (s *Service) Replay(ctx context.Context, routeID string, eventID string) (string, error) {
+	original, err := s.Get(ctx, routeID, eventID)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := s.Receive(ctx, routeID, original.DeliveryMode, original.Payload, original.Headers, original.MaxRetries)
+	if err != nil {
+		return "", fmt.Errorf("replaying webhook: %w", err)
+	}
+
+	return id, nil
+}
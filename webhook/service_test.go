@@ -2,12 +2,22 @@ package webhook_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
 	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestReceive(t *testing.T) {
@@ -21,7 +31,7 @@ func TestReceive(t *testing.T) {
 		headers := map[string]string{"Content-Type": "application/json"}
 
 		// Mock expects Store to be called and returns webhook ID
-		repo.On("Store", ctx, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
 			return wh.RouteID == "test-route" &&
 				wh.DeliveryMode == webhook.FIFO &&
 				string(wh.Payload) == string(payload) &&
@@ -44,7 +54,7 @@ func TestReceive(t *testing.T) {
 		payload := []byte(`{"event": "user.created"}`)
 		headers := map[string]string{"X-Event-Type": "user.created"}
 
-		repo.On("Store", ctx, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
 			return wh.DeliveryMode == webhook.PubSub
 		})).Return("webhook-456", nil)
 
@@ -55,6 +65,61 @@ func TestReceive(t *testing.T) {
 		repo.AssertExpectations(t)
 	})
 
+	t.Run("success - scheduled delivery", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+		deliverAt := time.Now().Add(time.Hour)
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.DeliverAt.Equal(deliverAt) && wh.Status == webhook.Pending
+		})).Return("webhook-789", nil)
+
+		id, err := service.ReceiveAt(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3, deliverAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, "webhook-789", id)
+	})
+
+	t.Run("success - timestamps come from the clock", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		now := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+		service := webhook.NewService(repo, webhook.WithClock(clock.NewFake(now)))
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.CreatedAt.Equal(now) && wh.UpdatedAt.Equal(now)
+		})).Return("webhook-clock", nil)
+
+		_, err := service.Receive(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("success - stores the request ID", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.RequestID == "req-123"
+		})).Return("webhook-123", nil)
+
+		_, err := service.Receive(webhook.ContextWithRequestID(ctx, "req-123"), "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("success - immediate delivery by default", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.DeliverAt.IsZero() && wh.Due(time.Now())
+		})).Return("webhook-123", nil)
+
+		_, err := service.Receive(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+
+		require.NoError(t, err)
+	})
+
 	t.Run("invalid delivery mode", func(t *testing.T) {
 		repo := mocks.NewRepository(t)
 		service := webhook.NewService(repo)
@@ -66,6 +131,153 @@ func TestReceive(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "validating delivery mode")
 	})
+
+	t.Run("success - custom ID generator", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		next := 0
+		service := webhook.NewService(repo, webhook.WithIDGenerator(func() string {
+			next++
+			return fmt.Sprintf("msg_%d", next)
+		}))
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.ID == "msg_1"
+		})).Return("msg_1", nil).Once()
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.ID == "msg_2"
+		})).Return("msg_2", nil).Once()
+
+		id, err := service.Receive(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "msg_1", id)
+
+		id, err = service.Receive(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "msg_2", id)
+	})
+
+	t.Run("success - route ID prefix", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		prefixes := map[string]string{"user-events": "user_"}
+		service := webhook.NewService(repo,
+			webhook.WithIDGenerator(func() string { return "msg1" }),
+			webhook.WithIDPrefix(func(routeID string) string { return prefixes[routeID] }),
+		)
+
+		var stored []webhook.Webhook
+		repo.On("Store", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = append(stored, args.Get(1).(webhook.Webhook))
+		}).Return(func(ctx context.Context, wh webhook.Webhook) string { return wh.ID }, nil).Twice()
+
+		id, err := service.Receive(ctx, "user-events", webhook.FIFO, []byte(`{}`), nil, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "user_msg1", id)
+
+		// Routes without a prefix keep the generated ID
+		id, err = service.Receive(ctx, "orders", webhook.FIFO, []byte(`{}`), nil, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "msg1", id)
+
+		require.Len(t, stored, 2)
+		assert.Equal(t, "user_msg1", stored[0].ID)
+		assert.Equal(t, "msg1", stored[1].ID)
+
+		// Prefixed IDs are valid Standard Webhooks message IDs
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+		sig, err := signature.Sign(secret, stored[0].ID, stored[0].CreatedAt, stored[0].Payload)
+		require.NoError(t, err)
+		valid, err := signature.Verify(secret, stored[0].ID, stored[0].CreatedAt, stored[0].Payload, sig)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("success - default IDs are prefixed", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo, webhook.WithIDPrefix(func(string) string { return "user_" }))
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return strings.HasPrefix(wh.ID, "user_") && len(wh.ID) == len("user_")+36
+		})).Return(func(ctx context.Context, wh webhook.Webhook) string { return wh.ID }, nil).Once()
+
+		id, err := service.Receive(ctx, "user-events", webhook.FIFO, []byte(`{}`), nil, 3)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(id, "user_"))
+	})
+
+	t.Run("success - priority webhooks", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo, webhook.WithPriority(func(routeID string, payload []byte) bool {
+			return routeID == "payments" && strings.Contains(string(payload), "payment.failed")
+		}))
+
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.Priority
+		})).Return("evt-1", nil).Once()
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return !wh.Priority
+		})).Return("evt-2", nil).Once()
+
+		_, err := service.Receive(ctx, "payments", webhook.FIFO, []byte(`{"type":"payment.failed"}`), nil, 3)
+		require.NoError(t, err)
+		_, err = service.Receive(ctx, "payments", webhook.FIFO, []byte(`{"type":"payment.succeeded"}`), nil, 3)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid ID prefix", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo, webhook.WithIDPrefix(func(string) string { return "user." }))
+
+		_, err := service.Receive(ctx, "user-events", webhook.FIFO, []byte(`{}`), nil, 3)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "generating webhook ID")
+	})
+
+	t.Run("invalid generated ID", func(t *testing.T) {
+		for _, generated := range []string{"", "msg.1"} {
+			repo := mocks.NewRepository(t)
+			service := webhook.NewService(repo, webhook.WithIDGenerator(func() string { return generated }))
+
+			_, err := service.Receive(ctx, "test-route", webhook.FIFO, []byte(`{}`), nil, 3)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "generating webhook ID")
+		}
+	})
+
+	t.Run("success - records payload size", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		sizes := &payloadSizes{}
+		service := webhook.NewService(repo, webhook.WithPayloadSizeRecorder(sizes))
+
+		payload := []byte(`{"type":"user.created"}`)
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.PayloadSize == len(payload)
+		})).Return("webhook-123", nil).Once()
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(webhook.Webhook) bool { return true })).Return("", errors.New("redis down")).Once()
+
+		_, err := service.Receive(ctx, "test-route", webhook.FIFO, payload, nil, 3)
+		require.NoError(t, err)
+
+		// Payloads that fail to store are not recorded
+		_, err = service.Receive(ctx, "test-route", webhook.FIFO, payload, nil, 3)
+		require.Error(t, err)
+
+		assert.Equal(t, map[string][]int{"test-route": {len(payload)}}, sizes.recorded)
+	})
+}
+
+// payloadSizes records observed payload sizes per route
+type payloadSizes struct {
+	recorded map[string][]int
+}
+
+func (p *payloadSizes) RecordPayloadSize(ctx context.Context, routeID string, size int) {
+	if p.recorded == nil {
+		p.recorded = make(map[string][]int)
+	}
+	p.recorded[routeID] = append(p.recorded[routeID], size)
 }
 
 func TestUpdateStatus(t *testing.T) {
@@ -75,6 +287,7 @@ func TestUpdateStatus(t *testing.T) {
 		repo := mocks.NewRepository(t)
 		service := webhook.NewService(repo)
 
+		repo.On("Get", ctx, "webhook-123").Return(webhook.Webhook{ID: "webhook-123", Status: webhook.Delivering}, nil)
 		repo.On("UpdateStatus", ctx, "webhook-123", webhook.Delivered).Return(nil)
 
 		err := service.UpdateStatus(ctx, "webhook-123", webhook.Delivered)
@@ -83,6 +296,30 @@ func TestUpdateStatus(t *testing.T) {
 		repo.AssertExpectations(t)
 	})
 
+	t.Run("illegal transition", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(webhook.Webhook{ID: "webhook-123", Status: webhook.Delivered}, nil)
+
+		err := service.UpdateStatus(ctx, "webhook-123", webhook.Pending)
+
+		require.ErrorIs(t, err, webhook.ErrInvalidTransition)
+		assert.Contains(t, err.Error(), "delivered to pending")
+		repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("webhook not found", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "missing").Return(webhook.Webhook{}, webhook.ErrNotFound)
+
+		err := service.UpdateStatus(ctx, "missing", webhook.Delivering)
+
+		require.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+
 	t.Run("invalid status", func(t *testing.T) {
 		repo := mocks.NewRepository(t)
 		service := webhook.NewService(repo)
@@ -111,3 +348,142 @@ func TestIncrementRetry(t *testing.T) {
 		repo.AssertExpectations(t)
 	})
 }
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+
+	stored := webhook.Webhook{ID: "webhook-123", RouteID: "test-route", Status: webhook.Delivered}
+
+	t.Run("success", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(stored, nil)
+
+		wh, err := service.Get(ctx, "test-route", "webhook-123")
+
+		require.NoError(t, err)
+		assert.Equal(t, stored, wh)
+	})
+
+	t.Run("webhook from another route is not found", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(stored, nil)
+
+		_, err := service.Get(ctx, "other-route", "webhook-123")
+
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+
+	original := webhook.Webhook{
+		ID:           "webhook-123",
+		RouteID:      "test-route",
+		Payload:      []byte(`{"test": "data"}`),
+		Headers:      map[string]string{"Content-Type": "application/json"},
+		Status:       webhook.Delivered,
+		RetryCount:   2,
+		MaxRetries:   5,
+		DeliveryMode: webhook.PubSub,
+	}
+
+	t.Run("success - enqueues a copy under a new ID", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(original, nil)
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(wh webhook.Webhook) bool {
+			return wh.ID != original.ID &&
+				wh.RouteID == original.RouteID &&
+				string(wh.Payload) == string(original.Payload) &&
+				wh.Headers["Content-Type"] == "application/json" &&
+				wh.DeliveryMode == webhook.PubSub &&
+				wh.Status == webhook.Pending &&
+				wh.RetryCount == 0 &&
+				wh.MaxRetries == 5
+		})).Return("webhook-456", nil)
+
+		id, err := service.Replay(ctx, "test-route", "webhook-123")
+
+		require.NoError(t, err)
+		assert.Equal(t, "webhook-456", id)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "missing").Return(webhook.Webhook{}, webhook.ErrNotFound)
+
+		_, err := service.Replay(ctx, "test-route", "missing")
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+
+	t.Run("webhook from another route is not found", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(original, nil)
+
+		_, err := service.Replay(ctx, "other-route", "webhook-123")
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		repo := mocks.NewRepository(t)
+		service := webhook.NewService(repo)
+
+		repo.On("Get", ctx, "webhook-123").Return(original, nil)
+		repo.On("Store", mock.Anything, webhook.MatchWebhook(func(webhook.Webhook) bool { return true })).Return("", errors.New("redis down"))
+
+		_, err := service.Replay(ctx, "test-route", "webhook-123")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "replaying webhook")
+	})
+}
+
+func TestReceive_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// Span of the sender, received through the traceparent header
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+
+	var stored webhook.Webhook
+	repo := mocks.NewRepository(t)
+	repo.On("Store", mock.Anything, mock.Anything).Return("evt-1", nil).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(webhook.Webhook)
+	})
+
+	service := webhook.NewService(repo, webhook.WithTracerProvider(provider))
+	_, err := service.Receive(ctx, "user-events", webhook.FIFO, []byte(`{}`), nil, 3)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	ingest := spans[0]
+	assert.Equal(t, "webhook.ingest", ingest.Name())
+	assert.Equal(t, remote.SpanID(), ingest.Parent().SpanID())
+	assert.Equal(t, remote.TraceID(), ingest.SpanContext().TraceID())
+
+	// The stored traceparent points at the ingestion span
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", ingest.SpanContext().TraceID(), ingest.SpanContext().SpanID()), stored.TraceParent)
+	parent := trace.SpanContextFromContext(webhook.ContextWithTraceParent(context.Background(), stored.TraceParent))
+	assert.Equal(t, ingest.SpanContext().SpanID(), parent.SpanID())
+}
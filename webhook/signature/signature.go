@@ -4,9 +4,12 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +22,9 @@ const (
 	// SignatureVersion is the version identifier for symmetric signatures
 	SignatureVersion = "v1"
 
+	// SignatureVersionSHA512 tags HMAC-SHA512 signatures, which Standard Webhooks doesn't define
+	SignatureVersionSHA512 = "v1-sha512"
+
 	// MinSecretBytes is the minimum recommended secret size (192 bits)
 	MinSecretBytes = 24
 
@@ -26,6 +32,46 @@ const (
 	MaxSecretBytes = 64
 )
 
+/* Algorithm is the HMAC hash used to sign webhooks
+ * The package-level functions use SHA256, the only algorithm Standard Webhooks defines;
+ * the methods of SHA512 produce and check signatures tagged SignatureVersionSHA512
+ */
+type Algorithm string
+
+// Supported signing algorithms
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+)
+
+// ParseAlgorithm returns the algorithm named s ("" is SHA256)
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch Algorithm(s) {
+	case "", SHA256:
+		return SHA256, nil
+	case SHA512:
+		return SHA512, nil
+	default:
+		return "", fmt.Errorf("unsupported signing algorithm %q (want %s or %s)", s, SHA256, SHA512)
+	}
+}
+
+// Version returns the version tag of the algorithm's signatures
+func (a Algorithm) Version() string {
+	if a == SHA512 {
+		return SignatureVersionSHA512
+	}
+	return SignatureVersion
+}
+
+// newHash returns the hash constructor of the algorithm
+func (a Algorithm) newHash() func() hash.Hash {
+	if a == SHA512 {
+		return sha512.New
+	}
+	return sha256.New
+}
+
 // Secret represents a Standard Webhooks signing secret
 type Secret struct {
 	raw    []byte
@@ -109,6 +155,11 @@ func ParseSignature(sig string) (Signature, error) {
 // Sign creates a Standard Webhooks signature for the given webhook
 // The signed content is: {msgID}.{timestamp}.{payload}
 func Sign(secret Secret, msgID string, timestamp time.Time, payload []byte) (Signature, error) {
+	return SHA256.Sign(secret, msgID, timestamp, payload)
+}
+
+// Sign creates a signature of the given webhook with the algorithm, tagged with its version
+func (a Algorithm) Sign(secret Secret, msgID string, timestamp time.Time, payload []byte) (Signature, error) {
 	// Validate inputs
 	if strings.Contains(msgID, ".") {
 		return Signature{}, fmt.Errorf("message ID must not contain '.'")
@@ -118,27 +169,69 @@ func Sign(secret Secret, msgID string, timestamp time.Time, payload []byte) (Sig
 	timestampStr := strconv.FormatInt(timestamp.Unix(), 10)
 	signedContent := fmt.Sprintf("%s.%s.%s", msgID, timestampStr, payload)
 
-	// Create HMAC-SHA256 signature
-	mac := hmac.New(sha256.New, secret.Bytes())
+	// Create HMAC signature
+	mac := hmac.New(a.newHash(), secret.Bytes())
 	mac.Write([]byte(signedContent))
 	signature := mac.Sum(nil)
 
 	return Signature{
-		Version:   SignatureVersion,
+		Version:   a.Version(),
 		Signature: base64.StdEncoding.EncodeToString(signature),
 	}, nil
 }
 
+// SignMultiple signs the webhook with every secret and returns the space-delimited header value
+// Used during secret rotation so receivers holding either the old or the new secret can verify
+func SignMultiple(secrets []Secret, msgID string, timestamp time.Time, payload []byte) (string, error) {
+	return SHA256.SignMultiple(secrets, msgID, timestamp, payload)
+}
+
+// SignMultiple signs the webhook with every secret using the algorithm (see SignMultiple)
+func (a Algorithm) SignMultiple(secrets []Secret, msgID string, timestamp time.Time, payload []byte) (string, error) {
+	if len(secrets) == 0 {
+		return "", fmt.Errorf("must provide at least one secret")
+	}
+
+	signatures := make([]Signature, 0, len(secrets))
+	for _, secret := range secrets {
+		sig, err := a.Sign(secret, msgID, timestamp, payload)
+		if err != nil {
+			return "", err
+		}
+		signatures = append(signatures, sig)
+	}
+
+	return BuildSignatureHeader(signatures), nil
+}
+
+// SignHex creates a hex-encoded HMAC-SHA256 of the payload alone, keyed with the raw secret bytes
+// This is the GitHub-style signature expected by receivers that don't implement Standard Webhooks
+func SignHex(secret Secret, payload []byte) string {
+	return SHA256.SignHex(secret, payload)
+}
+
+// SignHex creates a hex-encoded HMAC of the payload alone using the algorithm (see SignHex)
+func (a Algorithm) SignHex(secret Secret, payload []byte) string {
+	mac := hmac.New(a.newHash(), secret.Bytes())
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // Verify verifies a webhook signature using constant-time comparison
 // Returns true if the signature is valid, false otherwise
 func Verify(secret Secret, msgID string, timestamp time.Time, payload []byte, expectedSig Signature) (bool, error) {
-	// Only support v1 signatures
-	if expectedSig.Version != SignatureVersion {
+	return SHA256.Verify(secret, msgID, timestamp, payload, expectedSig)
+}
+
+// Verify verifies a signature made with the algorithm
+// Signatures tagged with another algorithm's version are rejected with an error
+func (a Algorithm) Verify(secret Secret, msgID string, timestamp time.Time, payload []byte, expectedSig Signature) (bool, error) {
+	if expectedSig.Version != a.Version() {
 		return false, fmt.Errorf("unsupported signature version: %s", expectedSig.Version)
 	}
 
 	// Generate the expected signature
-	calculatedSig, err := Sign(secret, msgID, timestamp, payload)
+	calculatedSig, err := a.Sign(secret, msgID, timestamp, payload)
 	if err != nil {
 		return false, fmt.Errorf("calculating signature: %w", err)
 	}
@@ -161,6 +254,12 @@ func Verify(secret Secret, msgID string, timestamp time.Time, payload []byte, ex
 // VerifyMultiple verifies a webhook against multiple signatures (for secret rotation)
 // Returns true if any of the signatures is valid
 func VerifyMultiple(secrets []Secret, msgID string, timestamp time.Time, payload []byte, signatures []Signature) (bool, error) {
+	return SHA256.VerifyMultiple(secrets, msgID, timestamp, payload, signatures)
+}
+
+// VerifyMultiple verifies a webhook against multiple signatures made with the algorithm
+// Signatures of other algorithms are skipped
+func (a Algorithm) VerifyMultiple(secrets []Secret, msgID string, timestamp time.Time, payload []byte, signatures []Signature) (bool, error) {
 	if len(secrets) == 0 || len(signatures) == 0 {
 		return false, fmt.Errorf("must provide at least one secret and one signature")
 	}
@@ -168,7 +267,7 @@ func VerifyMultiple(secrets []Secret, msgID string, timestamp time.Time, payload
 	// Try each signature against each secret
 	for _, sig := range signatures {
 		for _, secret := range secrets {
-			valid, err := Verify(secret, msgID, timestamp, payload, sig)
+			valid, err := a.Verify(secret, msgID, timestamp, payload, sig)
 			if err != nil {
 				// Log error but continue trying other combinations
 				continue
@@ -222,3 +321,17 @@ func BuildSignatureHeader(signatures []Signature) string {
 	}
 	return strings.Join(parts, " ")
 }
+
+// AppendSignature adds a signature to an existing webhook-signature header, e.g. when a proxy
+// verifies a webhook and re-signs it for the next hop with its own secret
+// A header that can't be parsed is kept as is, ahead of the new signature
+func AppendSignature(header string, sig Signature) string {
+	signatures, err := ParseSignatureHeader(header)
+	if err != nil {
+		if header = strings.TrimSpace(header); header != "" {
+			return header + " " + sig.String()
+		}
+		return sig.String()
+	}
+	return BuildSignatureHeader(append(signatures, sig))
+}
@@ -125,6 +125,97 @@ func TestSign(t *testing.T) {
 	})
 }
 
+func TestSignMultiple(t *testing.T) {
+	oldSecret, err := GenerateSecret(32)
+	require.NoError(t, err)
+	newSecret, err := GenerateSecret(32)
+	require.NoError(t, err)
+
+	msgID := "msg_test123"
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"type":"test.event","timestamp":"2024-01-01T12:00:00Z","data":{"foo":"bar"}}`)
+
+	t.Run("success - one signature per secret", func(t *testing.T) {
+		header, err := SignMultiple([]Secret{newSecret, oldSecret}, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		newSig, err := Sign(newSecret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		oldSig, err := Sign(oldSecret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		assert.Equal(t, newSig.String()+" "+oldSig.String(), header)
+	})
+
+	t.Run("success - receiver with only the old secret verifies the combined header", func(t *testing.T) {
+		header, err := SignMultiple([]Secret{newSecret, oldSecret}, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		signatures, err := ParseSignatureHeader(header)
+		require.NoError(t, err)
+		require.Len(t, signatures, 2)
+
+		valid, err := VerifyMultiple([]Secret{oldSecret}, msgID, timestamp, payload, signatures)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = VerifyMultiple([]Secret{newSecret}, msgID, timestamp, payload, signatures)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("success - single secret matches Sign", func(t *testing.T) {
+		header, err := SignMultiple([]Secret{newSecret}, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		sig, err := Sign(newSecret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		assert.Equal(t, sig.String(), header)
+	})
+
+	t.Run("error - no secrets", func(t *testing.T) {
+		_, err := SignMultiple(nil, msgID, timestamp, payload)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must provide at least one secret")
+	})
+
+	t.Run("error - invalid message ID", func(t *testing.T) {
+		_, err := SignMultiple([]Secret{newSecret, oldSecret}, "msg.invalid", timestamp, payload)
+		require.Error(t, err)
+	})
+}
+
+func TestSignHex(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		payload []byte
+		want    string
+	}{
+		{
+			// RFC 4231 test case 4: key 0x01..0x19, data 50 bytes of 0xcd
+			name:    "RFC 4231 HMAC-SHA256 vector",
+			secret:  "whsec_AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGQ==",
+			payload: []byte(strings.Repeat("\xcd", 50)),
+			want:    "82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b",
+		},
+		{
+			name:    "signs the payload alone",
+			secret:  "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+			payload: []byte(`{"test": 2432232314}`),
+			want:    "e2cb4f5251572539d458bfb8a7adb533b08801e6a794467e06f771debbb0818b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := ParseSecret(tt.secret)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, SignHex(secret, tt.payload))
+		})
+	}
+}
+
 func TestVerify(t *testing.T) {
 	secret, err := GenerateSecret(32)
 	require.NoError(t, err)
@@ -330,3 +421,133 @@ func TestBuildSignatureHeader(t *testing.T) {
 		assert.Equal(t, "v1,dGVzdA== v1a,YW5vdGhlcg==", header)
 	})
 }
+
+func TestAppendSignature(t *testing.T) {
+	sig := Signature{Version: "v1", Signature: "bmV3"}
+
+	t.Run("empty header", func(t *testing.T) {
+		assert.Equal(t, "v1,bmV3", AppendSignature("", sig))
+		assert.Equal(t, "v1,bmV3", AppendSignature("   ", sig))
+	})
+
+	t.Run("single signature", func(t *testing.T) {
+		assert.Equal(t, "v1,dGVzdA== v1,bmV3", AppendSignature("v1,dGVzdA==", sig))
+	})
+
+	t.Run("multiple signatures", func(t *testing.T) {
+		header := AppendSignature("  v1,dGVzdA==   v1a,YW5vdGhlcg== ", sig)
+		assert.Equal(t, "v1,dGVzdA== v1a,YW5vdGhlcg== v1,bmV3", header)
+
+		sigs, err := ParseSignatureHeader(header)
+		require.NoError(t, err)
+		assert.Equal(t, sig, sigs[2])
+	})
+
+	t.Run("unparseable header is kept", func(t *testing.T) {
+		assert.Equal(t, "invalid v1,bmV3", AppendSignature("invalid", sig))
+	})
+
+	t.Run("chained signing verifies at every hop", func(t *testing.T) {
+		first, err := GenerateSecret(32)
+		require.NoError(t, err)
+		second, err := GenerateSecret(32)
+		require.NoError(t, err)
+		timestamp := time.Now()
+		payload := []byte(`{"type":"user.created"}`)
+
+		sig1, err := Sign(first, "msg_1", timestamp, payload)
+		require.NoError(t, err)
+		sig2, err := Sign(second, "msg_1", timestamp, payload)
+		require.NoError(t, err)
+		sigs, err := ParseSignatureHeader(AppendSignature(sig1.String(), sig2))
+		require.NoError(t, err)
+
+		for _, secret := range []Secret{first, second} {
+			ok, err := VerifyMultiple([]Secret{secret}, "msg_1", timestamp, payload, sigs)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		}
+	})
+}
+
+func TestAlgorithm(t *testing.T) {
+	secret, err := ParseSecret("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	require.NoError(t, err)
+	msgID := "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	timestamp := time.Unix(1614265330, 0)
+	payload := []byte(`{"test": 2432232314}`)
+
+	t.Run("parse", func(t *testing.T) {
+		for name, want := range map[string]Algorithm{"": SHA256, "sha256": SHA256, "sha512": SHA512} {
+			alg, err := ParseAlgorithm(name)
+			require.NoError(t, err, name)
+			assert.Equal(t, want, alg)
+		}
+
+		_, err := ParseAlgorithm("md5")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported signing algorithm "md5"`)
+	})
+
+	t.Run("sha256 is the default", func(t *testing.T) {
+		sig, err := SHA256.Sign(secret, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		def, err := Sign(secret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		assert.Equal(t, def, sig)
+		assert.Equal(t, SignatureVersion, sig.Version)
+	})
+
+	for _, alg := range []Algorithm{SHA256, SHA512} {
+		t.Run("sign and verify with "+string(alg), func(t *testing.T) {
+			sig, err := alg.Sign(secret, msgID, timestamp, payload)
+			require.NoError(t, err)
+			assert.Equal(t, alg.Version(), sig.Version)
+
+			valid, err := alg.Verify(secret, msgID, timestamp, payload, sig)
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, err = alg.Verify(secret, msgID, timestamp, []byte(`{"test": 1}`), sig)
+			require.NoError(t, err)
+			assert.False(t, valid)
+		})
+	}
+
+	t.Run("sha512 signatures are 64 bytes", func(t *testing.T) {
+		sig, err := SHA512.Sign(secret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		assert.Equal(t, "v1-sha512", sig.Version)
+		assert.Len(t, sig.Signature, 88) // base64 of 64 bytes
+		assert.Len(t, SHA512.SignHex(secret, payload), 128)
+	})
+
+	t.Run("mismatched algorithms are rejected", func(t *testing.T) {
+		sig512, err := SHA512.Sign(secret, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		// The default verifier doesn't accept SHA512 signatures...
+		valid, err := Verify(secret, msgID, timestamp, payload, sig512)
+		require.Error(t, err)
+		assert.False(t, valid)
+
+		// ...nor a SHA512 MAC relabeled as v1
+		relabeled := Signature{Version: SignatureVersion, Signature: sig512.Signature}
+		valid, err = Verify(secret, msgID, timestamp, payload, relabeled)
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		// A SHA512 verifier skips SHA256 signatures
+		sig256, err := Sign(secret, msgID, timestamp, payload)
+		require.NoError(t, err)
+		valid, err = SHA512.VerifyMultiple([]Secret{secret}, msgID, timestamp, payload, []Signature{sig256})
+		require.NoError(t, err)
+		assert.False(t, valid)
+
+		// Both can travel in one header during a migration
+		valid, err = SHA512.VerifyMultiple([]Secret{secret}, msgID, timestamp, payload, []Signature{sig256, sig512})
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
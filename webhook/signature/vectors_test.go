@@ -0,0 +1,101 @@
+package signature
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/* Known-answer vectors for Standard Webhooks signatures
+ * The first vector is the example from the Standard Webhooks specification, also used by
+ * the official libraries; the others were computed with Python's hmac module
+ * Any change to the signed content ({msgID}.{timestamp}.{payload}) breaks these vectors
+ */
+var signatureVectors = []struct {
+	name      string
+	secret    string
+	msgID     string
+	timestamp int64
+	payload   string
+	expected  string
+}{
+	{
+		name:      "specification example",
+		secret:    "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+		msgID:     "msg_p5jXN8AQM9LWM0D4loKWxJek",
+		timestamp: 1614265330,
+		payload:   `{"test": 2432232314}`,
+		expected:  "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+	},
+	{
+		name:      "minimum size secret and empty payload",
+		secret:    "whsec_ZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXp7",
+		msgID:     "msg_2KWPBgLlAfxdpx2AI54pPJ85f4W",
+		timestamp: 1700000000,
+		payload:   ``,
+		expected:  "v1,PsL+3nLf6f028281yLAeTzR4mIQVKPOlrdXsH7V5ajA=",
+	},
+	{
+		name:      "maximum size secret and UTF-8 payload",
+		secret:    "whsec_AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw==",
+		msgID:     "evt_01HGW1A8Y0A6QZ3F9T2V5K7M8N",
+		timestamp: 1704110400,
+		payload:   `{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{"name":"Zoë","emoji":"🎉"}}`,
+		expected:  "v1,uXSRZsuZR2HFPiBl4dgcCBMiT9XLfz/VEFueAgFGPWE=",
+	},
+	{
+		name:      "UUID message ID, epoch timestamp and escaped characters",
+		secret:    "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw",
+		msgID:     "a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d",
+		timestamp: 0,
+		payload:   `{"nested":{"list":[1,2,3]},"text":"line1\nline2"}`,
+		expected:  "v1,xu22eo/DJXm5rqrMRG6vWYRkm2bq6e55/Jtpa4UaksU=",
+	},
+}
+
+func TestSignatureVectors(t *testing.T) {
+	for _, v := range signatureVectors {
+		t.Run(v.name, func(t *testing.T) {
+			secret, err := ParseSecret(v.secret)
+			require.NoError(t, err)
+			timestamp := time.Unix(v.timestamp, 0)
+
+			sig, err := Sign(secret, v.msgID, timestamp, []byte(v.payload))
+			require.NoError(t, err)
+			assert.Equal(t, v.expected, sig.String())
+
+			expected, err := ParseSignature(v.expected)
+			require.NoError(t, err)
+			valid, err := Verify(secret, v.msgID, timestamp, []byte(v.payload), expected)
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			// The vector only verifies the exact signed content
+			valid, err = Verify(secret, v.msgID, timestamp.Add(time.Second), []byte(v.payload), expected)
+			require.NoError(t, err)
+			assert.False(t, valid, "timestamp is signed")
+			valid, err = Verify(secret, v.msgID+"x", timestamp, []byte(v.payload), expected)
+			require.NoError(t, err)
+			assert.False(t, valid, "message ID is signed")
+			valid, err = Verify(secret, v.msgID, timestamp, []byte(v.payload+" "), expected)
+			require.NoError(t, err)
+			assert.False(t, valid, "payload is signed")
+		})
+	}
+}
+
+func TestSignatureVectors_Header(t *testing.T) {
+	v := signatureVectors[0]
+	secret, err := ParseSecret(v.secret)
+	require.NoError(t, err)
+
+	// Receivers accept a header where any signature matches
+	signatures, err := ParseSignatureHeader("v1,Zm9vYmFy " + v.expected)
+	require.NoError(t, err)
+
+	valid, err := VerifyMultiple([]Secret{secret}, v.msgID, time.Unix(v.timestamp, 0), []byte(v.payload), signatures)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,92 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Standard Webhooks request headers
+const (
+	HeaderID        = "webhook-id"
+	HeaderTimestamp = "webhook-timestamp"
+	HeaderSignature = "webhook-signature"
+)
+
+// DefaultTolerance is how far a webhook's timestamp may drift from the receiver's clock
+// Bounds replays of captured requests; it is the tolerance recommended by Standard Webhooks
+const DefaultTolerance = 5 * time.Minute
+
+// Errors returned by VerifyRequest; each failure wraps exactly one of them
+var (
+	ErrMissingHeader     = errors.New("missing webhook header")
+	ErrInvalidTimestamp  = errors.New("invalid webhook timestamp")
+	ErrTimestampTooOld   = errors.New("webhook timestamp too old")
+	ErrTimestampTooNew   = errors.New("webhook timestamp too new")
+	ErrInvalidSignature  = errors.New("invalid webhook signature header")
+	ErrSignatureMismatch = errors.New("no matching webhook signature")
+)
+
+// VerifyRequest checks the Standard Webhooks headers of a received request against its raw body
+// The timestamp must be within tolerance of now (DefaultTolerance when tolerance <= 0), and
+// any signature of the webhook-signature header may match, so rotated secrets keep verifying
+// Returns nil when the request is authentic, or an error wrapping one of the Err* sentinels
+func VerifyRequest(secret Secret, header http.Header, body []byte, tolerance time.Duration) error {
+	return SHA256.VerifyRequest(secret, header, body, tolerance)
+}
+
+// VerifyRequestAt is VerifyRequest with the tolerance window centered on now instead of the system time
+// Use it with a clock.Clock for deterministic tests, or to verify requests replayed from storage
+func VerifyRequestAt(secret Secret, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	return SHA256.VerifyRequestAt(secret, header, body, tolerance, now)
+}
+
+// VerifyRequest checks a received request signed with the algorithm (see VerifyRequest)
+func (a Algorithm) VerifyRequest(secret Secret, header http.Header, body []byte, tolerance time.Duration) error {
+	return a.VerifyRequestAt(secret, header, body, tolerance, time.Now())
+}
+
+// VerifyRequestAt checks a received request signed with the algorithm as of now (see VerifyRequestAt)
+func (a Algorithm) VerifyRequestAt(secret Secret, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	for _, name := range []string{HeaderID, HeaderTimestamp, HeaderSignature} {
+		if header.Get(name) == "" {
+			return fmt.Errorf("%w: %s", ErrMissingHeader, name)
+		}
+	}
+	msgID := header.Get(HeaderID)
+	timestampHeader := header.Get(HeaderTimestamp)
+	signatureHeader := header.Get(HeaderSignature)
+
+	seconds, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidTimestamp, timestampHeader)
+	}
+	timestamp := time.Unix(seconds, 0)
+
+	if timestamp.Before(now.Add(-tolerance)) {
+		return fmt.Errorf("%w: %s", ErrTimestampTooOld, timestamp.UTC().Format(time.RFC3339))
+	}
+	if timestamp.After(now.Add(tolerance)) {
+		return fmt.Errorf("%w: %s", ErrTimestampTooNew, timestamp.UTC().Format(time.RFC3339))
+	}
+
+	signatures, err := ParseSignatureHeader(signatureHeader)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	valid, err := a.VerifyMultiple([]Secret{secret}, msgID, timestamp, body, signatures)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignatureMismatch, err)
+	}
+	if !valid {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package signature
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	secret, err := GenerateSecret(32)
+	require.NoError(t, err)
+
+	msgID := "msg_test123"
+	payload := []byte(`{"type":"test.event","timestamp":"2024-01-01T12:00:00Z","data":{"foo":"bar"}}`)
+
+	// signedHeader returns the headers a webhook sent at timestamp would carry
+	signedHeader := func(t *testing.T, secrets []Secret, timestamp time.Time) http.Header {
+		t.Helper()
+		sig, err := SignMultiple(secrets, msgID, timestamp, payload)
+		require.NoError(t, err)
+
+		header := http.Header{}
+		header.Set(HeaderID, msgID)
+		header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+		header.Set(HeaderSignature, sig)
+		return header
+	}
+
+	t.Run("success - valid request", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now())
+
+		assert.NoError(t, VerifyRequest(secret, header, payload, DefaultTolerance))
+	})
+
+	t.Run("success - any signature may match during rotation", func(t *testing.T) {
+		previous, err := GenerateSecret(32)
+		require.NoError(t, err)
+		header := signedHeader(t, []Secret{previous, secret}, time.Now())
+
+		assert.NoError(t, VerifyRequest(secret, header, payload, DefaultTolerance))
+	})
+
+	t.Run("success - zero tolerance uses the default", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now().Add(-time.Minute))
+
+		assert.NoError(t, VerifyRequest(secret, header, payload, 0))
+	})
+
+	t.Run("success - tolerance is measured against the given time", func(t *testing.T) {
+		signedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		header := signedHeader(t, []Secret{secret}, signedAt)
+
+		assert.NoError(t, VerifyRequestAt(secret, header, payload, DefaultTolerance, signedAt.Add(4*time.Minute)))
+		assert.ErrorIs(t, VerifyRequestAt(secret, header, payload, DefaultTolerance, signedAt.Add(6*time.Minute)), ErrTimestampTooOld)
+	})
+
+	t.Run("error - missing headers", func(t *testing.T) {
+		for _, name := range []string{HeaderID, HeaderTimestamp, HeaderSignature} {
+			header := signedHeader(t, []Secret{secret}, time.Now())
+			header.Del(name)
+
+			err := VerifyRequest(secret, header, payload, DefaultTolerance)
+			require.ErrorIs(t, err, ErrMissingHeader, name)
+			assert.Contains(t, err.Error(), name)
+		}
+	})
+
+	t.Run("error - invalid timestamp", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now())
+		header.Set(HeaderTimestamp, "yesterday")
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, payload, DefaultTolerance), ErrInvalidTimestamp)
+	})
+
+	t.Run("error - stale timestamp", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now().Add(-10*time.Minute))
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, payload, DefaultTolerance), ErrTimestampTooOld)
+	})
+
+	t.Run("error - future timestamp", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now().Add(10*time.Minute))
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, payload, DefaultTolerance), ErrTimestampTooNew)
+	})
+
+	t.Run("error - malformed signature header", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now())
+		header.Set(HeaderSignature, "not-a-signature")
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, payload, DefaultTolerance), ErrInvalidSignature)
+	})
+
+	t.Run("error - bad signature", func(t *testing.T) {
+		other, err := GenerateSecret(32)
+		require.NoError(t, err)
+		header := signedHeader(t, []Secret{other}, time.Now())
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, payload, DefaultTolerance), ErrSignatureMismatch)
+	})
+
+	t.Run("error - tampered body", func(t *testing.T) {
+		header := signedHeader(t, []Secret{secret}, time.Now())
+
+		assert.ErrorIs(t, VerifyRequest(secret, header, []byte(`{"tampered":true}`), DefaultTolerance), ErrSignatureMismatch)
+	})
+}
@@ -586,31 +586,9 @@ func deliverWebhook(ctx context.Context, route *routes.Route, wh webhook.Webhook
 func verifyWebhookSignature(t *testing.T, secret signature.Secret, headers map[string]string, body []byte) bool {
 	t.Helper()
 
-	msgID := headers["webhook-id"]
-	timestampStr := headers["webhook-timestamp"]
-	signatureHeader := headers["webhook-signature"]
-
-	if msgID == "" || timestampStr == "" || signatureHeader == "" {
-		return false
-	}
-
-	// Parse timestamp
-	var timestamp int64
-	fmt.Sscanf(timestampStr, "%d", &timestamp)
-
-	// Parse signature
-	sigs, err := signature.ParseSignatureHeader(signatureHeader)
-	if err != nil {
-		return false
+	header := http.Header{}
+	for name, value := range headers {
+		header.Set(name, value)
 	}
-
-	// Verify
-	for _, sig := range sigs {
-		valid, err := signature.Verify(secret, msgID, time.Unix(timestamp, 0), body, sig)
-		if err == nil && valid {
-			return true
-		}
-	}
-
-	return false
+	return signature.VerifyRequest(secret, header, body, signature.DefaultTolerance) == nil
 }
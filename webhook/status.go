@@ -1,9 +1,13 @@
 package webhook
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 /* Status represents the current state of a webhook delivery
  * Follows the lifecycle: Pending -> Delivering -> Delivered/Failed/Retrying
+ * Retrying webhooks go back to Delivering; Delivered and Failed are terminal
  */
 type Status int
 
@@ -33,21 +37,44 @@ func (s Status) String() string {
 	}
 }
 
+// ErrInvalidTransition is returned when a status change breaks the webhook lifecycle
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions lists the statuses each non-terminal status may move to
+var transitions = map[Status][]Status{
+	Pending:    {Delivering},
+	Delivering: {Delivered, Failed, Retrying},
+	Retrying:   {Delivering},
+}
+
+// ErrUnknownStatus is returned when a string names no Status
+var ErrUnknownStatus = errors.New("unknown status")
+
 // NewStatus creates a Status from a string
+// Unknown strings map to Pending; use NewStatusStrict where that would hide bad data
 func NewStatus(str string) Status {
+	status, err := NewStatusStrict(str)
+	if err != nil {
+		return Pending
+	}
+	return status
+}
+
+// NewStatusStrict creates a Status from a string, returning ErrUnknownStatus for unknown or empty strings
+func NewStatusStrict(str string) (Status, error) {
 	switch str {
 	case "pending":
-		return Pending
+		return Pending, nil
 	case "delivering":
-		return Delivering
+		return Delivering, nil
 	case "delivered":
-		return Delivered
+		return Delivered, nil
 	case "failed":
-		return Failed
+		return Failed, nil
 	case "retrying":
-		return Retrying
+		return Retrying, nil
 	default:
-		return Pending
+		return 0, fmt.Errorf("%w: %q", ErrUnknownStatus, str)
 	}
 }
 
@@ -63,3 +90,13 @@ func (s Status) Validate() error {
 func (s Status) IsFinal() bool {
 	return s == Delivered || s == Failed
 }
+
+// CanTransitionTo reports whether a webhook in this status may move to next
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
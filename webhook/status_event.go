@@ -0,0 +1,13 @@
+package webhook
+
+import "time"
+
+/* StatusEvent reports a webhook moving to a new status
+ * Events are only delivered to subscribers listening when the transition happens
+ */
+type StatusEvent struct {
+	ID      string    `json:"id"`
+	RouteID string    `json:"route_id"`
+	Status  string    `json:"status"`
+	At      time.Time `json:"at"`
+}
@@ -0,0 +1,89 @@
+package webhook_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to webhook.Status
+		want     bool
+	}{
+		{webhook.Pending, webhook.Delivering, true},
+		{webhook.Delivering, webhook.Delivered, true},
+		{webhook.Delivering, webhook.Failed, true},
+		{webhook.Delivering, webhook.Retrying, true},
+		{webhook.Retrying, webhook.Delivering, true},
+
+		{webhook.Pending, webhook.Pending, false},
+		{webhook.Pending, webhook.Delivered, false},
+		{webhook.Pending, webhook.Failed, false},
+		{webhook.Pending, webhook.Retrying, false},
+		{webhook.Delivering, webhook.Pending, false},
+		{webhook.Delivering, webhook.Delivering, false},
+		{webhook.Retrying, webhook.Pending, false},
+		{webhook.Retrying, webhook.Delivered, false},
+		{webhook.Retrying, webhook.Retrying, false},
+		{webhook.Delivered, webhook.Pending, false},
+		{webhook.Delivered, webhook.Delivering, false},
+		{webhook.Delivered, webhook.Failed, false},
+		{webhook.Failed, webhook.Pending, false},
+		{webhook.Failed, webhook.Retrying, false},
+		{webhook.Failed, webhook.Delivered, false},
+		{webhook.Pending, webhook.Status(999), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from.String()+" to "+tt.to.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+
+	t.Run("terminal statuses are locked", func(t *testing.T) {
+		all := []webhook.Status{webhook.Pending, webhook.Delivering, webhook.Delivered, webhook.Failed, webhook.Retrying}
+		for _, from := range all {
+			if !from.IsFinal() {
+				continue
+			}
+			for _, to := range all {
+				assert.False(t, from.CanTransitionTo(to), "%s to %s", from, to)
+			}
+		}
+	})
+}
+
+func TestNewStatusStrict(t *testing.T) {
+	known := map[string]webhook.Status{
+		"pending":    webhook.Pending,
+		"delivering": webhook.Delivering,
+		"delivered":  webhook.Delivered,
+		"failed":     webhook.Failed,
+		"retrying":   webhook.Retrying,
+	}
+	for str, want := range known {
+		t.Run(str, func(t *testing.T) {
+			status, err := webhook.NewStatusStrict(str)
+			require.NoError(t, err)
+			assert.Equal(t, want, status)
+			assert.Equal(t, str, status.String())
+		})
+	}
+
+	for _, str := range []string{"unknown", "Failed", "canceled", " pending", ""} {
+		t.Run("rejects "+strconv.Quote(str), func(t *testing.T) {
+			_, err := webhook.NewStatusStrict(str)
+			assert.ErrorIs(t, err, webhook.ErrUnknownStatus)
+		})
+	}
+}
+
+func TestNewStatus_Lenient(t *testing.T) {
+	assert.Equal(t, webhook.Failed, webhook.NewStatus("failed"))
+	assert.Equal(t, webhook.Pending, webhook.NewStatus("canceled"))
+	assert.Equal(t, webhook.Pending, webhook.NewStatus(""))
+}
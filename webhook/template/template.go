@@ -0,0 +1,80 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+)
+
+/* Body templates reshape Standard Webhooks payloads into receiver-specific JSON
+ * (Slack, Discord, ...) using Go text/template syntax
+ * Templates see the event as {{.Type}}, {{.Timestamp}} and {{.Data}}, where Data
+ * is the decoded JSON object so fields read like {{.Data.user.name}}
+ * Use the json function to embed values safely: {"text": {{json .Data.name}}}
+ */
+
+// Data is the value templates are executed against
+type Data struct {
+	Type      string
+	Timestamp time.Time
+	Data      interface{}
+}
+
+// funcs are available to every template
+var funcs = template.FuncMap{
+	"json": toJSON,
+}
+
+// Validate checks that a template parses
+func Validate(tmpl string) error {
+	_, err := parse(tmpl)
+	return err
+}
+
+// Render executes a template against a payload and returns the outgoing body
+// The rendered body must be valid JSON
+func Render(tmpl string, p payload.StandardPayload) ([]byte, error) {
+	t, err := parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	if len(p.Data) > 0 {
+		if err := json.Unmarshal(p.Data, &data); err != nil {
+			return nil, fmt.Errorf("decoding payload data: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, Data{Type: p.Type, Timestamp: p.Timestamp, Data: data}); err != nil {
+		return nil, fmt.Errorf("executing body template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("body template must render valid JSON")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parse parses a body template with the shared functions
+func parse(tmpl string) (*template.Template, error) {
+	t, err := template.New("body").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing body template: %w", err)
+	}
+	return t, nil
+}
+
+// toJSON encodes a value as JSON, quoting and escaping strings
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	p := payload.StandardPayload{
+		Type:      "user.created",
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Data:      json.RawMessage(`{"user":{"name":"Ada \"The Countess\" Lovelace","email":"ada@example.com"},"plan":"pro"}`),
+	}
+
+	t.Run("success - Slack message", func(t *testing.T) {
+		tmpl := `{
+  "text": {{json (printf "New user: %s" .Data.user.name)}},
+  "blocks": [
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (printf "*%s* signed up for %s" .Data.user.email .Data.plan)}}}},
+    {"type": "context", "elements": [{"type": "mrkdwn", "text": "{{.Type}} at {{.Timestamp.Format "2006-01-02 15:04"}}"}]}
+  ]
+}`
+
+		body, err := Render(tmpl, p)
+		require.NoError(t, err)
+
+		var message struct {
+			Text   string `json:"text"`
+			Blocks []struct {
+				Text struct {
+					Text string `json:"text"`
+				} `json:"text"`
+				Elements []struct {
+					Text string `json:"text"`
+				} `json:"elements"`
+			} `json:"blocks"`
+		}
+		require.NoError(t, json.Unmarshal(body, &message))
+		assert.Equal(t, `New user: Ada "The Countess" Lovelace`, message.Text)
+		require.Len(t, message.Blocks, 2)
+		assert.Equal(t, "*ada@example.com* signed up for pro", message.Blocks[0].Text.Text)
+		assert.Equal(t, "user.created at 2024-01-01 12:00", message.Blocks[1].Elements[0].Text)
+	})
+
+	t.Run("success - embeds whole objects", func(t *testing.T) {
+		body, err := Render(`{"content": {{json .Type}}, "user": {{json .Data.user}}}`, p)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"content":"user.created","user":{"name":"Ada \"The Countess\" Lovelace","email":"ada@example.com"}}`, string(body))
+	})
+
+	t.Run("success - missing fields encode as null", func(t *testing.T) {
+		body, err := Render(`{"team": {{json .Data.team}}}`, p)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"team":null}`, string(body))
+	})
+
+	t.Run("error - invalid template", func(t *testing.T) {
+		_, err := Render(`{"text": {{.Type}`, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing body template")
+	})
+
+	t.Run("error - output is not JSON", func(t *testing.T) {
+		_, err := Render(`New user: {{.Data.user.name}}`, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "valid JSON")
+	})
+
+	t.Run("error - execution fails", func(t *testing.T) {
+		_, err := Render(`{"text": {{json (index .Data.user 3)}}}`, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "executing body template")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(`{"text": {{json .Type}}}`))
+	assert.Error(t, Validate(`{"text": {{json .Type}`))
+	assert.Error(t, Validate(`{{unknown .Type}}`))
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/* Distributed tracing across ingestion and delivery
+ * The ingestion span's W3C traceparent is stored with the webhook, so the
+ * delivery span continues the sender's trace even though it runs in another
+ * process, possibly long after the request that received the webhook
+ */
+
+// TracerName identifies the spans created by webhook-inbox
+const TracerName = "github.com/marcelsud/webhook-inbox"
+
+// traceContext propagates W3C traceparent and tracestate
+var traceContext = propagation.TraceContext{}
+
+// TraceParent returns the W3C traceparent of the span in ctx, or "" when ctx has no valid span
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextWithTraceParent returns ctx with the remote span described by a W3C traceparent as parent
+// Returns ctx unchanged when traceparent is empty or invalid
+func ContextWithTraceParent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
+
+// tracerFrom returns a tracer from provider, falling back to the global tracer provider
+func tracerFrom(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(TracerName)
+}
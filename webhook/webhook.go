@@ -1,6 +1,11 @@
 package webhook
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
 
 /* Webhook represents a received webhook message in the system
  * Uses value semantics as it represents data, not behavior
@@ -9,12 +14,162 @@ type Webhook struct {
 	ID           string
 	RouteID      string
 	Payload      []byte
+	PayloadSize  int // Size of Payload in bytes, as received
 	Headers      map[string]string
 	Status       Status
 	RetryCount   int
 	MaxRetries   int
 	NextRetryAt  time.Time
+	DeliverAt    time.Time // Optional: earliest delivery time (zero delivers immediately)
 	DeliveryMode DeliveryMode
+	Priority     bool   // Queued on the route's high-priority stream, drained before the normal one
+	TraceParent  string // W3C traceparent of the ingestion span, continued on delivery
+	RequestID    string // X-Request-ID of the request that received the webhook
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+/* webhookJSON is the canonical JSON form of a Webhook used by the API
+ * Status and delivery mode are rendered by name and times as RFC 3339
+ */
+type webhookJSON struct {
+	EventID      string            `json:"event_id"`
+	RouteID      string            `json:"route_id"`
+	Payload      json.RawMessage   `json:"payload"`
+	PayloadSize  int               `json:"payload_size,omitempty"`
+	Headers      map[string]string `json:"headers"`
+	Status       string            `json:"status"`
+	RetryCount   int               `json:"retry_count"`
+	MaxRetries   int               `json:"max_retries"`
+	NextRetryAt  string            `json:"next_retry_at,omitempty"`
+	DeliverAt    string            `json:"deliver_at,omitempty"`
+	DeliveryMode string            `json:"delivery_mode"`
+	Priority     bool              `json:"priority,omitempty"`
+	TraceParent  string            `json:"traceparent,omitempty"`
+	RequestID    string            `json:"request_id,omitempty"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
+}
+
+// MarshalJSON returns the canonical JSON encoding of the webhook
+// A payload that is valid JSON is embedded as is, anything else is encoded as a JSON string
+func (w Webhook) MarshalJSON() ([]byte, error) {
+	payload := json.RawMessage(w.Payload)
+	if !json.Valid(w.Payload) {
+		quoted, err := json.Marshal(string(w.Payload))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling payload: %w", err)
+		}
+		payload = quoted
+	}
+
+	aux := webhookJSON{
+		EventID:      w.ID,
+		RouteID:      w.RouteID,
+		Payload:      payload,
+		PayloadSize:  w.PayloadSize,
+		Headers:      w.Headers,
+		Status:       w.Status.String(),
+		RetryCount:   w.RetryCount,
+		MaxRetries:   w.MaxRetries,
+		DeliveryMode: w.DeliveryMode.String(),
+		Priority:     w.Priority,
+		TraceParent:  w.TraceParent,
+		RequestID:    w.RequestID,
+		CreatedAt:    w.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    w.UpdatedAt.Format(time.RFC3339),
+	}
+	if !w.NextRetryAt.IsZero() {
+		aux.NextRetryAt = w.NextRetryAt.Format(time.RFC3339)
+	}
+	if !w.DeliverAt.IsZero() {
+		aux.DeliverAt = w.DeliverAt.Format(time.RFC3339)
+	}
+
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON parses the canonical JSON encoding of a webhook
+func (w *Webhook) UnmarshalJSON(data []byte) error {
+	var aux webhookJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return fmt.Errorf("unmarshaling webhook: %w", err)
+	}
+
+	status, err := NewStatusStrict(aux.Status)
+	if err != nil {
+		return fmt.Errorf("invalid status: %w", err)
+	}
+	mode := NewDeliveryMode(aux.DeliveryMode)
+	if mode.String() != aux.DeliveryMode {
+		return fmt.Errorf("invalid delivery mode: %q", aux.DeliveryMode)
+	}
+
+	// Undo the string encoding applied to non-JSON payloads
+	payload := []byte(aux.Payload)
+	var str string
+	if err := json.Unmarshal(aux.Payload, &str); err == nil && !json.Valid([]byte(str)) {
+		payload = []byte(str)
+	}
+
+	createdAt, err := parseTime(aux.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("parsing created_at: %w", err)
+	}
+	updatedAt, err := parseTime(aux.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("parsing updated_at: %w", err)
+	}
+	nextRetryAt, err := parseTime(aux.NextRetryAt)
+	if err != nil {
+		return fmt.Errorf("parsing next_retry_at: %w", err)
+	}
+	deliverAt, err := parseTime(aux.DeliverAt)
+	if err != nil {
+		return fmt.Errorf("parsing deliver_at: %w", err)
+	}
+
+	*w = Webhook{
+		ID:           aux.EventID,
+		RouteID:      aux.RouteID,
+		Payload:      payload,
+		PayloadSize:  aux.PayloadSize,
+		Headers:      aux.Headers,
+		Status:       status,
+		RetryCount:   aux.RetryCount,
+		MaxRetries:   aux.MaxRetries,
+		NextRetryAt:  nextRetryAt,
+		DeliverAt:    deliverAt,
+		DeliveryMode: mode,
+		Priority:     aux.Priority,
+		TraceParent:  aux.TraceParent,
+		RequestID:    aux.RequestID,
+		CreatedAt:    createdAt,
+		UpdatedAt:    updatedAt,
+	}
+
+	return nil
+}
+
+// Due reports whether the webhook may be delivered at the given time
+func (w Webhook) Due(now time.Time) bool {
+	return !w.DeliverAt.After(now)
+}
+
+// parseTime parses an RFC 3339 timestamp, returning the zero time for an empty string
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// DefaultConsumerName identifies the current process as "{hostname}-{pid}"
+// Repositories name their stream consumers with it and workers their heartbeats, so both agree
+func DefaultConsumerName() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
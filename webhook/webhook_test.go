@@ -0,0 +1,120 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_JSON(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("renders status, mode and times by name", func(t *testing.T) {
+		wh := webhook.Webhook{
+			ID:           "evt-1",
+			RouteID:      "user-events",
+			Payload:      []byte(`{"type":"user.created"}`),
+			Headers:      map[string]string{"Content-Type": "application/json"},
+			Status:       webhook.Retrying,
+			RetryCount:   1,
+			MaxRetries:   3,
+			DeliveryMode: webhook.PubSub,
+			CreatedAt:    createdAt,
+			UpdatedAt:    createdAt.Add(time.Minute),
+		}
+
+		data, err := json.Marshal(wh)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{
+			"event_id": "evt-1",
+			"route_id": "user-events",
+			"payload": {"type": "user.created"},
+			"headers": {"Content-Type": "application/json"},
+			"status": "retrying",
+			"retry_count": 1,
+			"max_retries": 3,
+			"delivery_mode": "pubsub",
+			"created_at": "2024-01-01T12:00:00Z",
+			"updated_at": "2024-01-01T12:01:00Z"
+		}`, string(data))
+	})
+
+	t.Run("round-trip preserves the webhook", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			payload []byte
+		}{
+			{"JSON payload", []byte(`{"type":"user.created","data":{"id":1}}`)},
+			{"non-JSON payload", []byte(`plain text body`)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				original := webhook.Webhook{
+					ID:           "evt-1",
+					RouteID:      "user-events",
+					Payload:      tt.payload,
+					Headers:      map[string]string{"X-Source": "test"},
+					Status:       webhook.Failed,
+					RetryCount:   3,
+					MaxRetries:   3,
+					NextRetryAt:  createdAt.Add(time.Hour),
+					DeliverAt:    createdAt.Add(2 * time.Hour),
+					DeliveryMode: webhook.FIFO,
+					TraceParent:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+					RequestID:    "req-123",
+					CreatedAt:    createdAt,
+					UpdatedAt:    createdAt.Add(time.Minute),
+				}
+
+				data, err := json.Marshal(original)
+				require.NoError(t, err)
+
+				var decoded webhook.Webhook
+				require.NoError(t, json.Unmarshal(data, &decoded))
+
+				assert.Equal(t, original.ID, decoded.ID)
+				assert.Equal(t, original.RouteID, decoded.RouteID)
+				assert.Equal(t, string(original.Payload), string(decoded.Payload))
+				assert.Equal(t, original.Headers, decoded.Headers)
+				assert.Equal(t, original.Status, decoded.Status)
+				assert.Equal(t, original.RetryCount, decoded.RetryCount)
+				assert.Equal(t, original.MaxRetries, decoded.MaxRetries)
+				assert.Equal(t, original.DeliveryMode, decoded.DeliveryMode)
+				assert.Equal(t, original.TraceParent, decoded.TraceParent)
+				assert.Equal(t, original.RequestID, decoded.RequestID)
+				assert.True(t, original.NextRetryAt.Equal(decoded.NextRetryAt))
+				assert.True(t, original.DeliverAt.Equal(decoded.DeliverAt))
+				assert.True(t, original.CreatedAt.Equal(decoded.CreatedAt))
+				assert.True(t, original.UpdatedAt.Equal(decoded.UpdatedAt))
+			})
+		}
+	})
+
+	t.Run("omits unset next_retry_at", func(t *testing.T) {
+		data, err := json.Marshal(webhook.Webhook{ID: "evt-1", Payload: []byte(`{}`), Status: webhook.Pending, DeliveryMode: webhook.FIFO})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "next_retry_at")
+		assert.NotContains(t, string(data), "traceparent")
+		assert.NotContains(t, string(data), "deliver_at")
+	})
+
+	t.Run("error - unknown status", func(t *testing.T) {
+		var wh webhook.Webhook
+		err := json.Unmarshal([]byte(`{"event_id":"evt-1","payload":{},"status":"lost","delivery_mode":"fifo"}`), &wh)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid status")
+	})
+
+	t.Run("error - unknown delivery mode", func(t *testing.T) {
+		var wh webhook.Webhook
+		err := json.Unmarshal([]byte(`{"event_id":"evt-1","payload":{},"status":"pending","delivery_mode":"broadcast"}`), &wh)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid delivery mode")
+	})
+}
@@ -0,0 +1,233 @@
+package webhooktest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/* RunRepositorySuite checks that a repository behaves like every other webhook.Repository
+ * newRepo is called once per subtest and the repository is closed when the subtest ends
+ * Subtests use unique route and webhook IDs, so repositories may share a backend
+ *
+ * Usage from a backend's tests:
+ *   webhooktest.RunRepositorySuite(t, func() webhook.Repository { return newRepository(t) })
+ */
+func RunRepositorySuite(t *testing.T, newRepo func() webhook.Repository) {
+	t.Helper()
+
+	suite := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, repo webhook.Repository)
+	}{
+		{"store and get", testStoreAndGet},
+		{"get missing webhook", testGetMissing},
+		{"update status", testUpdateStatus},
+		{"increment retry", testIncrementRetry},
+		{"consume FIFO in order", testConsumeFIFO},
+		{"consume PubSub", testConsumePubSub},
+		{"consume empty route", testConsumeEmpty},
+		{"acknowledged webhooks are not redelivered", testAcknowledge},
+		{"TTL expires webhook", testSetTTL},
+		{"delete message ID", testDeleteMessageID},
+	}
+
+	for _, tc := range suite {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo()
+			t.Cleanup(func() { repo.Close(ctx) })
+
+			tc.run(t, ctx, repo)
+		})
+	}
+}
+
+func testStoreAndGet(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	wh := newWebhook(t, uniqueID("route"), webhook.PubSub)
+	wh.Headers = map[string]string{"X-Event-Type": "user.created"}
+	wh.MaxRetries = 5
+	wh.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	id, err := repo.Store(ctx, wh)
+	require.NoError(t, err)
+	assert.Equal(t, wh.ID, id)
+
+	stored, err := repo.Get(ctx, wh.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wh.ID, stored.ID)
+	assert.Equal(t, wh.RouteID, stored.RouteID)
+	assert.Equal(t, string(wh.Payload), string(stored.Payload))
+	assert.Equal(t, len(wh.Payload), stored.PayloadSize)
+	assert.Equal(t, wh.Headers, stored.Headers)
+	assert.Equal(t, webhook.Pending, stored.Status)
+	assert.Equal(t, 0, stored.RetryCount)
+	assert.Equal(t, 5, stored.MaxRetries)
+	assert.Equal(t, webhook.PubSub, stored.DeliveryMode)
+	assert.Equal(t, wh.TraceParent, stored.TraceParent)
+	assert.WithinDuration(t, wh.CreatedAt, stored.CreatedAt, time.Second)
+}
+
+func testGetMissing(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	_, err := repo.Get(ctx, uniqueID("missing"))
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+}
+
+func testUpdateStatus(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	wh := storeWebhook(t, ctx, repo, uniqueID("route"), webhook.FIFO)
+
+	for _, status := range []webhook.Status{webhook.Delivering, webhook.Retrying, webhook.Delivered} {
+		require.NoError(t, repo.UpdateStatus(ctx, wh.ID, status))
+
+		stored, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, status, stored.Status)
+	}
+}
+
+func testIncrementRetry(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	wh := storeWebhook(t, ctx, repo, uniqueID("route"), webhook.FIFO)
+
+	for want := 1; want <= 3; want++ {
+		require.NoError(t, repo.IncrementRetry(ctx, wh.ID))
+
+		stored, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want, stored.RetryCount)
+	}
+}
+
+func testConsumeFIFO(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	routeID := uniqueID("fifo")
+
+	var stored []string
+	for i := 0; i < 3; i++ {
+		stored = append(stored, storeWebhook(t, ctx, repo, routeID, webhook.FIFO).ID)
+	}
+
+	consumed := consumeAll(t, ctx, repo, routeID, webhook.FIFO, len(stored))
+	assert.Equal(t, stored, consumed)
+}
+
+func testConsumePubSub(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	routeID := uniqueID("pubsub")
+	wh := storeWebhook(t, ctx, repo, routeID, webhook.PubSub)
+
+	webhooks, err := repo.Consume(ctx, routeID, webhook.PubSub)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, wh.ID, webhooks[0].ID)
+	assert.Equal(t, routeID, webhooks[0].RouteID)
+	assert.Equal(t, webhook.PubSub, webhooks[0].DeliveryMode)
+}
+
+func testConsumeEmpty(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	webhooks, err := repo.Consume(ctx, uniqueID("empty"), webhook.FIFO)
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
+
+func testAcknowledge(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	routeID := uniqueID("ack")
+	wh := storeWebhook(t, ctx, repo, routeID, webhook.FIFO)
+
+	webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+
+	require.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+
+	webhooks, err = repo.Consume(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+
+	// Acknowledging twice is harmless
+	assert.NoError(t, repo.Acknowledge(ctx, routeID, webhook.FIFO, wh.ID))
+}
+
+func testSetTTL(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	wh := storeWebhook(t, ctx, repo, uniqueID("route"), webhook.FIFO)
+
+	require.NoError(t, repo.SetTTL(ctx, wh.ID, time.Second))
+
+	_, err := repo.Get(ctx, wh.ID)
+	require.NoError(t, err, "webhook should exist until its TTL elapses")
+
+	assert.Eventually(t, func() bool {
+		_, err := repo.Get(ctx, wh.ID)
+		return err != nil
+	}, 5*time.Second, 100*time.Millisecond, "webhook should expire after its TTL")
+
+	_, err = repo.Get(ctx, wh.ID)
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+}
+
+func testDeleteMessageID(t *testing.T, ctx context.Context, repo webhook.Repository) {
+	routeID := uniqueID("msgid")
+	wh := storeWebhook(t, ctx, repo, routeID, webhook.FIFO)
+
+	webhooks, err := repo.Consume(ctx, routeID, webhook.FIFO)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+
+	require.NoError(t, repo.DeleteMessageID(ctx, wh.ID))
+
+	// The webhook itself is untouched
+	_, err = repo.Get(ctx, wh.ID)
+	require.NoError(t, err)
+}
+
+// consumeAll consumes and acknowledges webhooks until n were received, returning their IDs in order
+func consumeAll(t *testing.T, ctx context.Context, repo webhook.Repository, routeID string, mode webhook.DeliveryMode, n int) []string {
+	t.Helper()
+
+	var ids []string
+	for attempts := 0; len(ids) < n && attempts < n*3; attempts++ {
+		webhooks, err := repo.Consume(ctx, routeID, mode)
+		require.NoError(t, err)
+
+		for _, wh := range webhooks {
+			ids = append(ids, wh.ID)
+			require.NoError(t, repo.Acknowledge(ctx, routeID, mode, wh.ID))
+		}
+	}
+	return ids
+}
+
+// storeWebhook stores a pending webhook for a route
+func storeWebhook(t *testing.T, ctx context.Context, repo webhook.Repository, routeID string, mode webhook.DeliveryMode) webhook.Webhook {
+	t.Helper()
+
+	wh := newWebhook(t, routeID, mode)
+	_, err := repo.Store(ctx, wh)
+	require.NoError(t, err)
+	return wh
+}
+
+// newWebhook builds a pending webhook with a unique ID
+func newWebhook(t *testing.T, routeID string, mode webhook.DeliveryMode) webhook.Webhook {
+	t.Helper()
+
+	now := time.Now()
+	return webhook.Webhook{
+		ID:           uniqueID("webhook"),
+		RouteID:      routeID,
+		Payload:      []byte(`{"type":"test.event","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		Headers:      map[string]string{},
+		Status:       webhook.Pending,
+		MaxRetries:   3,
+		DeliveryMode: mode,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// uniqueID returns an ID unlikely to collide with other subtests sharing the backend
+func uniqueID(prefix string) string {
+	return fmt.Sprintf("conformance-%s-%d", prefix, time.Now().UnixNano())
+}
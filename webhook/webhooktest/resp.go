@@ -0,0 +1,37 @@
+package webhooktest
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* RESP helpers for tests faking a Redis server on a plain listener
+ * Enough to answer the commands a test expects without running Redis
+ */
+
+// ReadRESPCommand reads one command sent as a RESP array of bulk strings
+func ReadRESPCommand(reader *bufio.Reader) ([]string, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected RESP header %q", header)
+	}
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if _, err := reader.ReadString('\n'); err != nil { // $<length>
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSpace(arg))
+	}
+	return args, nil
+}
@@ -0,0 +1,311 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
+	"github.com/marcelsud/webhook-inbox/webhook/template"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Standard Webhooks headers added to deliveries of routes with the standard header style
+const (
+	HeaderWebhookID        = "webhook-id"
+	HeaderWebhookTimestamp = "webhook-timestamp"
+	HeaderWebhookSignature = "webhook-signature"
+)
+
+// managedHeaders are set by the client itself and never copied from the inbound request
+var managedHeaders = map[string]bool{
+	"Host":                                   true,
+	"Content-Length":                         true,
+	http.CanonicalHeaderKey(HeaderWebhookID): true,
+	http.CanonicalHeaderKey(HeaderWebhookTimestamp): true,
+	http.CanonicalHeaderKey(HeaderWebhookSignature): true,
+}
+
+// ErrSignatureRequired is returned when a route requires signatures but the webhook could not be signed
+// Retrying cannot fix a signing failure, so the worker fails such webhooks immediately
+var ErrSignatureRequired = errors.New("signature required")
+
+// ErrNonRetryableStatus is returned when the target rejects a webhook with a status retrying cannot fix
+// (see routes.Route.IsRetryableStatus); the worker fails such webhooks without spending their retries
+var ErrNonRetryableStatus = errors.New("non-retryable status")
+
+// DefaultDeliveryTimeout bounds a single delivery attempt when no timeout is given
+const DefaultDeliveryTimeout = 10 * time.Second
+
+/* Client delivers webhooks to route targets following Standard Webhooks
+ * Keeps one HTTP client per route, so TLS settings (client certificates,
+ * custom root CAs) are loaded once and connections are pooled per target
+ * A client is rebuilt when the route's TLS files change path or modification
+ * time, e.g. after a routes reload or a certificate rotation
+ */
+type Client struct {
+	timeout time.Duration
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	clients map[string]routeClient
+}
+
+// routeClient is the cached HTTP client of a route, with the TLS inputs it was built from
+type routeClient struct {
+	tlsKey string
+	client *http.Client
+}
+
+// ClientOption configures optional Client behavior
+type ClientOption func(*Client)
+
+// WithClientClock sets the clock timestamping signed deliveries (default: clock.System)
+func WithClientClock(clk clock.Clock) ClientOption {
+	return func(c *Client) {
+		c.clock = clk
+	}
+}
+
+// NewClient creates a delivery client with the given per-attempt timeout
+func NewClient(timeout time.Duration, opts ...ClientOption) *Client {
+	if timeout <= 0 {
+		timeout = DefaultDeliveryTimeout
+	}
+	c := &Client{
+		timeout: timeout,
+		clock:   clock.System,
+		clients: make(map[string]routeClient),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Deliver POSTs the webhook payload to the route target with Standard Webhooks headers, named after the route's header style
+// Returns the response status code (0 when no response was received) and an error
+// when the request fails or the target answers with a status the route doesn't expect
+// The span in ctx is propagated with a W3C traceparent header, replacing any stored one
+// Errors are *webhook.DeliveryError, classified by what failed (see webhook.DeliveryErrorKind)
+// Cancelling ctx aborts the request in flight; the error then wraps ctx's error
+func (c *Client) Deliver(ctx context.Context, route *routes.Route, wh webhook.Webhook) (int, error) {
+	statusCode, err := c.deliver(ctx, route, wh)
+	return statusCode, webhook.NewDeliveryError(err)
+}
+
+// deliver sends a webhook; Deliver classifies the errors it returns
+func (c *Client) deliver(ctx context.Context, route *routes.Route, wh webhook.Webhook) (int, error) {
+	httpClient, err := c.httpClient(route)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := renderBody(route, wh)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	// Forward the stored inbound headers; headers set below always take precedence
+	// An inbound signature header is never forwarded, so unsigned deliveries can't carry a spoofed one
+	routeHeaders := map[string]bool{
+		http.CanonicalHeaderKey(route.GetIDHeader()):        true,
+		http.CanonicalHeaderKey(route.GetTimestampHeader()): true,
+		http.CanonicalHeaderKey(route.GetSignatureHeader()): true,
+	}
+	for key, value := range wh.Headers {
+		if canonical := http.CanonicalHeaderKey(key); managedHeaders[canonical] || routeHeaders[canonical] {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	timestamp := clock.OrSystem(c.clock).Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(route.GetIDHeader(), wh.ID)
+	req.Header.Set(route.GetTimestampHeader(), strconv.FormatInt(timestamp.Unix(), 10))
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	sig, err := sign(route, route.Secrets(nil), wh.ID, timestamp, body)
+	if err != nil {
+		return 0, err
+	}
+	if sig != "" {
+		req.Header.Set(route.GetSignatureHeader(), sig)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var captured []byte
+	if !route.IsExpectedStatus(resp.StatusCode) && route.CaptureResponseBytes > 0 {
+		// A body cut short by a read error is still worth keeping
+		captured, _ = io.ReadAll(io.LimitReader(resp.Body, int64(route.CaptureResponseBytes)))
+	}
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if !route.IsExpectedStatus(resp.StatusCode) {
+		err := fmt.Errorf("webhook delivery failed with status: %d", resp.StatusCode)
+		if !route.IsRetryableStatus(resp.StatusCode) {
+			err = fmt.Errorf("webhook delivery failed with status: %d: %w", resp.StatusCode, ErrNonRetryableStatus)
+		}
+		return resp.StatusCode, &webhook.DeliveryError{Kind: webhook.DeliveryErrorStatus, StatusCode: resp.StatusCode, ResponseBody: string(captured), Err: err}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// sign computes the signature header value, or "" when there is no secret to sign with
+// Failures wrap ErrSignatureRequired when the route requires signatures
+func sign(route *routes.Route, encoded []string, id string, timestamp time.Time, body []byte) (string, error) {
+	if len(encoded) == 0 && !route.RequireSignature {
+		return "", nil
+	}
+
+	sig, err := computeSignature(route, encoded, id, timestamp, body)
+	if err != nil && route.RequireSignature {
+		return "", fmt.Errorf("%w: %w", ErrSignatureRequired, err)
+	}
+	return sig, err
+}
+
+// computeSignature signs the outgoing body with the given secrets, in the route's signature format
+// Routes rotating secrets get one Standard Webhooks signature per secret in the same header
+func computeSignature(route *routes.Route, encoded []string, id string, timestamp time.Time, body []byte) (string, error) {
+	if len(encoded) == 0 {
+		return "", fmt.Errorf("route %s has no signing secret", route.RouteID)
+	}
+
+	secrets := make([]signature.Secret, 0, len(encoded))
+	for _, e := range encoded {
+		secret, err := signature.ParseSecret(e)
+		if err != nil {
+			return "", fmt.Errorf("parsing signing secret: %w", err)
+		}
+		secrets = append(secrets, secret)
+	}
+	algorithm := route.GetSignatureAlgorithm()
+	if route.SignatureFormat == routes.SignatureFormatHex {
+		return algorithm.SignHex(secrets[0], body), nil
+	}
+	header, err := algorithm.SignMultiple(secrets, id, timestamp, body)
+	if err != nil {
+		return "", fmt.Errorf("signing webhook: %w", err)
+	}
+	return header, nil
+}
+
+// renderBody returns the body to deliver: the route's body template rendered from the stored payload,
+// the stored payload with the webhook ID set in the route's inject_event_id_field, or the stored payload
+func renderBody(route *routes.Route, wh webhook.Webhook) ([]byte, error) {
+	if route.BodyTemplate != "" {
+		p, err := payload.Parse(wh.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("parsing payload for body template: %w", err)
+		}
+		body, err := template.Render(route.BodyTemplate, p)
+		if err != nil {
+			return nil, fmt.Errorf("rendering body template: %w", err)
+		}
+		return body, nil
+	}
+
+	if route.InjectEventIDField != "" {
+		return injectField(wh.Payload, route.InjectEventIDField, wh.ID)
+	}
+	return wh.Payload, nil
+}
+
+// injectField sets a top-level string field of a JSON object, replacing any value it had
+// The object is re-encoded minified, with its keys sorted
+func injectField(body []byte, key, value string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("injecting %s: body is not a JSON object", key)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("injecting %s: %w", key, err)
+	}
+	fields[key] = encoded
+
+	body, err = json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("injecting %s: %w", key, err)
+	}
+	return body, nil
+}
+
+// httpClient returns the cached HTTP client for a route, building it on first use
+// and again whenever the route's TLS inputs changed since
+func (c *Client) httpClient(route *routes.Route) (*http.Client, error) {
+	key := tlsKey(route)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.clients[route.RouteID]
+	if ok && cached.tlsKey == key {
+		return cached.client, nil
+	}
+
+	tlsConfig, err := route.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("building TLS config: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	client := &http.Client{
+		Timeout:   c.timeout,
+		Transport: transport,
+	}
+	if ok {
+		// Connections of the replaced client still use the old certificates
+		cached.client.CloseIdleConnections()
+	}
+	c.clients[route.RouteID] = routeClient{tlsKey: key, client: client}
+
+	return client, nil
+}
+
+// tlsKey identifies the TLS inputs of a route: the path and modification time of each of its files
+// Files that can't be stat'ed are keyed by path alone, so building the client reports the error
+func tlsKey(route *routes.Route) string {
+	var key strings.Builder
+	for _, path := range []string{route.ClientCertFile, route.ClientKeyFile, route.CAFile} {
+		key.WriteString(path)
+		if info, err := os.Stat(path); path != "" && err == nil {
+			fmt.Fprintf(&key, "@%d", info.ModTime().UnixNano())
+		}
+		key.WriteByte('|')
+	}
+	return key.String()
+}
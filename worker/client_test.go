@@ -0,0 +1,713 @@
+package worker_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
+	"github.com/marcelsud/webhook-inbox/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Deliver(t *testing.T) {
+	wh := webhook.Webhook{
+		ID:      "evt-1",
+		RouteID: "user-events",
+		Payload: []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+	}
+
+	t.Run("success - sends Standard Webhooks headers and signature", func(t *testing.T) {
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, SigningSecret: secret.String()}
+		client := worker.NewClient(time.Second)
+
+		statusCode, err := client.Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, statusCode)
+		assert.Equal(t, "evt-1", received.Get(worker.HeaderWebhookID))
+		assert.NotEmpty(t, received.Get(worker.HeaderWebhookTimestamp))
+		assert.Contains(t, received.Get(worker.HeaderWebhookSignature), "v1,")
+	})
+
+	t.Run("success - raw payload is forwarded verbatim and signed", func(t *testing.T) {
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		raw := webhook.Webhook{ID: "evt-raw", RouteID: "raw-json", Payload: []byte(`{"action":"opened","number":7}`)}
+
+		var (
+			received http.Header
+			body     []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{
+			RouteID:       "raw-json",
+			TargetURL:     server.URL,
+			SigningSecret: secret.String(),
+			PayloadFormat: routes.PayloadFormatRaw,
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, raw)
+		require.NoError(t, err)
+		assert.Equal(t, raw.Payload, body)
+
+		ts, err := strconv.ParseInt(received.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		sigs, err := signature.ParseSignatureHeader(received.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+
+		valid, err := signature.VerifyMultiple([]signature.Secret{secret}, raw.ID, time.Unix(ts, 0), body, sigs)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("success - forwards stored headers without overriding managed ones", func(t *testing.T) {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		withHeaders := wh
+		withHeaders.Headers = map[string]string{
+			"X-Request-Id": "req-1",
+			"Content-Type": "text/plain",
+			"Webhook-Id":   "spoofed",
+		}
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL}
+		_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, withHeaders)
+		require.NoError(t, err)
+		assert.Equal(t, "req-1", received.Get("X-Request-Id"))
+		assert.Equal(t, "application/json", received.Get("Content-Type"))
+		assert.Equal(t, "evt-1", received.Get(worker.HeaderWebhookID))
+	})
+
+	t.Run("header styles name the headers without changing what is signed", func(t *testing.T) {
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		tests := []struct {
+			style                         string
+			idHeader, tsHeader, sigHeader string
+			absentID, absentTS, absentSig string
+		}{
+			{routes.HeaderStyleStandard, "webhook-id", "webhook-timestamp", "webhook-signature", "X-Webhook-Id", "X-Webhook-Timestamp", "X-Webhook-Signature"},
+			{routes.HeaderStyleXPrefixed, "X-Webhook-Id", "X-Webhook-Timestamp", "X-Webhook-Signature", "webhook-id", "webhook-timestamp", "webhook-signature"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.style, func(t *testing.T) {
+				var (
+					received http.Header
+					body     []byte
+				)
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					received = r.Header.Clone()
+					body, _ = io.ReadAll(r.Body)
+					w.WriteHeader(http.StatusOK)
+				}))
+				defer server.Close()
+
+				// An inbound header named like an outgoing one is never forwarded
+				withHeaders := wh
+				withHeaders.Headers = map[string]string{tt.idHeader: "spoofed"}
+
+				route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, SigningSecret: secret.String(), HeaderStyle: tt.style}
+				_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, withHeaders)
+				require.NoError(t, err)
+
+				assert.Equal(t, "evt-1", received.Get(tt.idHeader))
+				assert.Empty(t, received.Get(tt.absentID))
+				assert.Empty(t, received.Get(tt.absentTS))
+				assert.Empty(t, received.Get(tt.absentSig))
+
+				ts, err := strconv.ParseInt(received.Get(tt.tsHeader), 10, 64)
+				require.NoError(t, err)
+				sigs, err := signature.ParseSignatureHeader(received.Get(tt.sigHeader))
+				require.NoError(t, err)
+				valid, err := signature.VerifyMultiple([]signature.Secret{secret}, wh.ID, time.Unix(ts, 0), body, sigs)
+				require.NoError(t, err)
+				assert.True(t, valid)
+			})
+		}
+	})
+
+	t.Run("success - rotating secrets sign with every secret", func(t *testing.T) {
+		oldSecret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+		newSecret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{
+			RouteID:        "user-events",
+			TargetURL:      server.URL,
+			SigningSecret:  newSecret.String(),
+			SigningSecrets: []string{oldSecret.String()},
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+
+		signatures, err := signature.ParseSignatureHeader(received.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+		assert.Len(t, signatures, 2)
+
+		// A receiver that hasn't rotated yet still verifies the delivery
+		unix, err := strconv.ParseInt(received.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		valid, err := signature.VerifyMultiple([]signature.Secret{oldSecret}, "evt-1", time.Unix(unix, 0), wh.Payload, signatures)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("success - hex signature in a custom header", func(t *testing.T) {
+		secret, err := signature.ParseSecret("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+		require.NoError(t, err)
+
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		withHeaders := wh
+		withHeaders.Headers = map[string]string{"X-Signature": "spoofed"}
+
+		route := &routes.Route{
+			RouteID:         "github-style",
+			TargetURL:       server.URL,
+			SigningSecret:   secret.String(),
+			SignatureHeader: "X-Signature",
+			SignatureFormat: routes.SignatureFormatHex,
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, withHeaders)
+		require.NoError(t, err)
+
+		assert.Equal(t, signature.SignHex(secret, wh.Payload), received.Get("X-Signature"))
+		assert.Len(t, received.Get("X-Signature"), 64)
+		assert.Empty(t, received.Get(worker.HeaderWebhookSignature))
+	})
+
+	t.Run("success - sha512 signatures", func(t *testing.T) {
+		secret, err := signature.ParseSecret("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+		require.NoError(t, err)
+
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{
+			RouteID:            "sha512-route",
+			TargetURL:          server.URL,
+			SigningSecret:      secret.String(),
+			SignatureAlgorithm: "sha512",
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+
+		require.NoError(t, signature.SHA512.VerifyRequest(secret, received, wh.Payload, 0))
+		err = signature.VerifyRequest(secret, received, wh.Payload, 0)
+		assert.ErrorIs(t, err, signature.ErrSignatureMismatch)
+	})
+
+	t.Run("success - inbound custom signature header is not forwarded unsigned", func(t *testing.T) {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		withHeaders := wh
+		withHeaders.Headers = map[string]string{"X-Signature": "spoofed"}
+
+		route := &routes.Route{RouteID: "github-style", TargetURL: server.URL, SignatureHeader: "X-Signature"}
+		_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, withHeaders)
+		require.NoError(t, err)
+		assert.Empty(t, received.Get("X-Signature"))
+	})
+
+	t.Run("success - body template renders and signs the outgoing body", func(t *testing.T) {
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		var (
+			received http.Header
+			body     []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{
+			RouteID:       "slack",
+			TargetURL:     server.URL,
+			SigningSecret: secret.String(),
+			BodyTemplate:  `{"text": {{json (printf "%s at %s" .Type (.Timestamp.Format "15:04"))}}}`,
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "user.created at 12:00"}`, string(body))
+
+		ts, err := strconv.ParseInt(received.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		sigs, err := signature.ParseSignatureHeader(received.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+
+		valid, err := signature.VerifyMultiple([]signature.Secret{secret}, wh.ID, time.Unix(ts, 0), body, sigs)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("success - event ID injected into the signed body", func(t *testing.T) {
+		secret, err := signature.GenerateSecret(32)
+		require.NoError(t, err)
+
+		var (
+			received http.Header
+			body     []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{
+			RouteID:            "user-events",
+			TargetURL:          server.URL,
+			SigningSecret:      secret.String(),
+			InjectEventIDField: "id",
+		}
+		_, err = worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"evt-1","type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`, string(body))
+
+		// Receivers verify the body they got, field included
+		ts, err := strconv.ParseInt(received.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		sigs, err := signature.ParseSignatureHeader(received.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+
+		valid, err := signature.VerifyMultiple([]signature.Secret{secret}, wh.ID, time.Unix(ts, 0), body, sigs)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("error - event ID cannot be injected into a body that is not an object", func(t *testing.T) {
+		route := &routes.Route{RouteID: "raw", TargetURL: "http://127.0.0.1:0", InjectEventIDField: "id"}
+		raw := webhook.Webhook{ID: "evt-2", RouteID: "raw", Payload: []byte(`[1, 2]`)}
+
+		statusCode, err := worker.NewClient(time.Second).Deliver(context.Background(), route, raw)
+		require.Error(t, err)
+		assert.Equal(t, 0, statusCode)
+		assert.Contains(t, err.Error(), "injecting id: body is not a JSON object")
+	})
+
+	t.Run("error - body template cannot render", func(t *testing.T) {
+		route := &routes.Route{RouteID: "slack", TargetURL: "http://127.0.0.1:0", BodyTemplate: `not json {{.Type}}`}
+
+		statusCode, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, 0, statusCode)
+		assert.Contains(t, err.Error(), "rendering body template")
+	})
+
+	t.Run("error - non-2xx status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL}
+		statusCode, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusInternalServerError, statusCode)
+		assert.Contains(t, err.Error(), "500")
+	})
+
+	t.Run("require signature - unsignable webhooks are never sent", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		for name, secret := range map[string]string{"no secret": "", "invalid secret": "whsec_!!!"} {
+			route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, SigningSecret: secret, RequireSignature: true}
+
+			statusCode, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+			require.ErrorIs(t, err, worker.ErrSignatureRequired, name)
+			assert.Equal(t, 0, statusCode, name)
+		}
+		assert.Zero(t, requests.Load())
+	})
+
+	t.Run("success - unsigned without a secret when signatures are optional", func(t *testing.T) {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL}
+		_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+		assert.Empty(t, received.Get(worker.HeaderWebhookSignature))
+	})
+
+	t.Run("expected statuses - route expecting only 204", func(t *testing.T) {
+		var status atomic.Int32
+		status.Store(http.StatusNoContent)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(int(status.Load()))
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "no-content", TargetURL: server.URL, ExpectedStatuses: []string{"204"}}
+		client := worker.NewClient(time.Second)
+
+		statusCode, err := client.Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, statusCode)
+
+		// Other 2xx responses are failures for this route
+		status.Store(http.StatusOK)
+		statusCode, err = client.Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, err.Error(), "200")
+	})
+}
+
+func TestClient_Deliver_Cancelled(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is read
+		io.Copy(io.Discard, r.Body)
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	route := &routes.Route{RouteID: "user-events", TargetURL: server.URL}
+	wh := webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`)}
+
+	// The client's own timeout is far longer, so only the cancellation can end the request
+	statusCode, err := worker.NewClient(time.Minute).Deliver(ctx, route, wh)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, statusCode)
+}
+
+func TestClient_Deliver_ResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"missing field user_id"}`))
+	}))
+	defer server.Close()
+
+	wh := webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`)}
+	client := worker.NewClient(time.Second)
+
+	t.Run("failed delivery captures the body", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, CaptureResponseBytes: routes.DefaultCaptureResponseBytes}
+
+		_, err := client.Deliver(context.Background(), route, wh)
+		var deliveryErr *webhook.DeliveryError
+		require.ErrorAs(t, err, &deliveryErr)
+		assert.Equal(t, `{"error":"missing field user_id"}`, deliveryErr.ResponseBody)
+		assert.Equal(t, deliveryErr.ResponseBody, webhook.ResponseBodyOf(err))
+	})
+
+	t.Run("body is truncated to the limit", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, CaptureResponseBytes: 9}
+
+		_, err := client.Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, `{"error":`, webhook.ResponseBodyOf(err))
+	})
+
+	t.Run("zero disables capture", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL}
+
+		_, err := client.Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Empty(t, webhook.ResponseBodyOf(err))
+	})
+}
+
+func TestClient_Deliver_ErrorKinds(t *testing.T) {
+	wh := webhook.Webhook{ID: "evt-1", Payload: []byte(`{}`)}
+
+	// deliveryError delivers wh to route and returns the resulting DeliveryError
+	deliveryError := func(t *testing.T, client *worker.Client, route *routes.Route) *webhook.DeliveryError {
+		t.Helper()
+		_, err := client.Deliver(context.Background(), route, wh)
+		var deliveryErr *webhook.DeliveryError
+		require.ErrorAs(t, err, &deliveryErr)
+		return deliveryErr
+	}
+
+	t.Run("status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := deliveryError(t, worker.NewClient(time.Second), &routes.Route{RouteID: "status", TargetURL: server.URL})
+		assert.Equal(t, webhook.DeliveryErrorStatus, err.Kind)
+		assert.Equal(t, http.StatusServiceUnavailable, err.StatusCode)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		err := deliveryError(t, worker.NewClient(50*time.Millisecond), &routes.Route{RouteID: "timeout", TargetURL: server.URL})
+		assert.Equal(t, webhook.DeliveryErrorTimeout, err.Kind)
+		assert.Zero(t, err.StatusCode)
+	})
+
+	t.Run("network", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		err := deliveryError(t, worker.NewClient(time.Second), &routes.Route{RouteID: "network", TargetURL: server.URL})
+		assert.Equal(t, webhook.DeliveryErrorNetwork, err.Kind)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		// The test server's certificate is not trusted by the default transport
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+
+		err := deliveryError(t, worker.NewClient(time.Second), &routes.Route{RouteID: "tls", TargetURL: server.URL})
+		assert.Equal(t, webhook.DeliveryErrorTLS, err.Kind)
+	})
+
+	t.Run("other", func(t *testing.T) {
+		route := &routes.Route{RouteID: "unsignable", TargetURL: "http://127.0.0.1:0", RequireSignature: true}
+
+		err := deliveryError(t, worker.NewClient(time.Second), route)
+		assert.Equal(t, webhook.DeliveryErrorOther, err.Kind)
+		assert.ErrorIs(t, err, worker.ErrSignatureRequired)
+	})
+}
+
+func TestClient_Deliver_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := generateCA(t)
+	certFile, keyFile := writeClientCert(t, dir, caCert, caKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(caCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	// Trust the test server through a custom CA file
+	serverCAFile := filepath.Join(dir, "server-ca.pem")
+	writePEM(t, serverCAFile, "CERTIFICATE", server.Certificate().Raw)
+
+	wh := webhook.Webhook{ID: "evt-1", Payload: []byte(`{}`)}
+
+	t.Run("success - client certificate configured", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "mtls",
+			TargetURL:      server.URL,
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			CAFile:         serverCAFile,
+		}
+
+		client := worker.NewClient(time.Second)
+		_, err := client.Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+
+		// The cached transport is reused for later deliveries
+		_, err = client.Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+	})
+
+	t.Run("error - no client certificate", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:   "no-mtls",
+			TargetURL: server.URL,
+			CAFile:    serverCAFile,
+		}
+
+		_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, webhook.DeliveryErrorTLS, webhook.DeliveryErrorKindOf(err))
+	})
+
+	t.Run("success - rotated TLS files are picked up", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "rotating-ca.pem")
+		writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+		route := &routes.Route{
+			RouteID:        "rotating-mtls",
+			TargetURL:      server.URL,
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			CAFile:         caFile,
+		}
+
+		client := worker.NewClient(time.Second)
+		_, err := client.Deliver(context.Background(), route, wh)
+		require.NoError(t, err)
+
+		// The CA file is replaced by one that doesn't trust the server
+		writePEM(t, caFile, "CERTIFICATE", caCert.Raw)
+		rotatedAt := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(caFile, rotatedAt, rotatedAt))
+		_, err = client.Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Equal(t, webhook.DeliveryErrorTLS, webhook.DeliveryErrorKindOf(err))
+
+		// A reloaded route pointing at another file is picked up too
+		reloaded := *route
+		reloaded.CAFile = serverCAFile
+		_, err = client.Deliver(context.Background(), &reloaded, wh)
+		require.NoError(t, err)
+	})
+
+	t.Run("error - unreadable client certificate", func(t *testing.T) {
+		route := &routes.Route{
+			RouteID:        "broken-mtls",
+			TargetURL:      server.URL,
+			ClientCertFile: filepath.Join(dir, "missing.pem"),
+			ClientKeyFile:  keyFile,
+		}
+
+		_, err := worker.NewClient(time.Second).Deliver(context.Background(), route, wh)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "building TLS config")
+	})
+}
+
+// generateCA creates a self-signed CA certificate for signing client certificates
+func generateCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "webhook-inbox test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+// writeClientCert issues a client certificate signed by the CA and writes it with its key as PEM files
+func writeClientCert(t *testing.T, dir string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "webhook-inbox worker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
@@ -0,0 +1,49 @@
+package worker
+
+import "context"
+
+/* DeliveryLimiter bounds the deliveries in flight across every worker sharing it
+ * Route parallelism only bounds a single route; create one limiter per process
+ * and pass it to every worker so all routes together stay under the limit
+ * A nil *DeliveryLimiter never blocks
+ */
+type DeliveryLimiter struct {
+	slots chan struct{}
+}
+
+// NewDeliveryLimiter allows up to max concurrent deliveries (max <= 0: unlimited, returns nil)
+func NewDeliveryLimiter(max int) *DeliveryLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &DeliveryLimiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire waits for a free delivery slot, or returns the context's error if it ends first
+func (l *DeliveryLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *DeliveryLimiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// InFlight returns the number of slots currently taken
+func (l *DeliveryLimiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
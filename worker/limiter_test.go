@@ -0,0 +1,139 @@
+package worker_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/marcelsud/webhook-inbox/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryLimiter(t *testing.T) {
+	t.Run("blocks once the limit is reached", func(t *testing.T) {
+		limiter := worker.NewDeliveryLimiter(2)
+		ctx := context.Background()
+
+		require.NoError(t, limiter.Acquire(ctx))
+		require.NoError(t, limiter.Acquire(ctx))
+		assert.Equal(t, 2, limiter.InFlight())
+
+		acquired := make(chan struct{})
+		go func() {
+			if limiter.Acquire(ctx) == nil {
+				close(acquired)
+			}
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("acquired a slot beyond the limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		limiter.Release()
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("released slot was not handed over")
+		}
+		assert.Equal(t, 2, limiter.InFlight())
+	})
+
+	t.Run("cancelled context stops waiting", func(t *testing.T) {
+		limiter := worker.NewDeliveryLimiter(1)
+		require.NoError(t, limiter.Acquire(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, limiter.Acquire(ctx), context.DeadlineExceeded)
+		assert.Equal(t, 1, limiter.InFlight())
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		limiter := worker.NewDeliveryLimiter(0)
+		assert.Nil(t, limiter)
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, limiter.Acquire(context.Background()))
+		}
+		limiter.Release()
+		assert.Equal(t, 0, limiter.InFlight())
+	})
+}
+
+func TestWorker_Run_DeliveryLimiter(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, requests := 0, 0, 0
+	received := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		requests++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		received <- struct{}{}
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	limiter := worker.NewDeliveryLimiter(1)
+	var acked sync.WaitGroup
+
+	// Two routes share the limiter, each with one webhook to deliver
+	for _, routeID := range []string{"user-events", "orders"} {
+		route := &routes.Route{RouteID: routeID, TargetURL: server.URL, Mode: webhook.FIFO}
+		wh := webhook.Webhook{ID: routeID + "-1", RouteID: routeID, Payload: []byte(`{}`), DeliveryMode: webhook.FIFO}
+
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, routeID, webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("Consume", mock.Anything, routeID, webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		repo.On("UpdateStatus", mock.Anything, wh.ID, webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, wh.ID, webhook.Delivered).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, wh.ID, time.Hour).Return(nil).Once()
+		acked.Add(1)
+		repo.On("Acknowledge", mock.Anything, routeID, webhook.FIFO, wh.ID).Return(nil).Once().Run(func(mock.Arguments) { acked.Done() })
+
+		runWorker(t, worker.New(route, repo, worker.NewClient(time.Second), worker.WithDeliveryLimiter(limiter)))
+	}
+
+	<-received
+	assert.Equal(t, 1, limiter.InFlight())
+
+	// The other route waits for the slot
+	select {
+	case <-received:
+		t.Fatal("second delivery started while the limit was reached")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Completing the first delivery releases the slot
+	close(release)
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("second delivery never started")
+	}
+	acked.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, maxInFlight)
+	assert.Equal(t, 0, limiter.InFlight())
+}
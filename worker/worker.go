@@ -0,0 +1,909 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/config"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
+	"github.com/marcelsud/webhook-inbox/webhook/payload"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Worker statuses reported in heartbeats
+const (
+	StatusIdle       = "idle"
+	StatusProcessing = "processing"
+	StatusPaused     = "paused" // The route is disabled (see routes.Route.Enabled)
+)
+
+// disabledPollInterval is how often a worker of a disabled route checks whether it was enabled again
+const disabledPollInterval = time.Second
+
+// DefaultHeartbeatInterval is how often a worker reports itself as alive
+// Heartbeats expire after 60 seconds, so two consecutive beats may be missed
+const DefaultHeartbeatInterval = 30 * time.Second
+
+// heartbeatCleanupTimeout bounds the heartbeat removal done on shutdown
+const heartbeatCleanupTimeout = 5 * time.Second
+
+// HeartbeatStore records worker liveness so metrics can count active workers
+type HeartbeatStore interface {
+	SetWorkerHeartbeat(ctx context.Context, workerID, routeID, status string) error
+	DeleteWorkerHeartbeat(ctx context.Context, workerID, routeID string) error
+}
+
+// DefaultTrimInterval is how often a worker trims its route's streams to routes.Route.MaxStreamLen
+const DefaultTrimInterval = time.Minute
+
+// StreamTrimmer drops a route's acknowledged stream entries beyond a length (see redis.Repository.TrimStream)
+type StreamTrimmer interface {
+	TrimStream(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, maxLen int64) (int64, error)
+}
+
+/* Worker consumes a single route's stream and delivers its webhooks
+ * Deliveries are retried in place following the route's backoff expression,
+ * so FIFO routes keep their ordering while a webhook is being retried
+ * PubSub routes with parallelism > 1 deliver up to that many webhooks concurrently
+ */
+type Worker struct {
+	id       string
+	route    *routes.Route
+	repo     webhook.Repository
+	client   *Client
+	cfg      *config.Config
+	dlq      webhook.DeadLetterQueue
+	attempts webhook.AttemptLog
+	schedule webhook.Scheduler
+	archiver webhook.Archiver
+	limiter  *DeliveryLimiter
+	lookup   func(routeID string) (*routes.Route, error)
+	enabled  func(routeID string) bool
+	secret   func(routeID string) (string, bool)
+	hooks    hooks
+	logger   *slog.Logger
+	tracer   trace.Tracer
+	clock    clock.Clock
+
+	randMu sync.Mutex
+	rand   *rand.Rand // retry jitter; shared by concurrent deliveries
+
+	heartbeats        HeartbeatStore
+	heartbeatInterval time.Duration
+	trimmer           StreamTrimmer
+	trimInterval      time.Duration
+	inFlight          atomic.Int32 // deliveries in progress; the worker is processing while > 0
+}
+
+// hooks are the embedder callbacks run on delivery outcomes (see OnSuccess, OnRetry and OnFailure)
+type hooks struct {
+	success func(wh webhook.Webhook)
+	retry   func(wh webhook.Webhook, err error)
+	failure func(wh webhook.Webhook, err error)
+}
+
+// Option configures optional Worker behavior
+type Option func(*Worker)
+
+// WithID sets the worker ID reported in heartbeats
+// Defaults to the repository's consumer name, or "{hostname}-{pid}"
+func WithID(id string) Option {
+	return func(w *Worker) {
+		w.id = id
+	}
+}
+
+// WithHeartbeats sets where heartbeats are written
+// Defaults to the repository when it implements HeartbeatStore
+func WithHeartbeats(store HeartbeatStore) Option {
+	return func(w *Worker) {
+		w.heartbeats = store
+	}
+}
+
+// WithHeartbeatInterval sets how often heartbeats are emitted (default: 30s)
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(w *Worker) {
+		if interval > 0 {
+			w.heartbeatInterval = interval
+		}
+	}
+}
+
+// WithStreamTrimmer sets what trims the route's streams when it has a max_stream_len
+// Defaults to the repository when it implements StreamTrimmer
+func WithStreamTrimmer(trimmer StreamTrimmer) Option {
+	return func(w *Worker) {
+		w.trimmer = trimmer
+	}
+}
+
+// WithTrimInterval sets how often the route's streams are trimmed (default: 1m)
+func WithTrimInterval(interval time.Duration) Option {
+	return func(w *Worker) {
+		if interval > 0 {
+			w.trimInterval = interval
+		}
+	}
+}
+
+// WithDeadLetterQueue parks webhooks that exhausted their retries instead of only marking them failed
+func WithDeadLetterQueue(dlq webhook.DeadLetterQueue) Option {
+	return func(w *Worker) {
+		w.dlq = dlq
+	}
+}
+
+// WithAttemptLog sets where delivery attempts are recorded
+// Defaults to the repository when it implements webhook.AttemptLog
+func WithAttemptLog(attempts webhook.AttemptLog) Option {
+	return func(w *Worker) {
+		w.attempts = attempts
+	}
+}
+
+// WithScheduler sets where webhooks consumed before their delivery time are parked
+// Defaults to the repository when it implements webhook.Scheduler; without one,
+// the worker waits until such webhooks are due
+func WithScheduler(scheduler webhook.Scheduler) Option {
+	return func(w *Worker) {
+		w.schedule = scheduler
+	}
+}
+
+// WithArchiver archives delivered webhooks of routes with archive: true before their TTL is set
+// An archiving error leaves the webhook unacknowledged: once it is consumed again (e.g. after a rebalance)
+// it is archived and acknowledged without being delivered twice
+func WithArchiver(archiver webhook.Archiver) Option {
+	return func(w *Worker) {
+		w.archiver = archiver
+	}
+}
+
+// WithConfig sets the configuration used for delivered and failed TTL defaults
+// Routes without a signing secret are signed with its default signing secret, whatever the client
+func WithConfig(cfg *config.Config) Option {
+	return func(w *Worker) {
+		w.cfg = cfg
+	}
+}
+
+// WithDeliveryLimiter makes every delivery attempt take a slot of a limiter shared across routes
+// Slots are only held while the request is in flight, not while waiting to retry
+func WithDeliveryLimiter(limiter *DeliveryLimiter) Option {
+	return func(w *Worker) {
+		w.limiter = limiter
+	}
+}
+
+// WithRouteLookup delivers each webhook with the settings of its own route, found through lookup
+// Used by workers draining a route group's shared stream (see routes.RouteGroup.Route), e.g. with loader.Get
+// Webhooks whose route the lookup doesn't find are left pending
+func WithRouteLookup(lookup func(routeID string) (*routes.Route, error)) Option {
+	return func(w *Worker) {
+		w.lookup = lookup
+	}
+}
+
+// WithRouteEnabled decides whether the worker's route is enabled, e.g. with loader.IsEnabled,
+// so disabling a route at runtime pauses its workers (default: the route's own Enabled setting)
+// Paused workers stop reading the route's streams; webhooks keep queueing until it is enabled again
+func WithRouteEnabled(enabled func(routeID string) bool) Option {
+	return func(w *Worker) {
+		w.enabled = enabled
+	}
+}
+
+// WithCurrentSecret signs every attempt with the route's current primary secret, found through secret,
+// e.g. with loader.SigningSecret, instead of the secrets the worker's route had when it was loaded
+// Secrets rotated at runtime then apply to retries and to webhooks replayed from the DLQ, and the
+// previous secret is no longer signed with. Routes secret doesn't know keep their own secrets
+func WithCurrentSecret(secret func(routeID string) (string, bool)) Option {
+	return func(w *Worker) {
+		w.secret = secret
+	}
+}
+
+// OnSuccess runs hook after a webhook is delivered and acknowledged, e.g. for auditing
+// Hooks run on the delivering goroutine, so slow hooks hold up deliveries; panics are recovered and logged
+func OnSuccess(hook func(wh webhook.Webhook)) Option {
+	return func(w *Worker) {
+		w.hooks.success = hook
+	}
+}
+
+// OnRetry runs hook with the delivery error each time a failed webhook is scheduled for another attempt
+// wh.RetryCount already counts the upcoming retry
+func OnRetry(hook func(wh webhook.Webhook, err error)) Option {
+	return func(w *Worker) {
+		w.hooks.retry = hook
+	}
+}
+
+// OnFailure runs hook with the last delivery error once a webhook is marked failed, e.g. to notify its owner
+// Covers exhausted retries, closed retry windows, non-retryable statuses (including ack_policy keep_pending) and unsignable webhooks
+func OnFailure(hook func(wh webhook.Webhook, err error)) Option {
+	return func(w *Worker) {
+		w.hooks.failure = hook
+	}
+}
+
+// WithLogger sets the logger used for delivery and heartbeat errors (default: slog.Default())
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Worker) {
+		w.logger = logger
+	}
+}
+
+// WithTracerProvider sets the tracer provider for consume and delivery spans (default: the global tracer provider)
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(w *Worker) {
+		w.tracer = provider.Tracer(webhook.TracerName)
+	}
+}
+
+// WithClock sets the clock used for due times, retry windows and attempt timestamps (default: clock.System)
+// Also used by the default client to timestamp signatures; waiting between attempts still takes real time
+func WithClock(c clock.Clock) Option {
+	return func(w *Worker) {
+		w.clock = c
+	}
+}
+
+// WithRand sets the random source for retry jitter (default: randomly seeded)
+// Tests pass a fixed seed to make jittered delays reproducible
+func WithRand(rnd *rand.Rand) Option {
+	return func(w *Worker) {
+		w.rand = rnd
+	}
+}
+
+// New creates a worker for a route
+func New(route *routes.Route, repo webhook.Repository, client *Client, opts ...Option) *Worker {
+	w := &Worker{
+		route:             route,
+		repo:              repo,
+		client:            client,
+		logger:            slog.Default(),
+		tracer:            otel.Tracer(webhook.TracerName),
+		clock:             clock.System,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		trimInterval:      DefaultTrimInterval,
+	}
+	if store, ok := repo.(HeartbeatStore); ok {
+		w.heartbeats = store
+	}
+	if trimmer, ok := repo.(StreamTrimmer); ok {
+		w.trimmer = trimmer
+	}
+	if attempts, ok := repo.(webhook.AttemptLog); ok {
+		w.attempts = attempts
+	}
+	if scheduler, ok := repo.(webhook.Scheduler); ok {
+		w.schedule = scheduler
+	}
+	if named, ok := repo.(interface{ ConsumerName() string }); ok {
+		w.id = named.ConsumerName()
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if w.id == "" {
+		w.id = webhook.DefaultConsumerName()
+	}
+	if w.client == nil {
+		w.client = NewClient(DefaultDeliveryTimeout, WithClientClock(w.clock))
+	}
+	if w.rand == nil {
+		w.rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	return w
+}
+
+// ID returns the worker ID reported in heartbeats
+func (w *Worker) ID() string {
+	return w.id
+}
+
+// Run consumes and delivers webhooks until the context is cancelled
+// Emits heartbeats while running and removes its heartbeat before returning
+// In-flight deliveries are interrupted on cancellation and left pending, never acknowledged
+func (w *Worker) Run(ctx context.Context) error {
+	w.run(ctx, 0)
+	return nil
+}
+
+/* RunN consumes and delivers n webhooks, then returns how many it processed, e.g. for cron-style batches
+ * It waits for webhooks like Run; when the context ends first it returns the count so far with ctx's error
+ * Every read asks for at most the webhooks still to process, so no more than n are claimed
+ */
+func (w *Worker) RunN(ctx context.Context, n int) (int, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("n must be positive (got %d)", n)
+	}
+
+	processed := w.run(ctx, n)
+	if processed < n {
+		return processed, ctx.Err()
+	}
+	return processed, nil
+}
+
+// run delivers webhooks until the context is cancelled or, when limit > 0, limit webhooks were processed
+// Heartbeats and stream trimming run alongside until it returns
+func (w *Worker) run(ctx context.Context, limit int) int {
+	var wg sync.WaitGroup
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	if w.heartbeats != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.heartbeat(backgroundCtx)
+		}()
+	}
+	if w.trimmer != nil && w.route.MaxStreamLen > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.trim(backgroundCtx)
+		}()
+	}
+	defer func() {
+		stopBackground()
+		wg.Wait()
+		w.removeHeartbeat(ctx)
+	}()
+
+	if w.route.Mode == webhook.PubSub && w.route.Parallelism > 1 {
+		return w.runParallel(ctx, w.route.Parallelism, limit)
+	}
+	return w.runSequential(ctx, limit)
+}
+
+// runSequential delivers webhooks one at a time, in stream order
+func (w *Worker) runSequential(ctx context.Context, limit int) int {
+	processed := 0
+	for ctx.Err() == nil && (limit == 0 || processed < limit) {
+		webhooks, err := w.consume(ctx, 1)
+		if err != nil {
+			w.consumeFailed(ctx, err)
+			continue
+		}
+
+		for _, wh := range webhooks {
+			w.deliver(ctx, wh)
+			processed++
+		}
+	}
+	return processed
+}
+
+/* runParallel delivers up to parallelism webhooks concurrently
+ * Webhooks are only read when a delivery slot is free, so none sit claimed but
+ * undelivered, and each delivery acknowledges its own webhook once it completes
+ */
+func (w *Worker) runParallel(ctx context.Context, parallelism, limit int) int {
+	var deliveries sync.WaitGroup
+	defer deliveries.Wait()
+
+	slots := make(chan struct{}, parallelism)
+	acquire := func() bool {
+		select {
+		case slots <- struct{}{}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	processed := 0
+	for (limit == 0 || processed < limit) && acquire() {
+		// Slots are only released concurrently, so every slot free now can be taken below
+		count := cap(slots) - len(slots) + 1
+		if limit > 0 {
+			count = min(count, limit-processed)
+		}
+		webhooks, err := w.consume(ctx, count)
+		if err != nil || len(webhooks) == 0 {
+			<-slots
+			if err != nil {
+				w.consumeFailed(ctx, err)
+			}
+			continue
+		}
+
+		for i, wh := range webhooks {
+			// Repositories without batch reads may return more webhooks than free slots
+			if i > 0 && !acquire() {
+				return processed
+			}
+			deliveries.Add(1)
+			processed++
+			go func() {
+				defer deliveries.Done()
+				defer func() { <-slots }()
+				w.deliver(ctx, wh)
+			}()
+		}
+	}
+	return processed
+}
+
+// consume reads up to count webhooks, in a single call when the repository supports batches
+// Reads nothing while the route is disabled, waiting a moment instead so paused workers don't spin
+func (w *Worker) consume(ctx context.Context, count int) ([]webhook.Webhook, error) {
+	if !w.routeEnabled() {
+		sleep(ctx, disabledPollInterval)
+		return nil, nil
+	}
+	started := time.Now()
+
+	var webhooks []webhook.Webhook
+	var err error
+	if batch, ok := w.repo.(webhook.BatchConsumer); ok && count > 1 {
+		webhooks, err = batch.ConsumeBatch(ctx, w.route.RouteID, w.route.Mode, count)
+	} else {
+		webhooks, err = w.repo.Consume(ctx, w.route.RouteID, w.route.Mode)
+	}
+
+	w.traceConsume(ctx, started, webhooks, err)
+	return webhooks, err
+}
+
+// traceConsume records a webhook.consume span linked to the ingestion span of every webhook read
+// Idle polls are not recorded, so blocking reads on quiet routes don't flood the traces
+func (w *Worker) traceConsume(ctx context.Context, started time.Time, webhooks []webhook.Webhook, err error) {
+	if len(webhooks) == 0 && err == nil {
+		return
+	}
+
+	links := make([]trace.Link, 0, len(webhooks))
+	for _, wh := range webhooks {
+		parent := trace.SpanContextFromContext(webhook.ContextWithTraceParent(context.Background(), wh.TraceParent))
+		if parent.IsValid() {
+			links = append(links, trace.Link{SpanContext: parent})
+		}
+	}
+
+	_, span := w.tracer.Start(ctx, "webhook.consume",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(started),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("webhook.route_id", w.route.RouteID),
+			attribute.Int("webhook.count", len(webhooks)),
+		),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// routeEnabled reports whether the worker's route is currently enabled
+func (w *Worker) routeEnabled() bool {
+	if w.enabled != nil {
+		return w.enabled(w.route.RouteID)
+	}
+	return w.route.IsEnabled()
+}
+
+// consumeFailed logs a consume error and backs off, unless the worker is stopping
+func (w *Worker) consumeFailed(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	w.logger.Error("consuming webhooks", "route_id", w.route.RouteID, "error", err)
+	sleep(ctx, time.Second)
+}
+
+// deliver processes a webhook, reporting the worker as processing meanwhile
+// The webhook.deliver span continues the trace of the webhook's ingestion span
+func (w *Worker) deliver(ctx context.Context, wh webhook.Webhook) {
+	w.inFlight.Add(1)
+	defer w.inFlight.Add(-1)
+
+	ctx, span := w.tracer.Start(webhook.ContextWithTraceParent(ctx, wh.TraceParent), "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.route_id", w.route.RouteID),
+			attribute.String("webhook.event_id", wh.ID),
+		),
+	)
+	defer span.End()
+
+	if err := w.process(ctx, wh); err != nil && ctx.Err() == nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.logger.Error("processing webhook", "route_id", w.route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "error", err)
+	}
+}
+
+// process delivers a webhook, retrying until it succeeds, runs out of retries or its retry window closes
+// Webhooks consumed before their delivery time are handed to the scheduler
+// Returns without acknowledging when the context is cancelled, leaving the message pending
+func (w *Worker) process(ctx context.Context, wh webhook.Webhook) error {
+	route, err := w.routeOf(wh)
+	if err != nil {
+		return err
+	}
+
+	if !subscribed(route, wh) {
+		return w.repo.Acknowledge(ctx, route.RouteID, route.Mode, wh.ID)
+	}
+	// A webhook already delivered was left pending by an error finishing it (e.g. archiving),
+	// so it is finished again rather than sent to the target a second time
+	if wh.Status == webhook.Delivered {
+		if err := w.finish(ctx, route, wh, webhook.Delivered); err != nil {
+			return err
+		}
+		w.succeeded(wh)
+		return nil
+	}
+	if !wh.Due(w.clock.Now()) {
+		if w.schedule != nil {
+			return w.schedule.Schedule(ctx, wh)
+		}
+		if !sleep(ctx, wh.DeliverAt.Sub(w.clock.Now())) {
+			return ctx.Err()
+		}
+	}
+
+	for {
+		// Wait for a slot before marking the webhook as delivering
+		if err := w.limiter.Acquire(ctx); err != nil {
+			return err
+		}
+		if err := w.repo.UpdateStatus(ctx, wh.ID, webhook.Delivering); err != nil {
+			w.limiter.Release()
+			return fmt.Errorf("updating status: %w", err)
+		}
+
+		started := w.clock.Now()
+		statusCode, deliveryErr := w.client.Deliver(ctx, w.signedWith(route), wh)
+		w.limiter.Release()
+		traceAttempt(ctx, wh, statusCode, deliveryErr)
+		// An attempt cut short by shutdown says nothing about the target: it is neither
+		// recorded nor retried, and the message stays pending for the next consumer
+		if deliveryErr != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.recordAttempt(ctx, wh, started, statusCode, deliveryErr)
+
+		if deliveryErr == nil {
+			if err := w.finish(ctx, route, wh, webhook.Delivered); err != nil {
+				return err
+			}
+			w.succeeded(wh)
+			return nil
+		}
+		if errors.Is(deliveryErr, ErrSignatureRequired) {
+			w.logger.Error("webhook could not be signed", "route_id", route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "error", deliveryErr)
+			return w.fail(ctx, route, wh, deliveryErr)
+		}
+		if rejected(route, deliveryErr) {
+			w.logger.Warn("webhook rejected by target", "route_id", route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "status", statusCode, "ack_policy", route.AckPolicy)
+			if route.AckPolicy == routes.AckPolicyKeepPending {
+				return w.keepPending(ctx, wh, deliveryErr)
+			}
+			return w.fail(ctx, route, wh, deliveryErr)
+		}
+		if wh.RetryCount >= wh.MaxRetries {
+			w.logger.Warn("webhook delivery failed", "route_id", route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "retries", wh.RetryCount, "error", deliveryErr)
+			return w.fail(ctx, route, wh, deliveryErr)
+		}
+
+		delay, err := w.backoff(route, wh.RetryCount)
+		if err != nil {
+			return err
+		}
+		// A retry that would start after the route's retry window is not attempted
+		if deadline, ok := route.RetryDeadline(wh.CreatedAt); ok && w.clock.Now().Add(delay).After(deadline) {
+			w.logger.Warn("webhook retry window exceeded", "route_id", route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "retries", wh.RetryCount, "created_at", wh.CreatedAt, "error", deliveryErr)
+			return w.fail(ctx, route, wh, deliveryErr)
+		}
+		err = w.repo.IncrementRetry(ctx, wh.ID)
+		if errors.Is(err, webhook.ErrRetryLimit) {
+			// The stored count ran past max_retries, e.g. through concurrent consumers
+			w.logger.Warn("webhook retry limit reached", "route_id", route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "error", deliveryErr)
+			return w.fail(ctx, route, wh, deliveryErr)
+		}
+		if err != nil {
+			return fmt.Errorf("incrementing retry count: %w", err)
+		}
+		if err := w.repo.UpdateStatus(ctx, wh.ID, webhook.Retrying); err != nil {
+			return fmt.Errorf("updating status: %w", err)
+		}
+		wh.RetryCount++
+		w.retrying(wh, deliveryErr)
+
+		if !sleep(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+}
+
+// rejected reports whether a delivery failed on a status the route never retries
+func rejected(route *routes.Route, deliveryErr error) bool {
+	var failure *webhook.DeliveryError
+	return errors.As(deliveryErr, &failure) && failure.Kind == webhook.DeliveryErrorStatus && !route.IsRetryableStatus(failure.StatusCode)
+}
+
+// routeOf returns the route whose settings apply to a webhook
+// Without a route lookup, every webhook is delivered with the worker's route
+func (w *Worker) routeOf(wh webhook.Webhook) (*routes.Route, error) {
+	if w.lookup == nil || wh.RouteID == w.route.RouteID {
+		return w.route, nil
+	}
+	route, err := w.lookup(wh.RouteID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up route %s: %w", wh.RouteID, err)
+	}
+	return route, nil
+}
+
+// signedWith returns the route an attempt is delivered with: a copy signing with the current
+// primary secret when WithCurrentSecret is set, then with the config's default signing secret
+// when the route has none, the route itself otherwise
+func (w *Worker) signedWith(route *routes.Route) *routes.Route {
+	if w.secret != nil {
+		if secret, ok := w.secret(route.RouteID); ok {
+			current := *route
+			current.SigningSecret = secret
+			current.SigningSecrets = nil
+			route = &current
+		}
+	}
+
+	if route.SigningSecret != "" || w.cfg == nil || w.cfg.WebhookDefaultSigningSecret == "" {
+		return route
+	}
+	fallback := *route
+	fallback.SigningSecret = w.cfg.WebhookDefaultSigningSecret
+	fallback.SigningSecrets = nil
+	return &fallback
+}
+
+// backoff returns the route's jittered delay before the next attempt
+func (w *Worker) backoff(route *routes.Route, retried int) (time.Duration, error) {
+	w.randMu.Lock()
+	defer w.randMu.Unlock()
+	return route.JitteredBackoff(retried, w.rand)
+}
+
+// finish records a terminal status, sets the webhook's TTL and acknowledges it
+// Failed webhooks are dead-lettered and acknowledged atomically when the DLQ supports it
+func (w *Worker) finish(ctx context.Context, route *routes.Route, wh webhook.Webhook, status webhook.Status) error {
+	if acknowledger, ok := w.dlq.(webhook.DeadLetterAcknowledger); ok && status == webhook.Failed {
+		if err := acknowledger.MoveToDLQAndAcknowledge(ctx, wh, route.Mode); err != nil {
+			return fmt.Errorf("moving to DLQ: %w", err)
+		}
+		return nil
+	}
+
+	switch {
+	case status == webhook.Failed && w.dlq != nil:
+		if err := w.dlq.MoveToDLQ(ctx, wh); err != nil {
+			return fmt.Errorf("moving to DLQ: %w", err)
+		}
+	case status == webhook.Failed:
+		if err := w.repo.UpdateStatus(ctx, wh.ID, status); err != nil {
+			return fmt.Errorf("updating status: %w", err)
+		}
+		if err := w.repo.SetTTL(ctx, wh.ID, route.GetFailedTTL(w.cfg)); err != nil {
+			return fmt.Errorf("setting TTL: %w", err)
+		}
+	default:
+		if err := w.repo.UpdateStatus(ctx, wh.ID, status); err != nil {
+			return fmt.Errorf("updating status: %w", err)
+		}
+		if route.Archive && w.archiver != nil {
+			wh.Status = status
+			if err := w.archiver.Archive(ctx, wh); err != nil {
+				return fmt.Errorf("archiving webhook: %w", err)
+			}
+		}
+		if err := w.repo.SetTTL(ctx, wh.ID, route.GetDeliveredTTL(w.cfg)); err != nil {
+			return fmt.Errorf("setting TTL: %w", err)
+		}
+	}
+
+	if err := w.repo.Acknowledge(ctx, route.RouteID, route.Mode, wh.ID); err != nil {
+		return fmt.Errorf("acknowledging webhook: %w", err)
+	}
+	return nil
+}
+
+// fail finishes a webhook that won't be retried as failed and runs the failure hook
+func (w *Worker) fail(ctx context.Context, route *routes.Route, wh webhook.Webhook, deliveryErr error) error {
+	if err := w.finish(ctx, route, wh, webhook.Failed); err != nil {
+		return err
+	}
+	w.failed(wh, deliveryErr)
+	return nil
+}
+
+// keepPending marks a webhook as failed without acknowledging it (ack_policy keep_pending)
+// The webhook keeps no TTL, so its pending message can be inspected and claimed manually
+func (w *Worker) keepPending(ctx context.Context, wh webhook.Webhook, deliveryErr error) error {
+	if err := w.repo.UpdateStatus(ctx, wh.ID, webhook.Failed); err != nil {
+		return fmt.Errorf("updating status: %w", err)
+	}
+	w.failed(wh, deliveryErr)
+	return nil
+}
+
+// succeeded runs the success hook, if any
+func (w *Worker) succeeded(wh webhook.Webhook) {
+	if w.hooks.success != nil {
+		defer w.recoverHook("success", wh)
+		w.hooks.success(wh)
+	}
+}
+
+// retrying runs the retry hook, if any
+func (w *Worker) retrying(wh webhook.Webhook, deliveryErr error) {
+	if w.hooks.retry != nil {
+		defer w.recoverHook("retry", wh)
+		w.hooks.retry(wh, deliveryErr)
+	}
+}
+
+// failed runs the failure hook, if any
+func (w *Worker) failed(wh webhook.Webhook, deliveryErr error) {
+	if w.hooks.failure != nil {
+		defer w.recoverHook("failure", wh)
+		w.hooks.failure(wh, deliveryErr)
+	}
+}
+
+// recoverHook logs a panicking hook instead of letting it stop the worker; deferred by the hook runners
+func (w *Worker) recoverHook(name string, wh webhook.Webhook) {
+	if recovered := recover(); recovered != nil {
+		w.logger.Error("webhook hook panicked", "hook", name, "route_id", wh.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "panic", recovered)
+	}
+}
+
+// recordAttempt adds the outcome of a delivery attempt to the webhook's history
+// Failing to record is logged but never blocks delivery
+func (w *Worker) recordAttempt(ctx context.Context, wh webhook.Webhook, started time.Time, statusCode int, deliveryErr error) {
+	if w.attempts == nil {
+		return
+	}
+
+	attempt := webhook.Attempt{
+		Timestamp:  started,
+		StatusCode: statusCode,
+		LatencyMs:  w.clock.Now().Sub(started).Milliseconds(),
+	}
+	if deliveryErr != nil {
+		attempt.Error = deliveryErr.Error()
+		attempt.ErrorKind = webhook.DeliveryErrorKindOf(deliveryErr)
+		attempt.ResponseBody = webhook.ResponseBodyOf(deliveryErr)
+	}
+
+	if err := w.attempts.RecordAttempt(ctx, wh.ID, attempt); err != nil && ctx.Err() == nil {
+		w.logger.Warn("recording delivery attempt", "route_id", w.route.RouteID, "event_id", wh.ID, "request_id", wh.RequestID, "error", err)
+	}
+}
+
+// traceAttempt adds a delivery attempt as an event of the webhook.deliver span
+func traceAttempt(ctx context.Context, wh webhook.Webhook, statusCode int, deliveryErr error) {
+	attrs := []attribute.KeyValue{
+		attribute.Int("webhook.retry_count", wh.RetryCount),
+		attribute.Int("http.response.status_code", statusCode),
+	}
+	if deliveryErr != nil {
+		attrs = append(attrs,
+			attribute.String("error.type", string(webhook.DeliveryErrorKindOf(deliveryErr))),
+			attribute.String("error.message", deliveryErr.Error()),
+		)
+	}
+	trace.SpanFromContext(ctx).AddEvent("webhook.attempt", trace.WithAttributes(attrs...))
+}
+
+// subscribed reports whether the route wants this webhook delivered
+// Raw routes and payloads that aren't Standard Webhooks are always delivered
+func subscribed(route *routes.Route, wh webhook.Webhook) bool {
+	if route.PayloadFormat == routes.PayloadFormatRaw || len(route.EventTypes) == 0 {
+		return true
+	}
+
+	p, err := payload.Parse(wh.Payload)
+	if err != nil {
+		return true
+	}
+	return route.MatchesType(p.Type)
+}
+
+// heartbeat reports the worker status immediately and then on every interval
+func (w *Worker) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(w.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		status := StatusIdle
+		if w.inFlight.Load() > 0 {
+			status = StatusProcessing
+		} else if !w.routeEnabled() {
+			status = StatusPaused
+		}
+		if err := w.heartbeats.SetWorkerHeartbeat(ctx, w.id, w.route.RouteID, status); err != nil && ctx.Err() == nil {
+			w.logger.Warn("sending heartbeat", "route_id", w.route.RouteID, "worker_id", w.id, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trim bounds the route's streams to its max_stream_len immediately and then on every interval
+// Only acknowledged entries are dropped, so the streams may stay longer while the route is behind
+func (w *Worker) trim(ctx context.Context) {
+	ticker := time.NewTicker(w.trimInterval)
+	defer ticker.Stop()
+
+	for {
+		trimmed, err := w.trimmer.TrimStream(ctx, w.route.RouteID, w.route.Mode, int64(w.route.MaxStreamLen))
+		if err != nil && ctx.Err() == nil {
+			w.logger.Warn("trimming stream", "route_id", w.route.RouteID, "error", err)
+		} else if trimmed > 0 {
+			w.logger.Debug("trimmed stream", "route_id", w.route.RouteID, "entries", trimmed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeHeartbeat deletes the worker's heartbeat so metrics drop it without waiting for the TTL
+func (w *Worker) removeHeartbeat(ctx context.Context) {
+	if w.heartbeats == nil {
+		return
+	}
+
+	// The run context is usually cancelled by now
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), heartbeatCleanupTimeout)
+	defer cancel()
+
+	if err := w.heartbeats.DeleteWorkerHeartbeat(ctx, w.id, w.route.RouteID); err != nil {
+		w.logger.Warn("removing heartbeat", "route_id", w.route.RouteID, "worker_id", w.id, "error", err)
+	}
+}
+
+// sleep waits for d or until the context is cancelled; reports whether the full delay elapsed
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
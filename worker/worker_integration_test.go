@@ -0,0 +1,678 @@
+//go:build integration
+
+package worker_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	httpchi "github.com/marcelsud/webhook-inbox/internal/http/chi"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/postgres"
+	"github.com/marcelsud/webhook-inbox/webhook/redis"
+	"github.com/marcelsud/webhook-inbox/worker"
+	"github.com/stretchr/testify/require"
+	testcontainerspostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	testcontainersredis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// setupRepository starts a Redis container and returns a repository connected to it
+func setupRepository(t *testing.T, ctx context.Context, opts ...redis.Option) *redis.Repository {
+	t.Helper()
+
+	container, err := testcontainersredis.Run(ctx, "redis:7-alpine")
+	require.NoError(t, err, "failed to start Redis container")
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate Redis container: %v", err)
+		}
+	})
+
+	addr, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	repo, err := redis.NewRepositoryWithContext(ctx, strings.TrimPrefix(addr, "redis://"), "", 0, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close(ctx) })
+
+	return repo
+}
+
+// setupArchiver starts a Postgres container and returns a migrated archiver connected to it
+func setupArchiver(t *testing.T, ctx context.Context) *postgres.Archiver {
+	t.Helper()
+
+	container, err := testcontainerspostgres.Run(ctx, "postgres:16-alpine", testcontainerspostgres.BasicWaitStrategies())
+	require.NoError(t, err, "failed to start Postgres container")
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate Postgres container: %v", err)
+		}
+	})
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+
+	archiver, err := postgres.NewArchiver(ctx, db)
+	require.NoError(t, err)
+	t.Cleanup(func() { archiver.Close(ctx) })
+	require.NoError(t, archiver.Migrate(ctx))
+
+	return archiver
+}
+
+func TestWorker_Heartbeat_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	route := &routes.Route{RouteID: "heartbeat-route", TargetURL: "https://example.com", Mode: webhook.FIFO}
+	w := worker.New(route, repo, nil, worker.WithHeartbeatInterval(100*time.Millisecond))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(runCtx)
+	}()
+
+	// The worker reports itself under the repository's consumer name
+	require.Eventually(t, func() bool {
+		workers, err := repo.GetActiveWorkers(ctx, route.RouteID)
+		require.NoError(t, err)
+		return len(workers) == 1 && workers[0].WorkerID == repo.ConsumerName() && workers[0].Status == worker.StatusIdle
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	// Removed on shutdown, without waiting for the 60s TTL
+	workers, err := repo.GetActiveWorkers(ctx, route.RouteID)
+	require.NoError(t, err)
+	require.Empty(t, workers)
+}
+
+func TestWorker_Parallel_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	const deliveryTime = 500 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(deliveryTime)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "parallel-route", TargetURL: server.URL, Mode: webhook.PubSub, Parallelism: 10}
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		wh := webhook.Webhook{
+			ID:           webhook.GenerateID(t, i),
+			RouteID:      route.RouteID,
+			Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.PubSub,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		ids = append(ids, wh.ID)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	started := time.Now()
+	go func() {
+		done <- worker.New(route, repo, worker.NewClient(5*time.Second)).Run(runCtx)
+	}()
+
+	require.Eventually(t, func() bool {
+		for _, id := range ids {
+			wh, err := repo.Get(ctx, id)
+			if err != nil || wh.Status != webhook.Delivered {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 20*time.Millisecond)
+	elapsed := time.Since(started)
+
+	// Ten sequential deliveries would take 5s
+	require.Less(t, elapsed, 3*deliveryTime, "deliveries should run concurrently")
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// Every delivery acknowledged its own message
+	pending, err := repo.GetClient().XPending(ctx, "webhooks:pubsub:parallel-route", "webhook-workers-parallel-route").Result()
+	require.NoError(t, err)
+	require.Zero(t, pending.Count)
+}
+
+func TestWorker_NonRetryableStatus_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	// run delivers a single webhook on the route until it is marked failed
+	run := func(t *testing.T, route *routes.Route, opts ...worker.Option) string {
+		t.Helper()
+
+		wh := webhook.Webhook{
+			ID:           webhook.GenerateID(t, 0),
+			RouteID:      route.RouteID,
+			Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		done := make(chan error, 1)
+		go func() {
+			done <- worker.New(route, repo, worker.NewClient(5*time.Second), opts...).Run(runCtx)
+		}()
+
+		require.Eventually(t, func() bool {
+			stored, err := repo.Get(ctx, wh.ID)
+			return err == nil && stored.Status == webhook.Failed
+		}, 10*time.Second, 20*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		stored, err := repo.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		require.Zero(t, stored.RetryCount, "a 400 must not be retried")
+		return wh.ID
+	}
+
+	t.Run("acknowledged and dead-lettered", func(t *testing.T) {
+		route := &routes.Route{RouteID: "rejecting-route", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", AckPolicy: routes.AckPolicyAck}
+		id := run(t, route, worker.WithDeadLetterQueue(repo))
+
+		pending, err := repo.GetClient().XPending(ctx, "webhooks:fifo:rejecting-route", "webhook-workers-rejecting-route").Result()
+		require.NoError(t, err)
+		require.Zero(t, pending.Count, "the message must leave the PEL")
+
+		_, err = repo.GetDLQ(ctx, route.RouteID, id)
+		require.NoError(t, err)
+	})
+
+	t.Run("keep_pending leaves the message pending", func(t *testing.T) {
+		route := &routes.Route{RouteID: "pending-route", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", AckPolicy: routes.AckPolicyKeepPending}
+		run(t, route)
+
+		pending, err := repo.GetClient().XPending(ctx, "webhooks:fifo:pending-route", "webhook-workers-pending-route").Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), pending.Count)
+
+		// Rebalancing the worker's consumer parks the entry instead of delivering it again
+		requeued, err := repo.Rebalance(ctx, route.RouteID, webhook.FIFO, webhook.DefaultConsumerName())
+		require.NoError(t, err)
+		require.Zero(t, requeued)
+
+		runCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		defer cancel()
+		processed, err := worker.New(route, repo, worker.NewClient(5*time.Second)).RunN(runCtx, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Zero(t, processed)
+
+		pending, err = repo.GetClient().XPending(ctx, "webhooks:fifo:pending-route", "webhook-workers-pending-route").Result()
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{redis.ParkedConsumer: 1}, pending.Consumers)
+	})
+}
+
+func TestWorker_Priority_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	var mu sync.Mutex
+	var delivered []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p struct {
+			Data struct {
+				Seq string `json:"seq"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err == nil {
+			mu.Lock()
+			delivered = append(delivered, p.Data.Seq)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{
+		RouteID:            "priority-route",
+		TargetURL:          server.URL,
+		Mode:               webhook.FIFO,
+		Parallelism:        1,
+		PriorityEventTypes: []string{"payment.failed"},
+	}
+
+	// Routine events are queued before the urgent one
+	events := []struct{ seq, eventType string }{
+		{"routine-1", "payment.succeeded"},
+		{"routine-2", "payment.succeeded"},
+		{"routine-3", "payment.succeeded"},
+		{"urgent-1", "payment.failed"},
+	}
+	for i, event := range events {
+		payload := []byte(fmt.Sprintf(`{"type":%q,"timestamp":"2024-01-01T12:00:00Z","data":{"seq":%q}}`, event.eventType, event.seq))
+		_, err := repo.Store(ctx, webhook.Webhook{
+			ID:           webhook.GenerateID(t, i),
+			RouteID:      route.RouteID,
+			Payload:      payload,
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			Priority:     route.IsPriority(payload),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.New(route, repo, worker.NewClient(5*time.Second)).Run(runCtx)
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) == len(events)
+	}, 10*time.Second, 20*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Equal(t, []string{"urgent-1", "routine-1", "routine-2", "routine-3"}, delivered)
+}
+
+func TestWorker_RetryWindow_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	var mu sync.Mutex
+	var attempts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "window-route", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "200", MaxRetryWindowHours: 1}
+
+	// Created just under an hour ago: the window closes in about three seconds,
+	// long before max_retries is exhausted
+	wh := webhook.Webhook{
+		ID:           webhook.GenerateID(t, 0),
+		RouteID:      route.RouteID,
+		Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		Status:       webhook.Pending,
+		MaxRetries:   1000,
+		DeliveryMode: webhook.FIFO,
+		CreatedAt:    time.Now().Add(-time.Hour + 3*time.Second),
+		UpdatedAt:    time.Now(),
+	}
+	_, err := repo.Store(ctx, wh)
+	require.NoError(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.New(route, repo, worker.NewClient(5*time.Second), worker.WithDeadLetterQueue(repo)).Run(runCtx)
+	}()
+
+	require.Eventually(t, func() bool {
+		stored, err := repo.Get(ctx, wh.ID)
+		return err == nil && stored.Status == webhook.Failed
+	}, 10*time.Second, 20*time.Millisecond)
+
+	stored, err := repo.Get(ctx, wh.ID)
+	require.NoError(t, err)
+	deadline, ok := route.RetryDeadline(stored.CreatedAt)
+	require.True(t, ok)
+	require.Less(t, time.Until(deadline), time.Second, "retries must not stop before the window closes")
+	require.Greater(t, stored.RetryCount, 0)
+	require.Less(t, stored.RetryCount, stored.MaxRetries)
+
+	// No attempt is made after the window, and none once the webhook failed
+	time.Sleep(500 * time.Millisecond)
+	mu.Lock()
+	require.Len(t, attempts, stored.RetryCount+1)
+	require.True(t, attempts[len(attempts)-1].Before(deadline))
+	mu.Unlock()
+
+	_, err = repo.GetDLQ(ctx, route.RouteID, wh.ID)
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWorker_RouteGroup_Integration(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	newTarget := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			received[name] = append(received[name], r.Header.Get("webhook-id"))
+			mu.Unlock()
+			if name == "crm" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	billing, crm := newTarget("billing"), newTarget("crm")
+
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+routes:
+  - route_id: "billing"
+    target_url: %q
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "50"
+    parallelism: 1
+  - route_id: "crm"
+    target_url: %q
+    mode: "fifo"
+    max_retries: 1
+    retry_backoff: "50"
+    parallelism: 1
+groups:
+  - group_id: "low-volume"
+    route_ids: ["billing", "crm"]
+`, billing.URL, crm.URL)), 0o644))
+	loader := routes.NewLoader()
+	require.NoError(t, loader.LoadStrict(path))
+
+	repo := setupRepository(t, ctx, redis.WithRouteGroups(loader.GroupOf))
+
+	stored := make(map[string]string)
+	var ids []string
+	for i, routeID := range []string{"billing", "crm", "billing"} {
+		route, err := loader.Get(routeID)
+		require.NoError(t, err)
+		wh := webhook.Webhook{
+			ID:           webhook.GenerateID(t, i),
+			RouteID:      routeID,
+			Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			Status:       webhook.Pending,
+			MaxRetries:   route.MaxRetries,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err = repo.Store(ctx, wh)
+		require.NoError(t, err)
+		stored[wh.ID] = routeID
+		ids = append(ids, wh.ID)
+	}
+
+	// Both routes are queued on the group's stream
+	length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:low-volume").Result()
+	require.NoError(t, err)
+	require.EqualValues(t, 3, length)
+	for _, routeID := range []string{"billing", "crm"} {
+		exists, err := repo.GetClient().Exists(ctx, "webhooks:fifo:"+routeID).Result()
+		require.NoError(t, err)
+		require.Zero(t, exists)
+	}
+
+	groups := loader.Groups()
+	require.Len(t, groups, 1)
+
+	// Each webhook goes to its own route's target; crm fails after its single retry
+	runCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	w := worker.New(groups[0].Route(), repo, worker.NewClient(5*time.Second), worker.WithRouteLookup(loader.Get))
+	processed, err := w.RunN(runCtx, len(ids))
+	require.NoError(t, err)
+	require.Equal(t, len(ids), processed)
+
+	for id, routeID := range stored {
+		wh, err := repo.Get(ctx, id)
+		require.NoError(t, err)
+		want := webhook.Delivered
+		if routeID == "crm" {
+			want = webhook.Failed
+		}
+		require.Equal(t, want, wh.Status, routeID)
+	}
+
+	mu.Lock()
+	require.Equal(t, []string{ids[0], ids[2]}, received["billing"])
+	require.Equal(t, []string{ids[1], ids[1]}, received["crm"])
+	mu.Unlock()
+
+	pending, err := repo.GetClient().XPending(ctx, "webhooks:fifo:low-volume", "webhook-workers-low-volume").Result()
+	require.NoError(t, err)
+	require.Zero(t, pending.Count)
+}
+
+func TestWorker_TrimStream_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "trimmed-route", TargetURL: server.URL, Mode: webhook.FIFO, Parallelism: 1, MaxStreamLen: 2}
+	for i := 0; i < 5; i++ {
+		_, err := repo.Store(ctx, webhook.Webhook{
+			ID:           webhook.GenerateID(t, i),
+			RouteID:      route.RouteID,
+			Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.New(route, repo, worker.NewClient(5*time.Second), worker.WithTrimInterval(50*time.Millisecond)).Run(runCtx)
+	}()
+
+	// Delivered entries are acknowledged, so the worker trims them down to max_stream_len
+	require.Eventually(t, func() bool {
+		length, err := repo.GetClient().XLen(ctx, "webhooks:fifo:trimmed-route").Result()
+		return err == nil && length == 2
+	}, 10*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// failingArchiver fails the first Archive call, then archives with the wrapped archiver
+type failingArchiver struct {
+	webhook.Archiver
+	failed atomic.Bool
+}
+
+func (f *failingArchiver) Archive(ctx context.Context, wh webhook.Webhook) error {
+	if f.failed.CompareAndSwap(false, true) {
+		return errors.New("archive unavailable")
+	}
+	return f.Archiver.Archive(ctx, wh)
+}
+
+func TestWorker_Archive_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+	archiver := setupArchiver(t, ctx)
+
+	var deliveries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// store queues a webhook on the route
+	store := func(t *testing.T, routeID string) webhook.Webhook {
+		t.Helper()
+		wh := webhook.Webhook{
+			ID:           webhook.GenerateID(t, 0),
+			RouteID:      routeID,
+			Payload:      []byte(`{"type":"order.paid","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			Headers:      map[string]string{},
+			Status:       webhook.Pending,
+			MaxRetries:   3,
+			DeliveryMode: webhook.FIFO,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		_, err := repo.Store(ctx, wh)
+		require.NoError(t, err)
+		return wh
+	}
+
+	t.Run("delivered webhooks are archived in Postgres", func(t *testing.T) {
+		deliveries.Store(0)
+		route := &routes.Route{RouteID: "archived-route", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", Archive: true}
+		wh := store(t, route.RouteID)
+
+		processed, err := worker.New(route, repo, worker.NewClient(5*time.Second), worker.WithArchiver(archiver)).RunN(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, processed)
+
+		archived, err := archiver.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		require.Equal(t, webhook.Delivered, archived.Status)
+		require.Equal(t, wh.Payload, archived.Payload)
+		require.Equal(t, int32(1), deliveries.Load())
+	})
+
+	t.Run("an archiving error neither loses the archive nor delivers twice", func(t *testing.T) {
+		deliveries.Store(0)
+		route := &routes.Route{RouteID: "flaky-archive-route", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", Archive: true}
+		wh := store(t, route.RouteID)
+		flaky := &failingArchiver{Archiver: archiver}
+
+		// The first run delivers the webhook but can't archive it, leaving it pending
+		_, err := worker.New(route, repo, worker.NewClient(5*time.Second), worker.WithArchiver(flaky)).RunN(ctx, 1)
+		require.NoError(t, err)
+		_, err = archiver.Get(ctx, wh.ID)
+		require.ErrorIs(t, err, webhook.ErrNotFound)
+
+		// Once requeued, it is archived and acknowledged without reaching the target again
+		requeued, err := repo.Rebalance(ctx, route.RouteID, route.Mode, repo.ConsumerName())
+		require.NoError(t, err)
+		require.Equal(t, 1, requeued)
+		_, err = worker.New(route, repo, worker.NewClient(5*time.Second), worker.WithArchiver(flaky)).RunN(ctx, 1)
+		require.NoError(t, err)
+
+		archived, err := archiver.Get(ctx, wh.ID)
+		require.NoError(t, err)
+		require.Equal(t, webhook.Delivered, archived.Status)
+		require.Equal(t, int32(1), deliveries.Load())
+
+		pending, err := repo.GetClient().XPending(ctx, "webhooks:fifo:flaky-archive-route", "webhook-workers-flaky-archive-route").Result()
+		require.NoError(t, err)
+		require.Zero(t, pending.Count)
+	})
+}
+
+func TestWorker_DefaultRoute_Integration(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t, ctx)
+
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := t.TempDir() + "/routes.yaml"
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+routes:
+  - route_id: "catch-all"
+    target_url: %q
+    mode: "fifo"
+    max_retries: 3
+    retry_backoff: "1"
+    parallelism: 1
+    is_default: true
+`, server.URL)), 0o644))
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(path))
+
+	// Post an event for a route that isn't configured
+	req := httptest.NewRequest(http.MethodPost, "/v1/routes/tenant-42/events",
+		strings.NewReader(`{"type":"tenant.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	httpchi.WebhookHandlers(ctx, webhook.NewService(repo), loader).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// The default route's worker delivers it to the default route's target
+	defaultRoute, err := loader.Get("catch-all")
+	require.NoError(t, err)
+	processed, err := worker.New(defaultRoute, repo, worker.NewClient(5*time.Second)).RunN(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+
+	select {
+	case r := <-received:
+		require.Equal(t, "tenant-42", r.Header.Get("X-Requested-Route-Id"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered to the default route's target")
+	}
+}
@@ -0,0 +1,1172 @@
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marcelsud/webhook-inbox/config"
+	"github.com/marcelsud/webhook-inbox/routes"
+	"github.com/marcelsud/webhook-inbox/webhook"
+	"github.com/marcelsud/webhook-inbox/webhook/clock"
+	"github.com/marcelsud/webhook-inbox/webhook/mocks"
+	"github.com/marcelsud/webhook-inbox/webhook/signature"
+	"github.com/marcelsud/webhook-inbox/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeHeartbeats records heartbeats in memory
+type fakeHeartbeats struct {
+	mu       sync.Mutex
+	statuses []string
+	deleted  bool
+}
+
+func (f *fakeHeartbeats) SetWorkerHeartbeat(ctx context.Context, workerID, routeID, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = append(f.statuses, status)
+	return nil
+}
+
+func (f *fakeHeartbeats) DeleteWorkerHeartbeat(ctx context.Context, workerID, routeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = true
+	return nil
+}
+
+func (f *fakeHeartbeats) beats() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.statuses)
+}
+
+// runWorker runs the worker in the background and returns a function that stops it
+func runWorker(t *testing.T, w *worker.Worker) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	t.Cleanup(cancel)
+
+	return cancel, done
+}
+
+func TestWorker_Heartbeat(t *testing.T) {
+	route := &routes.Route{RouteID: "user-events", TargetURL: "https://example.com", Mode: webhook.FIFO}
+
+	repo := mocks.NewRepository(t)
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil)
+
+	heartbeats := &fakeHeartbeats{}
+	w := worker.New(route, repo, nil,
+		worker.WithID("worker-1"),
+		worker.WithHeartbeats(heartbeats),
+		worker.WithHeartbeatInterval(10*time.Millisecond),
+	)
+	assert.Equal(t, "worker-1", w.ID())
+
+	cancel, done := runWorker(t, w)
+
+	require.Eventually(t, func() bool { return heartbeats.beats() >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	heartbeats.mu.Lock()
+	defer heartbeats.mu.Unlock()
+	assert.True(t, heartbeats.deleted)
+	for _, status := range heartbeats.statuses {
+		assert.Equal(t, worker.StatusIdle, status)
+	}
+}
+
+// fakeTrimmer records the lengths streams are trimmed to
+type fakeTrimmer struct {
+	mu      sync.Mutex
+	maxLens []int64
+}
+
+func (f *fakeTrimmer) TrimStream(ctx context.Context, routeID string, deliveryMode webhook.DeliveryMode, maxLen int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxLens = append(f.maxLens, maxLen)
+	return 0, nil
+}
+
+func (f *fakeTrimmer) trims() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.maxLens...)
+}
+
+func TestWorker_TrimStream(t *testing.T) {
+	newRepo := func(t *testing.T) *mocks.Repository {
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil)
+		return repo
+	}
+
+	t.Run("routes with a max stream length are trimmed every interval", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: "https://example.com", Mode: webhook.FIFO, MaxStreamLen: 100}
+		trimmer := &fakeTrimmer{}
+		w := worker.New(route, newRepo(t), nil, worker.WithStreamTrimmer(trimmer), worker.WithTrimInterval(10*time.Millisecond))
+
+		cancel, done := runWorker(t, w)
+		require.Eventually(t, func() bool { return len(trimmer.trims()) >= 3 }, time.Second, 5*time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		for _, maxLen := range trimmer.trims() {
+			assert.Equal(t, int64(100), maxLen)
+		}
+	})
+
+	t.Run("unbounded routes are not trimmed", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: "https://example.com", Mode: webhook.FIFO}
+		trimmer := &fakeTrimmer{}
+		w := worker.New(route, newRepo(t), nil, worker.WithStreamTrimmer(trimmer), worker.WithTrimInterval(time.Millisecond))
+
+		cancel, done := runWorker(t, w)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Empty(t, trimmer.trims())
+	})
+}
+
+func TestWorker_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO}
+	wh := webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`), DeliveryMode: webhook.FIFO}
+
+	repo := mocks.NewRepository(t)
+	acked := make(chan struct{})
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", mock.Anything).Return(nil)
+	repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+	repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+	var enabled atomic.Bool
+	heartbeats := &fakeHeartbeats{}
+	w := worker.New(route, repo, nil,
+		worker.WithRouteEnabled(func(routeID string) bool { return routeID == "user-events" && enabled.Load() }),
+		worker.WithHeartbeats(heartbeats),
+		worker.WithHeartbeatInterval(10*time.Millisecond),
+	)
+	cancel, done := runWorker(t, w)
+
+	// A disabled route's stream is not read, so its stored webhooks wait
+	require.Eventually(t, func() bool { return heartbeats.beats() >= 3 }, time.Second, 5*time.Millisecond)
+	repo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+	heartbeats.mu.Lock()
+	for _, status := range heartbeats.statuses {
+		assert.Equal(t, worker.StatusPaused, status)
+	}
+	heartbeats.mu.Unlock()
+
+	// Enabling the route resumes delivery
+	enabled.Store(true)
+	select {
+	case <-acked:
+	case <-time.After(3 * time.Second):
+		t.Fatal("webhook was not delivered after enabling the route")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWorker_CurrentSecret(t *testing.T) {
+	oldSecret, err := signature.GenerateSecret(32)
+	require.NoError(t, err)
+	newSecret, err := signature.GenerateSecret(32)
+	require.NoError(t, err)
+
+	routesFile := filepath.Join(t.TempDir(), "routes.yaml")
+	writeRoutes := func(secret signature.Secret) {
+		require.NoError(t, os.WriteFile(routesFile, []byte(fmt.Sprintf(`
+routes:
+  - route_id: "user-events"
+    target_url: "https://example.com/users"
+    mode: "fifo"
+    max_retries: 1
+    retry_backoff: "1"
+    parallelism: 1
+    signing_secret: %q
+`, secret)), 0o644))
+	}
+	writeRoutes(oldSecret)
+	loader := routes.NewLoader()
+	require.NoError(t, loader.Load(routesFile))
+
+	// The first attempt fails, and the secret is rotated before the retry
+	var (
+		mu       sync.Mutex
+		received []http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Clone())
+		if len(received) == 1 {
+			writeRoutes(newSecret)
+			_, err := loader.Reload(routesFile)
+			assert.NoError(t, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	loaded, err := loader.Get("user-events")
+	require.NoError(t, err)
+	route := *loaded
+	route.TargetURL = server.URL
+
+	wh := webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`), MaxRetries: 1, DeliveryMode: webhook.FIFO}
+	repo := mocks.NewRepository(t)
+	acked := make(chan struct{})
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", mock.Anything).Return(nil)
+	repo.On("IncrementRetry", mock.Anything, "evt-1").Return(nil).Once()
+	repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+	repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+	w := worker.New(&route, repo, worker.NewClient(time.Second), worker.WithCurrentSecret(loader.SigningSecret))
+	cancel, done := runWorker(t, w)
+	<-acked
+	cancel()
+	require.NoError(t, <-done)
+
+	// signedBy reports whether the headers of an attempt carry a valid signature made with secret
+	signedBy := func(header http.Header, secret signature.Secret) bool {
+		ts, err := strconv.ParseInt(header.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		sigs, err := signature.ParseSignatureHeader(header.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+		valid, err := signature.VerifyMultiple([]signature.Secret{secret}, "evt-1", time.Unix(ts, 0), []byte(`{}`), sigs)
+		require.NoError(t, err)
+		return valid
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.True(t, signedBy(received[0], oldSecret), "first attempt signed with the secret loaded at the time")
+	assert.True(t, signedBy(received[1], newSecret), "retry signed with the rotated secret")
+	assert.False(t, signedBy(received[1], oldSecret), "retry no longer signed with the previous secret")
+}
+
+func TestWorker_DefaultSigningSecret(t *testing.T) {
+	defaultSecret, err := signature.GenerateSecret(32)
+	require.NoError(t, err)
+	routeSecret, err := signature.GenerateSecret(32)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		received http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// deliver runs a worker with a client of its own until the webhook is acknowledged
+	deliver := func(route routes.Route) http.Header {
+		t.Helper()
+		wh := webhook.Webhook{ID: "evt-1", RouteID: route.RouteID, Payload: []byte(`{}`), MaxRetries: 1, DeliveryMode: webhook.FIFO}
+		repo := mocks.NewRepository(t)
+		acked := make(chan struct{})
+		repo.On("Consume", mock.Anything, route.RouteID, webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("Consume", mock.Anything, route.RouteID, webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", mock.Anything).Return(nil)
+		repo.On("SetTTL", mock.Anything, "evt-1", mock.Anything).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, route.RouteID, webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cfg := &config.Config{WebhookDefaultSigningSecret: defaultSecret.String()}
+		w := worker.New(&route, repo, worker.NewClient(time.Second), worker.WithConfig(cfg))
+		cancel, done := runWorker(t, w)
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+
+	// signedBy reports whether the headers of an attempt carry a valid signature made with secret
+	signedBy := func(header http.Header, secret signature.Secret) bool {
+		ts, err := strconv.ParseInt(header.Get(worker.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		sigs, err := signature.ParseSignatureHeader(header.Get(worker.HeaderWebhookSignature))
+		require.NoError(t, err)
+		valid, err := signature.VerifyMultiple([]signature.Secret{secret}, "evt-1", time.Unix(ts, 0), []byte(`{}`), sigs)
+		require.NoError(t, err)
+		return valid
+	}
+
+	t.Run("routes without a secret are signed with the default", func(t *testing.T) {
+		header := deliver(routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, MaxRetries: 1, RetryBackoff: "1", Parallelism: 1})
+		assert.True(t, signedBy(header, defaultSecret))
+	})
+
+	t.Run("a route-level secret takes precedence", func(t *testing.T) {
+		header := deliver(routes.Route{RouteID: "order-events", TargetURL: server.URL, Mode: webhook.FIFO, MaxRetries: 1, RetryBackoff: "1", Parallelism: 1, SigningSecret: routeSecret.String()})
+		assert.True(t, signedBy(header, routeSecret))
+		assert.False(t, signedBy(header, defaultSecret))
+	})
+}
+
+func TestWorker_CancelMidDelivery(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		close(started)
+		// Hold the request until the worker gives up on it
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1"}
+	wh := webhook.Webhook{ID: "evt-1", RouteID: "user-events", Payload: []byte(`{}`), MaxRetries: 3, DeliveryMode: webhook.FIFO}
+
+	repo := mocks.NewRepository(t)
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+
+	// No expectations: the interrupted attempt is neither recorded nor retried
+	attempts := mocks.NewAttemptLog(t)
+
+	var deliveryErr error
+	w := worker.New(route, repo, worker.NewClient(time.Minute),
+		worker.WithAttemptLog(attempts),
+		worker.OnFailure(func(wh webhook.Webhook, err error) { deliveryErr = err }),
+		worker.OnRetry(func(wh webhook.Webhook, err error) { deliveryErr = err }),
+	)
+	cancel, done := runWorker(t, w)
+
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("worker did not stop while a delivery was in flight")
+	}
+
+	assert.NoError(t, deliveryErr, "an interrupted delivery is not a failure")
+	repo.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "IncrementRetry", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, "evt-1", webhook.Failed)
+}
+
+func TestWorker_Run(t *testing.T) {
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "user-events",
+		Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		MaxRetries:   1,
+		DeliveryMode: webhook.FIFO,
+	}
+
+	newRepo := func(t *testing.T) *mocks.Repository {
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		return repo
+	}
+
+	t.Run("success - delivers and acknowledges", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivered).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("failure - retries then marks failed", func(t *testing.T) {
+		var requests int
+		var mu sync.Mutex
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1"}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Twice()
+		repo.On("IncrementRetry", mock.Anything, "evt-1").Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Retrying).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		attempts := mocks.NewAttemptLog(t)
+		attempts.On("RecordAttempt", mock.Anything, "evt-1", mock.MatchedBy(func(a webhook.Attempt) bool {
+			return a.StatusCode == http.StatusInternalServerError && a.Error != "" && a.ErrorKind == webhook.DeliveryErrorStatus && !a.Timestamp.IsZero()
+		})).Return(nil).Twice()
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second), worker.WithAttemptLog(attempts)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("failure - retry limit reached marks failed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1"}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("IncrementRetry", mock.Anything, "evt-1").Return(fmt.Errorf("%w: webhook evt-1", webhook.ErrRetryLimit)).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+		repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, "evt-1", webhook.Retrying)
+	})
+
+	t.Run("failure - closed retry window marks failed with retries left", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		// evt-1 has no created_at, so its window closed long ago
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", MaxRetryWindowHours: 24}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+		repo.AssertNotCalled(t, "IncrementRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure - unsignable webhook fails without retrying", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: "http://127.0.0.1:0", Mode: webhook.FIFO, RetryBackoff: "1", RequireSignature: true}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+		repo.AssertNotCalled(t, "IncrementRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure - non-retryable status fails without retrying", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"unknown user"}`))
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", CaptureResponseBytes: routes.DefaultCaptureResponseBytes}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		// The receiver's explanation is kept with the attempt
+		attempts := mocks.NewAttemptLog(t)
+		attempts.On("RecordAttempt", mock.Anything, "evt-1", mock.MatchedBy(func(a webhook.Attempt) bool {
+			return a.StatusCode == http.StatusBadRequest && a.ResponseBody == `{"error":"unknown user"}`
+		})).Return(nil).Once()
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second), worker.WithAttemptLog(attempts)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+		repo.AssertNotCalled(t, "IncrementRetry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure - keep_pending leaves non-retryable failures unacknowledged", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}))
+		defer server.Close()
+
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", AckPolicy: routes.AckPolicyKeepPending}
+		repo := newRepo(t)
+		failed := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once().Run(func(mock.Arguments) { close(failed) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-failed
+		cancel()
+		require.NoError(t, <-done)
+		repo.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "SetTTL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("success - skips unsubscribed event types", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: "http://127.0.0.1:0", Mode: webhook.FIFO, EventTypes: []string{"order.*"}}
+		repo := newRepo(t)
+		acked := make(chan struct{})
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+func TestWorker_Hooks(t *testing.T) {
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "user-events",
+		Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		MaxRetries:   1,
+		DeliveryMode: webhook.FIFO,
+	}
+	newServer := func(t *testing.T, status int) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	newRepo := func(t *testing.T, ttl time.Duration) *mocks.Repository {
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", mock.Anything).Return(nil)
+		repo.On("IncrementRetry", mock.Anything, "evt-1").Return(nil).Maybe()
+		repo.On("SetTTL", mock.Anything, "evt-1", ttl).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once()
+		return repo
+	}
+	// recorder collects hook calls as "<hook>:<event_id>:<retry_count>"
+	type recorder struct {
+		calls []string
+		errs  []error
+	}
+	hookOptions := func(r *recorder) []worker.Option {
+		return []worker.Option{
+			worker.OnSuccess(func(wh webhook.Webhook) {
+				r.calls = append(r.calls, fmt.Sprintf("success:%s:%d", wh.ID, wh.RetryCount))
+			}),
+			worker.OnRetry(func(wh webhook.Webhook, err error) {
+				r.calls = append(r.calls, fmt.Sprintf("retry:%s:%d", wh.ID, wh.RetryCount))
+				r.errs = append(r.errs, err)
+			}),
+			worker.OnFailure(func(wh webhook.Webhook, err error) {
+				r.calls = append(r.calls, fmt.Sprintf("failure:%s:%d", wh.ID, wh.RetryCount))
+				r.errs = append(r.errs, err)
+			}),
+		}
+	}
+
+	t.Run("success hook on delivery", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: newServer(t, http.StatusOK).URL, Mode: webhook.FIFO}
+		r := &recorder{}
+
+		_, err := worker.New(route, newRepo(t, time.Hour), worker.NewClient(time.Second), hookOptions(r)...).
+			RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"success:evt-1:0"}, r.calls)
+	})
+
+	t.Run("retry then failure hooks on exhausted retries", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: newServer(t, http.StatusInternalServerError).URL, Mode: webhook.FIFO, RetryBackoff: "1"}
+		r := &recorder{}
+
+		_, err := worker.New(route, newRepo(t, 24*time.Hour), worker.NewClient(time.Second), hookOptions(r)...).
+			RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"retry:evt-1:1", "failure:evt-1:1"}, r.calls)
+		for _, err := range r.errs {
+			assert.ErrorContains(t, err, "500")
+		}
+	})
+
+	t.Run("failure hook on non-retryable status", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: newServer(t, http.StatusBadRequest).URL, Mode: webhook.FIFO, RetryBackoff: "1"}
+		r := &recorder{}
+
+		_, err := worker.New(route, newRepo(t, 24*time.Hour), worker.NewClient(time.Second), hookOptions(r)...).
+			RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"failure:evt-1:0"}, r.calls)
+		require.Len(t, r.errs, 1)
+		assert.ErrorIs(t, r.errs[0], worker.ErrNonRetryableStatus)
+	})
+
+	t.Run("panicking hooks don't stop the worker", func(t *testing.T) {
+		route := &routes.Route{RouteID: "user-events", TargetURL: newServer(t, http.StatusOK).URL, Mode: webhook.FIFO}
+		repo := newRepo(t, time.Hour)
+		second := wh
+		second.ID = "evt-2"
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{second}, nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-2", mock.Anything).Return(nil)
+		repo.On("SetTTL", mock.Anything, "evt-2", time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-2").Return(nil).Once()
+
+		var calls int
+		w := worker.New(route, repo, worker.NewClient(time.Second), worker.OnSuccess(func(wh webhook.Webhook) {
+			calls++
+			panic("hook failed")
+		}))
+
+		processed, err := w.RunN(context.Background(), 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, processed)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestWorker_Archive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "orders",
+		Payload:      []byte(`{"type":"order.paid","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		DeliveryMode: webhook.FIFO,
+	}
+
+	newRepo := func(t *testing.T) *mocks.Repository {
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "orders", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivered).Return(nil).Once()
+		return repo
+	}
+
+	t.Run("archives delivered webhooks before setting their TTL", func(t *testing.T) {
+		route := &routes.Route{RouteID: "orders", TargetURL: server.URL, Mode: webhook.FIFO, Archive: true}
+		repo := newRepo(t)
+		archiver := mocks.NewArchiver(t)
+
+		var calls []string
+		archiver.On("Archive", mock.Anything, mock.MatchedBy(func(archived webhook.Webhook) bool {
+			return archived.ID == "evt-1" && archived.Status == webhook.Delivered
+		})).Return(nil).Once().Run(func(mock.Arguments) { calls = append(calls, "Archive") })
+		repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once().Run(func(mock.Arguments) { calls = append(calls, "SetTTL") })
+		repo.On("Acknowledge", mock.Anything, "orders", webhook.FIFO, "evt-1").Return(nil).Once()
+
+		_, err := worker.New(route, repo, worker.NewClient(time.Second), worker.WithArchiver(archiver)).RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Archive", "SetTTL"}, calls)
+	})
+
+	t.Run("archiving error leaves the webhook unacknowledged", func(t *testing.T) {
+		route := &routes.Route{RouteID: "orders", TargetURL: server.URL, Mode: webhook.FIFO, Archive: true}
+		repo := newRepo(t)
+		archiver := mocks.NewArchiver(t)
+		archiver.On("Archive", mock.Anything, mock.Anything).Return(fmt.Errorf("connection refused")).Once()
+
+		_, err := worker.New(route, repo, worker.NewClient(time.Second), worker.WithArchiver(archiver)).RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "SetTTL", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("webhooks delivered before an archiving error are archived without being delivered again", func(t *testing.T) {
+		var deliveries atomic.Int32
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deliveries.Add(1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer target.Close()
+
+		route := &routes.Route{RouteID: "orders", TargetURL: target.URL, Mode: webhook.FIFO, Archive: true}
+		delivered := wh
+		delivered.Status = webhook.Delivered
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "orders", webhook.FIFO).Return([]webhook.Webhook{delivered}, nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivered).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "orders", webhook.FIFO, "evt-1").Return(nil).Once()
+		archiver := mocks.NewArchiver(t)
+		archiver.On("Archive", mock.Anything, mock.MatchedBy(func(archived webhook.Webhook) bool {
+			return archived.ID == "evt-1"
+		})).Return(nil).Once()
+
+		_, err := worker.New(route, repo, worker.NewClient(time.Second), worker.WithArchiver(archiver)).RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Zero(t, deliveries.Load())
+	})
+
+	t.Run("routes without archive are not archived", func(t *testing.T) {
+		route := &routes.Route{RouteID: "orders", TargetURL: server.URL, Mode: webhook.FIFO}
+		repo := newRepo(t)
+		archiver := mocks.NewArchiver(t)
+		repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "orders", webhook.FIFO, "evt-1").Return(nil).Once()
+
+		_, err := worker.New(route, repo, worker.NewClient(time.Second), worker.WithArchiver(archiver)).RunN(context.Background(), 1)
+
+		require.NoError(t, err)
+		archiver.AssertNotCalled(t, "Archive", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorker_Clock(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var timestamp atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamp.Store(r.Header.Get("webhook-timestamp"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// Due and outside its one hour retry window by the fake clock, not by the real one
+	wh := webhook.Webhook{
+		ID:           "evt-1",
+		RouteID:      "orders",
+		Payload:      []byte(`{"type":"order.paid","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+		DeliveryMode: webhook.FIFO,
+		DeliverAt:    now.Add(-time.Minute),
+		CreatedAt:    now.Add(-2 * time.Hour),
+	}
+	route := &routes.Route{RouteID: "orders", TargetURL: server.URL, Mode: webhook.FIFO, RetryBackoff: "1", MaxRetryWindowHours: 1}
+
+	repo := mocks.NewRepository(t)
+	repo.On("Consume", mock.Anything, "orders", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Failed).Return(nil).Once()
+	repo.On("SetTTL", mock.Anything, "evt-1", 24*time.Hour).Return(nil).Once()
+	repo.On("Acknowledge", mock.Anything, "orders", webhook.FIFO, "evt-1").Return(nil).Once()
+
+	attempts := mocks.NewAttemptLog(t)
+	attempts.On("RecordAttempt", mock.Anything, "evt-1", mock.MatchedBy(func(a webhook.Attempt) bool {
+		return a.Timestamp.Equal(now) && a.LatencyMs == 0
+	})).Return(nil).Once()
+
+	w := worker.New(route, repo, nil, worker.WithClock(clock.NewFake(now)), worker.WithAttemptLog(attempts))
+	_, err := w.RunN(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), timestamp.Load())
+	repo.AssertNotCalled(t, "IncrementRetry", mock.Anything, mock.Anything)
+}
+
+func TestWorker_Run_Scheduled(t *testing.T) {
+	newWebhook := func(deliverAt time.Time) webhook.Webhook {
+		return webhook.Webhook{
+			ID:           "evt-1",
+			RouteID:      "user-events",
+			Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+			MaxRetries:   1,
+			DeliveryMode: webhook.FIFO,
+			DeliverAt:    deliverAt,
+		}
+	}
+	newRepo := func(t *testing.T, wh webhook.Webhook) *mocks.Repository {
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{wh}, nil).Once()
+		repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		return repo
+	}
+
+	t.Run("success - parks webhooks that are not due", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+		}))
+		defer server.Close()
+
+		wh := newWebhook(time.Now().Add(time.Hour))
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO}
+		scheduled := make(chan struct{})
+		scheduler := mocks.NewScheduler(t)
+		scheduler.On("Schedule", mock.Anything, wh).Return(nil).Once().Run(func(mock.Arguments) { close(scheduled) })
+
+		cancel, done := runWorker(t, worker.New(route, newRepo(t, wh), worker.NewClient(time.Second), worker.WithScheduler(scheduler)))
+
+		<-scheduled
+		cancel()
+		require.NoError(t, <-done)
+		assert.Zero(t, requests.Load(), "a webhook must not be delivered before its time")
+	})
+
+	t.Run("success - waits until due without a scheduler", func(t *testing.T) {
+		delivered := make(chan time.Time, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			delivered <- time.Now()
+		}))
+		defer server.Close()
+
+		deliverAt := time.Now().Add(200 * time.Millisecond)
+		route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO}
+		repo := newRepo(t, newWebhook(deliverAt))
+		acked := make(chan struct{})
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivering).Return(nil).Once()
+		repo.On("UpdateStatus", mock.Anything, "evt-1", webhook.Delivered).Return(nil).Once()
+		repo.On("SetTTL", mock.Anything, "evt-1", time.Hour).Return(nil).Once()
+		repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		<-acked
+		cancel()
+		require.NoError(t, <-done)
+		assert.False(t, (<-delivered).Before(deliverAt), "a webhook must not be delivered before its time")
+	})
+}
+
+func TestWorker_Run_Parallel(t *testing.T) {
+	const deliveryTime = 200 * time.Millisecond
+
+	newWebhooks := func(n int) []webhook.Webhook {
+		webhooks := make([]webhook.Webhook, n)
+		for i := range webhooks {
+			webhooks[i] = webhook.Webhook{
+				ID:           fmt.Sprintf("evt-%d", i),
+				RouteID:      "analytics",
+				Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+				DeliveryMode: webhook.PubSub,
+			}
+		}
+		return webhooks
+	}
+
+	// slowServer answers after deliveryTime and tracks the peak number of concurrent requests
+	slowServer := func(t *testing.T) (*httptest.Server, func() int32) {
+		var current, peak atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := current.Add(1)
+			defer current.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(deliveryTime)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		return server, peak.Load
+	}
+
+	// expectDeliveries sets up a successful delivery for every webhook and counts acknowledgements
+	expectDeliveries := func(repo *mocks.Repository, webhooks []webhook.Webhook) *sync.WaitGroup {
+		var acked sync.WaitGroup
+		acked.Add(len(webhooks))
+		for _, wh := range webhooks {
+			repo.On("UpdateStatus", mock.Anything, wh.ID, webhook.Delivering).Return(nil).Once()
+			repo.On("UpdateStatus", mock.Anything, wh.ID, webhook.Delivered).Return(nil).Once()
+			repo.On("SetTTL", mock.Anything, wh.ID, time.Hour).Return(nil).Once()
+			repo.On("Acknowledge", mock.Anything, "analytics", webhook.PubSub, wh.ID).Return(nil).Once().Run(func(mock.Arguments) { acked.Done() })
+		}
+		return &acked
+	}
+
+	t.Run("delivers up to parallelism webhooks concurrently", func(t *testing.T) {
+		server, peak := slowServer(t)
+		webhooks := newWebhooks(10)
+
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "analytics", webhook.PubSub).Return(webhooks, nil).Once()
+		repo.On("Consume", mock.Anything, "analytics", webhook.PubSub).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		acked := expectDeliveries(repo, webhooks)
+
+		route := &routes.Route{RouteID: "analytics", TargetURL: server.URL, Mode: webhook.PubSub, Parallelism: 5}
+		started := time.Now()
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		acked.Wait()
+		elapsed := time.Since(started)
+		cancel()
+		require.NoError(t, <-done)
+
+		// Two rounds of five, instead of ten sequential deliveries
+		assert.Equal(t, int32(5), peak())
+		assert.Less(t, elapsed, 4*deliveryTime)
+	})
+
+	t.Run("FIFO routes deliver one at a time", func(t *testing.T) {
+		server, peak := slowServer(t)
+		webhooks := newWebhooks(3)
+		for i := range webhooks {
+			webhooks[i].DeliveryMode = webhook.FIFO
+		}
+
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "analytics", webhook.FIFO).Return(webhooks, nil).Once()
+		repo.On("Consume", mock.Anything, "analytics", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+
+		var order []string
+		var mu sync.Mutex
+		var acked sync.WaitGroup
+		acked.Add(len(webhooks))
+		for _, wh := range webhooks {
+			repo.On("UpdateStatus", mock.Anything, wh.ID, mock.Anything).Return(nil)
+			repo.On("SetTTL", mock.Anything, wh.ID, time.Hour).Return(nil).Once()
+			repo.On("Acknowledge", mock.Anything, "analytics", webhook.FIFO, wh.ID).Return(nil).Once().Run(func(mock.Arguments) {
+				mu.Lock()
+				order = append(order, wh.ID)
+				mu.Unlock()
+				acked.Done()
+			})
+		}
+
+		route := &routes.Route{RouteID: "analytics", TargetURL: server.URL, Mode: webhook.FIFO, Parallelism: 1}
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		acked.Wait()
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Equal(t, int32(1), peak())
+		assert.Equal(t, []string{"evt-0", "evt-1", "evt-2"}, order)
+	})
+
+	t.Run("cancelled deliveries are not acknowledged", func(t *testing.T) {
+		server, _ := slowServer(t)
+		webhooks := newWebhooks(3)
+
+		repo := mocks.NewRepository(t)
+		repo.On("Consume", mock.Anything, "analytics", webhook.PubSub).Return(webhooks, nil).Once()
+		repo.On("Consume", mock.Anything, "analytics", webhook.PubSub).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+		var delivering sync.WaitGroup
+		delivering.Add(len(webhooks))
+		for _, wh := range webhooks {
+			repo.On("UpdateStatus", mock.Anything, wh.ID, webhook.Delivering).Return(nil).Once().Run(func(mock.Arguments) { delivering.Done() })
+		}
+
+		route := &routes.Route{RouteID: "analytics", TargetURL: server.URL, Mode: webhook.PubSub, Parallelism: 3}
+		cancel, done := runWorker(t, worker.New(route, repo, worker.NewClient(time.Second)))
+
+		delivering.Wait()
+		cancel()
+		require.NoError(t, <-done)
+
+		repo.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorker_RunN(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// queueRepo serves n webhooks one per Consume call, then none
+	queueRepo := func(t *testing.T, mode webhook.DeliveryMode, n int) (*mocks.Repository, *atomic.Int32) {
+		repo := mocks.NewRepository(t)
+		var next, acked atomic.Int32
+		repo.On("Consume", mock.Anything, "batch", mode).Return(func(context.Context, string, webhook.DeliveryMode) ([]webhook.Webhook, error) {
+			i := int(next.Add(1)) - 1
+			if i >= n {
+				time.Sleep(5 * time.Millisecond)
+				return []webhook.Webhook{}, nil
+			}
+			return []webhook.Webhook{{
+				ID:           fmt.Sprintf("evt-%d", i),
+				RouteID:      "batch",
+				Payload:      []byte(`{"type":"user.created","timestamp":"2024-01-01T12:00:00Z","data":{}}`),
+				DeliveryMode: mode,
+			}}, nil
+		})
+		repo.On("UpdateStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+		repo.On("SetTTL", mock.Anything, mock.Anything, time.Hour).Return(nil).Maybe()
+		repo.On("Acknowledge", mock.Anything, "batch", mode, mock.Anything).Return(nil).Run(func(mock.Arguments) { acked.Add(1) }).Maybe()
+		return repo, &acked
+	}
+
+	t.Run("sequential - processes exactly n", func(t *testing.T) {
+		repo, acked := queueRepo(t, webhook.FIFO, 5)
+		route := &routes.Route{RouteID: "batch", TargetURL: server.URL, Mode: webhook.FIFO}
+
+		processed, err := worker.New(route, repo, worker.NewClient(time.Second)).RunN(context.Background(), 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, processed)
+		assert.Equal(t, int32(3), acked.Load())
+		repo.AssertNumberOfCalls(t, "Consume", 3)
+	})
+
+	t.Run("parallel - processes exactly n", func(t *testing.T) {
+		repo, acked := queueRepo(t, webhook.PubSub, 10)
+		route := &routes.Route{RouteID: "batch", TargetURL: server.URL, Mode: webhook.PubSub, Parallelism: 3}
+
+		processed, err := worker.New(route, repo, worker.NewClient(time.Second)).RunN(context.Background(), 4)
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, processed)
+		// Every delivery completed before RunN returned
+		assert.Equal(t, int32(4), acked.Load())
+		repo.AssertNumberOfCalls(t, "Consume", 4)
+	})
+
+	t.Run("context ends before n", func(t *testing.T) {
+		repo, acked := queueRepo(t, webhook.FIFO, 1)
+		route := &routes.Route{RouteID: "batch", TargetURL: server.URL, Mode: webhook.FIFO}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		processed, err := worker.New(route, repo, worker.NewClient(time.Second)).RunN(ctx, 2)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, processed)
+		assert.Equal(t, int32(1), acked.Load())
+	})
+
+	t.Run("n must be positive", func(t *testing.T) {
+		route := &routes.Route{RouteID: "batch", TargetURL: server.URL, Mode: webhook.FIFO}
+
+		_, err := worker.New(route, mocks.NewRepository(t), nil).RunN(context.Background(), 0)
+
+		assert.ErrorContains(t, err, "n must be positive")
+	})
+}
+
+func TestWorker_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// Ingest a webhook as the API would, capturing what gets stored
+	var stored webhook.Webhook
+	ingestRepo := mocks.NewRepository(t)
+	ingestRepo.On("Store", mock.Anything, mock.Anything).Return("evt-1", nil).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(webhook.Webhook)
+	})
+	service := webhook.NewService(ingestRepo, webhook.WithTracerProvider(provider), webhook.WithIDGenerator(func() string { return "evt-1" }))
+	_, err := service.Receive(context.Background(), "user-events", webhook.FIFO, []byte(`{}`), nil, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, stored.TraceParent)
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	route := &routes.Route{RouteID: "user-events", TargetURL: server.URL, Mode: webhook.FIFO}
+	repo := mocks.NewRepository(t)
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).Return([]webhook.Webhook{stored}, nil).Once()
+	repo.On("Consume", mock.Anything, "user-events", webhook.FIFO).After(5*time.Millisecond).Return([]webhook.Webhook{}, nil).Maybe()
+	repo.On("UpdateStatus", mock.Anything, "evt-1", mock.Anything).Return(nil)
+	repo.On("SetTTL", mock.Anything, "evt-1", mock.Anything).Return(nil)
+	acked := make(chan struct{})
+	repo.On("Acknowledge", mock.Anything, "user-events", webhook.FIFO, "evt-1").Return(nil).Once().Run(func(mock.Arguments) { close(acked) })
+
+	w := worker.New(route, repo, nil, worker.WithTracerProvider(provider))
+	cancel, done := runWorker(t, w)
+
+	var traceparent string
+	select {
+	case traceparent = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	<-acked
+	cancel()
+	require.NoError(t, <-done)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	ingest, consume, deliver := spans["webhook.ingest"], spans["webhook.consume"], spans["webhook.deliver"]
+	require.NotNil(t, ingest)
+	require.NotNil(t, consume)
+	require.NotNil(t, deliver)
+
+	// Delivery is a child of ingestion, in the same trace
+	assert.Equal(t, ingest.SpanContext().TraceID(), deliver.SpanContext().TraceID())
+	assert.Equal(t, ingest.SpanContext().SpanID(), deliver.Parent().SpanID())
+
+	// Consume is linked to the ingestion span of the webhook it read
+	require.Len(t, consume.Links(), 1)
+	assert.Equal(t, ingest.SpanContext().SpanID(), consume.Links()[0].SpanContext.SpanID())
+
+	// The receiver continues the trace from the delivery span
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", deliver.SpanContext().TraceID(), deliver.SpanContext().SpanID()), traceparent)
+
+	require.Len(t, deliver.Events(), 1)
+	assert.Equal(t, "webhook.attempt", deliver.Events()[0].Name)
+}